@@ -6,13 +6,13 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"sync"
 
 	fggrpclog "github.com/forestgiant/grpclog"
 	"github.com/forestgiant/iris/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/grpclog"
 )
@@ -26,27 +26,66 @@ type UpdateHandler func(update *pb.Update)
 
 // Client for communicating with an Iris server
 type Client struct {
-	initialized  bool
-	conn         *grpc.ClientConn
-	rpc          pb.IrisClient
-	listenStream pb.Iris_ListenClient
+	initialized bool
+
+	// conn, rpc and session are replaced together when the client reconnects, and are read through connection,
+	// so that every request is sent with the session of the connection it is made through
+	connMutex sync.RWMutex
+	conn      *grpc.ClientConn
+	rpc       pb.IrisClient
+	session   string
 
-	session             string
-	sourceHandlersMutex *sync.Mutex
-	sourceHandlers      map[string][]*UpdateHandler
-	keyHandlersMutex    *sync.Mutex
-	keyHandlers         map[string]map[string][]*UpdateHandler
+	listenStream pb.Iris_ListenClient
+	listenErr    error
+	listenMutex  *sync.Mutex
+	listenCancel context.CancelFunc
+	closed       bool
+	done         chan struct{}
+
+	// options describe how the client reconnects, where address is the index of the address it is connected to
+	options     *clientOptions
+	dialOptions []grpc.DialOption
+	address     int
+
+	// cluster routes requests between the nodes of a cluster client
+	cluster *cluster
+
+	// reconnecting is set while the client establishes a new session, and reconnectInterrupted is set
+	// if the update stream of the new session ends before the session is established
+	reconnecting         bool
+	reconnectInterrupted bool
+
+	// subscriptions tracks the progress of each subscription, so that it can be resumed
+	subscriptions map[subscription]*subscriptionState
+
+	// watches are ended when the client is closed or their missed updates cannot be replayed, and watchers tracks the
+	// goroutines delivering their events, which Close waits for before ending the client's session
+	watches  map[*watch]struct{}
+	watchers sync.WaitGroup
+
+	heartbeating         string      //the session kept alive by the client's heartbeat, once it holds ephemeral values
+	subscriptionMutex    *sync.Mutex //serializes subscription requests, so that handlers can be read while they are made
+	handleMutex          *sync.Mutex //serializes calls to handlers, so that each handler handles one update at a time
+	sourceHandlersMutex  *sync.Mutex
+	sourceHandlers       map[string][]*UpdateHandler
+	sourceListHandlers   []*UpdateHandler
+	keyHandlersMutex     *sync.Mutex
+	keyHandlers          map[string]map[string][]*UpdateHandler
+	patternHandlersMutex *sync.Mutex
+	patternHandlers      map[subscription][]*UpdateHandler
 }
 
-// NewClient returns a new Iris GRPC client for the given server address.
+// NewClient returns a new Iris GRPC client for the given server address.  If the client's update stream ends,
+// it reconnects and resumes its subscriptions, as described by the client options.
 // The client's Close method should be called when the returned client is no longer needed.
-func NewClient(ctx context.Context, serverAddress string, opts []grpc.DialOption) (*Client, error) {
+func NewClient(ctx context.Context, serverAddress string, opts []grpc.DialOption, options ...ClientOption) (*Client, error) {
 	if len(serverAddress) == 0 {
 		return nil, errors.New("You must provide a server address to connect to")
 	}
 
 	var err error
-	c := &Client{}
+	c := &Client{options: newClientOptions(options)}
+	c.options.addresses = append([]string{serverAddress}, c.options.addresses...)
 
 	if len(opts) == 0 {
 		opts = append(opts, grpc.WithInsecure())
@@ -54,17 +93,27 @@ func NewClient(ctx context.Context, serverAddress string, opts []grpc.DialOption
 
 	opts = append(opts, grpc.FailOnNonTempDialError(true))
 	opts = append(opts, grpc.WithBlock())
+	c.dialOptions = opts
 
 	if c.conn, err = grpc.Dial(serverAddress, opts...); err != nil {
 		return nil, err
 	}
 
 	c.rpc = pb.NewIrisClient(c.conn)
-	resp, err := c.rpc.Connect(ctx, &pb.ConnectRequest{})
+	return c.connect(ctx)
+}
+
+// connect obtains a session and begins listening for its updates
+func (c *Client) connect(ctx context.Context) (*Client, error) {
+	rpc, _ := c.connection()
+	resp, err := rpc.Connect(ctx, &pb.ConnectRequest{})
 	if err != nil {
 		return c, err
 	}
+
+	c.connMutex.Lock()
 	c.session = resp.Session
+	c.connMutex.Unlock()
 
 	if err := c.listen(context.Background()); err != nil {
 		return nil, err
@@ -73,11 +122,18 @@ func NewClient(ctx context.Context, serverAddress string, opts []grpc.DialOption
 	return c, nil
 }
 
+// connection returns the client's rpc client along with the session obtained through it
+func (c *Client) connection() (pb.IrisClient, string) {
+	c.connMutex.RLock()
+	defer c.connMutex.RUnlock()
+	return c.rpc, c.session
+}
+
 // NewTLSClient returns a new Iris GRPC client for the given server address.  You must provide paths to a
 // certificate authority, client certificate, and client private key.  You must also provide a value for
 // server name that matches the common name in the certificate of the server you are connecting to.
 // The client's Close method should be called when the returned client is no longer needed.
-func NewTLSClient(ctx context.Context, serverAddress string, serverName string, cert string, privateKey string, certificateAuthority string) (*Client, error) {
+func NewTLSClient(ctx context.Context, serverAddress string, serverName string, cert string, privateKey string, certificateAuthority string, options ...ClientOption) (*Client, error) {
 	var opts []grpc.DialOption
 	if len(cert) == 0 || len(privateKey) == 0 || len(certificateAuthority) == 0 || len(serverName) == 0 {
 		return nil, errors.New("Insufficient security credentials provided")
@@ -108,7 +164,7 @@ func NewTLSClient(ctx context.Context, serverAddress string, serverName string,
 	})
 
 	opts = append(opts, grpc.WithTransportCredentials(creds))
-	return NewClient(ctx, serverAddress, opts)
+	return NewClient(ctx, serverAddress, opts, options...)
 }
 
 func (c *Client) initialize() {
@@ -117,13 +173,25 @@ func (c *Client) initialize() {
 	}
 
 	c.initialized = true
+	c.listenMutex = &sync.Mutex{}
+	c.done = make(chan struct{})
+	c.subscriptionMutex = &sync.Mutex{}
+	c.handleMutex = &sync.Mutex{}
 	c.sourceHandlersMutex = &sync.Mutex{}
 	c.keyHandlersMutex = &sync.Mutex{}
+	c.patternHandlersMutex = &sync.Mutex{}
 }
 
 //Join the node reachable at the address to this cluster
 func (c *Client) Join(ctx context.Context, address string) error {
-	if _, err := c.rpc.Join(ctx, &pb.JoinRequest{Address: address}); err != nil {
+	return c.JoinWithAddress(ctx, address, "")
+}
+
+//JoinWithAddress joins the node reachable at the raft address to this cluster, recording the grpc address it
+//advertises so that the other nodes can forward requests to it
+func (c *Client) JoinWithAddress(ctx context.Context, address string, grpcAddress string) error {
+	rpc, _ := c.connection()
+	if _, err := rpc.Join(ctx, &pb.JoinRequest{Address: address, GrpcAddress: grpcAddress}); err != nil {
 		return err
 	}
 	return nil
@@ -133,181 +201,250 @@ func (c *Client) Join(ctx context.Context, address string) error {
 func (c *Client) Close() error {
 	c.initialize()
 
+	c.listenMutex.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		defer c.notify(StateDisconnected, nil)
+	}
+	c.listenMutex.Unlock()
+	c.endWatches(nil, nil)
+	c.watchers.Wait()
+
+	c.connMutex.Lock()
 	c.session = ""
+	conn := c.conn
+	c.connMutex.Unlock()
 
 	c.sourceHandlersMutex.Lock()
 	defer c.sourceHandlersMutex.Unlock()
 
 	c.sourceHandlers = nil
+	c.sourceListHandlers = nil
 
 	c.keyHandlersMutex.Lock()
 	defer c.keyHandlersMutex.Unlock()
 
 	c.keyHandlers = nil
 
-	return c.conn.Close()
+	c.patternHandlersMutex.Lock()
+	defer c.patternHandlersMutex.Unlock()
+
+	c.patternHandlers = nil
+
+	if c.cluster != nil {
+		return c.cluster.close()
+	}
+	return conn.Close()
 }
 
 // Listen responds with a stream of objects representing source, key, value updates
 func (c *Client) listen(ctx context.Context) error {
 	c.initialize()
 
+	rpc, session := c.connection()
 	req := &pb.ListenRequest{
-		Session: c.session,
+		Session: session,
 	}
 
-	var err error
-	c.listenStream, err = c.rpc.Listen(ctx, req)
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := rpc.Listen(ctx, req)
 	if err != nil {
+		cancel()
 		return err
 	}
 
+	// Replace the previous update stream, so that it no longer delivers updates
+	c.listenMutex.Lock()
+	previous := c.listenCancel
+	c.listenStream, c.listenCancel = stream, cancel
+	c.listenMutex.Unlock()
+
+	if previous != nil {
+		previous()
+	}
+
 	go func() {
 		for {
-			resp, err := c.listenStream.Recv()
+			resp, err := stream.Recv()
 			if err != nil {
-				if err == io.EOF {
-					break
-				}
-
+				c.interrupted(stream, err)
 				return
 			}
 
-			shs := c.sourceHandlers[resp.Source]
-			khs := c.keyHandlers[resp.Source][resp.Key]
-
-			go func(update *pb.Update, sourceHandlers []*UpdateHandler, keyHandlers []*UpdateHandler) {
-				for _, h := range sourceHandlers {
-					go (*h)(resp)
-				}
+			var handlers []*UpdateHandler
+			for _, s := range c.received(resp) {
+				handlers = append(handlers, c.handlers(s)...)
+			}
 
-				for _, h := range keyHandlers {
-					go (*h)(resp)
-				}
-			}(resp, shs, khs)
+			// Handlers are called in turn, so that updates are handled in the order they were applied
+			c.handleMutex.Lock()
+			for _, h := range handlers {
+				(*h)(resp)
+			}
+			c.handleMutex.Unlock()
 		}
 	}()
 
 	return nil
 }
 
-// GetSources responds with an array of strings representing sources
-func (c *Client) GetSources(ctx context.Context) ([]string, error) {
+// Err returns an error if the server no longer retained the updates missed by some of the client's subscriptions when
+// it last reconnected, which can be determined using IsCompacted, or nil.  When the update stream ends, the client
+// reconnects until it resumes its subscriptions from the revisions they had reached.  A subscription whose missed
+// updates are no longer retained begins again with a snapshot of its current contents, delivered to its handlers as
+// SNAPSHOT updates, while a pattern subscription begins again from the current revision.  Its handlers first receive
+// a RESYNC update carrying the source and key of the subscription, upon which they should discard what they hold for
+// it, since the keys removed while the client reconnected are not reported.  The error is also given to the state
+// handler along with StateConnected.
+func (c *Client) Err() error {
 	c.initialize()
 
-	stream, err := c.rpc.GetSources(ctx, &pb.GetSourcesRequest{
-		Session: c.session,
-	})
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+	return c.listenErr
+}
 
-	if err != nil {
-		return nil, err
-	}
+// IsSlowConsumer indicates whether the error was produced because the server disconnected a client that could
+// not keep up with its updates
+func IsSlowConsumer(err error) bool {
+	return err != nil && grpc.Code(err) == codes.ResourceExhausted
+}
 
-	var sources []string
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+// GetSources responds with an array of strings representing sources, in lexical order
+func (c *Client) GetSources(ctx context.Context, opts ...ReadOption) ([]string, error) {
+	sources, _, err := c.ListSources(ctx, WithReadOptions(opts...))
+	return sources, err
+}
 
-			return nil, err
-		}
-		sources = append(sources, resp.Source)
-	}
+// GetKeys expects a source and responds with an array of strings representing the available keys, in lexical order
+func (c *Client) GetKeys(ctx context.Context, source string, opts ...ReadOption) ([]string, error) {
+	keys, _, err := c.ListKeys(ctx, source, WithReadOptions(opts...))
+	return keys, err
+}
+
+// SetValue sets the value for the specified source and key
+func (c *Client) SetValue(ctx context.Context, source string, key string, value []byte, opts ...SetOption) error {
+	_, err := c.SetValueRevision(ctx, source, key, value, opts...)
+	return err
+}
+
+// SetValueRevision sets the value for the specified source and key and responds with its new revision
+func (c *Client) SetValueRevision(ctx context.Context, source string, key string, value []byte, opts ...SetOption) (uint64, error) {
+	return c.setValue(ctx, source, key, value, pb.Condition_UNCONDITIONAL, 0, opts)
+}
+
+// CompareAndSet sets the value for the specified source and key only if the key is currently at the
+// provided revision, and responds with the new revision.  Use IsRevisionMismatch to determine whether
+// the returned error indicates that the condition was not met.
+func (c *Client) CompareAndSet(ctx context.Context, source string, key string, value []byte, revision uint64, opts ...SetOption) (uint64, error) {
+	return c.setValue(ctx, source, key, value, pb.Condition_REVISION_MATCHES, revision, opts)
+}
 
-	return sources, nil
+// SetIfAbsent sets the value for the specified source and key only if the key does not already exist,
+// and responds with the new revision.  Use IsRevisionMismatch to determine whether the returned error
+// indicates that the key already exists.
+func (c *Client) SetIfAbsent(ctx context.Context, source string, key string, value []byte, opts ...SetOption) (uint64, error) {
+	return c.setValue(ctx, source, key, value, pb.Condition_ABSENT, 0, opts)
 }
 
-// GetKeys expects a source and responds with an array of strings representing the available keys
-func (c *Client) GetKeys(ctx context.Context, source string) ([]string, error) {
+func (c *Client) setValue(ctx context.Context, source string, key string, value []byte, condition pb.Condition, revision uint64, opts []SetOption) (uint64, error) {
 	c.initialize()
 
-	stream, err := c.rpc.GetKeys(ctx, &pb.GetKeysRequest{
-		Session: c.session,
-		Source:  source,
-	})
+	rpc, session := c.connection()
+	req := &pb.SetValueRequest{
+		Session:   session,
+		Source:    source,
+		Key:       key,
+		Value:     value,
+		Condition: condition,
+		Revision:  revision,
+	}
 
-	if err != nil {
-		return nil, err
+	for _, opt := range opts {
+		opt(req)
 	}
 
-	var keys []string
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+	resp, err := rpc.SetValue(ctx, req)
 
-			return nil, err
-		}
-		keys = append(keys, resp.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	if req.Ephemeral {
+		c.heartbeat(req.Session)
 	}
 
-	return keys, nil
+	return resp.Revision, nil
 }
 
-// SetValue sets the value for the specified source and key
-func (c *Client) SetValue(ctx context.Context, source string, key string, value []byte) error {
-	c.initialize()
+// GetValue expects a source and key and responds with the associated value
+func (c *Client) GetValue(ctx context.Context, source string, key string, opts ...ReadOption) ([]byte, error) {
+	value, _, err := c.GetValueRevision(ctx, source, key, opts...)
+	return value, err
+}
 
-	_, err := c.rpc.SetValue(ctx, &pb.SetValueRequest{
-		Session: c.session,
-		Source:  source,
-		Key:     key,
-		Value:   value,
-	})
-	return err
+// GetValueRevision expects a source and key and responds with the associated value and its revision.
+// A revision of zero indicates that the key does not exist.
+func (c *Client) GetValueRevision(ctx context.Context, source string, key string, opts ...ReadOption) ([]byte, uint64, error) {
+	return c.GetValueAt(ctx, source, key, 0, opts...)
 }
 
-// GetValue expects a source and key and responds with the associated value
-func (c *Client) GetValue(ctx context.Context, source string, key string) ([]byte, error) {
+// RemoveValue expects a source and key and removes that entry from the source
+func (c *Client) RemoveValue(ctx context.Context, source string, key string) error {
 	c.initialize()
 
-	resp, err := c.rpc.GetValue(ctx, &pb.GetValueRequest{
-		Session: c.session,
+	rpc, session := c.connection()
+	_, err := rpc.RemoveValue(ctx, &pb.RemoveValueRequest{
+		Session: session,
 		Source:  source,
 		Key:     key,
 	})
-
-	if resp == nil {
-		return nil, err
-	}
-
-	return resp.Value, err
+	return err
 }
 
-// RemoveValue expects a source and key and removes that entry from the source
-func (c *Client) RemoveValue(ctx context.Context, source string, key string) error {
+// CompareAndRemove expects a source and key and removes that entry from the source only if the key is
+// currently at the provided revision.  Use IsRevisionMismatch to determine whether the returned error
+// indicates that the condition was not met.
+func (c *Client) CompareAndRemove(ctx context.Context, source string, key string, revision uint64) error {
 	c.initialize()
 
-	_, err := c.rpc.RemoveValue(ctx, &pb.RemoveValueRequest{
-		Session: c.session,
-		Source:  source,
-		Key:     key,
+	rpc, session := c.connection()
+	_, err := rpc.RemoveValue(ctx, &pb.RemoveValueRequest{
+		Session:   session,
+		Source:    source,
+		Key:       key,
+		Condition: pb.Condition_REVISION_MATCHES,
+		Revision:  revision,
 	})
 	return err
 }
 
+// IsRevisionMismatch indicates whether the error was produced because a conditional write was rejected
+func IsRevisionMismatch(err error) bool {
+	return err != nil && grpc.Code(err) == codes.FailedPrecondition
+}
+
 // RemoveSource removes the specified source and all its values from the server
 func (c *Client) RemoveSource(ctx context.Context, source string) error {
 	c.initialize()
 
-	_, err := c.rpc.RemoveSource(ctx, &pb.RemoveSourceRequest{
-		Session: c.session,
+	rpc, session := c.connection()
+	_, err := rpc.RemoveSource(ctx, &pb.RemoveSourceRequest{
+		Session: session,
 		Source:  source,
 	})
 	return err
 }
 
 // Subscribe indicates that the client wishes to be notified of all updates for the specified source
-func (c *Client) Subscribe(ctx context.Context, source string, handler *UpdateHandler) (*pb.SubscribeResponse, error) {
+func (c *Client) Subscribe(ctx context.Context, source string, handler *UpdateHandler, opts ...SubscribeOption) (*pb.SubscribeResponse, error) {
 	c.initialize()
 
-	c.sourceHandlersMutex.Lock()
-	defer c.sourceHandlersMutex.Unlock()
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
 
+	c.sourceHandlersMutex.Lock()
 	if c.sourceHandlers == nil {
 		c.sourceHandlers = make(map[string][]*UpdateHandler)
 	}
@@ -316,21 +453,45 @@ func (c *Client) Subscribe(ctx context.Context, source string, handler *UpdateHa
 		c.sourceHandlers[source] = []*UpdateHandler{}
 	}
 	c.sourceHandlers[source] = append(c.sourceHandlers[source], handler)
-
-	return c.rpc.Subscribe(ctx, &pb.SubscribeRequest{
-		Session: c.session,
-		Source:  source,
+	c.sourceHandlersMutex.Unlock()
+
+	r := newSubscribeRequest(opts)
+	s := subscription{source: source}
+	tracked := c.subscribing(s, r.revision)
+	rpc, session := c.connection()
+	resp, err := rpc.Subscribe(ctx, &pb.SubscribeRequest{
+		Session:       session,
+		Source:        source,
+		StartRevision: r.revision,
+		Snapshot:      r.snapshot,
 	})
+
+	if err != nil {
+		if tracked {
+			c.unsubscribed(s)
+		}
+
+		c.sourceHandlersMutex.Lock()
+		if c.sourceHandlers != nil {
+			c.sourceHandlers[source] = removeHandler(handler, c.sourceHandlers[source])
+		}
+		c.sourceHandlersMutex.Unlock()
+		return resp, err
+	}
+
+	c.subscribed(s, resp.Revision, r.revision)
+	return resp, nil
 }
 
 // SubscribeKey indicates that the client wishes to be notified of updates associated with
 // a specific key from the specified source
-func (c *Client) SubscribeKey(ctx context.Context, source string, key string, handler *UpdateHandler) (*pb.SubscribeKeyResponse, error) {
+func (c *Client) SubscribeKey(ctx context.Context, source string, key string, handler *UpdateHandler, opts ...SubscribeOption) (*pb.SubscribeKeyResponse, error) {
 	c.initialize()
 
-	c.keyHandlersMutex.Lock()
-	defer c.keyHandlersMutex.Unlock()
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
 
+	c.keyHandlersMutex.Lock()
 	if c.keyHandlers == nil {
 		c.keyHandlers = make(map[string]map[string][]*UpdateHandler)
 	}
@@ -340,33 +501,61 @@ func (c *Client) SubscribeKey(ctx context.Context, source string, key string, ha
 	}
 
 	c.keyHandlers[source][key] = append(c.keyHandlers[source][key], handler)
-
-	return c.rpc.SubscribeKey(ctx, &pb.SubscribeKeyRequest{
-		Session: c.session,
-		Source:  source,
-		Key:     key,
+	c.keyHandlersMutex.Unlock()
+
+	r := newSubscribeRequest(opts)
+	s := subscription{source: source, key: key}
+	tracked := c.subscribing(s, r.revision)
+	rpc, session := c.connection()
+	resp, err := rpc.SubscribeKey(ctx, &pb.SubscribeKeyRequest{
+		Session:       session,
+		Source:        source,
+		Key:           key,
+		StartRevision: r.revision,
+		Snapshot:      r.snapshot,
 	})
+
+	if err != nil {
+		if tracked {
+			c.unsubscribed(s)
+		}
+
+		c.keyHandlersMutex.Lock()
+		if c.keyHandlers != nil && c.keyHandlers[source] != nil {
+			c.keyHandlers[source][key] = removeHandler(handler, c.keyHandlers[source][key])
+		}
+		c.keyHandlersMutex.Unlock()
+		return resp, err
+	}
+
+	c.subscribed(s, resp.Revision, r.revision)
+	return resp, nil
 }
 
 // Unsubscribe indicates that the client no longer wishes to be notified of updates for the specified source
 func (c *Client) Unsubscribe(ctx context.Context, source string, handler *UpdateHandler) (*pb.UnsubscribeResponse, error) {
 	c.initialize()
 
-	c.sourceHandlersMutex.Lock()
-	defer c.sourceHandlersMutex.Unlock()
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
 
+	c.sourceHandlersMutex.Lock()
 	if c.sourceHandlers != nil && c.sourceHandlers[source] != nil {
 		c.sourceHandlers[source] = removeHandler(handler, c.sourceHandlers[source])
 
 		if len(c.sourceHandlers[source]) > 0 {
+			c.sourceHandlersMutex.Unlock()
 			return &pb.UnsubscribeResponse{
 				Source: source,
 			}, nil
 		}
 	}
+	c.sourceHandlersMutex.Unlock()
 
-	return c.rpc.Unsubscribe(ctx, &pb.UnsubscribeRequest{
-		Session: c.session,
+	c.unsubscribed(subscription{source: source})
+	rpc, session := c.connection()
+	return rpc.Unsubscribe(ctx, &pb.UnsubscribeRequest{
+		Session: session,
 		Source:  source,
 	})
 }
@@ -376,27 +565,56 @@ func (c *Client) Unsubscribe(ctx context.Context, source string, handler *Update
 func (c *Client) UnsubscribeKey(ctx context.Context, source string, key string, handler *UpdateHandler) (*pb.UnsubscribeKeyResponse, error) {
 	c.initialize()
 
-	c.keyHandlersMutex.Lock()
-	defer c.keyHandlersMutex.Unlock()
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
 
+	c.keyHandlersMutex.Lock()
 	if c.keyHandlers != nil && c.keyHandlers[source] != nil && c.keyHandlers[source][key] != nil {
 		c.keyHandlers[source][key] = removeHandler(handler, c.keyHandlers[source][key])
 
 		if len(c.keyHandlers[source][key]) > 0 {
+			c.keyHandlersMutex.Unlock()
 			return &pb.UnsubscribeKeyResponse{
 				Source: source,
 				Key:    key,
 			}, nil
 		}
 	}
+	c.keyHandlersMutex.Unlock()
 
-	return c.rpc.UnsubscribeKey(ctx, &pb.UnsubscribeKeyRequest{
-		Session: c.session,
+	c.unsubscribed(subscription{source: source, key: key})
+	rpc, session := c.connection()
+	return rpc.UnsubscribeKey(ctx, &pb.UnsubscribeKeyRequest{
+		Session: session,
 		Source:  source,
 		Key:     key,
 	})
 }
 
+// handlers returns a copy of the handlers of the subscription
+func (c *Client) handlers(s subscription) []*UpdateHandler {
+	var handlers []*UpdateHandler
+	switch {
+	case s.sources:
+		c.sourceHandlersMutex.Lock()
+		handlers = append(handlers, c.sourceListHandlers...)
+		c.sourceHandlersMutex.Unlock()
+	case s.pattern:
+		c.patternHandlersMutex.Lock()
+		handlers = append(handlers, c.patternHandlers[s]...)
+		c.patternHandlersMutex.Unlock()
+	case len(s.key) == 0:
+		c.sourceHandlersMutex.Lock()
+		handlers = append(handlers, c.sourceHandlers[s.source]...)
+		c.sourceHandlersMutex.Unlock()
+	default:
+		c.keyHandlersMutex.Lock()
+		handlers = append(handlers, c.keyHandlers[s.source][s.key]...)
+		c.keyHandlersMutex.Unlock()
+	}
+	return handlers
+}
+
 // RemoveHandler removes the specified handler from the collection
 func removeHandler(handler *UpdateHandler, handlers []*UpdateHandler) []*UpdateHandler {
 	index := -1
@@ -6,12 +6,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/forestgiant/portutil"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
 	"github.com/forestgiant/iris"
 	"github.com/forestgiant/iris/api"
@@ -27,6 +29,8 @@ var testServiceAddress string
 const testColorsSource = "com.forestgiant.iris.testing.colors"
 const testSoundsSource = "com.forestgiant.iris.testing.sounds"
 
+const testSessionTTL = 300 * time.Millisecond
+
 const exitStatusSuccess = 0
 const exitStatusError = 1
 
@@ -70,7 +74,8 @@ func TestMain(m *testing.M) {
 		var opts []grpc.ServerOption
 		grpcServer := grpc.NewServer(opts...)
 		pb.RegisterIrisServer(grpcServer, &transport.Server{
-			Store: store,
+			Store:      store,
+			SessionTTL: testSessionTTL,
 		})
 		errchan := make(chan error)
 		go func() {
@@ -108,6 +113,102 @@ func TestMain(m *testing.M) {
 	os.Exit(main())
 }
 
+// testServer serves a store of its own, so that its options and restarts do not affect the other tests
+type testServer struct {
+	address string
+	dir     string
+	store   *store.Store
+	server  *transport.Server
+	grpc    *grpc.Server
+}
+
+// startTestServer opens a single node store named by name once it leads its cluster, and serves it with the options
+// of the server
+func startTestServer(t *testing.T, name string, server *transport.Server) *testServer {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &testServer{
+		address: uniqueTestAddress(t),
+		dir:     filepath.Join(wd, "com.forestgiant.iris.testing."+name+".raftDir"),
+	}
+	os.RemoveAll(ts.dir)
+
+	ts.store = store.NewStore(uniqueTestAddress(t), ts.dir, fglog.Logger{Writer: &SuppressedWriter{}})
+	if err := ts.store.Open(true); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); !ts.store.IsLeader(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the test server's store to lead its cluster.")
+		}
+	}
+
+	ts.serve(t, server)
+	return ts
+}
+
+// serve serves the store at the test server's address with the options of the server
+func (ts *testServer) serve(t *testing.T, server *transport.Server) {
+	listener, err := net.Listen("tcp", ts.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Store = ts.store
+	ts.server = server
+	ts.grpc = grpc.NewServer()
+	pb.RegisterIrisServer(ts.grpc, server)
+	go ts.grpc.Serve(listener)
+}
+
+// restart stops serving the store, and serves it again at the same address with the options of the server, which
+// begins with an empty event log
+func (ts *testServer) restart(t *testing.T, server *transport.Server) {
+	ts.grpc.Stop()
+	ts.serve(t, server)
+}
+
+// waitPublished waits for the update applied at the revision to be published by the server, even once it is no
+// longer served
+func (ts *testServer) waitPublished(t *testing.T, revision uint64) {
+	ctx := context.Background()
+	resp, err := ts.server.Connect(ctx, &pb.ConnectRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The revision of a subscription is the last update the server published
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		req := &pb.SubscribeRequest{Session: resp.Session, Source: testColorsSource, StartRevision: revision}
+		if sub, err := ts.server.Subscribe(ctx, req); err == nil && sub.Revision >= revision {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the update to be published.")
+		}
+	}
+}
+
+// close stops serving the store and removes its raft directory
+func (ts *testServer) close() {
+	ts.grpc.Stop()
+	os.RemoveAll(ts.dir)
+}
+
+// uniqueTestAddress returns a local address that is not in use
+func uniqueTestAddress(t *testing.T) string {
+	port, err := portutil.GetUniqueTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
 func deleteTestSources() []error {
 	var returnErrors []error
 	for _, source := range []string{testColorsSource, testSoundsSource} {
@@ -242,6 +343,302 @@ func TestSettersAndGetters(t *testing.T) {
 	})
 }
 
+func TestConditionalWrites(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	revision, err := testClient.SetIfAbsent(ctx, testColorsSource, "primary", []byte("red"))
+	if err != nil {
+		t.Error("Error setting absent value.", err)
+		return
+	}
+
+	if _, err := testClient.SetIfAbsent(ctx, testColorsSource, "primary", []byte("blue")); !api.IsRevisionMismatch(err) {
+		t.Error("SetIfAbsent should report a revision mismatch when the key exists.", err)
+		return
+	}
+
+	value, current, err := testClient.GetValueRevision(ctx, testColorsSource, "primary")
+	if err != nil {
+		t.Error("Error getting value.", err)
+		return
+	}
+
+	if string(value) != "red" || current != revision {
+		t.Error("GetValueRevision did not respond with the expected value and revision")
+		return
+	}
+
+	updated, err := testClient.CompareAndSet(ctx, testColorsSource, "primary", []byte("blue"), revision)
+	if err != nil {
+		t.Error("Error comparing and setting value.", err)
+		return
+	}
+
+	if _, err := testClient.CompareAndSet(ctx, testColorsSource, "primary", []byte("green"), revision); !api.IsRevisionMismatch(err) {
+		t.Error("CompareAndSet should report a revision mismatch for a stale revision.", err)
+		return
+	}
+
+	if err := testClient.CompareAndRemove(ctx, testColorsSource, "primary", revision); !api.IsRevisionMismatch(err) {
+		t.Error("CompareAndRemove should report a revision mismatch for a stale revision.", err)
+		return
+	}
+
+	if err := testClient.CompareAndRemove(ctx, testColorsSource, "primary", updated); err != nil {
+		t.Error("Error comparing and removing value.", err)
+		return
+	}
+}
+
+func TestTxn(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan *pb.Update, 2)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		updates <- u
+	}
+
+	if _, err := testClient.Subscribe(ctx, testSoundsSource, &handler); err != nil {
+		t.Error(err)
+		return
+	}
+	defer testClient.Unsubscribe(ctx, testSoundsSource, &handler)
+
+	resp, err := testClient.Txn().
+		IfAbsent(testSoundsSource, "loud").
+		SetValue(testSoundsSource, "loud", []byte("thunder")).
+		SetValue(testSoundsSource, "quiet", []byte("snow")).
+		Commit(ctx)
+	if err != nil {
+		t.Error("Error committing transaction.", err)
+		return
+	}
+
+	if !resp.Succeeded || resp.Revision == 0 {
+		t.Error("Transaction should have succeeded.")
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case u := <-updates:
+			if u.Transaction != resp.Revision {
+				t.Error("Update should be tagged with the transaction identifier.")
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("Transaction updates took too long to be received.")
+			return
+		}
+	}
+
+	resp, err = testClient.Txn().
+		IfAbsent(testSoundsSource, "loud").
+		RemoveSource(testSoundsSource).
+		Commit(ctx)
+	if err != nil {
+		t.Error("Error committing transaction.", err)
+		return
+	}
+
+	if resp.Succeeded {
+		t.Error("Transaction should not have succeeded when a guard is not satisfied.")
+		return
+	}
+
+	value, err := testClient.GetValue(ctx, testSoundsSource, "quiet")
+	if err != nil || string(value) != "snow" {
+		t.Error("Transaction with an unsatisfied guard should not have made changes.")
+	}
+}
+
+func TestLeases(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	removed := make(chan *pb.Update, 1)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		if u.Value == nil {
+			removed <- u
+		}
+	}
+
+	if _, err := testClient.SubscribeKey(ctx, testColorsSource, "fleeting", &handler); err != nil {
+		t.Error(err)
+		return
+	}
+	defer testClient.UnsubscribeKey(ctx, testColorsSource, "fleeting", &handler)
+
+	if err := testClient.SetValue(ctx, testColorsSource, "fleeting", []byte("violet"), api.WithTTL(300*time.Millisecond)); err != nil {
+		t.Error("Error setting value with a ttl.", err)
+		return
+	}
+
+	select {
+	case <-removed:
+	case <-time.After(5 * time.Second):
+		t.Error("Value set with a ttl should be removed once it expires.")
+		return
+	}
+
+	lease, err := testClient.GrantLease(ctx, time.Minute)
+	if err != nil {
+		t.Error("Error granting lease.", err)
+		return
+	}
+
+	if err := testClient.SetValue(ctx, testColorsSource, "leased", []byte("indigo"), api.WithLease(lease)); err != nil {
+		t.Error("Error setting value with a lease.", err)
+		return
+	}
+
+	if ttl, err := testClient.KeepAliveLease(ctx, lease); err != nil || ttl != time.Minute {
+		t.Error("Error keeping lease alive.", err)
+		return
+	}
+
+	if err := testClient.RevokeLease(ctx, lease); err != nil {
+		t.Error("Error revoking lease.", err)
+		return
+	}
+
+	if _, revision, err := testClient.GetValueRevision(ctx, testColorsSource, "leased"); err != nil || revision != 0 {
+		t.Error("Value attached to a revoked lease should be removed.", err)
+		return
+	}
+
+	if _, err := testClient.KeepAliveLease(ctx, lease); !api.IsLeaseNotFound(err) {
+		t.Error("KeepAliveLease should report that a revoked lease was not found.", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := testClient.SetValueRevision(ctx, testColorsSource, "history", []byte("red"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := testClient.SetValueRevision(ctx, testColorsSource, "history", []byte("green"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	value, revision, err := testClient.GetValueAt(ctx, testColorsSource, "history", second-1)
+	if err != nil || string(value) != "red" || revision != first {
+		t.Error("GetValueAt did not respond with the value at the requested revision.", err)
+		return
+	}
+
+	versions, err := testClient.GetHistory(ctx, testColorsSource, "history")
+	if err != nil {
+		t.Error("Error getting history.", err)
+		return
+	}
+
+	if len(versions) != 2 || string(versions[0].Value) != "red" || versions[1].Revision != second {
+		t.Error("GetHistory did not respond with the expected versions.")
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, key := range []string{"blue", "cyan", "green", "red", "teal"} {
+		if err := testClient.SetValue(ctx, testColorsSource, key, []byte(key)); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	var listed []string
+	var pages int
+	continuation := ""
+	for {
+		keys, next, err := testClient.ListKeys(ctx, testColorsSource, api.WithLimit(2), api.WithContinuation(continuation))
+		if err != nil {
+			t.Error("Error listing keys.", err)
+			return
+		}
+
+		pages++
+		listed = append(listed, keys...)
+		if continuation = next; len(continuation) == 0 {
+			break
+		}
+	}
+
+	if pages != 3 || strings.Join(listed, ",") != "blue,cyan,green,red,teal" {
+		t.Error("Paginated listing did not respond with every key in order.", pages, listed)
+	}
+
+	keys, _, err := testClient.ListKeys(ctx, testColorsSource, api.WithPrefix("t"))
+	if err != nil || strings.Join(keys, ",") != "teal" {
+		t.Error("Listing by prefix did not respond with the expected keys.", keys, err)
+	}
+
+	keys, _, err = testClient.ListKeys(ctx, testColorsSource, api.WithRange("c", "red"))
+	if err != nil || strings.Join(keys, ",") != "cyan,green" {
+		t.Error("Listing by range did not respond with the expected keys.", keys, err)
+	}
+
+	sources, _, err := testClient.ListSources(ctx, api.WithPrefix(testColorsSource), api.WithLimit(1))
+	if err != nil || len(sources) != 1 || sources[0] != testColorsSource {
+		t.Error("Listing sources did not respond with the expected source.", sources, err)
+	}
+}
+
+func TestReadConsistency(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	revision, err := testClient.SetValueRevision(ctx, testColorsSource, "blue", []byte("blue"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, opt := range []api.ReadOption{api.WithLinearizableRead(), api.WithStaleRead(time.Minute), api.WithStaleRead(0)} {
+		var index uint64
+		value, err := testClient.GetValue(ctx, testColorsSource, "blue", opt, api.WithAppliedIndex(&index))
+		if err != nil || string(value) != "blue" {
+			t.Error("Read did not respond with the expected value.", string(value), err)
+		}
+
+		if index < revision {
+			t.Error("Read should respond with an applied index including the preceding write.", index, revision)
+		}
+
+		index = 0
+		keys, _, err := testClient.ListKeys(ctx, testColorsSource, api.WithReadOptions(opt, api.WithAppliedIndex(&index)))
+		if err != nil || strings.Join(keys, ",") != "blue" || index < revision {
+			t.Error("Listing did not respond with the expected keys and applied index.", keys, index, err)
+		}
+	}
+
+	if _, err := testClient.GetValue(ctx, testColorsSource, "blue", api.WithStaleRead(-time.Second)); err == nil {
+		t.Error("Read with a negative maximum staleness should fail.")
+	}
+}
+
 func TestSubscriptions(t *testing.T) {
 	t.Run("TestSourceSubscriptions", func(t *testing.T) {
 		deleteTestSources()
@@ -521,34 +918,1095 @@ func TestRemoveSource(t *testing.T) {
 	}
 }
 
-func TestRemoveValue(t *testing.T) {
+func TestUpdateOrder(t *testing.T) {
 	deleteTestSources()
 
-	test := iris.KeyValuePair{Key: "primary", Value: []byte("red")}
-	setContext, cancelSet := context.WithCancel(context.Background())
-	defer cancelSet()
-	if err := testClient.SetValue(setContext, testColorsSource, test.Key, test.Value); err != nil {
-		t.Error("Error setting value.", err)
-		return
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := grpc.Dial(testServiceAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer conn.Close()
 
-	removeContext, cancelRemove := context.WithCancel(context.Background())
-	defer cancelRemove()
-	if err := testClient.RemoveValue(removeContext, testColorsSource, test.Key); err != nil {
-		t.Error("Error removing value for key.", err)
-		return
+	rpc := pb.NewIrisClient(conn)
+	resp, err := rpc.Connect(ctx, &pb.ConnectRequest{})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	getKeysContext, cancelGetKeys := context.WithCancel(context.Background())
-	defer cancelGetKeys()
-	keys, err := testClient.GetKeys(getKeysContext, testColorsSource)
+	stream, err := rpc.Listen(ctx, &pb.ListenRequest{Session: resp.Session})
 	if err != nil {
-		t.Error("Error getting keys for the test source.", err)
-		return
+		t.Fatal(err)
 	}
 
-	if len(keys) > 0 {
-		t.Error("Test should have removed all keys for the test source.")
-		return
+	if _, err := rpc.Subscribe(ctx, &pb.SubscribeRequest{Session: resp.Session, Source: testColorsSource}); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []string
+	for i := 0; i < 50; i++ {
+		value := fmt.Sprintf("value%d", i)
+		if err := testClient.SetValue(ctx, testColorsSource, "key", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := testClient.RemoveValue(ctx, testColorsSource, "key"); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, value, "")
+	}
+
+	for i, e := range expected {
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(update.Value) != e {
+			t.Fatal("Updates should be delivered in the order they were applied.", i, string(update.Value), e)
+		}
+
+		operation, previous := pb.UpdateOperation_SET, ""
+		if i%2 == 1 {
+			operation, previous = pb.UpdateOperation_DELETE_KEY, expected[i-1]
+		}
+
+		if update.Operation != operation || string(update.PreviousValue) != previous || update.Index == 0 || update.Timestamp == 0 {
+			t.Error("Update did not describe the change that was applied.", i, update)
+		}
+	}
+
+	if err := testClient.Err(); err != nil {
+		t.Error("The client's update stream should not have ended.", err)
+	}
+}
+
+func TestSubscribeFromRevision(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var revisions []uint64
+	for _, value := range []string{"red", "green", "blue"} {
+		revision, err := testClient.SetValueRevision(ctx, testColorsSource, "replay", []byte(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	client, err := api.NewClient(ctx, testServiceAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	if _, err := client.SubscribeKey(ctx, testColorsSource, "replay", &handler, api.FromRevision(revisions[1])); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"green", "blue"} {
+		select {
+		case u := <-received:
+			if string(u.Value) != expected {
+				t.Error("Replayed updates should be delivered in the order they were applied.", string(u.Value), expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for replayed updates.")
+		}
+	}
+
+	if revision := client.SubscriptionRevision(testColorsSource, "replay"); revision != revisions[2] {
+		t.Error("The subscription should track the revision of the last update received.", revision, revisions[2])
+	}
+
+	revision, err := testClient.SetValueRevision(ctx, testColorsSource, "replay", []byte("cyan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case u := <-received:
+		if string(u.Value) != "cyan" || u.Index != revision {
+			t.Error("New updates should follow the replayed updates.", string(u.Value))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for new updates.")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Record the client's connections so that they can be severed
+	var connsMutex sync.Mutex
+	var conns []net.Conn
+	dialer := func(address string, timeout time.Duration) (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err == nil {
+			connsMutex.Lock()
+			conns = append(conns, conn)
+			connsMutex.Unlock()
+		}
+		return conn, err
+	}
+
+	states := make(chan api.ConnectionState, 10)
+	stateHandler := func(state api.ConnectionState, err error) {
+		states <- state
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithDialer(dialer), grpc.WithBackoffMaxDelay(50 * time.Millisecond)}
+	client, err := api.NewClient(ctx, testServiceAddress, opts, api.WithReconnectBackoff(10*time.Millisecond, 100*time.Millisecond), api.WithStateHandler(stateHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	if _, err := client.SubscribeKey(ctx, testColorsSource, "reconnect", &handler); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(value string) {
+		select {
+		case u := <-received:
+			if string(u.Value) != value {
+				t.Error("Received an unexpected update.", string(u.Value), value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for an update.", value)
+		}
+	}
+
+	if err := testClient.SetValue(ctx, testColorsSource, "reconnect", []byte("red")); err != nil {
+		t.Fatal(err)
+	}
+	expect("red")
+
+	connsMutex.Lock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	connsMutex.Unlock()
+
+	// Updates applied while the client is reconnecting are delivered once it resumes its subscriptions
+	if err := testClient.SetValue(ctx, testColorsSource, "reconnect", []byte("green")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []api.ConnectionState{api.StateReconnecting, api.StateConnected} {
+		select {
+		case state := <-states:
+			if state != expected {
+				t.Error("The client reported an unexpected connection state.", state, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the client to reconnect.", expected)
+		}
+	}
+	expect("green")
+
+	if err := testClient.SetValue(ctx, testColorsSource, "reconnect", []byte("blue")); err != nil {
+		t.Fatal(err)
+	}
+	expect("blue")
+
+	if err := client.Err(); err != nil {
+		t.Error("The client should have resumed its subscriptions.", err)
+	}
+
+	client.Close()
+	select {
+	case state := <-states:
+		if state != api.StateDisconnected {
+			t.Error("Closing the client should report that it is disconnected.", state)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for the client to report that it is disconnected.")
+	}
+}
+
+func TestResumeAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := startTestServer(t, "restart", &transport.Server{})
+	defer server.close()
+
+	type stateChange struct {
+		state api.ConnectionState
+		err   error
+	}
+
+	changes := make(chan stateChange, 10)
+	stateHandler := func(state api.ConnectionState, err error) {
+		changes <- stateChange{state, err}
+	}
+
+	client, err := api.NewClient(ctx, server.address, nil, api.WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond), api.WithStateHandler(stateHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	if _, err := client.SubscribeKey(ctx, testColorsSource, "restart", &handler); err != nil {
+		t.Fatal(err)
+	}
+
+	patternEvents, err := client.WatchPattern(ctx, testColorsSource, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyEvents, err := client.WatchKey(ctx, testColorsSource, "restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(value string, operation pb.UpdateOperation) {
+		select {
+		case u := <-received:
+			if string(u.Value) != value || u.Operation != operation || u.Source != testColorsSource || u.Key != "restart" {
+				t.Error("Received an unexpected update.", string(u.Value), u.Operation, value, operation)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for an update.", value)
+		}
+	}
+
+	if err := client.SetValue(ctx, testColorsSource, "restart", []byte("red")); err != nil {
+		t.Fatal(err)
+	}
+	expect("red", pb.UpdateOperation_SET)
+
+	select {
+	case e := <-keyEvents:
+		if e.Update == nil || string(e.Update.Value) != "red" {
+			t.Error("The watch should receive the update.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the watch to receive the update.")
+	}
+
+	// The restarted server no longer retains the update applied while it was stopped
+	server.grpc.Stop()
+	revision, err := server.store.Set(testColorsSource, "restart", []byte("green"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.waitPublished(t, revision)
+	server.restart(t, &transport.Server{})
+
+	for _, expected := range []api.ConnectionState{api.StateReconnecting, api.StateConnected} {
+		select {
+		case change := <-changes:
+			if change.state != expected {
+				t.Error("The client reported an unexpected connection state.", change.state, expected)
+			}
+
+			if change.state == api.StateConnected && !api.IsCompacted(change.err) {
+				t.Error("The client should report that the missed updates are no longer retained.", change.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the client to reconnect.", expected)
+		}
+	}
+
+	// The subscription begins again with a snapshot, preceded by a marker telling the handlers to discard their state,
+	// and followed by new updates
+	expect("", pb.UpdateOperation_RESYNC)
+	expect("green", pb.UpdateOperation_SNAPSHOT)
+
+	if err := client.SetValue(ctx, testColorsSource, "restart", []byte("blue")); err != nil {
+		t.Fatal(err)
+	}
+	expect("blue", pb.UpdateOperation_SET)
+
+	if !api.IsCompacted(client.Err()) {
+		t.Error("Err should report that the missed updates are no longer retained.", client.Err())
+	}
+
+	// The watch reports the interruption, followed by the resync and snapshot once the client reconnects
+	var interrupted bool
+	for _, expected := range []pb.UpdateOperation{pb.UpdateOperation_RESYNC, pb.UpdateOperation_SNAPSHOT, pb.UpdateOperation_SET} {
+		var e *api.WatchEvent
+		for e == nil {
+			select {
+			case e = <-keyEvents:
+				if e.Update == nil {
+					interrupted = interrupted || e.Err != nil
+					e = nil
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the watch to resume.", expected)
+			}
+		}
+
+		if e.Update.Operation != expected || (expected == pb.UpdateOperation_RESYNC) != api.IsCompacted(e.Err) {
+			t.Error("The watch received an unexpected event.", e.Update.Operation, e.Err, expected)
+		}
+	}
+
+	if !interrupted {
+		t.Error("The watch should report that the client's update stream was interrupted.")
+	}
+
+	// A pattern watch has no snapshot, so it ends with the error following the interruption and resync
+	var events []*api.WatchEvent
+	for e := range patternEvents {
+		events = append(events, e)
+	}
+
+	if len(events) < 4 || events[1].Update != nil || events[1].Err == nil {
+		t.Fatal("A pattern watch should report that the client's update stream was interrupted.", events)
+	}
+
+	var resynced bool
+	for _, e := range events[2:] {
+		resynced = resynced || (e.Update != nil && e.Update.Operation == pb.UpdateOperation_RESYNC && api.IsCompacted(e.Err))
+	}
+
+	if !resynced {
+		t.Error("A pattern watch should receive a resync when its missed updates are no longer retained.", events)
+	}
+
+	last := events[len(events)-1]
+	if last.Update != nil || !api.IsCompacted(last.Err) {
+		t.Error("A pattern watch should end when its missed updates are no longer retained.", last)
+	}
+}
+
+func TestClusterClient(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// No node is listening at the first address, so the client must use the second
+	port, err := portutil.GetUniqueTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := fmt.Sprintf("127.0.0.1:%d", port)
+
+	client, err := api.NewClusterClient(ctx, []string{unreachable, testServiceAddress}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	if _, err := client.SubscribeKey(ctx, testColorsSource, "cluster", &handler); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		value := fmt.Sprintf("value%d", i)
+		if err := client.SetValue(ctx, testColorsSource, "cluster", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := client.GetValue(ctx, testColorsSource, "cluster", api.WithStaleRead(0)); err != nil || string(got) != value {
+			t.Error("The cluster client should read from a reachable node.", string(got), err)
+		}
+
+		select {
+		case u := <-received:
+			if string(u.Value) != value {
+				t.Error("Received an unexpected update.", string(u.Value), value)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for an update.")
+		}
+	}
+
+	if _, err := api.NewClusterClient(ctx, []string{unreachable}, nil); err == nil {
+		t.Error("NewClusterClient should fail when no node can be reached.")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	sourceEvents, err := testClient.Watch(watchCtx, testColorsSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyEvents, err := testClient.WatchKey(watchCtx, testColorsSource, "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"red", "green", "blue", "cyan", "teal"}
+	for _, value := range expected {
+		if err := testClient.SetValue(ctx, testColorsSource, "watch", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Events are delivered in order even while another watch is not being received from
+	for _, events := range []<-chan *api.WatchEvent{keyEvents, sourceEvents} {
+		for _, value := range expected {
+			select {
+			case e := <-events:
+				if e.Err != nil || string(e.Update.Value) != value {
+					t.Error("Watch did not deliver the updates in the order they were applied.", e.Err, value)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for a watch event.")
+			}
+		}
+	}
+
+	cancelWatch()
+	for _, events := range []<-chan *api.WatchEvent{keyEvents, sourceEvents} {
+		select {
+		case _, ok := <-events:
+			if ok {
+				t.Error("The watch should end without further events when its context is done.")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the watch to end.")
+		}
+	}
+}
+
+func TestWatchClose(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := api.NewClient(ctx, testServiceAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.Watch(ctx, testColorsSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Close must not wait for the queued events of a watch that is not being received from
+	for _, value := range []string{"red", "green", "blue"} {
+		if err := testClient.SetValue(ctx, testColorsSource, "close", []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- client.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the client to close.")
+	}
+
+	for range events {
+	}
+
+	if _, err := client.Watch(ctx, testColorsSource); err == nil {
+		t.Error("A closed client should not start a watch.")
+	}
+}
+
+func TestWatchPattern(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	patternEvents, err := testClient.WatchPattern(ctx, "com.forestgiant.iris.testing.*", "p*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firehoseEvents, err := testClient.WatchPattern(ctx, "*", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyEvents, err := testClient.WatchKey(ctx, testColorsSource, "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testClient.SubscribePattern(ctx, "", "", nil); err == nil {
+		t.Error("SubscribePattern should require a source pattern.")
+	}
+
+	updates := []*pb.Update{
+		{Source: testColorsSource, Key: "primary", Value: []byte("red")},
+		{Source: testSoundsSource, Key: "pitch", Value: []byte("high")},
+		{Source: testColorsSource, Key: "secondary", Value: []byte("green")},
+		{Source: testSoundsSource, Key: "piano", Value: []byte("loud")},
+	}
+	for _, u := range updates {
+		if err := testClient.SetValue(ctx, u.Source, u.Key, u.Value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		events   <-chan *api.WatchEvent
+		expected []*pb.Update
+	}{
+		{events: patternEvents, expected: []*pb.Update{updates[0], updates[1], updates[3]}},
+		{events: firehoseEvents, expected: updates},
+		{events: keyEvents, expected: []*pb.Update{updates[0]}},
+	}
+
+	for i, test := range tests {
+		for _, u := range test.expected {
+			select {
+			case e := <-test.events:
+				if e.Err != nil || e.Update.Source != u.Source || e.Update.Key != u.Key || string(e.Update.Value) != string(u.Value) {
+					t.Error("Test", i, "- The watch did not deliver the matching updates in order.", e.Err, u.Key)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Test", i, "- Timed out waiting for a watch event.")
+			}
+		}
+
+		select {
+		case e := <-test.events:
+			t.Error("Test", i, "- The watch delivered an update that does not match.", e.Update)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestWatchSources(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := testClient.WatchSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testClient.SetValue(ctx, testColorsSource, "primary", []byte("red")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testClient.SetValue(ctx, testColorsSource, "secondary", []byte("green")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testClient.RemoveValue(ctx, testColorsSource, "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testClient.RemoveSource(ctx, testColorsSource); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first key set and the last key removed change the list of sources
+	for _, expected := range []pb.UpdateOperation{pb.UpdateOperation_SOURCE_CREATED, pb.UpdateOperation_SOURCE_DELETED} {
+		select {
+		case e := <-events:
+			if e.Err != nil || e.Update.Source != testColorsSource || len(e.Update.Key) > 0 || e.Update.Operation != expected {
+				t.Error("The watch did not deliver the change to the list of sources.", e.Err, e.Update, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a source event.")
+		}
+	}
+
+	select {
+	case e := <-events:
+		t.Error("The watch delivered an update that does not change the list of sources.", e.Update)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := testClient.SetValue(ctx, testSoundsSource, "bark", []byte("woof")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the new source to be published before subscribing, so the handler only receives the snapshot
+	select {
+	case e := <-events:
+		if e.Err != nil || e.Update.Source != testSoundsSource || e.Update.Operation != pb.UpdateOperation_SOURCE_CREATED {
+			t.Error("The watch did not deliver the creation of the source.", e.Err, e.Update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a source event.")
+	}
+
+	received := make(chan *pb.Update, 100)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	resp, err := testClient.SubscribeSources(ctx, &handler, api.WithSnapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.UnsubscribeSources(ctx, &handler)
+
+	var found bool
+	for i := uint32(0); i < resp.SnapshotSize; i++ {
+		select {
+		case u := <-received:
+			if u.Operation != pb.UpdateOperation_SNAPSHOT || len(u.Key) > 0 {
+				t.Error("The snapshot should describe each source without a key.", u)
+			}
+			found = found || u.Source == testSoundsSource
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the snapshot of the sources.")
+		}
+	}
+
+	if !found {
+		t.Error("The snapshot did not include a source that exists.")
+	}
+}
+
+func TestEphemeralValues(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// waitRemoved waits for the key to be removed by the cluster
+	waitRemoved := func(key string) bool {
+		deadline := time.Now().Add(3 * testSessionTTL)
+		for time.Now().Before(deadline) {
+			if _, revision, err := testClient.GetValueRevision(ctx, testColorsSource, key); err == nil && revision == 0 {
+				return true
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return false
+	}
+
+	client, err := api.NewClient(ctx, testServiceAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetValue(ctx, testColorsSource, "presence", []byte("here"), api.Ephemeral(), api.WithTTL(time.Minute)); err == nil {
+		t.Error("An ephemeral value should not be given a ttl.")
+	}
+
+	if err := client.SetValue(ctx, testColorsSource, "presence", []byte("here"), api.Ephemeral()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client's heartbeat keeps its session alive beyond the time-to-live
+	time.Sleep(2 * testSessionTTL)
+	if value, err := testClient.GetValue(ctx, testColorsSource, "presence"); err != nil || string(value) != "here" {
+		t.Error("The ephemeral value should remain while the session is alive.", err, string(value))
+	}
+
+	client.Close()
+	if !waitRemoved("presence") {
+		t.Error("The ephemeral value should be removed when its session ends.")
+	}
+
+	// A session that does not heartbeat expires along with its ephemeral values
+	conn, err := grpc.Dial(testServiceAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rpc := pb.NewIrisClient(conn)
+	resp, err := rpc.Connect(ctx, &pb.ConnectRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &pb.SetValueRequest{Session: resp.Session, Source: testColorsSource, Key: "lapsed", Value: []byte("here"), Ephemeral: true}
+	if _, err := rpc.SetValue(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if !waitRemoved("lapsed") {
+		t.Error("The ephemeral value should be removed when its session fails to heartbeat.")
+	}
+}
+
+func TestSessionLeaseExpired(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := startTestServer(t, "sessionLease", &transport.Server{SessionTTL: testSessionTTL})
+	defer server.close()
+
+	type stateChange struct {
+		state api.ConnectionState
+		err   error
+	}
+
+	changes := make(chan stateChange, 10)
+	stateHandler := func(state api.ConnectionState, err error) {
+		changes <- stateChange{state, err}
+	}
+
+	client, err := api.NewClient(ctx, server.address, nil, api.WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond), api.WithStateHandler(stateHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	revision, err := client.SetValueRevision(ctx, testColorsSource, "presence", []byte("here"), api.Ephemeral())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The session's lease is granted through raft immediately before its first ephemeral value is set
+	if err := client.RevokeLease(ctx, revision-1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case change := <-changes:
+		if change.state != api.StateReconnecting || grpc.ErrorDesc(change.err) != grpc.ErrorDesc(transport.ErrSessionLeaseExpired) {
+			t.Error("The session should end once its lease no longer exists.", change.state, change.err)
+		}
+	case <-time.After(3 * testSessionTTL):
+		t.Fatal("Timed out waiting for the session to end.")
+	}
+}
+
+func TestSubscribeWithSnapshot(t *testing.T) {
+	deleteTestSources()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var last uint64
+	for _, key := range []string{"blue", "green", "red"} {
+		revision, err := testClient.SetValueRevision(ctx, testColorsSource, key, []byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = revision
+	}
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	resp, err := testClient.SubscribeWithSnapshot(ctx, testColorsSource, &handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testClient.Unsubscribe(ctx, testColorsSource, &handler)
+
+	if resp.SnapshotSize != 3 || resp.Revision < last {
+		t.Error("The subscription should report the size and revision of its snapshot.", resp.SnapshotSize, resp.Revision)
+	}
+
+	if _, err := testClient.SetValueRevision(ctx, testColorsSource, "teal", []byte("teal")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot precedes every update applied after it
+	for _, expected := range []string{"blue", "green", "red", "teal"} {
+		select {
+		case u := <-received:
+			operation := pb.UpdateOperation_SNAPSHOT
+			if expected == "teal" {
+				operation = pb.UpdateOperation_SET
+			}
+
+			if u.Key != expected || string(u.Value) != expected || u.Operation != operation {
+				t.Error("Received an unexpected update.", u.Key, u.Operation, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for an update.", expected)
+		}
+	}
+
+	if _, err := testClient.SubscribeKey(ctx, testColorsSource, "red", &handler, api.WithSnapshot(), api.FromRevision(last)); err == nil {
+		t.Error("A subscription should not both begin with a snapshot and replay updates.")
+	}
+}
+
+func TestSnapshotQueueSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := startTestServer(t, "snapshotQueueSize", &transport.Server{QueueSize: 2, SlowConsumer: transport.SlowConsumerDropOldest})
+	defer server.close()
+
+	client, err := api.NewClient(ctx, server.address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, key := range []string{"blue", "green", "red"} {
+		if err := client.SetValue(ctx, testColorsSource, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := make(chan *pb.Update, 10)
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		received <- u
+	}
+
+	// A snapshot that would be truncated by the session's queue fails the subscription instead
+	if _, err := client.SubscribeWithSnapshot(ctx, testColorsSource, &handler); grpc.Code(err) != codes.ResourceExhausted {
+		t.Fatal("A snapshot larger than the session's queue should exhaust it.", err)
+	}
+
+	if err := client.RemoveValue(ctx, testColorsSource, "red"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case u := <-received:
+		t.Error("A failed subscription should not receive updates.", u)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resp, err := client.SubscribeWithSnapshot(ctx, testColorsSource, &handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Unsubscribe(ctx, testColorsSource, &handler)
+
+	if resp.SnapshotSize != 2 {
+		t.Error("A snapshot that fits in the session's queue should be delivered.", resp.SnapshotSize)
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Slow consumers are disconnected unless the server specifies otherwise
+	server := startTestServer(t, "slowConsumer", &transport.Server{QueueSize: 2})
+	defer server.close()
+
+	interrupted := make(chan error, 10)
+	stateHandler := func(state api.ConnectionState, err error) {
+		if state == api.StateReconnecting {
+			interrupted <- err
+		}
+	}
+
+	client, err := api.NewClient(ctx, server.address, nil, api.WithStateHandler(stateHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The handler holds up the client's update stream until released
+	release := make(chan struct{})
+	var handler api.UpdateHandler = func(u *pb.Update) {
+		<-release
+	}
+
+	if _, err := client.Subscribe(ctx, testColorsSource, &handler); err != nil {
+		t.Fatal(err)
+	}
+
+	value := make([]byte, 32*1024)
+	for i := 0; i < 50; i++ {
+		if err := client.SetValue(ctx, testColorsSource, "slow", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+
+	select {
+	case err := <-interrupted:
+		if !api.IsSlowConsumer(err) {
+			t.Error("A client that cannot keep up with its updates should be disconnected.", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the slow consumer to be disconnected.")
+	}
+}
+
+func TestRemoveValue(t *testing.T) {
+	deleteTestSources()
+
+	test := iris.KeyValuePair{Key: "primary", Value: []byte("red")}
+	setContext, cancelSet := context.WithCancel(context.Background())
+	defer cancelSet()
+	if err := testClient.SetValue(setContext, testColorsSource, test.Key, test.Value); err != nil {
+		t.Error("Error setting value.", err)
+		return
+	}
+
+	removeContext, cancelRemove := context.WithCancel(context.Background())
+	defer cancelRemove()
+	if err := testClient.RemoveValue(removeContext, testColorsSource, test.Key); err != nil {
+		t.Error("Error removing value for key.", err)
+		return
+	}
+
+	getKeysContext, cancelGetKeys := context.WithCancel(context.Background())
+	defer cancelGetKeys()
+	keys, err := testClient.GetKeys(getKeysContext, testColorsSource)
+	if err != nil {
+		t.Error("Error getting keys for the test source.", err)
+		return
+	}
+
+	if len(keys) > 0 {
+		t.Error("Test should have removed all keys for the test source.")
+		return
+	}
+}
+
+func TestClusterMembership(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	peers, err := testClient.ListPeers(ctx)
+	if err != nil || len(peers) != 1 {
+		t.Fatal("The test server should be the only peer of its cluster.", peers, err)
+	}
+
+	status, err := testClient.ClusterStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Leader != peers[0] || status.Address != peers[0] || status.AppliedIndex == 0 {
+		t.Error("The cluster status should describe the test server as the leader.", status)
+	}
+
+	if len(status.Peers) != 1 || status.Peers[0].Address != peers[0] || status.Peers[0].State != store.PeerStateLeader {
+		t.Error("The cluster status should describe the state of each peer.", status.Peers)
+	}
+
+	if err := testClient.RemovePeer(ctx, ""); err == nil {
+		t.Error("RemovePeer should require an address.")
+	}
+
+	// The last member of a cluster remains its leader after leaving
+	if err := testClient.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := testClient.ClusterStatus(ctx); err != nil || status.State != store.PeerStateLeader {
+		t.Error("The last member of a cluster should not leave it.", status, err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type stateChange struct {
+		state api.ConnectionState
+		err   error
+	}
+
+	states := make(chan stateChange, 10)
+	stateHandler := func(state api.ConnectionState, err error) {
+		states <- stateChange{state: state, err: err}
+	}
+
+	client, err := api.NewClient(ctx, testServiceAddress, nil, api.WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond), api.WithStateHandler(stateHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	sessions, err := client.Drain(ctx)
+	if err != nil || sessions == 0 {
+		t.Fatal("Drain should end the sessions held by the node.", sessions, err)
+	}
+
+	select {
+	case change := <-states:
+		if change.state != api.StateReconnecting || !api.IsDraining(change.err) {
+			t.Error("The update stream should end with the draining status.", change.state, change.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the update stream to end.")
+	}
+
+	drained, err := api.NewClient(ctx, testServiceAddress, nil)
+	if !api.IsDraining(err) || drained == nil {
+		t.Fatal("A draining node should refuse new sessions.", err)
+	}
+	defer drained.Close()
+
+	// Requests without a session are still served
+	if err := drained.SetValue(ctx, testColorsSource, "drain", []byte("red")); err != nil {
+		t.Error("A draining node should serve requests made without a session.", err)
+	}
+
+	if _, err := drained.TransferLeadership(ctx, ""); err == nil {
+		t.Error("TransferLeadership should require an address.")
+	}
+
+	conn, err := grpc.Dial(testServiceAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if status, err := pb.NewIrisClient(conn).NodeStatus(ctx, &pb.NodeStatusRequest{}); err != nil || status.Ready {
+		t.Error("A draining node should not report that it is ready.", status, err)
+	}
+
+	if err := drained.Undrain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := pb.NewIrisClient(conn).NodeStatus(ctx, &pb.NodeStatusRequest{}); err != nil || !status.Ready {
+		t.Error("A node should report that it is ready once it stops draining.", status, err)
+	}
+
+	// The client resumes once the node accepts sessions again
+	for {
+		select {
+		case change := <-states:
+			if change.state == api.StateConnected {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for the client to reconnect.")
+		}
 	}
 }
@@ -0,0 +1,548 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"github.com/forestgiant/stela"
+	stela_api "github.com/forestgiant/stela/api"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Timing of the health checks made by cluster clients when the client does not specify otherwise
+const (
+	DefaultHealthCheckInterval = 5 * time.Second
+	DefaultHealthCheckTimeout  = time.Second
+)
+
+// NewClusterClient returns a new Iris GRPC client for the nodes of a cluster reachable at the given addresses.
+// Requests are sent to a healthy node, failing over to another node when a node cannot be reached.  Reads are
+// retried by another node when the node becomes unavailable, while writes are only retried when they could not be
+// sent, since the node may have applied them.  Writes and reads requiring the leader are sent directly to the leader
+// when it is known, while stale reads are spread across the followers.  The client's update stream is held by a
+// single node, and moves to another node if it ends.  The client's Close method should be called when the returned
+// client is no longer needed.
+func NewClusterClient(ctx context.Context, addresses []string, opts []grpc.DialOption, options ...ClientOption) (*Client, error) {
+	c := &Client{options: newClientOptions(options)}
+
+	// Additional addresses are served by the cluster, rather than being failed over to by the client
+	addresses = append(addresses, c.options.addresses...)
+	c.options.addresses = nil
+
+	if len(addresses) == 0 {
+		return nil, errors.New("You must provide the addresses of the cluster's nodes")
+	}
+
+	if len(opts) == 0 {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	c.dialOptions = opts
+
+	var err error
+	if c.cluster, err = dialCluster(ctx, addresses, opts); err != nil {
+		return nil, err
+	}
+
+	c.rpc = c.cluster
+	return c.connect(ctx)
+}
+
+// NewStelaClusterClient returns a new Iris GRPC client for the nodes of a cluster registered with stela under
+// the service name, as described by NewClusterClient
+func NewStelaClusterClient(ctx context.Context, stela *stela_api.Client, serviceName string, opts []grpc.DialOption, options ...ClientOption) (*Client, error) {
+	services, err := stela.Discover(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, service := range services {
+		addresses = append(addresses, ServiceAddress(service))
+	}
+
+	if len(addresses) == 0 {
+		return nil, errors.New("No nodes are registered with stela under the service name " + serviceName)
+	}
+	return NewClusterClient(ctx, addresses, opts, options...)
+}
+
+// ServiceAddress returns the address of the service registered with stela, preferring its IPv4 address over its
+// IPv6 address, in a form suitable for dialing where IPv6 addresses are enclosed in brackets
+func ServiceAddress(service *stela.Service) string {
+	host := service.IPv4
+	if len(host) == 0 {
+		host = service.IPv6
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(service.Port)))
+}
+
+// clusterNode describes a connection to a node of the cluster
+type clusterNode struct {
+	address string
+	conn    *grpc.ClientConn
+	rpc     pb.IrisClient
+	healthy bool
+	leader  bool
+}
+
+// cluster routes the requests of a cluster client between the nodes of the cluster
+type cluster struct {
+	mu      sync.Mutex
+	nodes   []*clusterNode
+	next    int          //used to spread stale reads and sessions across the nodes
+	session *clusterNode //node holding the client's session
+	checks  bool         //set while health checks are being made
+	done    chan struct{}
+}
+
+// dialCluster connects to each of the addresses, and waits until the health of every node is known.
+// An error is returned unless at least one node is healthy.
+func dialCluster(ctx context.Context, addresses []string, opts []grpc.DialOption) (*cluster, error) {
+	cl := &cluster{done: make(chan struct{})}
+	for _, address := range addresses {
+		conn, err := grpc.Dial(address, opts...)
+		if err != nil {
+			cl.close()
+			return nil, err
+		}
+		cl.nodes = append(cl.nodes, &clusterNode{address: address, conn: conn, rpc: pb.NewIrisClient(conn)})
+	}
+
+	cl.check(ctx)
+	if cl.pick(nil, true, false) == nil {
+		cl.close()
+		return nil, errors.New("None of the cluster's nodes could be reached")
+	}
+
+	go cl.monitor(DefaultHealthCheckInterval)
+	return cl, nil
+}
+
+// close tears down the connections to every node
+func (cl *cluster) close() error {
+	cl.mu.Lock()
+	select {
+	case <-cl.done:
+	default:
+		close(cl.done)
+	}
+	cl.mu.Unlock()
+
+	var err error
+	for _, node := range cl.nodes {
+		if closeErr := node.conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// monitor checks the health of every node at the interval until the cluster is closed
+func (cl *cluster) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cl.check(context.Background())
+		case <-cl.done:
+			return
+		}
+	}
+}
+
+// check requests the status of every node, recording which nodes are healthy and which node is the leader.
+// A node that is draining its sessions is not healthy, so that requests prefer the other nodes.
+func (cl *cluster) check(ctx context.Context) {
+	cl.mu.Lock()
+	if cl.checks {
+		cl.mu.Unlock()
+		return
+	}
+	cl.checks = true
+	cl.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, node := range cl.nodes {
+		wg.Add(1)
+		go func(node *clusterNode) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, DefaultHealthCheckTimeout)
+			defer cancel()
+
+			resp, err := node.rpc.NodeStatus(checkCtx, &pb.NodeStatusRequest{})
+
+			cl.mu.Lock()
+			defer cl.mu.Unlock()
+			node.healthy = err == nil && resp.Ready
+			node.leader = err == nil && resp.Leader
+		}(node)
+	}
+	wg.Wait()
+
+	cl.mu.Lock()
+	cl.checks = false
+	cl.mu.Unlock()
+}
+
+// pick returns a node that has not been tried, preferring healthy nodes.  The leader is preferred if leader is set,
+// and otherwise the followers are taken in turn.  Nil is returned if every node has been tried, or if no node is
+// healthy when healthy is set.
+func (cl *cluster) pick(tried map[*clusterNode]bool, healthy bool, leader bool) *clusterNode {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var candidates, followers []*clusterNode
+	for _, node := range cl.nodes {
+		if tried[node] || !node.healthy {
+			continue
+		}
+
+		if leader && node.leader {
+			return node
+		}
+
+		candidates = append(candidates, node)
+		if !node.leader {
+			followers = append(followers, node)
+		}
+	}
+
+	if len(followers) > 0 {
+		candidates = followers
+	}
+
+	// Fall back to the nodes that are not known to be healthy
+	if len(candidates) == 0 && !healthy {
+		for _, node := range cl.nodes {
+			if !tried[node] {
+				candidates = append(candidates, node)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if leader {
+		return candidates[0]
+	}
+
+	cl.next++
+	return candidates[cl.next%len(candidates)]
+}
+
+// unreachable records that the node could not be reached, and checks the health of the cluster
+// in case a new leader has been elected
+func (cl *cluster) unreachable(node *clusterNode) {
+	cl.mu.Lock()
+	node.healthy, node.leader = false, false
+	cl.mu.Unlock()
+
+	go cl.check(context.Background())
+}
+
+// unsentMessages describe the errors returned when a request could not be sent because the connection to the node
+// was not established or was closing
+var unsentMessages = map[string]bool{
+	"grpc: the connection is unavailable": true,
+	"grpc: the connection is closing":     true,
+	"there is no address available":       true,
+}
+
+// unsent indicates whether the request failed before it was sent to the node
+func unsent(err error) bool {
+	return grpc.Code(err) == codes.Unavailable && unsentMessages[grpc.ErrorDesc(err)]
+}
+
+// call sends the request to the nodes chosen by pick in turn, until a node can be reached.  A node that is too far
+// behind the leader to serve a stale read is also unavailable, so the read is retried by another node.  Requests
+// that are not idempotent are only retried when they were not sent, since a node that became unavailable while
+// handling the request may already have applied it.
+func (cl *cluster) call(leader bool, idempotent bool, fn func(rpc pb.IrisClient) error) error {
+	tried := make(map[*clusterNode]bool)
+	for {
+		node := cl.pick(tried, false, leader)
+		if node == nil {
+			return grpc.Errorf(codes.Unavailable, "None of the cluster's nodes could be reached")
+		}
+		tried[node] = true
+
+		err := fn(node.rpc)
+		if grpc.Code(err) != codes.Unavailable {
+			return err
+		}
+		cl.unreachable(node)
+
+		if !idempotent && !unsent(err) {
+			return err
+		}
+	}
+}
+
+// sessionNode returns the node holding the client's session
+func (cl *cluster) sessionNode() pb.IrisClient {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.session == nil {
+		return cl.nodes[0].rpc
+	}
+	return cl.session.rpc
+}
+
+// stale indicates whether a read may be served by any node
+func stale(consistency pb.ReadConsistency) bool {
+	return consistency == pb.ReadConsistency_STALE
+}
+
+//Join is sent to the leader
+func (cl *cluster) Join(ctx context.Context, in *pb.JoinRequest, opts ...grpc.CallOption) (resp *pb.JoinResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.Join(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//RemovePeer is sent to the leader
+func (cl *cluster) RemovePeer(ctx context.Context, in *pb.RemovePeerRequest, opts ...grpc.CallOption) (resp *pb.RemovePeerResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.RemovePeer(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//Leave is sent to the node holding the session, which leaves the cluster
+func (cl *cluster) Leave(ctx context.Context, in *pb.LeaveRequest, opts ...grpc.CallOption) (*pb.LeaveResponse, error) {
+	return cl.sessionNode().Leave(ctx, in, opts...)
+}
+
+//TransferLeadership is sent to the leader
+func (cl *cluster) TransferLeadership(ctx context.Context, in *pb.TransferLeadershipRequest, opts ...grpc.CallOption) (resp *pb.TransferLeadershipResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.TransferLeadership(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//Drain is sent to the node holding the session, which drains its sessions
+func (cl *cluster) Drain(ctx context.Context, in *pb.DrainRequest, opts ...grpc.CallOption) (*pb.DrainResponse, error) {
+	return cl.sessionNode().Drain(ctx, in, opts...)
+}
+
+//ListPeers is sent to the leader
+func (cl *cluster) ListPeers(ctx context.Context, in *pb.ListPeersRequest, opts ...grpc.CallOption) (resp *pb.ListPeersResponse, err error) {
+	err = cl.call(true, true, func(rpc pb.IrisClient) error {
+		resp, err = rpc.ListPeers(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//ClusterStatus is sent to the leader
+func (cl *cluster) ClusterStatus(ctx context.Context, in *pb.ClusterStatusRequest, opts ...grpc.CallOption) (resp *pb.ClusterStatusResponse, err error) {
+	err = cl.call(true, true, func(rpc pb.IrisClient) error {
+		resp, err = rpc.ClusterStatus(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//Connect obtains a session from one of the nodes in turn, which receives the requests made for the session
+func (cl *cluster) Connect(ctx context.Context, in *pb.ConnectRequest, opts ...grpc.CallOption) (*pb.ConnectResponse, error) {
+	tried := make(map[*clusterNode]bool)
+	for {
+		node := cl.pick(tried, false, false)
+		if node == nil {
+			return nil, grpc.Errorf(codes.Unavailable, "None of the cluster's nodes could be reached")
+		}
+		tried[node] = true
+
+		resp, err := node.rpc.Connect(ctx, in, opts...)
+		if err == nil {
+			cl.mu.Lock()
+			cl.session = node
+			cl.mu.Unlock()
+			return resp, nil
+		}
+
+		if grpc.Code(err) != codes.Unavailable {
+			return nil, err
+		}
+		cl.unreachable(node)
+	}
+}
+
+//Listen is sent to the node holding the session
+func (cl *cluster) Listen(ctx context.Context, in *pb.ListenRequest, opts ...grpc.CallOption) (pb.Iris_ListenClient, error) {
+	return cl.sessionNode().Listen(ctx, in, opts...)
+}
+
+//GetSources is sent to any node for stale reads, and otherwise to the leader
+func (cl *cluster) GetSources(ctx context.Context, in *pb.GetSourcesRequest, opts ...grpc.CallOption) (stream pb.Iris_GetSourcesClient, err error) {
+	err = cl.call(!stale(in.Consistency), true, func(rpc pb.IrisClient) error {
+		stream, err = rpc.GetSources(ctx, in, opts...)
+		return err
+	})
+	return stream, err
+}
+
+//GetKeys is sent to any node for stale reads, and otherwise to the leader
+func (cl *cluster) GetKeys(ctx context.Context, in *pb.GetKeysRequest, opts ...grpc.CallOption) (stream pb.Iris_GetKeysClient, err error) {
+	err = cl.call(!stale(in.Consistency), true, func(rpc pb.IrisClient) error {
+		stream, err = rpc.GetKeys(ctx, in, opts...)
+		return err
+	})
+	return stream, err
+}
+
+//SetValue is sent to the leader, unless the value is ephemeral and must be sent to the node holding the session
+func (cl *cluster) SetValue(ctx context.Context, in *pb.SetValueRequest, opts ...grpc.CallOption) (resp *pb.SetValueResponse, err error) {
+	if in.Ephemeral {
+		return cl.sessionNode().SetValue(ctx, in, opts...)
+	}
+
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.SetValue(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//GetValue is sent to any node for stale reads, and otherwise to the leader
+func (cl *cluster) GetValue(ctx context.Context, in *pb.GetValueRequest, opts ...grpc.CallOption) (resp *pb.GetValueResponse, err error) {
+	err = cl.call(!stale(in.Consistency), true, func(rpc pb.IrisClient) error {
+		resp, err = rpc.GetValue(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//GetHistory is sent to the leader
+func (cl *cluster) GetHistory(ctx context.Context, in *pb.GetHistoryRequest, opts ...grpc.CallOption) (stream pb.Iris_GetHistoryClient, err error) {
+	err = cl.call(true, true, func(rpc pb.IrisClient) error {
+		stream, err = rpc.GetHistory(ctx, in, opts...)
+		return err
+	})
+	return stream, err
+}
+
+//RemoveValue is sent to the leader
+func (cl *cluster) RemoveValue(ctx context.Context, in *pb.RemoveValueRequest, opts ...grpc.CallOption) (resp *pb.RemoveValueResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.RemoveValue(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//RemoveSource is sent to the leader
+func (cl *cluster) RemoveSource(ctx context.Context, in *pb.RemoveSourceRequest, opts ...grpc.CallOption) (resp *pb.RemoveSourceResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.RemoveSource(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//Subscribe is sent to the node holding the session
+func (cl *cluster) Subscribe(ctx context.Context, in *pb.SubscribeRequest, opts ...grpc.CallOption) (*pb.SubscribeResponse, error) {
+	return cl.sessionNode().Subscribe(ctx, in, opts...)
+}
+
+//SubscribeKey is sent to the node holding the session
+func (cl *cluster) SubscribeKey(ctx context.Context, in *pb.SubscribeKeyRequest, opts ...grpc.CallOption) (*pb.SubscribeKeyResponse, error) {
+	return cl.sessionNode().SubscribeKey(ctx, in, opts...)
+}
+
+//Unsubscribe is sent to the node holding the session
+func (cl *cluster) Unsubscribe(ctx context.Context, in *pb.UnsubscribeRequest, opts ...grpc.CallOption) (*pb.UnsubscribeResponse, error) {
+	return cl.sessionNode().Unsubscribe(ctx, in, opts...)
+}
+
+//UnsubscribeKey is sent to the node holding the session
+func (cl *cluster) UnsubscribeKey(ctx context.Context, in *pb.UnsubscribeKeyRequest, opts ...grpc.CallOption) (*pb.UnsubscribeKeyResponse, error) {
+	return cl.sessionNode().UnsubscribeKey(ctx, in, opts...)
+}
+
+//SubscribePattern is sent to the node holding the session
+func (cl *cluster) SubscribePattern(ctx context.Context, in *pb.SubscribePatternRequest, opts ...grpc.CallOption) (*pb.SubscribePatternResponse, error) {
+	return cl.sessionNode().SubscribePattern(ctx, in, opts...)
+}
+
+//UnsubscribePattern is sent to the node holding the session
+func (cl *cluster) UnsubscribePattern(ctx context.Context, in *pb.UnsubscribePatternRequest, opts ...grpc.CallOption) (*pb.UnsubscribePatternResponse, error) {
+	return cl.sessionNode().UnsubscribePattern(ctx, in, opts...)
+}
+
+//KeepAliveSession is sent to the node holding the session
+func (cl *cluster) KeepAliveSession(ctx context.Context, in *pb.KeepAliveSessionRequest, opts ...grpc.CallOption) (*pb.KeepAliveSessionResponse, error) {
+	return cl.sessionNode().KeepAliveSession(ctx, in, opts...)
+}
+
+//SubscribeSources is sent to the node holding the session
+func (cl *cluster) SubscribeSources(ctx context.Context, in *pb.SubscribeSourcesRequest, opts ...grpc.CallOption) (*pb.SubscribeSourcesResponse, error) {
+	return cl.sessionNode().SubscribeSources(ctx, in, opts...)
+}
+
+//UnsubscribeSources is sent to the node holding the session
+func (cl *cluster) UnsubscribeSources(ctx context.Context, in *pb.UnsubscribeSourcesRequest, opts ...grpc.CallOption) (*pb.UnsubscribeSourcesResponse, error) {
+	return cl.sessionNode().UnsubscribeSources(ctx, in, opts...)
+}
+
+//Txn is sent to the leader
+func (cl *cluster) Txn(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallOption) (resp *pb.TxnResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.Txn(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//GrantLease is sent to the leader
+func (cl *cluster) GrantLease(ctx context.Context, in *pb.GrantLeaseRequest, opts ...grpc.CallOption) (resp *pb.GrantLeaseResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.GrantLease(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//KeepAliveLease is sent to the leader
+func (cl *cluster) KeepAliveLease(ctx context.Context, in *pb.KeepAliveLeaseRequest, opts ...grpc.CallOption) (resp *pb.KeepAliveLeaseResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.KeepAliveLease(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//RevokeLease is sent to the leader
+func (cl *cluster) RevokeLease(ctx context.Context, in *pb.RevokeLeaseRequest, opts ...grpc.CallOption) (resp *pb.RevokeLeaseResponse, err error) {
+	err = cl.call(true, false, func(rpc pb.IrisClient) error {
+		resp, err = rpc.RevokeLease(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
+
+//NodeStatus is sent to the leader
+func (cl *cluster) NodeStatus(ctx context.Context, in *pb.NodeStatusRequest, opts ...grpc.CallOption) (resp *pb.NodeStatusResponse, err error) {
+	err = cl.call(true, true, func(rpc pb.IrisClient) error {
+		resp, err = rpc.NodeStatus(ctx, in, opts...)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/forestgiant/iris/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// failingNode is a node of a cluster failing every request with its error, and counting the requests it receives
+type failingNode struct {
+	pb.IrisClient
+	err      error
+	requests int
+}
+
+func (n *failingNode) NodeStatus(ctx context.Context, in *pb.NodeStatusRequest, opts ...grpc.CallOption) (*pb.NodeStatusResponse, error) {
+	return nil, n.err
+}
+
+func (n *failingNode) SetValue(ctx context.Context, in *pb.SetValueRequest, opts ...grpc.CallOption) (*pb.SetValueResponse, error) {
+	n.requests++
+	return nil, n.err
+}
+
+func (n *failingNode) GetValue(ctx context.Context, in *pb.GetValueRequest, opts ...grpc.CallOption) (*pb.GetValueResponse, error) {
+	n.requests++
+	return nil, n.err
+}
+
+func TestClusterRetry(t *testing.T) {
+	var tests = []struct {
+		err      error
+		writes   int
+		requests int
+	}{
+		{grpc.Errorf(codes.Unavailable, "%s", "grpc: the connection is unavailable"), 2, 2},
+		{grpc.Errorf(codes.Unavailable, "%s", "transport is closing"), 1, 2},
+	}
+
+	for _, test := range tests {
+		first, second := &failingNode{err: test.err}, &failingNode{err: test.err}
+		cl := &cluster{
+			done: make(chan struct{}),
+			nodes: []*clusterNode{
+				{rpc: first, healthy: true, leader: true},
+				{rpc: second, healthy: true},
+			},
+		}
+
+		if _, err := cl.SetValue(context.Background(), &pb.SetValueRequest{}); grpc.Code(err) != codes.Unavailable {
+			t.Error("SetValue should fail when no node is available.", err)
+		}
+
+		if requests := first.requests + second.requests; requests != test.writes {
+			t.Error("A write should only be retried by another node when it could not be sent.", grpc.ErrorDesc(test.err), requests)
+		}
+
+		first.requests, second.requests = 0, 0
+		if _, err := cl.GetValue(context.Background(), &pb.GetValueRequest{}); grpc.Code(err) != codes.Unavailable {
+			t.Error("GetValue should fail when no node is available.", err)
+		}
+
+		if requests := first.requests + second.requests; requests != test.requests {
+			t.Error("A read should be retried by every node.", grpc.ErrorDesc(test.err), requests)
+		}
+	}
+}
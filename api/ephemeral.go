@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// heartbeatRetryInterval is the delay before a failed heartbeat is retried
+const heartbeatRetryInterval = time.Second
+
+// Ephemeral attaches the value to the client's session, so that it is removed when the session ends.  The session
+// ends when the client is closed, when its update stream ends, or when the node holding the session fails.  Once
+// the client sets an ephemeral value it sends heartbeats to keep its session alive.  Ephemeral values may not be
+// given a ttl or a lease, and are not restored when the client reconnects with a new session.
+func Ephemeral() SetOption {
+	return func(req *pb.SetValueRequest) {
+		req.Ephemeral = true
+	}
+}
+
+// heartbeat begins keeping the session alive unless it is already being kept alive
+func (c *Client) heartbeat(session string) {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	if c.closed || c.heartbeating == session {
+		return
+	}
+	c.heartbeating = session
+	go c.keepSessionAlive(session)
+}
+
+// keepSessionAlive sends heartbeats at a third of the session's time-to-live, until the client is closed,
+// the client obtains a new session, or the server no longer holds the session
+func (c *Client) keepSessionAlive(session string) {
+	for {
+		rpc, current := c.connection()
+		c.listenMutex.Lock()
+		heartbeating := c.heartbeating
+		c.listenMutex.Unlock()
+
+		if heartbeating != session || current != session {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), heartbeatRetryInterval)
+		resp, err := rpc.KeepAliveSession(ctx, &pb.KeepAliveSessionRequest{Session: session})
+		cancel()
+
+		interval := heartbeatRetryInterval
+		if err == nil {
+			interval = time.Duration(resp.Ttl) * time.Millisecond / 3
+		} else if grpc.Code(err) == codes.NotFound {
+			return
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-c.done:
+			return
+		}
+	}
+}
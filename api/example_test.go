@@ -120,6 +120,88 @@ func ExampleSetValue() {
 	}
 }
 
+func ExampleClient_CompareAndSet() {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	testClient, err := NewClient(ctx, "127.0.0.1:32000", nil)
+	if err != nil {
+		//handle connection error
+		return
+	}
+	defer testClient.Close()
+
+	value, revision, err := testClient.GetValueRevision(ctx, "source", "key")
+	if err != nil {
+		//handle GetValueRevision error
+		return
+	}
+
+	value = append(value, []byte("-modified")...)
+	if _, err := testClient.CompareAndSet(ctx, "source", "key", value, revision); err != nil {
+		if IsRevisionMismatch(err) {
+			//another client modified the value first, read it again and retry
+			return
+		}
+
+		//handle CompareAndSet error
+		return
+	}
+}
+
+func ExampleClient_GrantLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	testClient, err := NewClient(ctx, "127.0.0.1:32000", nil)
+	if err != nil {
+		//handle connection error
+		return
+	}
+	defer testClient.Close()
+
+	lease, err := testClient.GrantLease(ctx, 10*time.Second)
+	if err != nil {
+		//handle GrantLease error
+		return
+	}
+
+	if err := testClient.SetValue(ctx, "presence", "node-1", []byte("online"), WithLease(lease)); err != nil {
+		//handle SetValue error
+		return
+	}
+
+	//keep the lease alive periodically, and the value is removed once the lease is no longer kept alive
+	if _, err := testClient.KeepAliveLease(ctx, lease); err != nil {
+		//handle KeepAliveLease error
+		return
+	}
+}
+
+func ExampleClient_Txn() {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	testClient, err := NewClient(ctx, "127.0.0.1:32000", nil)
+	if err != nil {
+		//handle connection error
+		return
+	}
+	defer testClient.Close()
+
+	resp, err := testClient.Txn().
+		IfValue("source", "version", []byte("1")).
+		SetValue("source", "version", []byte("2")).
+		SetValue("source", "key", []byte("value")).
+		Commit(ctx)
+	if err != nil {
+		//handle Txn error
+		return
+	}
+
+	fmt.Println("The transaction succeeded:", resp.Succeeded)
+}
+
 func ExampleGetValue() {
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
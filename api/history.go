@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"io"
+
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// GetValueAt expects a source and key and responds with the value it held at the provided revision, where zero reads
+// the current value, along with the revision at which that value was written.  A revision of zero in the response indicates that the key did not
+// exist at the requested revision.  Use IsCompacted to determine whether the returned error indicates that the
+// version is no longer retained.
+func (c *Client) GetValueAt(ctx context.Context, source string, key string, revision uint64, opts ...ReadOption) ([]byte, uint64, error) {
+	c.initialize()
+
+	r := newReadRequest(opts)
+	rpc, session := c.connection()
+	resp, err := rpc.GetValue(ctx, &pb.GetValueRequest{
+		Session:      session,
+		Source:       source,
+		Key:          key,
+		Revision:     revision,
+		Consistency:  r.consistency,
+		MaxStaleness: r.maxStaleness,
+	})
+
+	if resp == nil {
+		return nil, 0, err
+	}
+
+	r.served(resp.Index)
+	return resp.Value, resp.Revision, err
+}
+
+// GetHistory expects a source and key and responds with its retained versions in the order they were written,
+// ending with the current value
+func (c *Client) GetHistory(ctx context.Context, source string, key string) ([]*pb.Version, error) {
+	c.initialize()
+
+	rpc, session := c.connection()
+	stream, err := rpc.GetHistory(ctx, &pb.GetHistoryRequest{
+		Session: session,
+		Source:  source,
+		Key:     key,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*pb.Version
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, err
+		}
+		versions = append(versions, resp)
+	}
+
+	return versions, nil
+}
+
+// IsCompacted indicates whether the error was produced because the requested revision is no longer retained
+func IsCompacted(err error) bool {
+	return err != nil && grpc.Code(err) == codes.OutOfRange
+}
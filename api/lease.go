@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// SetOption modifies how a value is set
+type SetOption func(req *pb.SetValueRequest)
+
+// WithTTL removes the value once the ttl elapses, unless it is set again before then.
+// A ttl of zero leaves the value in place indefinitely.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(req *pb.SetValueRequest) {
+		req.Ttl = int64(ttl / time.Millisecond)
+	}
+}
+
+// WithLease attaches the value to the lease, so that it is removed when the lease expires or is revoked.
+// A lease of zero leaves the value unattached.
+func WithLease(lease uint64) SetOption {
+	return func(req *pb.SetValueRequest) {
+		req.Lease = lease
+	}
+}
+
+// GrantLease creates a lease that expires after the ttl unless kept alive, and responds with its identifier
+func (c *Client) GrantLease(ctx context.Context, ttl time.Duration) (uint64, error) {
+	c.initialize()
+
+	rpc, session := c.connection()
+	resp, err := rpc.GrantLease(ctx, &pb.GrantLeaseRequest{
+		Session: session,
+		Ttl:     int64(ttl / time.Millisecond),
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Lease, nil
+}
+
+// KeepAliveLease restarts the time-to-live of the lease and responds with its ttl.  Use IsLeaseNotFound
+// to determine whether the returned error indicates that the lease has already expired.
+func (c *Client) KeepAliveLease(ctx context.Context, lease uint64) (time.Duration, error) {
+	c.initialize()
+
+	rpc, session := c.connection()
+	resp, err := rpc.KeepAliveLease(ctx, &pb.KeepAliveLeaseRequest{
+		Session: session,
+		Lease:   lease,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(resp.Ttl) * time.Millisecond, nil
+}
+
+// RevokeLease removes the lease along with every value attached to it
+func (c *Client) RevokeLease(ctx context.Context, lease uint64) error {
+	c.initialize()
+
+	rpc, session := c.connection()
+	_, err := rpc.RevokeLease(ctx, &pb.RevokeLeaseRequest{
+		Session: session,
+		Lease:   lease,
+	})
+	return err
+}
+
+// IsLeaseNotFound indicates whether the error was produced because the lease does not exist or has expired
+func IsLeaseNotFound(err error) bool {
+	return err != nil && grpc.Code(err) == codes.NotFound
+}
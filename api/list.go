@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"io"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// listRequest holds the range shared by the requests listing sources and keys
+type listRequest struct {
+	prefix       string
+	start        string
+	end          string
+	limit        uint32
+	continuation string
+	read         []ReadOption
+}
+
+// ListOption modifies which sources or keys are listed
+type ListOption func(r *listRequest)
+
+// WithPrefix lists only the names beginning with prefix
+func WithPrefix(prefix string) ListOption {
+	return func(r *listRequest) {
+		r.prefix = prefix
+	}
+}
+
+// WithRange lists only the names from start, inclusive, up to but not including end.
+// An empty end continues to the last name.
+func WithRange(start, end string) ListOption {
+	return func(r *listRequest) {
+		r.start = start
+		r.end = end
+	}
+}
+
+// WithLimit lists at most limit names, where zero lists every name
+func WithLimit(limit int) ListOption {
+	return func(r *listRequest) {
+		r.limit = uint32(limit)
+	}
+}
+
+// WithContinuation resumes a listing after the page that returned the continuation token.
+// The options used to request the previous page should be provided again.
+func WithContinuation(continuation string) ListOption {
+	return func(r *listRequest) {
+		r.continuation = continuation
+	}
+}
+
+// WithReadOptions serves the listing with the consistency described by the read options
+func WithReadOptions(opts ...ReadOption) ListOption {
+	return func(r *listRequest) {
+		r.read = append(r.read, opts...)
+	}
+}
+
+func newListRequest(opts []ListOption) *listRequest {
+	r := &listRequest{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ListSources responds with the sources selected by the options in lexical order, along with a continuation
+// token for use with WithContinuation when more sources remain beyond the limit.  The token is empty once
+// every selected source has been listed.
+func (c *Client) ListSources(ctx context.Context, opts ...ListOption) ([]string, string, error) {
+	c.initialize()
+
+	r := newListRequest(opts)
+	read := newReadRequest(r.read)
+	rpc, session := c.connection()
+	stream, err := rpc.GetSources(ctx, &pb.GetSourcesRequest{
+		Session:      session,
+		Prefix:       r.prefix,
+		Start:        r.start,
+		End:          r.end,
+		Limit:        r.limit,
+		Continuation: r.continuation,
+		Consistency:  read.consistency,
+		MaxStaleness: read.maxStaleness,
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sources []string
+	var continuation string
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, "", err
+		}
+		sources = append(sources, resp.Source)
+		continuation = resp.Continuation
+		read.served(resp.Index)
+	}
+
+	return sources, continuation, nil
+}
+
+// ListKeys responds with the keys of the source selected by the options in lexical order, along with a continuation
+// token for use with WithContinuation when more keys remain beyond the limit.  The token is empty once every
+// selected key has been listed.
+func (c *Client) ListKeys(ctx context.Context, source string, opts ...ListOption) ([]string, string, error) {
+	c.initialize()
+
+	r := newListRequest(opts)
+	read := newReadRequest(r.read)
+	rpc, session := c.connection()
+	stream, err := rpc.GetKeys(ctx, &pb.GetKeysRequest{
+		Session:      session,
+		Source:       source,
+		Prefix:       r.prefix,
+		Start:        r.start,
+		End:          r.end,
+		Limit:        r.limit,
+		Continuation: r.continuation,
+		Consistency:  read.consistency,
+		MaxStaleness: read.maxStaleness,
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	var keys []string
+	var continuation string
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, "", err
+		}
+		keys = append(keys, resp.Key)
+		continuation = resp.Continuation
+		read.served(resp.Index)
+	}
+
+	return keys, continuation, nil
+}
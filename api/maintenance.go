@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+
+	"github.com/forestgiant/iris"
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// TransferLeadership moves leadership of the cluster to the node reachable at the raft address, and responds with
+// the raft address of the new leader.  Writes are refused while leadership is transferred, and the transfer fails
+// unless it completes before the context's deadline.
+func (c *Client) TransferLeadership(ctx context.Context, address string) (string, error) {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	resp, err := rpc.TransferLeadership(ctx, &pb.TransferLeadershipRequest{Address: address})
+	if err != nil {
+		return "", err
+	}
+	return resp.Leader, nil
+}
+
+// Drain puts the node the client is connected to into maintenance mode, and responds with the number of sessions
+// it ended.  A draining node refuses new sessions and ends those it holds, so that their clients reconnect to another
+// node, and its NodeStatus reports that it is not ready.  The ephemeral values of the ended sessions are removed, which
+// releases any locks or leadership they hold.  Use IsDraining to determine whether an error was produced by a
+// draining node.
+func (c *Client) Drain(ctx context.Context) (int, error) {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	resp, err := rpc.Drain(ctx, &pb.DrainRequest{Draining: true})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.Sessions), nil
+}
+
+// Undrain ends the maintenance mode of the node the client is connected to, so that it accepts new sessions
+func (c *Client) Undrain(ctx context.Context) error {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	_, err := rpc.Drain(ctx, &pb.DrainRequest{Draining: false})
+	return err
+}
+
+// IsDraining indicates whether the error was produced because the node is draining its clients for maintenance.
+// A client created for a draining node is returned along with the error, and may still make requests that do not
+// require a session.
+func IsDraining(err error) bool {
+	return err != nil && grpc.Code(err) == codes.Unavailable && grpc.ErrorDesc(err) == iris.DrainingMessage
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// RemovePeer removes the node reachable at the raft address from the cluster, such as a node that has failed
+// and will not return
+func (c *Client) RemovePeer(ctx context.Context, address string) error {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	_, err := rpc.RemovePeer(ctx, &pb.RemovePeerRequest{Address: address})
+	return err
+}
+
+// Leave removes the node the client is connected to from its cluster.  The node stops taking part in raft
+// consensus once it has been removed, unless it is the last member of the cluster.
+func (c *Client) Leave(ctx context.Context) error {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	_, err := rpc.Leave(ctx, &pb.LeaveRequest{})
+	return err
+}
+
+// ListPeers responds with the raft addresses of the members of the cluster in sorted order
+func (c *Client) ListPeers(ctx context.Context) ([]string, error) {
+	c.initialize()
+
+	rpc, _ := c.connection()
+	resp, err := rpc.ListPeers(ctx, &pb.ListPeersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Peers, nil
+}
+
+// ClusterStatus responds with the raft state of the node serving the request, including the cluster leader, its
+// current term, the indexes it has committed and applied, and the state of each member of the cluster as seen by it
+func (c *Client) ClusterStatus(ctx context.Context) (*pb.ClusterStatusResponse, error) {
+	c.initialize()
+	rpc, _ := c.connection()
+	return rpc.ClusterStatus(ctx, &pb.ClusterStatusRequest{})
+}
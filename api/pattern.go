@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// SubscribePattern indicates that the client wishes to be notified of updates to the keys matching keyPattern
+// within the sources matching sourcePattern.  Patterns are globs where * matches any sequence of characters,
+// including none, and ? matches a single character.  An empty key pattern matches every key, so subscribing to
+// the source pattern "*" with an empty key pattern receives every update.  Each update is delivered once to the
+// client's session, however many of its subscriptions match it.
+func (c *Client) SubscribePattern(ctx context.Context, sourcePattern string, keyPattern string, handler *UpdateHandler, opts ...SubscribeOption) (*pb.SubscribePatternResponse, error) {
+	c.initialize()
+
+	if len(sourcePattern) == 0 {
+		return nil, errors.New("You must provide a source pattern to subscribe to")
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	s := subscription{source: sourcePattern, key: keyPattern, pattern: true}
+
+	c.patternHandlersMutex.Lock()
+	if c.patternHandlers == nil {
+		c.patternHandlers = make(map[subscription][]*UpdateHandler)
+	}
+	c.patternHandlers[s] = append(c.patternHandlers[s], handler)
+	c.patternHandlersMutex.Unlock()
+
+	r := newSubscribeRequest(opts)
+	tracked := c.subscribing(s, r.revision)
+	rpc, session := c.connection()
+	resp, err := rpc.SubscribePattern(ctx, &pb.SubscribePatternRequest{
+		Session:       session,
+		Source:        sourcePattern,
+		Key:           keyPattern,
+		StartRevision: r.revision,
+	})
+
+	if err != nil {
+		if tracked {
+			c.unsubscribed(s)
+		}
+
+		c.patternHandlersMutex.Lock()
+		if c.patternHandlers != nil {
+			c.patternHandlers[s] = removeHandler(handler, c.patternHandlers[s])
+		}
+		c.patternHandlersMutex.Unlock()
+		return resp, err
+	}
+
+	c.subscribed(s, resp.Revision, r.revision)
+	return resp, nil
+}
+
+// UnsubscribePattern indicates that the client no longer wishes to be notified of updates matching the patterns
+func (c *Client) UnsubscribePattern(ctx context.Context, sourcePattern string, keyPattern string, handler *UpdateHandler) (*pb.UnsubscribePatternResponse, error) {
+	c.initialize()
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	s := subscription{source: sourcePattern, key: keyPattern, pattern: true}
+
+	c.patternHandlersMutex.Lock()
+	if c.patternHandlers != nil && c.patternHandlers[s] != nil {
+		c.patternHandlers[s] = removeHandler(handler, c.patternHandlers[s])
+
+		if len(c.patternHandlers[s]) > 0 {
+			c.patternHandlersMutex.Unlock()
+			return &pb.UnsubscribePatternResponse{
+				Source: sourcePattern,
+				Key:    keyPattern,
+			}, nil
+		}
+		delete(c.patternHandlers, s)
+	}
+	c.patternHandlersMutex.Unlock()
+
+	c.unsubscribed(s)
+	rpc, session := c.connection()
+	return rpc.UnsubscribePattern(ctx, &pb.UnsubscribePatternRequest{
+		Session: session,
+		Source:  sourcePattern,
+		Key:     keyPattern,
+	})
+}
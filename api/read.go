@@ -0,0 +1,62 @@
+package api
+
+import (
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// readRequest holds the consistency requested for a read
+type readRequest struct {
+	consistency  pb.ReadConsistency
+	maxStaleness int64
+	index        *uint64
+}
+
+// ReadOption modifies the consistency of a read.  Reads are served by the leader unless an option states otherwise.
+type ReadOption func(r *readRequest)
+
+// WithConsistency serves the read with the provided consistency.  The maximum staleness only applies to stale reads,
+// where zero allows any staleness.
+func WithConsistency(consistency pb.ReadConsistency, maxStaleness time.Duration) ReadOption {
+	return func(r *readRequest) {
+		r.consistency = consistency
+		r.maxStaleness = int64(maxStaleness / time.Millisecond)
+	}
+}
+
+// WithLinearizableRead serves the read by the leader once it has confirmed its leadership with a quorum of the cluster,
+// so that the read reflects every write completed before it began
+func WithLinearizableRead() ReadOption {
+	return WithConsistency(pb.ReadConsistency_LINEARIZABLE, 0)
+}
+
+// WithStaleRead serves the read from the state held by the server the client is connected to, which may not reflect
+// recent writes.  The read fails if the server has been out of contact with the leader for longer than maxStaleness,
+// where zero allows any staleness.
+func WithStaleRead(maxStaleness time.Duration) ReadOption {
+	return WithConsistency(pb.ReadConsistency_STALE, maxStaleness)
+}
+
+// WithAppliedIndex stores the raft log index applied by the server that served the read in index.
+// A listing that returns no names leaves index unchanged.
+func WithAppliedIndex(index *uint64) ReadOption {
+	return func(r *readRequest) {
+		r.index = index
+	}
+}
+
+func newReadRequest(opts []ReadOption) *readRequest {
+	r := &readRequest{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// served records the index applied by the server that served the read
+func (r *readRequest) served(index uint64) {
+	if r.index != nil {
+		*r.index = index
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+)
+
+// Delays between attempts to reconnect when the client does not specify otherwise
+const (
+	DefaultReconnectBackoff    = 100 * time.Millisecond
+	DefaultMaxReconnectBackoff = 10 * time.Second
+)
+
+// ConnectionState describes the client's connection to the cluster
+type ConnectionState int
+
+const (
+	// StateConnected indicates that the client is receiving updates
+	StateConnected ConnectionState = iota
+
+	// StateReconnecting indicates that the client's update stream ended and it is establishing a new session
+	StateReconnecting
+
+	// StateDisconnected indicates that the client was closed
+	StateDisconnected
+)
+
+// String returns the name of the connection state
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateDisconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+// StateHandler describes a function notified when the client's connection state changes, along with the error
+// that caused the change if any
+type StateHandler func(state ConnectionState, err error)
+
+// clientOptions holds the options of a client
+type clientOptions struct {
+	addresses    []string
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	stateHandler StateHandler
+}
+
+// ClientOption modifies how a client maintains its connection
+type ClientOption func(o *clientOptions)
+
+// WithAddresses provides the addresses of other nodes in the cluster, which the client fails over to in turn
+// when it cannot reconnect to the node it was connected to.  The addresses are added to those of a cluster client.
+func WithAddresses(addresses ...string) ClientOption {
+	return func(o *clientOptions) {
+		o.addresses = append(o.addresses, addresses...)
+	}
+}
+
+// WithReconnectBackoff sets the delay before the client's second attempt to reconnect, which doubles after
+// every failed attempt up to the maximum
+func WithReconnectBackoff(initial time.Duration, max time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.backoff = initial
+		o.maxBackoff = max
+	}
+}
+
+// WithStateHandler sets the handler notified when the client's connection state changes
+func WithStateHandler(handler StateHandler) ClientOption {
+	return func(o *clientOptions) {
+		o.stateHandler = handler
+	}
+}
+
+func newClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{
+		backoff:    DefaultReconnectBackoff,
+		maxBackoff: DefaultMaxReconnectBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.backoff <= 0 {
+		o.backoff = DefaultReconnectBackoff
+	}
+
+	if o.maxBackoff < o.backoff {
+		o.maxBackoff = o.backoff
+	}
+	return o
+}
+
+// notify calls the state handler with the client's new connection state
+func (c *Client) notify(state ConnectionState, err error) {
+	if c.options != nil && c.options.stateHandler != nil {
+		c.options.stateHandler(state, err)
+	}
+}
+
+// interrupted is called when the update stream ends.  It reconnects the client unless the stream was replaced
+// or the client was closed, and marks the reconnection as interrupted if the client is already reconnecting.
+func (c *Client) interrupted(stream pb.Iris_ListenClient, err error) {
+	c.listenMutex.Lock()
+	if c.closed || c.listenStream != stream {
+		c.listenMutex.Unlock()
+		return
+	}
+
+	if c.reconnecting {
+		c.reconnectInterrupted = true
+		c.listenMutex.Unlock()
+		return
+	}
+	c.reconnecting = true
+	c.listenMutex.Unlock()
+
+	c.reconnect(err)
+}
+
+// reconnect establishes a new session, waiting longer after each failed attempt and failing over to the next
+// address when the client was given several, until the client's subscriptions are resumed or the client is closed.
+// The cause and the error of each failed attempt are delivered to the client's watches.  Should some subscriptions
+// begin again because the updates they missed are no longer retained, the client reports that it is connected along
+// with an error available through Err, which also ends the watches of those subscriptions that could not begin with a
+// snapshot.
+func (c *Client) reconnect(cause error) {
+	c.notify(StateReconnecting, cause)
+	c.interruptWatches(cause)
+
+	missed := make(map[subscription]bool)
+	backoff := c.options.backoff
+	for attempt := 0; ; attempt++ {
+		var conn *grpc.ClientConn
+		var err error
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-c.done:
+				return
+			}
+
+			if backoff *= 2; backoff > c.options.maxBackoff {
+				backoff = c.options.maxBackoff
+			}
+
+			conn, err = c.failover()
+		}
+
+		if err == nil {
+			var resynced []subscription
+			resynced, err = c.resubscribe(conn)
+			for _, s := range resynced {
+				missed[s] = true
+			}
+		}
+
+		c.listenMutex.Lock()
+		if c.closed {
+			c.listenMutex.Unlock()
+			return
+		}
+
+		// The new update stream may have ended before the subscriptions were resumed
+		if err == nil && c.reconnectInterrupted {
+			err = cause
+		}
+		c.reconnectInterrupted = false
+
+		if err == nil {
+			c.listenErr = nil
+			if len(missed) > 0 {
+				c.listenErr = errUpdatesMissed
+			}
+			err = c.listenErr
+			c.reconnecting = false
+			c.listenMutex.Unlock()
+
+			if err != nil {
+				c.endWatches(err, func(s subscription) bool { return s.pattern && missed[s] })
+			}
+			c.notify(StateConnected, err)
+			return
+		}
+		c.listenMutex.Unlock()
+
+		c.interruptWatches(err)
+	}
+}
+
+// failover connects to the next of the client's addresses, or again to its only address, returning the connection
+// that replaces the client's connection once a session is obtained through it.  The only address is dialed again
+// since the client's connection is closed for good if its server could not be reached, as it is while restarting.
+// No connection is returned for a cluster client, whose nodes are reconnected to by the cluster.
+func (c *Client) failover() (*grpc.ClientConn, error) {
+	if len(c.options.addresses) == 0 {
+		return nil, nil
+	}
+
+	c.address = (c.address + 1) % len(c.options.addresses)
+
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+
+	return grpc.DialContext(ctx, c.options.addresses[c.address], c.dialOptions...)
+}
+
+// replace publishes the session along with the rpc client it was obtained through, and the connection of the rpc
+// client if it replaces the client's connection, so that requests are never sent with the session of another
+// connection.  Nothing is replaced if the client was closed meanwhile, and false is returned.
+func (c *Client) replace(conn *grpc.ClientConn, rpc pb.IrisClient, session string) bool {
+	c.listenMutex.Lock()
+	if c.closed {
+		c.listenMutex.Unlock()
+		return false
+	}
+
+	c.connMutex.Lock()
+	previous := c.conn
+	if conn != nil {
+		c.conn = conn
+	}
+	c.rpc, c.session = rpc, session
+	c.connMutex.Unlock()
+	c.listenMutex.Unlock()
+
+	if conn != nil && previous != nil {
+		previous.Close()
+	}
+	return true
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/forestgiant/iris"
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// resumeTimeout bounds the time taken by each attempt to resume the client's subscriptions after its update stream ends
+const resumeTimeout = 10 * time.Second
+
+// errClosed is returned by attempts to resume the client's subscriptions that are made once the client is closed
+var errClosed = errors.New("The client was closed")
+
+// errUpdatesMissed reports that some of the client's subscriptions began again from the current contents of the store,
+// since the updates they missed while the client reconnected were no longer retained
+var errUpdatesMissed = grpc.Errorf(codes.OutOfRange, "The updates missed while reconnecting are no longer retained, some subscriptions began again from the current revision")
+
+// subscription identifies a subscription to a source, or to one of its keys when the key is not empty.  The source
+// and key of a pattern subscription are glob patterns, where an empty key pattern matches every key.  A subscription
+// to the source list has neither a source nor a key.
+type subscription struct {
+	source  string
+	key     string
+	pattern bool
+	sources bool
+}
+
+// matches indicates whether the pattern subscription matches the source and key
+func (s subscription) matches(source string, key string) bool {
+	if !iris.MatchPattern(s.source, source) {
+		return false
+	}
+	return len(s.key) == 0 || iris.MatchPattern(s.key, key)
+}
+
+// subscribeRequest holds the options of a subscription
+type subscribeRequest struct {
+	revision uint64
+	snapshot bool
+}
+
+// SubscribeOption modifies how a subscription begins
+type SubscribeOption func(r *subscribeRequest)
+
+// FromRevision replays the updates applied at or after the revision before delivering new updates.  Every handler
+// subscribed to the source or key receives the replayed updates.  Use IsCompacted to determine whether the returned
+// error indicates that the server no longer retains the updates following the revision.
+func FromRevision(revision uint64) SubscribeOption {
+	return func(r *subscribeRequest) {
+		r.revision = revision
+	}
+}
+
+func newSubscribeRequest(opts []SubscribeOption) *subscribeRequest {
+	r := &subscribeRequest{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// subscriptionState tracks the progress of a subscription through the updates it has received
+type subscriptionState struct {
+	// revision is the index of the last update received, and received is the number of updates received at it,
+	// since updates published by a transaction share an index
+	revision uint64
+	received int
+
+	// resuming is set while the updates replayed after resuming the subscription have yet to pass the revision,
+	// where the first skip updates at the revision were received before the subscription was resumed
+	resuming bool
+	skip     int
+}
+
+// subscribing begins tracking the subscription before it is requested, so that replayed updates delivered before
+// the request completes are tracked.  It indicates whether the subscription was not already being tracked.
+func (c *Client) subscribing(s subscription, from uint64) bool {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[subscription]*subscriptionState)
+	}
+
+	if _, ok := c.subscriptions[s]; ok {
+		return false
+	}
+
+	state := &subscriptionState{}
+	if from > 0 {
+		state.revision = from - 1
+	}
+	c.subscriptions[s] = state
+	return true
+}
+
+// subscribed advances the subscription to the revision it began at when no replay was requested, since updates
+// applied at or before that revision will not be delivered
+func (c *Client) subscribed(s subscription, revision uint64, from uint64) {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	if state, ok := c.subscriptions[s]; ok && from == 0 && state.revision < revision {
+		state.revision, state.received = revision, 0
+	}
+}
+
+// resynced tracks the subscription from the revision it began again at, since the updates it missed are no longer
+// retained and will not be replayed
+func (c *Client) resynced(s subscription, revision uint64) {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	if state, ok := c.subscriptions[s]; ok {
+		state.resuming, state.skip = false, 0
+		if state.revision < revision {
+			state.revision, state.received = revision, 0
+		}
+	}
+}
+
+// unsubscribed stops tracking the subscription
+func (c *Client) unsubscribed(s subscription) {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+	delete(c.subscriptions, s)
+}
+
+// received advances the subscriptions the update was delivered to, and returns those whose handlers should handle it.
+// Updates replayed after resuming that were already received are skipped.
+func (c *Client) received(u *pb.Update) []subscription {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	handle := func(s subscription) bool {
+		state, ok := c.subscriptions[s]
+		if !ok || u.Operation == pb.UpdateOperation_SNAPSHOT {
+			return true
+		}
+
+		if state.resuming {
+			if u.Index < state.revision {
+				return false
+			}
+
+			if u.Index == state.revision && state.skip > 0 {
+				state.skip--
+				return false
+			}
+		}
+
+		if u.Index > state.revision {
+			state.revision, state.received, state.resuming = u.Index, 0, false
+		}
+
+		if u.Index == state.revision {
+			state.received++
+		}
+		return true
+	}
+
+	// Updates to the source list carry no key
+	if len(u.Key) == 0 {
+		if s := (subscription{sources: true}); handle(s) {
+			return []subscription{s}
+		}
+		return nil
+	}
+
+	var handled []subscription
+	for _, s := range []subscription{{source: u.Source}, {source: u.Source, key: u.Key}} {
+		if handle(s) {
+			handled = append(handled, s)
+		}
+	}
+
+	for s := range c.subscriptions {
+		if s.pattern && s.matches(u.Source, u.Key) && handle(s) {
+			handled = append(handled, s)
+		}
+	}
+	return handled
+}
+
+// SubscriptionRevision returns the revision of the last update received by the subscription to the source, or to the
+// key of the source when key is not empty
+func (c *Client) SubscriptionRevision(source string, key string) uint64 {
+	c.initialize()
+
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	if state, ok := c.subscriptions[subscription{source: source, key: key}]; ok {
+		return state.revision
+	}
+	return 0
+}
+
+// resubscribe connects a new session and subscribes it from the revisions reached by the previous session.  The session
+// is obtained through the connection when one is provided, which then replaces the client's connection.  Subscriptions
+// whose missed updates are no longer retained begin again with a snapshot of their current contents, which their
+// handlers receive as SNAPSHOT updates following a RESYNC update, or from the current revision for pattern
+// subscriptions, and are returned.
+func (c *Client) resubscribe(conn *grpc.ClientConn) ([]subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+
+	rpc, _ := c.connection()
+	if conn != nil {
+		rpc = pb.NewIrisClient(conn)
+	}
+
+	resp, err := rpc.Connect(ctx, &pb.ConnectRequest{})
+	if err == nil && !c.replace(conn, rpc, resp.Session) {
+		err = errClosed
+	}
+
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, err
+	}
+	session := resp.Session
+
+	if err := c.listen(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// Subscriptions resume from the revision they reached, skipping the updates already received at it
+	c.listenMutex.Lock()
+	revisions := make(map[subscription]uint64)
+	for s, state := range c.subscriptions {
+		revision := state.revision + 1
+		if state.received > 0 {
+			revision = state.revision
+		}
+
+		state.resuming, state.skip = true, state.received
+		revisions[s] = revision
+	}
+	c.listenMutex.Unlock()
+
+	var missed []subscription
+	for s, revision := range revisions {
+		if _, err := resume(ctx, rpc, session, s, revision, false); !IsCompacted(err) {
+			if err != nil {
+				return missed, err
+			}
+			continue
+		}
+
+		// The handlers discard what they hold for the subscription before it begins again, since the keys removed
+		// while the client reconnected are not included in the snapshot
+		c.resync(s)
+		revision, err := resume(ctx, rpc, session, s, 0, !s.pattern)
+		if err != nil {
+			return missed, err
+		}
+		c.resynced(s, revision)
+		missed = append(missed, s)
+	}
+	return missed, nil
+}
+
+// resync delivers a RESYNC update carrying the source and key of the subscription to its handlers, ahead of any
+// update delivered once the subscription is requested again
+func (c *Client) resync(s subscription) {
+	u := &pb.Update{Source: s.source, Key: s.key, Operation: pb.UpdateOperation_RESYNC}
+
+	c.handleMutex.Lock()
+	defer c.handleMutex.Unlock()
+	for _, h := range c.handlers(s) {
+		(*h)(u)
+	}
+}
+
+// resume subscribes the session to the subscription from the revision, or with a snapshot when snapshot is set, and
+// returns the revision the subscription began at
+func resume(ctx context.Context, rpc pb.IrisClient, session string, s subscription, revision uint64, snapshot bool) (uint64, error) {
+	if s.sources {
+		resp, err := rpc.SubscribeSources(ctx, &pb.SubscribeSourcesRequest{Session: session, StartRevision: revision, Snapshot: snapshot})
+		if err != nil {
+			return 0, err
+		}
+		return resp.Revision, nil
+	}
+
+	if s.pattern {
+		resp, err := rpc.SubscribePattern(ctx, &pb.SubscribePatternRequest{Session: session, Source: s.source, Key: s.key, StartRevision: revision})
+		if err != nil {
+			return 0, err
+		}
+		return resp.Revision, nil
+	}
+
+	if len(s.key) == 0 {
+		resp, err := rpc.Subscribe(ctx, &pb.SubscribeRequest{Session: session, Source: s.source, StartRevision: revision, Snapshot: snapshot})
+		if err != nil {
+			return 0, err
+		}
+		return resp.Revision, nil
+	}
+
+	resp, err := rpc.SubscribeKey(ctx, &pb.SubscribeKeyRequest{Session: session, Source: s.source, Key: s.key, StartRevision: revision, Snapshot: snapshot})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Revision, nil
+}
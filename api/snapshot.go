@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// WithSnapshot delivers the current value of every key the subscription matches as a SNAPSHOT update before any
+// new updates.  The snapshot is captured atomically at the revision returned by the subscription, and is followed
+// by every update applied after that revision.  It cannot be combined with FromRevision.
+func WithSnapshot() SubscribeOption {
+	return func(r *subscribeRequest) {
+		r.snapshot = true
+	}
+}
+
+// SubscribeWithSnapshot indicates that the client wishes to receive the current contents of the specified source,
+// followed by all updates for the source applied after them.  The handler receives the SnapshotSize of the returned
+// response SNAPSHOT updates before any new updates.
+func (c *Client) SubscribeWithSnapshot(ctx context.Context, source string, handler *UpdateHandler) (*pb.SubscribeResponse, error) {
+	return c.Subscribe(ctx, source, handler, WithSnapshot())
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// SubscribeSources indicates that the client wishes to be notified when sources are created or deleted.  A source is
+// created when its first key is set, and deleted when its last key is removed.  The handler receives updates without
+// a key, whose operation is SOURCE_CREATED or SOURCE_DELETED.  With WithSnapshot, each source that exists is first
+// delivered as a SNAPSHOT update, so that a list of sources can be kept current without polling GetSources.
+func (c *Client) SubscribeSources(ctx context.Context, handler *UpdateHandler, opts ...SubscribeOption) (*pb.SubscribeSourcesResponse, error) {
+	c.initialize()
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	c.sourceHandlersMutex.Lock()
+	c.sourceListHandlers = append(c.sourceListHandlers, handler)
+	c.sourceHandlersMutex.Unlock()
+
+	r := newSubscribeRequest(opts)
+	s := subscription{sources: true}
+	tracked := c.subscribing(s, r.revision)
+	rpc, session := c.connection()
+	resp, err := rpc.SubscribeSources(ctx, &pb.SubscribeSourcesRequest{
+		Session:       session,
+		StartRevision: r.revision,
+		Snapshot:      r.snapshot,
+	})
+
+	if err != nil {
+		if tracked {
+			c.unsubscribed(s)
+		}
+
+		c.sourceHandlersMutex.Lock()
+		c.sourceListHandlers = removeHandler(handler, c.sourceListHandlers)
+		c.sourceHandlersMutex.Unlock()
+		return resp, err
+	}
+
+	c.subscribed(s, resp.Revision, r.revision)
+	return resp, nil
+}
+
+// UnsubscribeSources indicates that the client no longer wishes to be notified when sources are created or deleted
+func (c *Client) UnsubscribeSources(ctx context.Context, handler *UpdateHandler) (*pb.UnsubscribeSourcesResponse, error) {
+	c.initialize()
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	c.sourceHandlersMutex.Lock()
+	c.sourceListHandlers = removeHandler(handler, c.sourceListHandlers)
+	if len(c.sourceListHandlers) > 0 {
+		c.sourceHandlersMutex.Unlock()
+		return &pb.UnsubscribeSourcesResponse{}, nil
+	}
+	c.sourceHandlersMutex.Unlock()
+
+	c.unsubscribed(subscription{sources: true})
+	rpc, session := c.connection()
+	return rpc.UnsubscribeSources(ctx, &pb.UnsubscribeSourcesRequest{
+		Session: session,
+	})
+}
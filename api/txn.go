@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// Txn is used to build a set of operations that are applied atomically, optionally guarded by
+// conditions that must all be satisfied for any of the operations to be applied
+type Txn struct {
+	client     *Client
+	guards     []*pb.TxnGuard
+	operations []*pb.TxnOperation
+}
+
+// Txn returns a new transaction builder.  No changes are made until Commit is called.
+func (c *Client) Txn() *Txn {
+	return &Txn{client: c}
+}
+
+// Guard adds the provided guard to the transaction
+func (t *Txn) Guard(guard *pb.TxnGuard) *Txn {
+	t.guards = append(t.guards, guard)
+	return t
+}
+
+// IfExists requires that the key exists in the specified source
+func (t *Txn) IfExists(source string, key string) *Txn {
+	return t.Guard(&pb.TxnGuard{Type: pb.GuardType_KEY_EXISTS, Source: source, Key: key})
+}
+
+// IfAbsent requires that the key does not exist in the specified source
+func (t *Txn) IfAbsent(source string, key string) *Txn {
+	return t.IfRevision(source, key, 0)
+}
+
+// IfRevision requires that the key in the specified source is currently at the provided revision
+func (t *Txn) IfRevision(source string, key string, revision uint64) *Txn {
+	return t.Guard(&pb.TxnGuard{Type: pb.GuardType_REVISION_EQUALS, Source: source, Key: key, Revision: revision})
+}
+
+// IfValue requires that the key in the specified source currently has the provided value
+func (t *Txn) IfValue(source string, key string, value []byte) *Txn {
+	return t.Guard(&pb.TxnGuard{Type: pb.GuardType_VALUE_EQUALS, Source: source, Key: key, Value: value})
+}
+
+// Operation adds the provided operation to the transaction
+func (t *Txn) Operation(operation *pb.TxnOperation) *Txn {
+	t.operations = append(t.operations, operation)
+	return t
+}
+
+// SetValue sets the value for the specified source and key
+func (t *Txn) SetValue(source string, key string, value []byte) *Txn {
+	return t.Operation(&pb.TxnOperation{Type: pb.OperationType_SET_VALUE, Source: source, Key: key, Value: value})
+}
+
+// RemoveValue removes the key from the specified source
+func (t *Txn) RemoveValue(source string, key string) *Txn {
+	return t.Operation(&pb.TxnOperation{Type: pb.OperationType_REMOVE_VALUE, Source: source, Key: key})
+}
+
+// RemoveSource removes the specified source and all its values
+func (t *Txn) RemoveSource(source string) *Txn {
+	return t.Operation(&pb.TxnOperation{Type: pb.OperationType_REMOVE_SOURCE, Source: source})
+}
+
+// Commit submits the transaction.  The response indicates whether the guards were satisfied and the
+// operations applied, along with the revision assigned to the transaction.  Updates published as a result
+// of the transaction carry this revision as their transaction identifier.
+func (t *Txn) Commit(ctx context.Context) (*pb.TxnResponse, error) {
+	t.client.initialize()
+
+	rpc, session := t.client.connection()
+	return rpc.Txn(ctx, &pb.TxnRequest{
+		Session:    session,
+		Guards:     t.guards,
+		Operations: t.operations,
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+)
+
+// unwatchTimeout bounds the time taken to unsubscribe a watch once it ends
+const unwatchTimeout = 5 * time.Second
+
+// WatchEvent is delivered by a watch for each update it receives.  An event carrying an error instead of an update
+// reports that the client's update stream was interrupted, such as when the server disconnects a slow consumer, its
+// node drains, or the session's lease expires, or that an attempt to resume it failed.  The watch continues once the
+// client reconnects.  A RESYNC update carries the error returned by Err.  The final event of a watch ended by an error
+// also carries the error instead of an update.
+type WatchEvent struct {
+	Update *pb.Update
+	Err    error
+}
+
+// watch queues the events of a subscription until they are received from its channel, so that a slow receiver
+// does not hold up the delivery of updates to the client's other subscriptions
+type watch struct {
+	subscription subscription
+
+	mu      sync.Mutex
+	pending []*WatchEvent
+	ended   bool
+	signal  chan struct{}
+	events  chan *WatchEvent
+}
+
+// Watch returns a channel delivering the updates of the specified source in the order they were applied.  When the
+// context is done the watch is unsubscribed and the channel is closed, as it is when the client is closed.  The errors
+// interrupting the client's update stream are delivered as they occur.  If the updates missed while the client
+// reconnected are no longer retained, the watch receives a RESYNC update followed by a snapshot of the source, as
+// described by Err.
+func (c *Client) Watch(ctx context.Context, source string, opts ...SubscribeOption) (<-chan *WatchEvent, error) {
+	return c.watch(ctx, subscription{source: source}, opts)
+}
+
+// WatchKey returns a channel delivering the updates of a specific key from the specified source in the order they
+// were applied, as described by Watch
+func (c *Client) WatchKey(ctx context.Context, source string, key string, opts ...SubscribeOption) (<-chan *WatchEvent, error) {
+	return c.watch(ctx, subscription{source: source, key: key}, opts)
+}
+
+// WatchPattern returns a channel delivering the updates to the keys matching keyPattern within the sources matching
+// sourcePattern in the order they were applied, as described by Watch and SubscribePattern.  A pattern has no
+// snapshot, so the watch ends with an event carrying the error returned by Err, following its RESYNC update, if the
+// updates missed while the client reconnected are no longer retained.
+func (c *Client) WatchPattern(ctx context.Context, sourcePattern string, keyPattern string, opts ...SubscribeOption) (<-chan *WatchEvent, error) {
+	return c.watch(ctx, subscription{source: sourcePattern, key: keyPattern, pattern: true}, opts)
+}
+
+// WatchSources returns a channel delivering the creation and deletion of sources in the order they were applied,
+// as described by Watch and SubscribeSources
+func (c *Client) WatchSources(ctx context.Context, opts ...SubscribeOption) (<-chan *WatchEvent, error) {
+	return c.watch(ctx, subscription{sources: true}, opts)
+}
+
+func (c *Client) watch(ctx context.Context, s subscription, opts []SubscribeOption) (<-chan *WatchEvent, error) {
+	c.initialize()
+
+	w := &watch{
+		subscription: s,
+		signal:       make(chan struct{}, 1),
+		events:       make(chan *WatchEvent),
+	}
+
+	var handler UpdateHandler = func(u *pb.Update) {
+		e := &WatchEvent{Update: u}
+		if u.Operation == pb.UpdateOperation_RESYNC {
+			e.Err = errUpdatesMissed
+		}
+		w.push(e)
+	}
+
+	c.listenMutex.Lock()
+	if c.closed {
+		c.listenMutex.Unlock()
+		return nil, errClosed
+	}
+
+	if c.watches == nil {
+		c.watches = make(map[*watch]struct{})
+	}
+	c.watches[w] = struct{}{}
+	c.watchers.Add(1)
+	c.listenMutex.Unlock()
+
+	var err error
+	if s.sources {
+		_, err = c.SubscribeSources(ctx, &handler, opts...)
+	} else if s.pattern {
+		_, err = c.SubscribePattern(ctx, s.source, s.key, &handler, opts...)
+	} else if len(s.key) == 0 {
+		_, err = c.Subscribe(ctx, s.source, &handler, opts...)
+	} else {
+		_, err = c.SubscribeKey(ctx, s.source, s.key, &handler, opts...)
+	}
+
+	if err != nil {
+		c.unwatch(w, s, &handler)
+		c.watchers.Done()
+		return nil, err
+	}
+
+	go c.deliver(ctx, w, s, &handler)
+	return w.events, nil
+}
+
+// push queues the event for delivery unless the watch has ended
+func (w *watch) push(e *WatchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ended {
+		return
+	}
+	w.pending = append(w.pending, e)
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// end delivers the error, if any, as the last event of the watch
+func (w *watch) end(err error) {
+	if err != nil {
+		w.push(&WatchEvent{Err: err})
+	}
+
+	w.mu.Lock()
+	w.ended = true
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued events of the watch to its channel in order, until the context is done, the client is
+// closed, or the watch has ended and its remaining events have been received
+func (c *Client) deliver(ctx context.Context, w *watch, s subscription, handler *UpdateHandler) {
+	defer c.watchers.Done()
+	defer close(w.events)
+	defer c.unwatch(w, s, handler)
+
+	for {
+		w.mu.Lock()
+		if len(w.pending) == 0 {
+			ended := w.ended
+			w.mu.Unlock()
+
+			if ended {
+				return
+			}
+
+			select {
+			case <-w.signal:
+				continue
+			case <-ctx.Done():
+				return
+			case <-c.done:
+				return
+			}
+		}
+
+		e := w.pending[0]
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		w.mu.Unlock()
+
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// unwatch stops tracking the watch and unsubscribes its handler, unless the client was closed and its session ended
+func (c *Client) unwatch(w *watch, s subscription, handler *UpdateHandler) {
+	c.listenMutex.Lock()
+	delete(c.watches, w)
+	closed := c.closed
+	c.listenMutex.Unlock()
+
+	w.end(nil)
+	if closed {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unwatchTimeout)
+	defer cancel()
+
+	if s.sources {
+		c.UnsubscribeSources(ctx, handler)
+	} else if s.pattern {
+		c.UnsubscribePattern(ctx, s.source, s.key, handler)
+	} else if len(s.key) == 0 {
+		c.Unsubscribe(ctx, s.source, handler)
+	} else {
+		c.UnsubscribeKey(ctx, s.source, s.key, handler)
+	}
+}
+
+// endWatches ends the watches of the subscriptions matched by fn, or every watch when fn is nil, delivering the error
+// if any as the last event of each
+func (c *Client) endWatches(err error, fn func(s subscription) bool) {
+	for _, w := range c.matchWatches(fn) {
+		w.end(err)
+	}
+}
+
+// interruptWatches delivers the error to every watch without ending it
+func (c *Client) interruptWatches(err error) {
+	for _, w := range c.matchWatches(nil) {
+		w.push(&WatchEvent{Err: err})
+	}
+}
+
+// matchWatches returns the watches of the subscriptions matched by fn, or every watch when fn is nil
+func (c *Client) matchWatches(fn func(s subscription) bool) []*watch {
+	c.listenMutex.Lock()
+	defer c.listenMutex.Unlock()
+
+	var watches []*watch
+	for w := range c.watches {
+		if fn == nil || fn(w.subscription) {
+			watches = append(watches, w)
+		}
+	}
+	return watches
+}
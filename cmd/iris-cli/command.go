@@ -4,18 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"time"
 
+	"github.com/forestgiant/iris"
 	"github.com/forestgiant/iris/api"
 	fglog "github.com/forestgiant/log"
 )
 
+// transferTimeout bounds the time taken to transfer leadership of the cluster
+const transferTimeout = 10 * time.Second
+
 type runner struct {
 	Client *api.Client
 	Logger *fglog.Logger
 }
 
-func (r *runner) setValue(source string, key string, value []byte) error {
+func (r *runner) setValue(source string, key string, value []byte, revision uint64, ifAbsent bool, ttl time.Duration, lease uint64) error {
 	if len(source) == 0 {
 		return errors.New("You must provide a source")
 	}
@@ -31,15 +37,35 @@ func (r *runner) setValue(source string, key string, value []byte) error {
 	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancelCommand()
 
-	if err := r.Client.SetValue(commandCtx, source, key, []byte(value)); err != nil {
+	if ifAbsent && revision > 0 {
+		return errors.New("You may not provide a revision when setting a value only if it is absent")
+	}
+
+	if ttl > 0 && lease > 0 {
+		return errors.New("You may provide either a ttl or a lease, but not both")
+	}
+
+	opts := []api.SetOption{api.WithTTL(ttl), api.WithLease(lease)}
+
+	var err error
+	switch {
+	case ifAbsent:
+		revision, err = r.Client.SetIfAbsent(commandCtx, source, key, []byte(value), opts...)
+	case revision > 0:
+		revision, err = r.Client.CompareAndSet(commandCtx, source, key, []byte(value), revision, opts...)
+	default:
+		revision, err = r.Client.SetValueRevision(commandCtx, source, key, []byte(value), opts...)
+	}
+
+	if err != nil {
 		return err
 	}
 
-	r.Logger.Info("Success", "source", source, "key", key, "value", string(value))
+	r.Logger.Info("Success", "source", source, "key", key, "value", string(value), "revision", revision)
 	return nil
 }
 
-func (r *runner) getValue(source, key string) error {
+func (r *runner) getValue(source, key string, revision uint64) error {
 	if len(source) == 0 {
 		return errors.New("You must provide a source")
 	}
@@ -51,12 +77,12 @@ func (r *runner) getValue(source, key string) error {
 	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancelCommand()
 
-	value, err := r.Client.GetValue(commandCtx, source, key)
+	value, revision, err := r.Client.GetValueAt(commandCtx, source, key, revision)
 	if err != nil {
 		return err
 	}
 
-	r.Logger.Info("Success", "source", source, "key", key, "value", string(value))
+	r.Logger.Info("Success", "source", source, "key", key, "value", string(value), "revision", revision)
 	return nil
 }
 
@@ -76,7 +102,7 @@ func (r *runner) getSources() error {
 	return nil
 }
 
-func (r *runner) getKeys(source string) error {
+func (r *runner) getKeys(source string, prefix string, limit int, continuation string) error {
 	if len(source) == 0 {
 		return errors.New("You must provide a source")
 	}
@@ -84,18 +110,108 @@ func (r *runner) getKeys(source string) error {
 	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancelCommand()
 
-	keys, err := r.Client.GetKeys(commandCtx, source)
+	keys, next, err := r.Client.ListKeys(commandCtx, source, api.WithPrefix(prefix), api.WithLimit(limit), api.WithContinuation(continuation))
 	if err != nil {
 		return err
 	}
 
-	r.Logger.Info("Success", "source", source, "count", len(keys))
+	if len(next) > 0 {
+		r.Logger.Info("Success", "source", source, "count", len(keys), "continue", next)
+	} else {
+		r.Logger.Info("Success", "source", source, "count", len(keys))
+	}
+
 	for _, k := range keys {
 		fmt.Println(k)
 	}
 	return nil
 }
 
+func (r *runner) history(source, key string) error {
+	if len(source) == 0 {
+		return errors.New("You must provide a source")
+	}
+
+	if len(key) == 0 {
+		return errors.New("You must provide a key")
+	}
+
+	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelCommand()
+
+	versions, err := r.Client.GetHistory(commandCtx, source, key)
+	if err != nil {
+		return err
+	}
+
+	r.Logger.Info("Success", "source", source, "key", key, "count", len(versions))
+	for _, v := range versions {
+		timestamp := time.Unix(0, v.Timestamp).Format(time.RFC3339Nano)
+		if v.Deleted {
+			fmt.Printf("%d\t%s\t(deleted)\n", v.Revision, timestamp)
+			continue
+		}
+		fmt.Printf("%d\t%s\t%s\n", v.Revision, timestamp, string(v.Value))
+	}
+	return nil
+}
+
+func (r *runner) watch(source, key string, revision uint64) error {
+	// Watching without a source receives every update applied to the cluster
+	if len(source) == 0 {
+		source = string(iris.AnyCharacters)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var opts []api.SubscribeOption
+	if revision > 0 {
+		opts = append(opts, api.FromRevision(revision))
+	}
+
+	var events <-chan *api.WatchEvent
+	var err error
+	switch {
+	case iris.IsPattern(source) || iris.IsPattern(key):
+		events, err = r.Client.WatchPattern(ctx, source, key, opts...)
+	case len(key) > 0:
+		events, err = r.Client.WatchKey(ctx, source, key, opts...)
+	default:
+		events, err = r.Client.Watch(ctx, source, opts...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.Logger.Info("Watching", "source", source, "key", key)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			// The watch continues once the client reconnects after an interruption
+			if e.Update == nil {
+				r.Logger.Warning("Interrupted", "error", e.Err)
+				continue
+			}
+
+			u := e.Update
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\n", u.Index, u.Operation, u.Source, u.Key, string(u.Value))
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
 func (r *runner) removeSource(source string) error {
 	if len(source) == 0 {
 		return errors.New("You must provide a source")
@@ -112,7 +228,7 @@ func (r *runner) removeSource(source string) error {
 	return nil
 }
 
-func (r *runner) removeValue(source, key string) error {
+func (r *runner) removeValue(source, key string, revision uint64) error {
 	if len(source) == 0 {
 		return errors.New("You must provide a source")
 	}
@@ -124,10 +240,94 @@ func (r *runner) removeValue(source, key string) error {
 	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancelCommand()
 
-	if err := r.Client.RemoveValue(commandCtx, source, key); err != nil {
+	var err error
+	if revision > 0 {
+		err = r.Client.CompareAndRemove(commandCtx, source, key, revision)
+	} else {
+		err = r.Client.RemoveValue(commandCtx, source, key)
+	}
+
+	if err != nil {
 		return err
 	}
 
 	r.Logger.Info("Success", "source", source, "key", key)
 	return nil
 }
+
+func (r *runner) cluster(subcommand string, peer string) error {
+	commandCtx, cancelCommand := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelCommand()
+
+	switch subcommand {
+	case clusterStatusSubcommand:
+		status, err := r.Client.ClusterStatus(commandCtx)
+		if err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success", "address", status.Address, "state", status.State, "leader", status.Leader, "term", status.Term,
+			"commitIndex", status.CommitIndex, "appliedIndex", status.AppliedIndex)
+		for _, p := range status.Peers {
+			var contact string
+			if p.LastContact > 0 {
+				contact = time.Since(time.Unix(0, p.LastContact)).String()
+			}
+			fmt.Printf("%s\t%s\t%s\t%d\t%s\n", p.Address, p.State, p.GrpcAddress, p.MatchIndex, contact)
+		}
+		return nil
+	case clusterPeersSubcommand:
+		peers, err := r.Client.ListPeers(commandCtx)
+		if err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success", "count", len(peers))
+		for _, p := range peers {
+			fmt.Println(p)
+		}
+		return nil
+	case clusterRemoveSubcommand:
+		if len(peer) == 0 {
+			return errors.New("You must provide the raft address of a peer")
+		}
+
+		if err := r.Client.RemovePeer(commandCtx, peer); err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success", "peer", peer)
+		return nil
+	case clusterTransferSubcommand:
+		if len(peer) == 0 {
+			return errors.New("You must provide the raft address of a peer")
+		}
+
+		transferCtx, cancelTransfer := context.WithTimeout(context.Background(), transferTimeout)
+		defer cancelTransfer()
+
+		leader, err := r.Client.TransferLeadership(transferCtx, peer)
+		if err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success", "leader", leader)
+		return nil
+	case clusterDrainSubcommand:
+		sessions, err := r.Client.Drain(commandCtx)
+		if err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success", "sessions", sessions)
+		return nil
+	case clusterUndrainSubcommand:
+		if err := r.Client.Undrain(commandCtx); err != nil {
+			return err
+		}
+
+		r.Logger.Info("Success")
+		return nil
+	}
+	return errors.New("Unknown cluster subcommand")
+}
@@ -23,6 +23,16 @@ const (
 	getKeysCommandName      = "getkeys"
 	removeSourceCommandName = "removesource"
 	removeValueCommandName  = "removekey"
+	historyCommandName      = "history"
+	watchCommandName        = "watch"
+	clusterCommandName      = "cluster"
+
+	clusterStatusSubcommand   = "status"
+	clusterPeersSubcommand    = "peers"
+	clusterRemoveSubcommand   = "remove"
+	clusterTransferSubcommand = "transfer"
+	clusterDrainSubcommand    = "drain"
+	clusterUndrainSubcommand  = "undrain"
 
 	sourceUsage   = "The name of the source to be used."
 	sourceParam   = "source"
@@ -30,6 +40,22 @@ const (
 	keyParam      = "key"
 	valueUsage    = "The value to be used."
 	valueParam    = "value"
+	revisionUsage = "Only apply the change if the key is currently at this revision, or get the value as of this revision."
+	revisionParam = "revision"
+	ifAbsentUsage = "Only set the value if the key does not already exist."
+	ifAbsentParam = "ifabsent"
+	ttlUsage      = "Remove the value once this duration elapses, e.g. 30s."
+	ttlParam      = "ttl"
+	leaseUsage    = "Attach the value to this lease so that it is removed when the lease expires."
+	leaseParam    = "lease"
+	prefixUsage   = "Only list the keys beginning with this prefix."
+	prefixParam   = "prefix"
+	limitUsage    = "The maximum number of keys to list."
+	limitParam    = "limit"
+	continueUsage = "Continue a listing from the token reported by a previous listing."
+	continueParam = "continue"
+	peerUsage     = "The raft address of the peer to remove from the cluster, or to transfer leadership to."
+	peerParam     = "peer"
 	addrUsage     = "Address of the stela server to connect to."
 	addrParam     = "addr"
 	insecureUsage = "Disable SSL, allowing unenecrypted communication with the service."
@@ -68,6 +94,14 @@ func printUsageInstructions() {
 	fmt.Printf("\t%s\t\t\tGet a list of keys contained in a source\n", getKeysCommandName)
 	fmt.Printf("\t%s\t\tRemove a source\n", removeSourceCommandName)
 	fmt.Printf("\t%s\t\tRemove a key/value pair\n", removeValueCommandName)
+	fmt.Printf("\t%s\t\t\tGet the previous versions of a value\n", historyCommandName)
+	fmt.Printf("\t%s\t\t\tPrint updates to the sources and keys matching a pattern as they are applied\n", watchCommandName)
+	fmt.Printf("\t%s %s\t\tPrint the raft state of the cluster and each of its peers\n", clusterCommandName, clusterStatusSubcommand)
+	fmt.Printf("\t%s %s\t\tList the raft addresses of the cluster's peers\n", clusterCommandName, clusterPeersSubcommand)
+	fmt.Printf("\t%s %s\t\tRemove a peer from the cluster\n", clusterCommandName, clusterRemoveSubcommand)
+	fmt.Printf("\t%s %s\tTransfer leadership of the cluster to a peer\n", clusterCommandName, clusterTransferSubcommand)
+	fmt.Printf("\t%s %s\t\tStop accepting sessions on a node and move its clients to other nodes\n", clusterCommandName, clusterDrainSubcommand)
+	fmt.Printf("\t%s %s\t\tAccept sessions on a drained node again\n", clusterCommandName, clusterUndrainSubcommand)
 }
 
 func main() {
@@ -87,6 +121,14 @@ func run() (status int) {
 		source   string
 		key      string
 		value    string
+		revision uint64
+		ifAbsent = false
+		ttl      time.Duration
+		lease    uint64
+		prefix   string
+		limit    int
+		cont     string
+		peer     string
 		insecure = false
 		noStela  = false
 
@@ -112,16 +154,48 @@ func run() (status int) {
 		command != getSourcesCommandName &&
 		command != getKeysCommandName &&
 		command != removeSourceCommandName &&
-		command != removeValueCommandName {
+		command != removeValueCommandName &&
+		command != historyCommandName &&
+		command != watchCommandName &&
+		command != clusterCommandName {
 		printUsageInstructions()
 		return exitStatusError
 	}
 
+	// Cluster administration is divided into subcommands preceding the flags
+	args := os.Args[2:]
+	var subcommand string
+	if command == clusterCommandName {
+		if len(args) == 0 {
+			printUsageInstructions()
+			return exitStatusError
+		}
+		subcommand, args = args[0], args[1:]
+
+		if subcommand != clusterStatusSubcommand &&
+			subcommand != clusterPeersSubcommand &&
+			subcommand != clusterRemoveSubcommand &&
+			subcommand != clusterTransferSubcommand &&
+			subcommand != clusterDrainSubcommand &&
+			subcommand != clusterUndrainSubcommand {
+			printUsageInstructions()
+			return exitStatusError
+		}
+	}
+
 	flag := flag.NewFlagSet(command, flag.ExitOnError)
 	flag.StringVar(&addr, addrParam, addr, addrUsage)
 	flag.StringVar(&source, sourceParam, source, sourceUsage)
 	flag.StringVar(&key, keyParam, key, keyUsage)
 	flag.StringVar(&value, valueParam, value, valueUsage)
+	flag.Uint64Var(&revision, revisionParam, revision, revisionUsage)
+	flag.BoolVar(&ifAbsent, ifAbsentParam, ifAbsent, ifAbsentUsage)
+	flag.DurationVar(&ttl, ttlParam, ttl, ttlUsage)
+	flag.Uint64Var(&lease, leaseParam, lease, leaseUsage)
+	flag.StringVar(&prefix, prefixParam, prefix, prefixUsage)
+	flag.IntVar(&limit, limitParam, limit, limitUsage)
+	flag.StringVar(&cont, continueParam, cont, continueUsage)
+	flag.StringVar(&peer, peerParam, peer, peerUsage)
 	flag.BoolVar(&insecure, insecureParam, insecure, insecureUsage)
 	flag.BoolVar(&noStela, noStelaParam, noStela, noStelaUsage)
 
@@ -135,7 +209,7 @@ func run() (status int) {
 	flag.StringVar(&stelaCA, stelaCAPathParam, stelaCA, stelaCAPathUsage)
 	flag.StringVar(&stelaServerName, stelaServerNameParam, stelaServerName, stelaServerNameUsage)
 
-	flag.Parse(os.Args[2:])
+	flag.Parse(args)
 
 	if insecure {
 		ca = ""
@@ -160,7 +234,7 @@ func run() (status int) {
 			defer cancelDiscover()
 			service, err := stelaclient.DiscoverOne(discoverCtx, iris.DefaultServiceName)
 			if err == nil {
-				addr = service.IPv4Address()
+				addr = api.ServiceAddress(service)
 			}
 		}
 	}
@@ -180,7 +254,8 @@ func run() (status int) {
 		client, err = api.NewTLSClient(connectCtx, addr, serverName, clientCert, clientKey, ca)
 	}
 
-	if err != nil {
+	// A draining node refuses new sessions, but can still be administered
+	if err != nil && !(command == clusterCommandName && api.IsDraining(err)) {
 		logger.Error("Failed to connect to Iris server", "error", err.Error())
 		return exitStatusError
 	}
@@ -189,17 +264,23 @@ func run() (status int) {
 	r := &runner{Client: client, Logger: &logger}
 	switch command {
 	case setCommandName:
-		err = r.setValue(source, key, []byte(value))
+		err = r.setValue(source, key, []byte(value), revision, ifAbsent, ttl, lease)
 	case getCommandName:
-		err = r.getValue(source, key)
+		err = r.getValue(source, key, revision)
 	case getSourcesCommandName:
 		err = r.getSources()
 	case getKeysCommandName:
-		err = r.getKeys(source)
+		err = r.getKeys(source, prefix, limit, cont)
 	case removeSourceCommandName:
 		err = r.removeSource(source)
 	case removeValueCommandName:
-		err = r.removeValue(source, key)
+		err = r.removeValue(source, key, revision)
+	case historyCommandName:
+		err = r.history(source, key)
+	case watchCommandName:
+		err = r.watch(source, key, revision)
+	case clusterCommandName:
+		err = r.cluster(subcommand, peer)
 	default:
 		err = errors.New("Unknown command")
 	}
@@ -37,6 +37,8 @@ import (
 const (
 	version             = "0.11.0"               // version represents the semantic version of this service/api
 	timeout             = 500 * time.Millisecond // default timeout for context objects
+	leaveTimeout        = 5 * time.Second        // time allowed for leaving the raft cluster when interrupted
+	registerTimeout     = 10 * time.Second       // time allowed for recording the advertised grpc address with the leader
 	exitStatusSuccess   = 0
 	exitStatusError     = 1
 	exitStatusInterrupt = 2
@@ -86,10 +88,26 @@ func run() (status int) {
 		raftDir  = "raftDir"
 		port     = iris.DefaultServicePort
 		joinAddr = ""
+
+		bindAddr          = ""
+		advertiseAddr     = ""
+		raftPort          = 0
+		raftBindAddr      = ""
+		raftAdvertiseAddr = ""
+
+		historyLimit  = store.DefaultHistoryLimit
+		historyMaxAge time.Duration
+		tombstoneAge  = store.DefaultTombstoneMaxAge
+		storage       = store.StorageMemory
+
+		queueSize    = transport.DefaultQueueSize
+		slowConsumer = transport.SlowConsumerDisconnect
+		eventLogSize = transport.DefaultEventLogSize
+		sessionTTL   = transport.DefaultSessionTTL
 	)
 
 	// Parse, prepare, and validate inputs
-	if err := prepareInputs(&port, &insecure, &nostela, &stelaAddr, &certPath, &keyPath, &caPath, &serverName, &stelaCertPath, &stelaKeyPath, &stelaCAPath, &stelaServerName, &raftDir, &joinAddr); err != nil {
+	if err := prepareInputs(&port, &bindAddr, &advertiseAddr, &raftPort, &raftBindAddr, &raftAdvertiseAddr, &insecure, &nostela, &stelaAddr, &certPath, &keyPath, &caPath, &serverName, &stelaCertPath, &stelaKeyPath, &stelaCAPath, &stelaServerName, &raftDir, &joinAddr, &historyLimit, &historyMaxAge, &tombstoneAge, &storage, &queueSize, &slowConsumer, &eventLogSize, &sessionTTL); err != nil {
 		logger.Error("Error parsing inputs.", "error", err.Error())
 		return exitStatusError
 	}
@@ -115,12 +133,35 @@ func run() (status int) {
 		defer client.Close()
 	}
 
-	// Set up our grpc service parameters
-	raftPort := port + 1
-	service := &stela.Service{
-		Name: iris.DefaultServiceName,
-		Port: int32(port),
+	// Set up our grpc service parameters, registering the advertised address with stela when it is known
+	_, bindPort, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		logger.Error("Unable to determine grpc bind address.", "error", err.Error())
+		return exitStatusError
+	}
+
+	servicePort := bindPort
+	service := &stela.Service{Name: iris.DefaultServiceName}
+	if len(advertiseAddr) > 0 {
+		var host string
+		if host, servicePort, err = net.SplitHostPort(advertiseAddr); err != nil {
+			logger.Error("Unable to determine grpc advertise address.", "error", err.Error())
+			return exitStatusError
+		}
+
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			service.IPv4 = host
+		} else if ip != nil {
+			service.IPv6 = host
+		}
+	}
+
+	registeredPort, err := strconv.Atoi(servicePort)
+	if err != nil {
+		logger.Error("Unable to determine grpc port.", "error", err.Error())
+		return exitStatusError
 	}
+	service.Port = int32(registeredPort)
 
 	// Determine join address before registering our service
 	// Important not to discover ourselves as a node to join
@@ -148,20 +189,45 @@ func run() (status int) {
 		}()
 	}
 
-	// Determine grpc and raft addr
-	host, _, err := net.SplitHostPort(service.IPv4Address())
+	// Determine the grpc and raft addresses advertised to clients and the other nodes
+	host, err := advertiseHost(advertiseAddr, bindAddr, service)
 	if err != nil {
-		logger.Error("Unable to determine grpc address.", err)
+		logger.Error("Unable to determine grpc advertise address.", "error", err.Error())
 		return exitStatusError
 	}
 
-	listenAddr := net.JoinHostPort("", strconv.Itoa(port))
-	grpcAddr := net.JoinHostPort(host, strconv.Itoa(port))
-	raftAddr := net.JoinHostPort(host, strconv.Itoa(raftPort))
+	grpcAddr := advertiseAddr
+	if len(grpcAddr) == 0 {
+		grpcAddr = net.JoinHostPort(host, bindPort)
+	}
+
+	raftAddr := raftAdvertiseAddr
+	if len(raftAddr) == 0 {
+		raftAddr = net.JoinHostPort(host, strconv.Itoa(raftPort))
+	}
+
+	if len(raftBindAddr) == 0 {
+		raftBindAddr = net.JoinHostPort("", strconv.Itoa(raftPort))
+	}
 	logger = logger.With("raftAddr", raftAddr, "grpcAddr", grpcAddr)
 
+	// Load the certificates securing grpc requests and raft communications unless running insecurely
+	var tlsConfig *tls.Config
+	if !insecure {
+		if tlsConfig, err = loadTLSConfig(serverName, certPath, keyPath, caPath); err != nil {
+			logger.Error("Failed to load TLS configuration.", "error", err.Error())
+			return exitStatusError
+		}
+	}
+
 	// Setup our data store
-	store := store.NewStore(raftAddr, raftDir, logger)
+	store := store.NewStore(raftBindAddr, raftDir, logger)
+	store.RaftAdvertiseAddr = raftAddr
+	store.RaftTLSConfig = tlsConfig
+	store.HistoryLimit = historyLimit
+	store.HistoryMaxAge = historyMaxAge
+	store.TombstoneMaxAge = tombstoneAge
+	store.Storage = storage
 	if err := store.Open(startAsLeader); err != nil {
 		logger.Error("Failed to open data store.", "error", err)
 		return exitStatusError
@@ -174,69 +240,55 @@ func run() (status int) {
 		intchan <- handleInterrupts()
 	}()
 
+	server := &transport.Server{
+		Store:        store,
+		QueueSize:    queueSize,
+		SlowConsumer: slowConsumer,
+		EventLogSize: eventLogSize,
+		SessionTTL:   sessionTTL,
+		Proxy: &transport.Proxy{
+			ServerName: serverName,
+			CertPath:   certPath,
+			KeyPath:    keyPath,
+			CAPath:     caPath,
+			Insecure:   insecure,
+		},
+	}
+	defer server.Proxy.Close()
+
 	// Serve our remote procedures
 	go func() {
-		l, err := net.Listen("tcp", listenAddr)
+		l, err := net.Listen("tcp", bindAddr)
 		if err != nil {
 			errchan <- fmt.Errorf("Failed to start tcp listener. %s", err)
 		}
 
 		var opts []grpc.ServerOption
-		if !insecure {
-			// Load the certificates from disk
-			certificate, err := tls.LoadX509KeyPair(certPath, keyPath)
-			if err != nil {
-				errchan <- fmt.Errorf("Failed to load certificate. %s", err)
-				return
-			}
-
-			// Create a certificate pool from the certificate authority
-			certPool := x509.NewCertPool()
-			ca, err := ioutil.ReadFile(caPath)
-			if err != nil {
-				errchan <- fmt.Errorf("Failed to read CA certificate. %s", err)
-				return
-			}
-
-			// Append the client certificates from the CA
-			if ok := certPool.AppendCertsFromPEM(ca); !ok {
-				errchan <- errors.New("Failed to append client certs")
-				return
-			}
-
-			// Create the TLS credentials
-			creds := credentials.NewTLS(&tls.Config{
-				ClientAuth:   tls.RequireAndVerifyClientCert,
-				Certificates: []tls.Certificate{certificate},
-				ClientCAs:    certPool,
-			})
-
-			opts = append(opts, grpc.Creds(creds))
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 		}
 
 		logger.Info("Starting iris")
 		grpcServer := grpc.NewServer(opts...)
-		server := &transport.Server{
-			Store: store,
-			Proxy: &transport.Proxy{
-				ServerName: serverName,
-				CertPath:   certPath,
-				KeyPath:    keyPath,
-				CAPath:     caPath,
-			},
-		}
 		pb.RegisterIrisServer(grpcServer, server)
 		errchan <- grpcServer.Serve(l)
 	}()
 
-	// Join the raft leader if necessary
+	// Join the raft leader if necessary, which records the grpc address of this node along with its raft address.
+	// Nodes that are already members record their grpc address with the leader once one is elected.
 	if !startAsLeader {
 		go func() {
 			logger.Info("Joining raft cluster")
-			if err := join(joinAddr, raftAddr, serverName, certPath, keyPath, caPath, 500*time.Millisecond); err != nil {
+			if err := join(joinAddr, store.RaftAddr(), grpcAddr, serverName, certPath, keyPath, caPath, 500*time.Millisecond); err != nil {
 				errchan <- fmt.Errorf("Failed to join raft cluster. %s", err)
 			}
 		}()
+	} else {
+		go func() {
+			if err := register(server, grpcAddr, registerTimeout); err != nil {
+				logger.Error("Failed to record grpc address with the leader.", "error", err.Error())
+			}
+		}()
 	}
 
 	// Wait for our exit signals
@@ -246,6 +298,13 @@ func run() (status int) {
 		return exitStatusError
 	case status := <-intchan:
 		logger.Info("Interrupted")
+
+		// Leave the raft cluster so that the remaining nodes no longer count this node towards their quorum
+		leaveCtx, cancelLeave := context.WithTimeout(context.Background(), leaveTimeout)
+		defer cancelLeave()
+		if _, err := server.Leave(leaveCtx, &pb.LeaveRequest{}); err != nil {
+			logger.Error("Failed to leave raft cluster.", "error", err.Error())
+		}
 		return status
 	}
 }
@@ -261,12 +320,40 @@ func fetchJoinAddress(client *stela_api.Client) (string, error) {
 	if len(services) == 0 {
 		return "", fmt.Errorf("Discover request returned no services matching %s", iris.DefaultServiceName)
 	}
-	return services[0].IPv4Address(), nil
+	return iris_api.ServiceAddress(services[0]), nil
 }
 
-func prepareInputs(port *int, insecure *bool, nostela *bool, stelaAddr *string, certPath *string, keyPath *string, caPath *string, serverName *string, stelaCertPath *string, stelaKeyPath *string, stelaCAPath *string, stelaServerName *string, raftDir *string, joinAddr *string) error {
+// advertiseHost returns the host advertised to clients and the other nodes, which is the host of the advertise
+// address if provided, followed by the address registered with stela, and then the bound host if it is not
+// every interface
+func advertiseHost(advertiseAddr string, bindAddr string, service *stela.Service) (string, error) {
+	if len(advertiseAddr) > 0 {
+		host, _, err := net.SplitHostPort(advertiseAddr)
+		return host, err
+	}
+
+	if len(service.IPv4) > 0 {
+		return service.IPv4, nil
+	}
+
+	if len(service.IPv6) > 0 {
+		return service.IPv6, nil
+	}
+
+	host, _, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return "", err
+	}
+
+	if ip := net.ParseIP(host); len(host) == 0 || (ip != nil && ip.IsUnspecified()) {
+		return "", errors.New("The address to advertise is unknown, provide it with -advertise")
+	}
+	return host, nil
+}
+
+func prepareInputs(port *int, bindAddr *string, advertiseAddr *string, raftPort *int, raftBindAddr *string, raftAdvertiseAddr *string, insecure *bool, nostela *bool, stelaAddr *string, certPath *string, keyPath *string, caPath *string, serverName *string, stelaCertPath *string, stelaKeyPath *string, stelaCAPath *string, stelaServerName *string, raftDir *string, joinAddr *string, historyLimit *int, historyMaxAge *time.Duration, tombstoneAge *time.Duration, storage *string, queueSize *int, slowConsumer *string, eventLogSize *int, sessionTTL *time.Duration) error {
 	// Parse command line flags
-	flag.BoolVar(insecure, "insecure", *insecure, "Disable SSL, allowing unenecrypted communication with this service.")
+	flag.BoolVar(insecure, "insecure", *insecure, "Disable SSL, allowing unenecrypted communication with this service and between the members of its cluster.")
 	flag.BoolVar(nostela, "nostela", *nostela, "Disable automatic stela registration.")
 	flag.StringVar(stelaAddr, "stela", *stelaAddr, "Address of the stela service you would like to use for discovery")
 
@@ -281,10 +368,67 @@ func prepareInputs(port *int, insecure *bool, nostela *bool, stelaAddr *string,
 	flag.StringVar(stelaServerName, "stelaServerName", *stelaServerName, "The common name of the stela server you are connecting to.")
 
 	flag.IntVar(port, "port", *port, "Port used for grpc communications.")
+	flag.StringVar(bindAddr, "bind", *bindAddr, "Address the grpc service listens on. Defaults to every interface on the grpc port.")
+	flag.StringVar(advertiseAddr, "advertise", *advertiseAddr, "Address clients and other nodes reach the grpc service at. Defaults to the address registered with stela on the grpc port.")
+	flag.IntVar(raftPort, "raftPort", *raftPort, "Port used for raft communications. Defaults to the port following the grpc bind port.")
+	flag.StringVar(raftBindAddr, "raftBind", *raftBindAddr, "Address raft communications are received on. Defaults to every interface on the raft port.")
+	flag.StringVar(raftAdvertiseAddr, "raftAdvertise", *raftAdvertiseAddr, "Address other nodes reach this node's raft communications at. Defaults to the host of the grpc advertise address on the raft port.")
 	flag.StringVar(raftDir, "raftdir", *raftDir, "Directory used to store raft data.")
 	flag.StringVar(joinAddr, "join", *joinAddr, "Address of the raft cluster leader you would like to join.")
+	flag.IntVar(historyLimit, "historyLimit", *historyLimit, "Number of previous versions retained for each key. Zero retains all versions, and a negative value disables history.")
+	flag.DurationVar(historyMaxAge, "historyAge", *historyMaxAge, "Duration previous versions are retained for. Zero retains versions regardless of age.")
+	flag.DurationVar(tombstoneAge, "tombstoneAge", *tombstoneAge, "Duration the history of a removed key is retained for after its removal. Zero retains it until its versions are discarded by age.")
+	flag.StringVar(storage, "storage", *storage, "Storage engine holding the data of this node, either memory or bolt. The bolt engine persists data in the raft directory.")
+	flag.IntVar(queueSize, "queueSize", *queueSize, "Number of updates queued for delivery to each client.")
+	flag.StringVar(slowConsumer, "slowConsumer", *slowConsumer, "Policy applied when a client's update queue is full, either disconnect, drop to discard its oldest update, or block.")
+	flag.IntVar(eventLogSize, "eventLog", *eventLogSize, "Number of recent updates retained so that subscriptions can resume from a past revision.")
+	flag.DurationVar(sessionTTL, "sessionTTL", *sessionTTL, "Duration a client holding ephemeral values remains connected without a heartbeat.")
 	flag.Parse()
 
+	// Validate network inputs, where IPv6 hosts are enclosed in brackets
+	if len(*bindAddr) == 0 {
+		*bindAddr = net.JoinHostPort("", strconv.Itoa(*port))
+	}
+
+	for _, addr := range []string{*bindAddr, *advertiseAddr, *raftBindAddr, *raftAdvertiseAddr} {
+		if len(addr) == 0 {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("Invalid address %s: %s", addr, err)
+		}
+	}
+
+	// The raft port follows the port grpc requests are received on
+	if *raftPort == 0 {
+		_, bindPort, _ := net.SplitHostPort(*bindAddr)
+		grpcPort, err := strconv.Atoi(bindPort)
+		if err != nil {
+			return fmt.Errorf("Invalid grpc port %s: %s", bindPort, err)
+		}
+		*raftPort = grpcPort + 1
+	}
+
+	// Validate update delivery inputs
+	if *queueSize <= 0 {
+		return errors.New("You must provide a positive update queue size")
+	}
+
+	switch *slowConsumer {
+	case transport.SlowConsumerBlock, transport.SlowConsumerDropOldest, transport.SlowConsumerDisconnect:
+	default:
+		return fmt.Errorf("Unrecognized slow consumer policy %s", *slowConsumer)
+	}
+
+	if *eventLogSize <= 0 {
+		return errors.New("You must provide a positive event log size")
+	}
+
+	if *sessionTTL <= 0 {
+		return errors.New("You must provide a positive session ttl")
+	}
+
 	// Validate authentication inputs
 	if !*insecure && len(*certPath) == 0 {
 		return errors.New("You must provide the path to an SSL certificate used to encrypt communications with this service")
@@ -297,8 +441,38 @@ func prepareInputs(port *int, insecure *bool, nostela *bool, stelaAddr *string,
 	return nil
 }
 
+// loadTLSConfig loads the certificate of this node along with the certificate authority verifying the certificates
+// presented by its clients and the other nodes of the cluster, which must be issued to the server name
+func loadTLSConfig(serverName string, cert string, key string, ca string) (*tls.Config, error) {
+	// Load the certificates from disk
+	certificate, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load certificate. %s", err)
+	}
+
+	// Create a certificate pool from the certificate authority
+	certPool := x509.NewCertPool()
+	b, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read CA certificate. %s", err)
+	}
+
+	// Append the client certificates from the CA
+	if ok := certPool.AppendCertsFromPEM(b); !ok {
+		return nil, errors.New("Failed to append client certs")
+	}
+
+	return &tls.Config{
+		ServerName:   serverName,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    certPool,
+		RootCAs:      certPool,
+	}, nil
+}
+
 // join the specified raft cluster
-func join(joinAddr, raftAddr string, serverName string, cert string, key string, ca string, timeout time.Duration) error {
+func join(joinAddr, raftAddr, grpcAddr string, serverName string, cert string, key string, ca string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -307,13 +481,30 @@ func join(joinAddr, raftAddr string, serverName string, cert string, key string,
 		return err
 	}
 
-	if err := client.Join(ctx, raftAddr); err != nil {
+	if err := client.JoinWithAddress(ctx, raftAddr, grpcAddr); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// register records the grpc address of this node with the leader of its cluster, once a leader is elected
+func register(server *transport.Server, grpcAddr string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for len(server.Leader()) == 0 {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	_, err := server.Join(ctx, &pb.JoinRequest{Address: server.Store.RaftAddr(), GrpcAddress: grpcAddr})
+	return err
+}
+
 // listen for interrupt notifications and return when they have been received
 func handleInterrupts() int {
 	c := make(chan os.Signal)
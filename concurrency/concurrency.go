@@ -0,0 +1,177 @@
+// Package concurrency provides distributed locks and leader elections built on the ephemeral values of an Iris client.
+// A lock or leadership is held by a key that exists only while the session of the client holding it remains alive,
+// so it is released when the client disconnects, its node fails, or its node is drained.  Each acquisition is
+// identified by a fencing token, the raft index at which the key was written, which increases with every acquisition.
+// Resources protected by a lock or leadership should reject requests carrying a token lower than the highest they have
+// seen, since a holder whose session was lost may not yet know it.  A holder learns that its lock or leadership was
+// lost through the channel returned by Done, which is closed once the client's update stream is interrupted, since the
+// client then reconnects with a new session without the ephemeral values of the previous one.
+package concurrency
+
+import (
+	"context"
+	"errors"
+
+	"github.com/forestgiant/iris/api"
+	"github.com/forestgiant/iris/pb"
+)
+
+// Sources holding the keys of locks and elections, where each lock or election is a key named after it
+const (
+	LockSource     = "com.forestgiant.iris.concurrency.locks"
+	ElectionSource = "com.forestgiant.iris.concurrency.elections"
+)
+
+// ErrLocked is returned when a lock or leadership is already held
+var ErrLocked = errors.New("The lock is already held")
+
+// ErrNotHeld is returned when releasing a lock or leadership that is not held, or that was lost along with the session
+var ErrNotHeld = errors.New("The lock is not held")
+
+var errWatchEnded = errors.New("The watch ended before the lock was released")
+
+// closed is returned by Done while nothing is held
+var closed = make(chan struct{})
+
+func init() {
+	close(closed)
+}
+
+// hold tracks an acquisition through the watch of its key, which begins before the key is written so that no update
+// following the write is missed
+type hold struct {
+	token  uint64
+	cancel context.CancelFunc
+	lost   chan struct{}
+	done   chan struct{}
+}
+
+// newHold begins tracking the acquisition at the token, closing lost once the key is updated after the write or
+// the client's update stream is interrupted, or the watch ends
+func newHold(token uint64, cancel context.CancelFunc, events <-chan *api.WatchEvent) *hold {
+	h := &hold{token: token, cancel: cancel, lost: make(chan struct{}), done: make(chan struct{})}
+
+	go func() {
+		defer close(h.done)
+
+		// The updates preceding the write are those of the previous holder
+		for e := range events {
+			if e.Update == nil || e.Update.Index > token {
+				break
+			}
+		}
+
+		close(h.lost)
+		unwatch(cancel, events)
+	}()
+	return h
+}
+
+// held indicates whether the acquisition has yet to be lost
+func (h *hold) held() bool {
+	if h == nil {
+		return false
+	}
+
+	select {
+	case <-h.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop ends the watch of the acquisition, and waits until it has been unsubscribed
+func (h *hold) stop() {
+	h.cancel()
+	<-h.done
+}
+
+// tryAcquire writes the key as an ephemeral value if it does not exist, and returns the acquisition
+func tryAcquire(ctx context.Context, client *api.Client, source string, key string, value []byte) (*hold, error) {
+	h, _, err := write(ctx, client, source, key, value)
+	return h, err
+}
+
+// acquire waits until the key can be written as an ephemeral value, and returns the acquisition.  The key is watched
+// before each attempt, so that its removal is not missed.
+func acquire(ctx context.Context, client *api.Client, source string, key string, value []byte) (*hold, error) {
+	for {
+		h, wait, err := write(ctx, client, source, key, value)
+		if err != ErrLocked {
+			return h, err
+		}
+
+		if err := wait(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// write watches the key and writes it as an ephemeral value if it does not exist.  Should the key exist, ErrLocked is
+// returned along with a function waiting for the key to be released.
+func write(ctx context.Context, client *api.Client, source string, key string, value []byte) (*hold, func() error, error) {
+	// The watch outlives the context, since it tracks the acquisition for as long as it is held
+	watchCtx, cancel := context.WithCancel(context.Background())
+	events, err := client.WatchKey(watchCtx, source, key)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	token, err := client.SetIfAbsent(ctx, source, key, value, api.Ephemeral())
+	if err == nil {
+		return newHold(token, cancel, events), nil, nil
+	}
+
+	if !api.IsRevisionMismatch(err) {
+		unwatch(cancel, events)
+		return nil, nil, err
+	}
+
+	wait := func() error {
+		defer unwatch(cancel, events)
+		return released(ctx, events)
+	}
+	return nil, wait, ErrLocked
+}
+
+// unwatch cancels the watch and waits until it has been unsubscribed, so that none of its requests remain in flight
+// once the lock is acquired and the client may be closed
+func unwatch(cancel context.CancelFunc, events <-chan *api.WatchEvent) {
+	cancel()
+	for range events {
+	}
+}
+
+// released waits for the watched key to be removed, or until the context is done
+func released(ctx context.Context, events <-chan *api.WatchEvent) error {
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return errWatchEnded
+			}
+
+			// The key's removal while the client reconnects is replayed, or followed by a resync, once it reconnects
+			if e.Update != nil && e.Update.Operation != pb.UpdateOperation_SET {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release removes the key if it is still at the fencing token
+func release(ctx context.Context, client *api.Client, source string, key string, token uint64) error {
+	if token == 0 {
+		return ErrNotHeld
+	}
+
+	err := client.CompareAndRemove(ctx, source, key, token)
+	if api.IsRevisionMismatch(err) {
+		return ErrNotHeld
+	}
+	return err
+}
@@ -0,0 +1,288 @@
+package concurrency_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/forestgiant/portutil"
+	"google.golang.org/grpc"
+
+	"github.com/forestgiant/iris/api"
+	"github.com/forestgiant/iris/concurrency"
+	"github.com/forestgiant/iris/pb"
+	"github.com/forestgiant/iris/store"
+	"github.com/forestgiant/iris/transport"
+	fglog "github.com/forestgiant/log"
+)
+
+var testServiceAddress string
+
+const testSessionTTL = 300 * time.Millisecond
+
+const exitStatusError = 1
+
+type SuppressedWriter struct{}
+
+func (w *SuppressedWriter) Write(p []byte) (n int, err error) {
+	return 0, nil
+}
+
+func TestMain(m *testing.M) {
+	main := func() int {
+		wd, err := os.Getwd()
+		if err != nil {
+			fmt.Println("Unable to get current working directory", err)
+			return exitStatusError
+		}
+
+		port, err := portutil.GetUniqueTCP()
+		if err != nil {
+			fmt.Println("unable to obtain open port", err)
+			return exitStatusError
+		}
+
+		testServiceAddress = fmt.Sprintf("127.0.0.1:%d", port)
+		testRaftAddress := fmt.Sprintf("127.0.0.1:%d", port+1)
+
+		listener, err := net.Listen("tcp", testServiceAddress)
+		if err != nil {
+			fmt.Println("unable to start tcp listener", err)
+			return exitStatusError
+		}
+
+		testRaftDir := filepath.Join(wd, "com.forestgiant.iris.testing.concurrency.raftDir")
+		defer os.RemoveAll(testRaftDir)
+
+		var store = store.NewStore(testRaftAddress, testRaftDir, fglog.Logger{Writer: &SuppressedWriter{}})
+		if err := store.Open(true); err != nil {
+			return exitStatusError
+		}
+
+		grpcServer := grpc.NewServer()
+		pb.RegisterIrisServer(grpcServer, &transport.Server{
+			Store:      store,
+			SessionTTL: testSessionTTL,
+		})
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		// Wait for the single node cluster to elect itself
+		deadline := time.Now().Add(5 * time.Second)
+		for !store.IsLeader() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		return m.Run()
+	}
+
+	os.Exit(main())
+}
+
+func newTestClient(t *testing.T) *api.Client {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	client, err := api.NewClient(ctx, testServiceAddress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestMutex(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, second, third := newTestClient(t), newTestClient(t), newTestClient(t)
+	defer first.Close()
+	defer third.Close()
+
+	m1 := concurrency.NewMutex(first, "mutex")
+	m2 := concurrency.NewMutex(second, "mutex")
+	m3 := concurrency.NewMutex(third, "mutex")
+
+	token1, err := m1.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := m1.Done()
+	select {
+	case <-done:
+		t.Error("Done should not be closed while the lock is held.")
+	default:
+	}
+
+	if _, err := m2.TryLock(ctx); err != concurrency.ErrLocked {
+		t.Error("TryLock should fail while the lock is held.", err)
+	}
+
+	acquired := make(chan uint64, 1)
+	go func() {
+		token, err := m2.Lock(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- token
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Lock should wait while the lock is held.")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := m1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	default:
+		t.Error("Done should be closed once the lock is unlocked.")
+	}
+
+	if err := m1.Unlock(ctx); err != concurrency.ErrNotHeld {
+		t.Error("Unlocking a lock that is not held should fail.", err)
+	}
+
+	select {
+	case token2 := <-acquired:
+		if token2 <= token1 || m2.Token() != token2 {
+			t.Error("Each acquisition should have a greater fencing token.", token1, token2)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The waiting lock was not acquired once released.")
+	}
+
+	// The lock is released when the session holding it ends
+	second.Close()
+	lockCtx, cancelLock := context.WithTimeout(ctx, time.Second)
+	defer cancelLock()
+	if _, err := m3.Lock(lockCtx); err != nil {
+		t.Error("The lock should be released when the session holding it ends.", err)
+	}
+}
+
+func TestMutexDrain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	holder, admin := newTestClient(t), newTestClient(t)
+	defer holder.Close()
+	defer admin.Close()
+
+	m := concurrency.NewMutex(holder, "drain")
+	if _, err := m.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	e := concurrency.NewElection(holder, "drain")
+	if _, err := e.Campaign(ctx, []byte("holder")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Draining the node ends the session holding the lock, which releases it, and the holder is told it was lost
+	if _, err := admin.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, done := range []<-chan struct{}{m.Done(), e.Done()} {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("The holder should be told once its session ends.")
+		}
+	}
+
+	if m.Token() != 0 || e.Term() != 0 {
+		t.Error("A lost lock or leadership should not report its token.", m.Token(), e.Term())
+	}
+
+	if err := m.Unlock(ctx); err != concurrency.ErrNotHeld {
+		t.Error("Unlocking a lost lock should fail.", err)
+	}
+
+	if err := admin.Undrain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	waiter := newTestClient(t)
+	defer waiter.Close()
+
+	lockCtx, cancelLock := context.WithTimeout(ctx, time.Second)
+	defer cancelLock()
+	if _, err := concurrency.NewMutex(waiter, "drain").Lock(lockCtx); err != nil {
+		t.Error("The lock should be released when the node holding its session is drained.", err)
+	}
+}
+
+func TestElection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, second := newTestClient(t), newTestClient(t)
+	defer first.Close()
+	defer second.Close()
+
+	e1 := concurrency.NewElection(first, "election")
+	e2 := concurrency.NewElection(second, "election")
+
+	if _, err := e2.Leader(ctx); err != concurrency.ErrNoLeader {
+		t.Error("An election without candidates should have no leader.", err)
+	}
+
+	term1, err := e1.Campaign(ctx, []byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaders, err := e2.Observe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elected := make(chan uint64, 1)
+	go func() {
+		term, err := e2.Campaign(ctx, []byte("second"))
+		if err != nil {
+			t.Error(err)
+		}
+		elected <- term
+	}()
+
+	if leader, err := e2.Leader(ctx); err != nil || string(leader.Value) != "first" || leader.Term != term1 {
+		t.Error("The leader should be the elected candidate.", err, leader)
+	}
+
+	if err := e1.Resign(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var term2 uint64
+	select {
+	case term2 = <-elected:
+		if term2 <= term1 {
+			t.Error("Each term should be greater than the last.", term1, term2)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The waiting candidate was not elected once the leader resigned.")
+	}
+
+	expected := []concurrency.Leader{{Value: []byte("first"), Term: term1}, {}, {Value: []byte("second"), Term: term2}}
+	for _, e := range expected {
+		select {
+		case leader := <-leaders:
+			if string(leader.Value) != string(e.Value) || leader.Term != e.Term {
+				t.Error("Observe did not deliver the change of leadership.", leader, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out observing the election.")
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/forestgiant/iris/api"
+	"github.com/forestgiant/iris/pb"
+)
+
+// ErrNoLeader is returned when an election has no leader
+var ErrNoLeader = errors.New("The election has no leader")
+
+// Leader describes the leader of an election, where a term of zero indicates that there is no leader
+type Leader struct {
+	Value []byte
+	Term  uint64
+}
+
+// Election elects one of the campaigning Elections of the same name across the cluster as its leader
+type Election struct {
+	client *api.Client
+	name   string
+
+	mu   sync.Mutex
+	hold *hold
+}
+
+// NewElection returns an election with the given name, in which the client's session campaigns
+func NewElection(client *api.Client, name string) *Election {
+	return &Election{client: client, name: name}
+}
+
+// Campaign waits until elected or the context is done, and returns the term of the leadership, a fencing token
+// that increases with every election.  The value is published to observers of the election while leading.
+func (e *Election) Campaign(ctx context.Context, value []byte) (uint64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.hold.held() {
+		return 0, ErrLocked
+	}
+
+	h, err := acquire(ctx, e.client, ElectionSource, e.name, value)
+	if err != nil {
+		return 0, err
+	}
+
+	e.hold = h
+	return h.token, nil
+}
+
+// Resign gives up leadership, so that another candidate may be elected.  ErrNotHeld is returned if the election
+// is not leading, including when leadership was lost because the client's session ended.
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.hold == nil {
+		return ErrNotHeld
+	}
+
+	err := release(ctx, e.client, ElectionSource, e.name, e.hold.token)
+	if err == nil || err == ErrNotHeld {
+		e.hold.stop()
+		e.hold = nil
+	}
+	return err
+}
+
+// Term returns the term of the current leadership, or zero if the election is not leading, including when leadership
+// was lost because the client's session ended
+func (e *Election) Term() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.hold.held() {
+		return 0
+	}
+	return e.hold.token
+}
+
+// Done returns a channel that is closed once the current leadership ends, whether the election resigned or lost
+// leadership because the client's session ended.  The channel is already closed if the election is not leading.
+func (e *Election) Done() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.hold == nil {
+		return closed
+	}
+	return e.hold.lost
+}
+
+// Leader returns the current leader of the election, or ErrNoLeader if there is none
+func (e *Election) Leader(ctx context.Context) (*Leader, error) {
+	value, term, err := e.client.GetValueRevision(ctx, ElectionSource, e.name, api.WithLinearizableRead())
+	if err != nil {
+		return nil, err
+	}
+
+	if term == 0 {
+		return nil, ErrNoLeader
+	}
+	return &Leader{Value: value, Term: term}, nil
+}
+
+// Observe returns a channel delivering the current leader, if any, followed by each change of leadership, where
+// a leader with a term of zero indicates that the leader resigned or was lost.  Should the changes missed while the
+// client reconnected no longer be retained, a leader with a term of zero is delivered ahead of the current leader.
+// The channel is closed when the context is done, or when the client is closed.
+func (e *Election) Observe(ctx context.Context) (<-chan *Leader, error) {
+	events, err := e.client.WatchKey(ctx, ElectionSource, e.name, api.WithSnapshot())
+	if err != nil {
+		return nil, err
+	}
+
+	leaders := make(chan *Leader)
+	go func() {
+		defer close(leaders)
+
+		for event := range events {
+			// Errors interrupting the client's update stream are followed by the updates missed once it reconnects
+			if event.Update == nil {
+				continue
+			}
+
+			leader := &Leader{}
+			if u := event.Update; u.Operation == pb.UpdateOperation_SET || u.Operation == pb.UpdateOperation_SNAPSHOT {
+				leader.Value, leader.Term = u.Value, u.Index
+			}
+
+			select {
+			case leaders <- leader:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return leaders, nil
+}
@@ -0,0 +1,91 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+
+	"github.com/forestgiant/iris/api"
+)
+
+// Mutex is a distributed lock, held by at most one Mutex of the same name across the cluster at a time
+type Mutex struct {
+	client *api.Client
+	name   string
+
+	mu   sync.Mutex
+	hold *hold
+}
+
+// NewMutex returns a lock with the given name, acquired through the client's session
+func NewMutex(client *api.Client, name string) *Mutex {
+	return &Mutex{client: client, name: name}
+}
+
+// Lock waits until the lock is acquired or the context is done, and returns the fencing token of the acquisition
+func (m *Mutex) Lock(ctx context.Context) (uint64, error) {
+	return m.lock(ctx, acquire)
+}
+
+// TryLock acquires the lock if it is not held, and otherwise returns ErrLocked without waiting
+func (m *Mutex) TryLock(ctx context.Context) (uint64, error) {
+	return m.lock(ctx, tryAcquire)
+}
+
+func (m *Mutex) lock(ctx context.Context, fn func(context.Context, *api.Client, string, string, []byte) (*hold, error)) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hold.held() {
+		return 0, ErrLocked
+	}
+
+	h, err := fn(ctx, m.client, LockSource, m.name, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	m.hold = h
+	return h.token, nil
+}
+
+// Unlock releases the lock.  ErrNotHeld is returned if the lock is not held, including when it was released
+// because the client's session ended.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hold == nil {
+		return ErrNotHeld
+	}
+
+	err := release(ctx, m.client, LockSource, m.name, m.hold.token)
+	if err == nil || err == ErrNotHeld {
+		m.hold.stop()
+		m.hold = nil
+	}
+	return err
+}
+
+// Token returns the fencing token of the current acquisition, or zero if the lock is not held, including when it
+// was lost because the client's session ended
+func (m *Mutex) Token() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.hold.held() {
+		return 0
+	}
+	return m.hold.token
+}
+
+// Done returns a channel that is closed once the current acquisition ends, whether the lock was unlocked or lost
+// because the client's session ended.  The channel is already closed if the lock is not held.
+func (m *Mutex) Done() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hold == nil {
+		return closed
+	}
+	return m.hold.lost
+}
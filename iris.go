@@ -14,6 +14,9 @@ const (
 
 	//DefaultIdentifier is the default identifier for sources to use in their implementations
 	DefaultIdentifier = "default"
+
+	//DrainingMessage describes the status returned by a node draining its clients for maintenance
+	DrainingMessage = "The node is draining its clients for maintenance, reconnect to another node"
 )
 
 // Marshaller comment
@@ -76,3 +76,42 @@ func TestKeyValuePairToString(t *testing.T) {
 		t.Fatal("marshalled results did not match expected results")
 	}
 }
+
+func TestMatchPattern(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "com.forestgiant.colors", true},
+		{"device-*", "device-42", true},
+		{"device-*", "device-", true},
+		{"device-*", "sensor-42", false},
+		{"metrics/cpu/*", "metrics/cpu/core/0", true},
+		{"metrics/cpu/*", "metrics/memory/used", false},
+		{"*/cpu/*/load", "metrics/cpu/0/load", true},
+		{"*/cpu/*/load", "metrics/cpu/0/idle", false},
+		{"device-??", "device-42", true},
+		{"device-??", "device-420", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"é*", "écran", true},
+	}
+
+	for _, test := range tests {
+		if MatchPattern(test.pattern, test.name) != test.match {
+			t.Error("Pattern did not match as expected", test.pattern, test.name, test.match)
+		}
+	}
+
+	if prefix := PatternPrefix("metrics/cpu/*"); prefix != "metrics/cpu/" {
+		t.Error("PatternPrefix should return the literal prefix of the pattern", prefix)
+	}
+
+	if IsPattern("device-42") || !IsPattern("device-*") {
+		t.Error("IsPattern should only report strings containing wildcards")
+	}
+}
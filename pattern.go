@@ -0,0 +1,56 @@
+package iris
+
+import "strings"
+
+// Wildcards recognized by MatchPattern
+const (
+	// AnyCharacters matches any sequence of characters, including an empty sequence
+	AnyCharacters = '*'
+
+	// AnyCharacter matches exactly one character
+	AnyCharacter = '?'
+)
+
+// IsPattern indicates whether the string contains a wildcard
+func IsPattern(s string) bool {
+	return strings.ContainsAny(s, "*?")
+}
+
+// PatternPrefix returns the literal prefix of the pattern, which every name it matches begins with
+func PatternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// MatchPattern indicates whether the name matches the glob pattern, where * matches any sequence of characters,
+// including separators such as / and ., and ? matches any single character
+func MatchPattern(pattern string, name string) bool {
+	p, n := []rune(pattern), []rune(name)
+
+	// star is the position in the pattern following the last * seen, and mark is the position in the name
+	// it was first tried against, so that a failed match can backtrack to let the * consume one more character
+	star, mark := -1, 0
+	i, j := 0, 0
+	for j < len(n) {
+		switch {
+		case i < len(p) && p[i] == AnyCharacters:
+			i++
+			star, mark = i, j
+		case i < len(p) && (p[i] == AnyCharacter || p[i] == n[j]):
+			i++
+			j++
+		case star >= 0:
+			mark++
+			i, j = star, mark
+		default:
+			return false
+		}
+	}
+
+	for i < len(p) && p[i] == AnyCharacters {
+		i++
+	}
+	return i == len(p)
+}
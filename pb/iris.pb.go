@@ -11,6 +11,19 @@ It is generated from these files:
 It has these top-level messages:
 	JoinRequest
 	JoinResponse
+	RemovePeerRequest
+	RemovePeerResponse
+	LeaveRequest
+	LeaveResponse
+	ListPeersRequest
+	ListPeersResponse
+	Peer
+	ClusterStatusRequest
+	ClusterStatusResponse
+	TransferLeadershipRequest
+	TransferLeadershipResponse
+	DrainRequest
+	DrainResponse
 	ConnectRequest
 	ConnectResponse
 	ListenRequest
@@ -19,6 +32,8 @@ It has these top-level messages:
 	GetSourcesResponse
 	GetValueRequest
 	GetValueResponse
+	GetHistoryRequest
+	Version
 	SetValueRequest
 	SetValueResponse
 	RemoveValueRequest
@@ -31,10 +46,39 @@ It has these top-level messages:
 	SubscribeResponse
 	SubscribeKeyRequest
 	SubscribeKeyResponse
+	SubscribePatternRequest
+	SubscribePatternResponse
+	UnsubscribePatternRequest
+	UnsubscribePatternResponse
+	SubscribeSourcesRequest
+	SubscribeSourcesResponse
+	UnsubscribeSourcesRequest
+	UnsubscribeSourcesResponse
 	UnsubscribeRequest
 	UnsubscribeResponse
 	UnsubscribeKeyRequest
 	UnsubscribeKeyResponse
+	TxnGuard
+	TxnOperation
+	TxnRequest
+	TxnResponse
+	GrantLeaseRequest
+	GrantLeaseResponse
+	KeepAliveLeaseRequest
+	KeepAliveLeaseResponse
+	KeepAliveSessionRequest
+	KeepAliveSessionResponse
+	RevokeLeaseRequest
+	RevokeLeaseResponse
+	NodeStatusRequest
+	NodeStatusResponse
+	Command
+	SnapshotEntry
+	SnapshotLease
+	SnapshotHistory
+	SnapshotIndex
+	SnapshotAddress
+	SnapshotVersion
 */
 package pb
 
@@ -58,8 +102,174 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Condition describes the requirement a key must satisfy for a write to be applied
+// UpdateOperation describes the change an update was published for
+type UpdateOperation int32
+
+const (
+	// SET updates carry the value the key was set to
+	UpdateOperation_SET UpdateOperation = 0
+	// DELETE_KEY updates are published when a key is removed
+	UpdateOperation_DELETE_KEY UpdateOperation = 1
+	// DELETE_SOURCE updates are published for each key removed along with its source
+	UpdateOperation_DELETE_SOURCE UpdateOperation = 2
+	// EXPIRE updates are published for each key removed because its lease expired
+	UpdateOperation_EXPIRE UpdateOperation = 3
+	// SNAPSHOT updates carry the value of a key, and the revision it was written at, or a source without a key,
+	// when a subscription requesting a snapshot began
+	UpdateOperation_SNAPSHOT UpdateOperation = 4
+	// SOURCE_CREATED updates carry no key, and are published when the first key of a source is set
+	UpdateOperation_SOURCE_CREATED UpdateOperation = 5
+	// SOURCE_DELETED updates carry no key, and are published when the last key of a source is removed
+	UpdateOperation_SOURCE_DELETED UpdateOperation = 6
+	// RESYNC updates are delivered by the API client, carrying the source and key of a subscription, before the
+	// subscription begins again because the updates it missed while reconnecting are no longer retained, so that
+	// handlers discard what they hold for the subscription before receiving its current contents
+	UpdateOperation_RESYNC UpdateOperation = 7
+)
+
+var UpdateOperation_name = map[int32]string{
+	0: "SET",
+	1: "DELETE_KEY",
+	2: "DELETE_SOURCE",
+	3: "EXPIRE",
+	4: "SNAPSHOT",
+	5: "SOURCE_CREATED",
+	6: "SOURCE_DELETED",
+	7: "RESYNC",
+}
+var UpdateOperation_value = map[string]int32{
+	"SET":            0,
+	"DELETE_KEY":     1,
+	"DELETE_SOURCE":  2,
+	"EXPIRE":         3,
+	"SNAPSHOT":       4,
+	"SOURCE_CREATED": 5,
+	"SOURCE_DELETED": 6,
+	"RESYNC":         7,
+}
+
+func (x UpdateOperation) String() string {
+	return proto.EnumName(UpdateOperation_name, int32(x))
+}
+func (UpdateOperation) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// ReadConsistency describes the guarantees made by a read
+type ReadConsistency int32
+
+const (
+	// LEADER reads are served by the leader from its state machine, which may briefly lag the cluster after an election
+	ReadConsistency_LEADER ReadConsistency = 0
+	// LINEARIZABLE reads are served by the leader once it has confirmed its leadership with a quorum of the cluster
+	// and applied every preceding entry of the raft log
+	ReadConsistency_LINEARIZABLE ReadConsistency = 1
+	// STALE reads are served by any node from its local state machine
+	ReadConsistency_STALE ReadConsistency = 2
+)
+
+var ReadConsistency_name = map[int32]string{
+	0: "LEADER",
+	1: "LINEARIZABLE",
+	2: "STALE",
+}
+var ReadConsistency_value = map[string]int32{
+	"LEADER":       0,
+	"LINEARIZABLE": 1,
+	"STALE":        2,
+}
+
+func (x ReadConsistency) String() string {
+	return proto.EnumName(ReadConsistency_name, int32(x))
+}
+func (ReadConsistency) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type Condition int32
+
+const (
+	// UNCONDITIONAL writes are always applied
+	Condition_UNCONDITIONAL Condition = 0
+	// REVISION_MATCHES writes are only applied if the key is currently at the provided revision
+	Condition_REVISION_MATCHES Condition = 1
+	// ABSENT writes are only applied if the key does not exist
+	Condition_ABSENT Condition = 2
+)
+
+var Condition_name = map[int32]string{
+	0: "UNCONDITIONAL",
+	1: "REVISION_MATCHES",
+	2: "ABSENT",
+}
+var Condition_value = map[string]int32{
+	"UNCONDITIONAL":    0,
+	"REVISION_MATCHES": 1,
+	"ABSENT":           2,
+}
+
+func (x Condition) String() string {
+	return proto.EnumName(Condition_name, int32(x))
+}
+func (Condition) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+// GuardType describes the condition a transaction guard checks
+type GuardType int32
+
+const (
+	// KEY_EXISTS guards are satisfied if the key exists
+	GuardType_KEY_EXISTS GuardType = 0
+	// REVISION_EQUALS guards are satisfied if the key is at the provided revision, where zero indicates the key does not exist
+	GuardType_REVISION_EQUALS GuardType = 1
+	// VALUE_EQUALS guards are satisfied if the key exists with the provided value
+	GuardType_VALUE_EQUALS GuardType = 2
+)
+
+var GuardType_name = map[int32]string{
+	0: "KEY_EXISTS",
+	1: "REVISION_EQUALS",
+	2: "VALUE_EQUALS",
+}
+var GuardType_value = map[string]int32{
+	"KEY_EXISTS":      0,
+	"REVISION_EQUALS": 1,
+	"VALUE_EQUALS":    2,
+}
+
+func (x GuardType) String() string {
+	return proto.EnumName(GuardType_name, int32(x))
+}
+func (GuardType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+// OperationType describes the change a transaction operation makes
+type OperationType int32
+
+const (
+	// SET_VALUE sets the value for the source and key
+	OperationType_SET_VALUE OperationType = 0
+	// REMOVE_VALUE removes the key from the source
+	OperationType_REMOVE_VALUE OperationType = 1
+	// REMOVE_SOURCE removes the source and all of its keys
+	OperationType_REMOVE_SOURCE OperationType = 2
+)
+
+var OperationType_name = map[int32]string{
+	0: "SET_VALUE",
+	1: "REMOVE_VALUE",
+	2: "REMOVE_SOURCE",
+}
+var OperationType_value = map[string]int32{
+	"SET_VALUE":     0,
+	"REMOVE_VALUE":  1,
+	"REMOVE_SOURCE": 2,
+}
+
+func (x OperationType) String() string {
+	return proto.EnumName(OperationType_name, int32(x))
+}
+func (OperationType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
 type JoinRequest struct {
 	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	// grpc_address is the address the joining node advertises to clients and to the other members
+	GrpcAddress string `protobuf:"bytes,2,opt,name=grpc_address,json=grpcAddress" json:"grpc_address,omitempty"`
 }
 
 func (m *JoinRequest) Reset()                    { *m = JoinRequest{} }
@@ -74,6 +284,13 @@ func (m *JoinRequest) GetAddress() string {
 	return ""
 }
 
+func (m *JoinRequest) GetGrpcAddress() string {
+	if m != nil {
+		return m.GrpcAddress
+	}
+	return ""
+}
+
 type JoinResponse struct {
 }
 
@@ -82,13 +299,266 @@ func (m *JoinResponse) String() string            { return proto.CompactTextStri
 func (*JoinResponse) ProtoMessage()               {}
 func (*JoinResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
+type RemovePeerRequest struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+}
+
+func (m *RemovePeerRequest) Reset()                    { *m = RemovePeerRequest{} }
+func (m *RemovePeerRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemovePeerRequest) ProtoMessage()               {}
+func (*RemovePeerRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *RemovePeerRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+type RemovePeerResponse struct {
+}
+
+func (m *RemovePeerResponse) Reset()                    { *m = RemovePeerResponse{} }
+func (m *RemovePeerResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemovePeerResponse) ProtoMessage()               {}
+func (*RemovePeerResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type LeaveRequest struct {
+}
+
+func (m *LeaveRequest) Reset()                    { *m = LeaveRequest{} }
+func (m *LeaveRequest) String() string            { return proto.CompactTextString(m) }
+func (*LeaveRequest) ProtoMessage()               {}
+func (*LeaveRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type LeaveResponse struct {
+}
+
+func (m *LeaveResponse) Reset()                    { *m = LeaveResponse{} }
+func (m *LeaveResponse) String() string            { return proto.CompactTextString(m) }
+func (*LeaveResponse) ProtoMessage()               {}
+func (*LeaveResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type ListPeersRequest struct {
+}
+
+func (m *ListPeersRequest) Reset()                    { *m = ListPeersRequest{} }
+func (m *ListPeersRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListPeersRequest) ProtoMessage()               {}
+func (*ListPeersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+type ListPeersResponse struct {
+	Peers []string `protobuf:"bytes,1,rep,name=peers" json:"peers,omitempty"`
+}
+
+func (m *ListPeersResponse) Reset()                    { *m = ListPeersResponse{} }
+func (m *ListPeersResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListPeersResponse) ProtoMessage()               {}
+func (*ListPeersResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *ListPeersResponse) GetPeers() []string {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+// Peer describes a member of the cluster, whose state is Leader, Follower, Candidate, Shutdown or Unknown
+type Peer struct {
+	Address     string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	State       string `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	GrpcAddress string `protobuf:"bytes,3,opt,name=grpc_address,json=grpcAddress" json:"grpc_address,omitempty"`
+	// last_contact is the time the peer last responded to the leader, in nanoseconds since the Unix epoch, and is
+	// only reported by the leader
+	LastContact int64 `protobuf:"varint,4,opt,name=last_contact,json=lastContact" json:"last_contact,omitempty"`
+	// match_index is the index of the last raft log entry the leader knows the peer to hold
+	MatchIndex uint64 `protobuf:"varint,5,opt,name=match_index,json=matchIndex" json:"match_index,omitempty"`
+}
+
+func (m *Peer) Reset()                    { *m = Peer{} }
+func (m *Peer) String() string            { return proto.CompactTextString(m) }
+func (*Peer) ProtoMessage()               {}
+func (*Peer) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *Peer) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Peer) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Peer) GetGrpcAddress() string {
+	if m != nil {
+		return m.GrpcAddress
+	}
+	return ""
+}
+
+func (m *Peer) GetLastContact() int64 {
+	if m != nil {
+		return m.LastContact
+	}
+	return 0
+}
+
+func (m *Peer) GetMatchIndex() uint64 {
+	if m != nil {
+		return m.MatchIndex
+	}
+	return 0
+}
+
+type ClusterStatusRequest struct {
+}
+
+func (m *ClusterStatusRequest) Reset()                    { *m = ClusterStatusRequest{} }
+func (m *ClusterStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*ClusterStatusRequest) ProtoMessage()               {}
+func (*ClusterStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type ClusterStatusResponse struct {
+	Address      string  `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	State        string  `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	Leader       string  `protobuf:"bytes,3,opt,name=leader" json:"leader,omitempty"`
+	Term         uint64  `protobuf:"varint,4,opt,name=term" json:"term,omitempty"`
+	CommitIndex  uint64  `protobuf:"varint,5,opt,name=commit_index,json=commitIndex" json:"commit_index,omitempty"`
+	AppliedIndex uint64  `protobuf:"varint,6,opt,name=applied_index,json=appliedIndex" json:"applied_index,omitempty"`
+	Peers        []*Peer `protobuf:"bytes,7,rep,name=peers" json:"peers,omitempty"`
+}
+
+func (m *ClusterStatusResponse) Reset()                    { *m = ClusterStatusResponse{} }
+func (m *ClusterStatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*ClusterStatusResponse) ProtoMessage()               {}
+func (*ClusterStatusResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ClusterStatusResponse) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *ClusterStatusResponse) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ClusterStatusResponse) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+func (m *ClusterStatusResponse) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *ClusterStatusResponse) GetCommitIndex() uint64 {
+	if m != nil {
+		return m.CommitIndex
+	}
+	return 0
+}
+
+func (m *ClusterStatusResponse) GetAppliedIndex() uint64 {
+	if m != nil {
+		return m.AppliedIndex
+	}
+	return 0
+}
+
+func (m *ClusterStatusResponse) GetPeers() []*Peer {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+type TransferLeadershipRequest struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+}
+
+func (m *TransferLeadershipRequest) Reset()                    { *m = TransferLeadershipRequest{} }
+func (m *TransferLeadershipRequest) String() string            { return proto.CompactTextString(m) }
+func (*TransferLeadershipRequest) ProtoMessage()               {}
+func (*TransferLeadershipRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *TransferLeadershipRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+type TransferLeadershipResponse struct {
+	Leader string `protobuf:"bytes,1,opt,name=leader" json:"leader,omitempty"`
+}
+
+func (m *TransferLeadershipResponse) Reset()                    { *m = TransferLeadershipResponse{} }
+func (m *TransferLeadershipResponse) String() string            { return proto.CompactTextString(m) }
+func (*TransferLeadershipResponse) ProtoMessage()               {}
+func (*TransferLeadershipResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *TransferLeadershipResponse) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+type DrainRequest struct {
+	Draining bool `protobuf:"varint,1,opt,name=draining" json:"draining,omitempty"`
+}
+
+func (m *DrainRequest) Reset()                    { *m = DrainRequest{} }
+func (m *DrainRequest) String() string            { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()               {}
+func (*DrainRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *DrainRequest) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+type DrainResponse struct {
+	// sessions is the number of sessions ended by the request
+	Sessions uint32 `protobuf:"varint,1,opt,name=sessions" json:"sessions,omitempty"`
+}
+
+func (m *DrainResponse) Reset()                    { *m = DrainResponse{} }
+func (m *DrainResponse) String() string            { return proto.CompactTextString(m) }
+func (*DrainResponse) ProtoMessage()               {}
+func (*DrainResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *DrainResponse) GetSessions() uint32 {
+	if m != nil {
+		return m.Sessions
+	}
+	return 0
+}
+
 type ConnectRequest struct {
 }
 
 func (m *ConnectRequest) Reset()                    { *m = ConnectRequest{} }
 func (m *ConnectRequest) String() string            { return proto.CompactTextString(m) }
 func (*ConnectRequest) ProtoMessage()               {}
-func (*ConnectRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*ConnectRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 type ConnectResponse struct {
 	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
@@ -97,7 +567,7 @@ type ConnectResponse struct {
 func (m *ConnectResponse) Reset()                    { *m = ConnectResponse{} }
 func (m *ConnectResponse) String() string            { return proto.CompactTextString(m) }
 func (*ConnectResponse) ProtoMessage()               {}
-func (*ConnectResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*ConnectResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 func (m *ConnectResponse) GetSession() string {
 	if m != nil {
@@ -113,7 +583,7 @@ type ListenRequest struct {
 func (m *ListenRequest) Reset()                    { *m = ListenRequest{} }
 func (m *ListenRequest) String() string            { return proto.CompactTextString(m) }
 func (*ListenRequest) ProtoMessage()               {}
-func (*ListenRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*ListenRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *ListenRequest) GetSession() string {
 	if m != nil {
@@ -123,15 +593,25 @@ func (m *ListenRequest) GetSession() string {
 }
 
 type Update struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
-	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
-	Value  []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Source      string          `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key         string          `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value       []byte          `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Transaction uint64          `protobuf:"varint,4,opt,name=transaction" json:"transaction,omitempty"`
+	Operation   UpdateOperation `protobuf:"varint,5,opt,name=operation,enum=iris.pb.UpdateOperation" json:"operation,omitempty"`
+	// index is the raft log index the change was applied at
+	Index uint64 `protobuf:"varint,6,opt,name=index" json:"index,omitempty"`
+	// timestamp is the time the change was applied, in nanoseconds since the Unix epoch
+	Timestamp int64 `protobuf:"varint,7,opt,name=timestamp" json:"timestamp,omitempty"`
+	// previous_value is the value the key held before the change, where a previous_revision of zero
+	// indicates that the key did not exist
+	PreviousValue    []byte `protobuf:"bytes,8,opt,name=previous_value,json=previousValue,proto3" json:"previous_value,omitempty"`
+	PreviousRevision uint64 `protobuf:"varint,9,opt,name=previous_revision,json=previousRevision" json:"previous_revision,omitempty"`
 }
 
 func (m *Update) Reset()                    { *m = Update{} }
 func (m *Update) String() string            { return proto.CompactTextString(m) }
 func (*Update) ProtoMessage()               {}
-func (*Update) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*Update) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *Update) GetSource() string {
 	if m != nil {
@@ -140,503 +620,1913 @@ func (m *Update) GetSource() string {
 	return ""
 }
 
-func (m *Update) GetKey() string {
+func (m *Update) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Update) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Update) GetTransaction() uint64 {
+	if m != nil {
+		return m.Transaction
+	}
+	return 0
+}
+
+func (m *Update) GetOperation() UpdateOperation {
+	if m != nil {
+		return m.Operation
+	}
+	return UpdateOperation_SET
+}
+
+func (m *Update) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Update) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Update) GetPreviousValue() []byte {
+	if m != nil {
+		return m.PreviousValue
+	}
+	return nil
+}
+
+func (m *Update) GetPreviousRevision() uint64 {
+	if m != nil {
+		return m.PreviousRevision
+	}
+	return 0
+}
+
+type GetSourcesRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	// prefix limits the listing to sources beginning with it
+	Prefix string `protobuf:"bytes,2,opt,name=prefix" json:"prefix,omitempty"`
+	// start is the first source listed, inclusive
+	Start string `protobuf:"bytes,3,opt,name=start" json:"start,omitempty"`
+	// end is the source the listing stops before, where empty continues to the last source
+	End string `protobuf:"bytes,4,opt,name=end" json:"end,omitempty"`
+	// limit is the maximum number of sources listed, where zero lists every source
+	Limit uint32 `protobuf:"varint,5,opt,name=limit" json:"limit,omitempty"`
+	// continuation resumes a listing after the page that returned it
+	Continuation string          `protobuf:"bytes,6,opt,name=continuation" json:"continuation,omitempty"`
+	Consistency  ReadConsistency `protobuf:"varint,7,opt,name=consistency,enum=iris.pb.ReadConsistency" json:"consistency,omitempty"`
+	// max_staleness in milliseconds bounds how long a node serving a stale read may have been out of contact with the leader,
+	// where zero leaves it unbounded
+	MaxStaleness int64 `protobuf:"varint,8,opt,name=max_staleness,json=maxStaleness" json:"max_staleness,omitempty"`
+}
+
+func (m *GetSourcesRequest) Reset()                    { *m = GetSourcesRequest{} }
+func (m *GetSourcesRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetSourcesRequest) ProtoMessage()               {}
+func (*GetSourcesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *GetSourcesRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *GetSourcesRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *GetSourcesRequest) GetStart() string {
+	if m != nil {
+		return m.Start
+	}
+	return ""
+}
+
+func (m *GetSourcesRequest) GetEnd() string {
+	if m != nil {
+		return m.End
+	}
+	return ""
+}
+
+func (m *GetSourcesRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *GetSourcesRequest) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
+func (m *GetSourcesRequest) GetConsistency() ReadConsistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return ReadConsistency_LEADER
+}
+
+func (m *GetSourcesRequest) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+type GetSourcesResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	// continuation is set on the last source of a page that was cut short by its limit
+	Continuation string `protobuf:"bytes,2,opt,name=continuation" json:"continuation,omitempty"`
+	// index is the raft log index applied by the node that served the read
+	Index uint64 `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *GetSourcesResponse) Reset()                    { *m = GetSourcesResponse{} }
+func (m *GetSourcesResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetSourcesResponse) ProtoMessage()               {}
+func (*GetSourcesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *GetSourcesResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *GetSourcesResponse) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
+func (m *GetSourcesResponse) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type GetValueRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	// revision to read the value at, where zero reads the current value
+	Revision    uint64          `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+	Consistency ReadConsistency `protobuf:"varint,5,opt,name=consistency,enum=iris.pb.ReadConsistency" json:"consistency,omitempty"`
+	// max_staleness in milliseconds bounds how long a node serving a stale read may have been out of contact with the leader,
+	// where zero leaves it unbounded
+	MaxStaleness int64 `protobuf:"varint,6,opt,name=max_staleness,json=maxStaleness" json:"max_staleness,omitempty"`
+}
+
+func (m *GetValueRequest) Reset()                    { *m = GetValueRequest{} }
+func (m *GetValueRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetValueRequest) ProtoMessage()               {}
+func (*GetValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *GetValueRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *GetValueRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *GetValueRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetValueRequest) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *GetValueRequest) GetConsistency() ReadConsistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return ReadConsistency_LEADER
+}
+
+func (m *GetValueRequest) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+type GetValueResponse struct {
+	Value    []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Revision uint64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+	// index is the raft log index applied by the node that served the read
+	Index uint64 `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *GetValueResponse) Reset()                    { *m = GetValueResponse{} }
+func (m *GetValueResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetValueResponse) ProtoMessage()               {}
+func (*GetValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *GetValueResponse) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *GetValueResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *GetValueResponse) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type GetHistoryRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *GetHistoryRequest) Reset()                    { *m = GetHistoryRequest{} }
+func (m *GetHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetHistoryRequest) ProtoMessage()               {}
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *GetHistoryRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *GetHistoryRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *GetHistoryRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type Version struct {
+	Value    []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Revision uint64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+	// timestamp in nanoseconds since the epoch at which the version was applied
+	Timestamp int64 `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	Deleted   bool  `protobuf:"varint,4,opt,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *Version) Reset()                    { *m = Version{} }
+func (m *Version) String() string            { return proto.CompactTextString(m) }
+func (*Version) ProtoMessage()               {}
+func (*Version) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *Version) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Version) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *Version) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Version) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+type SetValueRequest struct {
+	Session   string    `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source    string    `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key       string    `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Value     []byte    `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	Condition Condition `protobuf:"varint,5,opt,name=condition,enum=iris.pb.Condition" json:"condition,omitempty"`
+	Revision  uint64    `protobuf:"varint,6,opt,name=revision" json:"revision,omitempty"`
+	// ttl in milliseconds after which the value is removed
+	Ttl   int64  `protobuf:"varint,7,opt,name=ttl" json:"ttl,omitempty"`
+	Lease uint64 `protobuf:"varint,8,opt,name=lease" json:"lease,omitempty"`
+	// ephemeral attaches the value to the session, so that it is removed when the session ends
+	Ephemeral bool `protobuf:"varint,9,opt,name=ephemeral" json:"ephemeral,omitempty"`
+}
+
+func (m *SetValueRequest) Reset()                    { *m = SetValueRequest{} }
+func (m *SetValueRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetValueRequest) ProtoMessage()               {}
+func (*SetValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *SetValueRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SetValueRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SetValueRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SetValueRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *SetValueRequest) GetCondition() Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return Condition_UNCONDITIONAL
+}
+
+func (m *SetValueRequest) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *SetValueRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *SetValueRequest) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *SetValueRequest) GetEphemeral() bool {
+	if m != nil {
+		return m.Ephemeral
+	}
+	return false
+}
+
+type SetValueResponse struct {
+	Value    []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Revision uint64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *SetValueResponse) Reset()                    { *m = SetValueResponse{} }
+func (m *SetValueResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetValueResponse) ProtoMessage()               {}
+func (*SetValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *SetValueResponse) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *SetValueResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+type RemoveValueRequest struct {
+	Session   string    `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source    string    `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key       string    `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Condition Condition `protobuf:"varint,4,opt,name=condition,enum=iris.pb.Condition" json:"condition,omitempty"`
+	Revision  uint64    `protobuf:"varint,5,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *RemoveValueRequest) Reset()                    { *m = RemoveValueRequest{} }
+func (m *RemoveValueRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveValueRequest) ProtoMessage()               {}
+func (*RemoveValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *RemoveValueRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *RemoveValueRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *RemoveValueRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *RemoveValueRequest) GetCondition() Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return Condition_UNCONDITIONAL
+}
+
+func (m *RemoveValueRequest) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+type RemoveValueResponse struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *RemoveValueResponse) Reset()                    { *m = RemoveValueResponse{} }
+func (m *RemoveValueResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveValueResponse) ProtoMessage()               {}
+func (*RemoveValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *RemoveValueResponse) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *RemoveValueResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *RemoveValueResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type RemoveSourceRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+}
+
+func (m *RemoveSourceRequest) Reset()                    { *m = RemoveSourceRequest{} }
+func (m *RemoveSourceRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveSourceRequest) ProtoMessage()               {}
+func (*RemoveSourceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *RemoveSourceRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *RemoveSourceRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+type RemoveSourceResponse struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+}
+
+func (m *RemoveSourceResponse) Reset()                    { *m = RemoveSourceResponse{} }
+func (m *RemoveSourceResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveSourceResponse) ProtoMessage()               {}
+func (*RemoveSourceResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *RemoveSourceResponse) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *RemoveSourceResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+type GetKeysRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	// prefix limits the listing to keys beginning with it
+	Prefix string `protobuf:"bytes,3,opt,name=prefix" json:"prefix,omitempty"`
+	// start is the first key listed, inclusive
+	Start string `protobuf:"bytes,4,opt,name=start" json:"start,omitempty"`
+	// end is the key the listing stops before, where empty continues to the last key
+	End string `protobuf:"bytes,5,opt,name=end" json:"end,omitempty"`
+	// limit is the maximum number of keys listed, where zero lists every key
+	Limit uint32 `protobuf:"varint,6,opt,name=limit" json:"limit,omitempty"`
+	// continuation resumes a listing after the page that returned it
+	Continuation string          `protobuf:"bytes,7,opt,name=continuation" json:"continuation,omitempty"`
+	Consistency  ReadConsistency `protobuf:"varint,8,opt,name=consistency,enum=iris.pb.ReadConsistency" json:"consistency,omitempty"`
+	// max_staleness in milliseconds bounds how long a node serving a stale read may have been out of contact with the leader,
+	// where zero leaves it unbounded
+	MaxStaleness int64 `protobuf:"varint,9,opt,name=max_staleness,json=maxStaleness" json:"max_staleness,omitempty"`
+}
+
+func (m *GetKeysRequest) Reset()                    { *m = GetKeysRequest{} }
+func (m *GetKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetKeysRequest) ProtoMessage()               {}
+func (*GetKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *GetKeysRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetStart() string {
+	if m != nil {
+		return m.Start
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetEnd() string {
+	if m != nil {
+		return m.End
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *GetKeysRequest) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
+func (m *GetKeysRequest) GetConsistency() ReadConsistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return ReadConsistency_LEADER
+}
+
+func (m *GetKeysRequest) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+type GetKeysResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	// continuation is set on the last key of a page that was cut short by its limit
+	Continuation string `protobuf:"bytes,2,opt,name=continuation" json:"continuation,omitempty"`
+	// index is the raft log index applied by the node that served the read
+	Index uint64 `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *GetKeysResponse) Reset()                    { *m = GetKeysResponse{} }
+func (m *GetKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetKeysResponse) ProtoMessage()               {}
+func (*GetKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *GetKeysResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetKeysResponse) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
+func (m *GetKeysResponse) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type SubscribeRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	// start_revision replays the retained updates applied at or after it before any new updates,
+	// where zero only delivers new updates
+	StartRevision uint64 `protobuf:"varint,3,opt,name=start_revision,json=startRevision" json:"start_revision,omitempty"`
+	// snapshot delivers the current value of every key as a SNAPSHOT update before any new updates
+	Snapshot bool `protobuf:"varint,4,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *SubscribeRequest) Reset()                    { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()               {}
+func (*SubscribeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *SubscribeRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SubscribeRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribeRequest) GetStartRevision() uint64 {
+	if m != nil {
+		return m.StartRevision
+	}
+	return 0
+}
+
+func (m *SubscribeRequest) GetSnapshot() bool {
+	if m != nil {
+		return m.Snapshot
+	}
+	return false
+}
+
+type SubscribeResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	// revision is the index of the last update published before the subscription began, where every
+	// update applied after it is delivered to the subscriber
+	Revision uint64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+	// snapshot_size is the number of SNAPSHOT updates delivered before any new updates
+	SnapshotSize uint32 `protobuf:"varint,3,opt,name=snapshot_size,json=snapshotSize" json:"snapshot_size,omitempty"`
+}
+
+func (m *SubscribeResponse) Reset()                    { *m = SubscribeResponse{} }
+func (m *SubscribeResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeResponse) ProtoMessage()               {}
+func (*SubscribeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *SubscribeResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribeResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *SubscribeResponse) GetSnapshotSize() uint32 {
+	if m != nil {
+		return m.SnapshotSize
+	}
+	return 0
+}
+
+type SubscribeKeyRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	// start_revision replays the retained updates applied at or after it before any new updates,
+	// where zero only delivers new updates
+	StartRevision uint64 `protobuf:"varint,4,opt,name=start_revision,json=startRevision" json:"start_revision,omitempty"`
+	// snapshot delivers the current value of the key as a SNAPSHOT update before any new updates
+	Snapshot bool `protobuf:"varint,5,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *SubscribeKeyRequest) Reset()                    { *m = SubscribeKeyRequest{} }
+func (m *SubscribeKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeKeyRequest) ProtoMessage()               {}
+func (*SubscribeKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *SubscribeKeyRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SubscribeKeyRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribeKeyRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SubscribeKeyRequest) GetStartRevision() uint64 {
+	if m != nil {
+		return m.StartRevision
+	}
+	return 0
+}
+
+func (m *SubscribeKeyRequest) GetSnapshot() bool {
+	if m != nil {
+		return m.Snapshot
+	}
+	return false
+}
+
+type SubscribeKeyResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	// revision is the index of the last update published before the subscription began, where every
+	// update applied after it is delivered to the subscriber
+	Revision uint64 `protobuf:"varint,3,opt,name=revision" json:"revision,omitempty"`
+	// snapshot_size is the number of SNAPSHOT updates delivered before any new updates
+	SnapshotSize uint32 `protobuf:"varint,4,opt,name=snapshot_size,json=snapshotSize" json:"snapshot_size,omitempty"`
+}
+
+func (m *SubscribeKeyResponse) Reset()                    { *m = SubscribeKeyResponse{} }
+func (m *SubscribeKeyResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeKeyResponse) ProtoMessage()               {}
+func (*SubscribeKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+func (m *SubscribeKeyResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribeKeyResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SubscribeKeyResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *SubscribeKeyResponse) GetSnapshotSize() uint32 {
+	if m != nil {
+		return m.SnapshotSize
+	}
+	return 0
+}
+
+type SubscribePatternRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	// source is a glob pattern, where * matches any sequence of characters and ? matches any single character
+	Source string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	// key is a glob pattern, where an empty pattern matches every key
+	Key string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	// start_revision replays the retained updates applied at or after it before any new updates,
+	// where zero only delivers new updates
+	StartRevision uint64 `protobuf:"varint,4,opt,name=start_revision,json=startRevision" json:"start_revision,omitempty"`
+}
+
+func (m *SubscribePatternRequest) Reset()                    { *m = SubscribePatternRequest{} }
+func (m *SubscribePatternRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribePatternRequest) ProtoMessage()               {}
+func (*SubscribePatternRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *SubscribePatternRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SubscribePatternRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribePatternRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SubscribePatternRequest) GetStartRevision() uint64 {
+	if m != nil {
+		return m.StartRevision
+	}
+	return 0
+}
+
+type SubscribePatternResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	// revision is the index of the last update published before the subscription began, where every
+	// update applied after it is delivered to the subscriber
+	Revision uint64 `protobuf:"varint,3,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *SubscribePatternResponse) Reset()                    { *m = SubscribePatternResponse{} }
+func (m *SubscribePatternResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubscribePatternResponse) ProtoMessage()               {}
+func (*SubscribePatternResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *SubscribePatternResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *SubscribePatternResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SubscribePatternResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+type UnsubscribePatternRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *UnsubscribePatternRequest) Reset()                    { *m = UnsubscribePatternRequest{} }
+func (m *UnsubscribePatternRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribePatternRequest) ProtoMessage()               {}
+func (*UnsubscribePatternRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+func (m *UnsubscribePatternRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *UnsubscribePatternRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *UnsubscribePatternRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type UnsubscribePatternResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *UnsubscribePatternResponse) Reset()                    { *m = UnsubscribePatternResponse{} }
+func (m *UnsubscribePatternResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribePatternResponse) ProtoMessage()               {}
+func (*UnsubscribePatternResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+func (m *UnsubscribePatternResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *UnsubscribePatternResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type SubscribeSourcesRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	// start_revision replays the retained updates applied at or after it before any new updates,
+	// where zero only delivers new updates
+	StartRevision uint64 `protobuf:"varint,2,opt,name=start_revision,json=startRevision" json:"start_revision,omitempty"`
+	// snapshot delivers each source that exists as a SNAPSHOT update without a key before any new updates
+	Snapshot bool `protobuf:"varint,3,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *SubscribeSourcesRequest) Reset()                    { *m = SubscribeSourcesRequest{} }
+func (m *SubscribeSourcesRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeSourcesRequest) ProtoMessage()               {}
+func (*SubscribeSourcesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+func (m *SubscribeSourcesRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SubscribeSourcesRequest) GetStartRevision() uint64 {
+	if m != nil {
+		return m.StartRevision
+	}
+	return 0
+}
+
+func (m *SubscribeSourcesRequest) GetSnapshot() bool {
+	if m != nil {
+		return m.Snapshot
+	}
+	return false
+}
+
+type SubscribeSourcesResponse struct {
+	// revision is the index of the last update published before the subscription began, where every
+	// update applied after it is delivered to the subscriber
+	Revision uint64 `protobuf:"varint,1,opt,name=revision" json:"revision,omitempty"`
+	// snapshot_size is the number of SNAPSHOT updates delivered before any new updates
+	SnapshotSize uint32 `protobuf:"varint,2,opt,name=snapshot_size,json=snapshotSize" json:"snapshot_size,omitempty"`
+}
+
+func (m *SubscribeSourcesResponse) Reset()                    { *m = SubscribeSourcesResponse{} }
+func (m *SubscribeSourcesResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeSourcesResponse) ProtoMessage()               {}
+func (*SubscribeSourcesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *SubscribeSourcesResponse) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *SubscribeSourcesResponse) GetSnapshotSize() uint32 {
+	if m != nil {
+		return m.SnapshotSize
+	}
+	return 0
+}
+
+type UnsubscribeSourcesRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+}
+
+func (m *UnsubscribeSourcesRequest) Reset()                    { *m = UnsubscribeSourcesRequest{} }
+func (m *UnsubscribeSourcesRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeSourcesRequest) ProtoMessage()               {}
+func (*UnsubscribeSourcesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+func (m *UnsubscribeSourcesRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+type UnsubscribeSourcesResponse struct {
+}
+
+func (m *UnsubscribeSourcesResponse) Reset()                    { *m = UnsubscribeSourcesResponse{} }
+func (m *UnsubscribeSourcesResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeSourcesResponse) ProtoMessage()               {}
+func (*UnsubscribeSourcesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
+
+type UnsubscribeRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+}
+
+func (m *UnsubscribeRequest) Reset()                    { *m = UnsubscribeRequest{} }
+func (m *UnsubscribeRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeRequest) ProtoMessage()               {}
+func (*UnsubscribeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{45} }
+
+func (m *UnsubscribeRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *UnsubscribeRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+type UnsubscribeResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+}
+
+func (m *UnsubscribeResponse) Reset()                    { *m = UnsubscribeResponse{} }
+func (m *UnsubscribeResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeResponse) ProtoMessage()               {}
+func (*UnsubscribeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
+
+func (m *UnsubscribeResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+type UnsubscribeKeyRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *UnsubscribeKeyRequest) Reset()                    { *m = UnsubscribeKeyRequest{} }
+func (m *UnsubscribeKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeKeyRequest) ProtoMessage()               {}
+func (*UnsubscribeKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{47} }
+
+func (m *UnsubscribeKeyRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *UnsubscribeKeyRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *UnsubscribeKeyRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type UnsubscribeKeyResponse struct {
+	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *UnsubscribeKeyResponse) Reset()                    { *m = UnsubscribeKeyResponse{} }
+func (m *UnsubscribeKeyResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnsubscribeKeyResponse) ProtoMessage()               {}
+func (*UnsubscribeKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{48} }
+
+func (m *UnsubscribeKeyResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *UnsubscribeKeyResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type TxnGuard struct {
+	Type     GuardType `protobuf:"varint,1,opt,name=type,enum=iris.pb.GuardType" json:"type,omitempty"`
+	Source   string    `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key      string    `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Revision uint64    `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+	Value    []byte    `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *TxnGuard) Reset()                    { *m = TxnGuard{} }
+func (m *TxnGuard) String() string            { return proto.CompactTextString(m) }
+func (*TxnGuard) ProtoMessage()               {}
+func (*TxnGuard) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{49} }
+
+func (m *TxnGuard) GetType() GuardType {
+	if m != nil {
+		return m.Type
+	}
+	return GuardType_KEY_EXISTS
+}
+
+func (m *TxnGuard) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *TxnGuard) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *TxnGuard) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *TxnGuard) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type TxnOperation struct {
+	Type   OperationType `protobuf:"varint,1,opt,name=type,enum=iris.pb.OperationType" json:"type,omitempty"`
+	Source string        `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Key    string        `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Value  []byte        `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *TxnOperation) Reset()                    { *m = TxnOperation{} }
+func (m *TxnOperation) String() string            { return proto.CompactTextString(m) }
+func (*TxnOperation) ProtoMessage()               {}
+func (*TxnOperation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{50} }
+
+func (m *TxnOperation) GetType() OperationType {
+	if m != nil {
+		return m.Type
+	}
+	return OperationType_SET_VALUE
+}
+
+func (m *TxnOperation) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *TxnOperation) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *TxnOperation) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type TxnRequest struct {
+	Session    string          `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Guards     []*TxnGuard     `protobuf:"bytes,2,rep,name=guards" json:"guards,omitempty"`
+	Operations []*TxnOperation `protobuf:"bytes,3,rep,name=operations" json:"operations,omitempty"`
+}
+
+func (m *TxnRequest) Reset()                    { *m = TxnRequest{} }
+func (m *TxnRequest) String() string            { return proto.CompactTextString(m) }
+func (*TxnRequest) ProtoMessage()               {}
+func (*TxnRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{51} }
+
+func (m *TxnRequest) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+func (m *TxnRequest) GetGuards() []*TxnGuard {
+	if m != nil {
+		return m.Guards
+	}
+	return nil
+}
+
+func (m *TxnRequest) GetOperations() []*TxnOperation {
+	if m != nil {
+		return m.Operations
+	}
+	return nil
+}
+
+type TxnResponse struct {
+	Succeeded bool   `protobuf:"varint,1,opt,name=succeeded" json:"succeeded,omitempty"`
+	Revision  uint64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *TxnResponse) Reset()                    { *m = TxnResponse{} }
+func (m *TxnResponse) String() string            { return proto.CompactTextString(m) }
+func (*TxnResponse) ProtoMessage()               {}
+func (*TxnResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{52} }
+
+func (m *TxnResponse) GetSucceeded() bool {
 	if m != nil {
-		return m.Key
+		return m.Succeeded
 	}
-	return ""
+	return false
 }
 
-func (m *Update) GetValue() []byte {
+func (m *TxnResponse) GetRevision() uint64 {
 	if m != nil {
-		return m.Value
+		return m.Revision
 	}
-	return nil
+	return 0
 }
 
-type GetSourcesRequest struct {
+type GrantLeaseRequest struct {
 	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	// ttl in milliseconds
+	Ttl int64 `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
 }
 
-func (m *GetSourcesRequest) Reset()                    { *m = GetSourcesRequest{} }
-func (m *GetSourcesRequest) String() string            { return proto.CompactTextString(m) }
-func (*GetSourcesRequest) ProtoMessage()               {}
-func (*GetSourcesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (m *GrantLeaseRequest) Reset()                    { *m = GrantLeaseRequest{} }
+func (m *GrantLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*GrantLeaseRequest) ProtoMessage()               {}
+func (*GrantLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{53} }
 
-func (m *GetSourcesRequest) GetSession() string {
+func (m *GrantLeaseRequest) GetSession() string {
 	if m != nil {
 		return m.Session
 	}
 	return ""
 }
 
-type GetSourcesResponse struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+func (m *GrantLeaseRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
 }
 
-func (m *GetSourcesResponse) Reset()                    { *m = GetSourcesResponse{} }
-func (m *GetSourcesResponse) String() string            { return proto.CompactTextString(m) }
-func (*GetSourcesResponse) ProtoMessage()               {}
-func (*GetSourcesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+type GrantLeaseResponse struct {
+	Lease uint64 `protobuf:"varint,1,opt,name=lease" json:"lease,omitempty"`
+	Ttl   int64  `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
+}
 
-func (m *GetSourcesResponse) GetSource() string {
+func (m *GrantLeaseResponse) Reset()                    { *m = GrantLeaseResponse{} }
+func (m *GrantLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*GrantLeaseResponse) ProtoMessage()               {}
+func (*GrantLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{54} }
+
+func (m *GrantLeaseResponse) GetLease() uint64 {
 	if m != nil {
-		return m.Source
+		return m.Lease
 	}
-	return ""
+	return 0
 }
 
-type GetValueRequest struct {
+func (m *GrantLeaseResponse) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+type KeepAliveLeaseRequest struct {
 	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Lease   uint64 `protobuf:"varint,2,opt,name=lease" json:"lease,omitempty"`
 }
 
-func (m *GetValueRequest) Reset()                    { *m = GetValueRequest{} }
-func (m *GetValueRequest) String() string            { return proto.CompactTextString(m) }
-func (*GetValueRequest) ProtoMessage()               {}
-func (*GetValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (m *KeepAliveLeaseRequest) Reset()                    { *m = KeepAliveLeaseRequest{} }
+func (m *KeepAliveLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*KeepAliveLeaseRequest) ProtoMessage()               {}
+func (*KeepAliveLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{55} }
 
-func (m *GetValueRequest) GetSession() string {
+func (m *KeepAliveLeaseRequest) GetSession() string {
 	if m != nil {
 		return m.Session
 	}
 	return ""
 }
 
-func (m *GetValueRequest) GetSource() string {
+func (m *KeepAliveLeaseRequest) GetLease() uint64 {
 	if m != nil {
-		return m.Source
+		return m.Lease
 	}
-	return ""
+	return 0
 }
 
-func (m *GetValueRequest) GetKey() string {
+type KeepAliveLeaseResponse struct {
+	Lease uint64 `protobuf:"varint,1,opt,name=lease" json:"lease,omitempty"`
+	Ttl   int64  `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
+}
+
+func (m *KeepAliveLeaseResponse) Reset()                    { *m = KeepAliveLeaseResponse{} }
+func (m *KeepAliveLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*KeepAliveLeaseResponse) ProtoMessage()               {}
+func (*KeepAliveLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{56} }
+
+func (m *KeepAliveLeaseResponse) GetLease() uint64 {
 	if m != nil {
-		return m.Key
+		return m.Lease
 	}
-	return ""
+	return 0
 }
 
-type GetValueResponse struct {
-	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+func (m *KeepAliveLeaseResponse) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
 }
 
-func (m *GetValueResponse) Reset()                    { *m = GetValueResponse{} }
-func (m *GetValueResponse) String() string            { return proto.CompactTextString(m) }
-func (*GetValueResponse) ProtoMessage()               {}
-func (*GetValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+type KeepAliveSessionRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+}
 
-func (m *GetValueResponse) GetValue() []byte {
+func (m *KeepAliveSessionRequest) Reset()                    { *m = KeepAliveSessionRequest{} }
+func (m *KeepAliveSessionRequest) String() string            { return proto.CompactTextString(m) }
+func (*KeepAliveSessionRequest) ProtoMessage()               {}
+func (*KeepAliveSessionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{57} }
+
+func (m *KeepAliveSessionRequest) GetSession() string {
 	if m != nil {
-		return m.Value
+		return m.Session
 	}
-	return nil
+	return ""
 }
 
-type SetValueRequest struct {
+type KeepAliveSessionResponse struct {
 	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
-	Value   []byte `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// ttl in milliseconds within which the session must heartbeat again
+	Ttl int64 `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
 }
 
-func (m *SetValueRequest) Reset()                    { *m = SetValueRequest{} }
-func (m *SetValueRequest) String() string            { return proto.CompactTextString(m) }
-func (*SetValueRequest) ProtoMessage()               {}
-func (*SetValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (m *KeepAliveSessionResponse) Reset()                    { *m = KeepAliveSessionResponse{} }
+func (m *KeepAliveSessionResponse) String() string            { return proto.CompactTextString(m) }
+func (*KeepAliveSessionResponse) ProtoMessage()               {}
+func (*KeepAliveSessionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{58} }
 
-func (m *SetValueRequest) GetSession() string {
+func (m *KeepAliveSessionResponse) GetSession() string {
 	if m != nil {
 		return m.Session
 	}
 	return ""
 }
 
-func (m *SetValueRequest) GetSource() string {
+func (m *KeepAliveSessionResponse) GetTtl() int64 {
 	if m != nil {
-		return m.Source
+		return m.Ttl
 	}
-	return ""
+	return 0
 }
 
-func (m *SetValueRequest) GetKey() string {
+type RevokeLeaseRequest struct {
+	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
+	Lease   uint64 `protobuf:"varint,2,opt,name=lease" json:"lease,omitempty"`
+}
+
+func (m *RevokeLeaseRequest) Reset()                    { *m = RevokeLeaseRequest{} }
+func (m *RevokeLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevokeLeaseRequest) ProtoMessage()               {}
+func (*RevokeLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{59} }
+
+func (m *RevokeLeaseRequest) GetSession() string {
 	if m != nil {
-		return m.Key
+		return m.Session
 	}
 	return ""
 }
 
-func (m *SetValueRequest) GetValue() []byte {
+func (m *RevokeLeaseRequest) GetLease() uint64 {
 	if m != nil {
-		return m.Value
+		return m.Lease
 	}
-	return nil
+	return 0
 }
 
-type SetValueResponse struct {
-	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+type RevokeLeaseResponse struct {
+	Lease uint64 `protobuf:"varint,1,opt,name=lease" json:"lease,omitempty"`
 }
 
-func (m *SetValueResponse) Reset()                    { *m = SetValueResponse{} }
-func (m *SetValueResponse) String() string            { return proto.CompactTextString(m) }
-func (*SetValueResponse) ProtoMessage()               {}
-func (*SetValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (m *RevokeLeaseResponse) Reset()                    { *m = RevokeLeaseResponse{} }
+func (m *RevokeLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*RevokeLeaseResponse) ProtoMessage()               {}
+func (*RevokeLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{60} }
 
-func (m *SetValueResponse) GetValue() []byte {
+func (m *RevokeLeaseResponse) GetLease() uint64 {
 	if m != nil {
-		return m.Value
+		return m.Lease
 	}
-	return nil
+	return 0
 }
 
-type RemoveValueRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+type NodeStatusRequest struct {
 }
 
-func (m *RemoveValueRequest) Reset()                    { *m = RemoveValueRequest{} }
-func (m *RemoveValueRequest) String() string            { return proto.CompactTextString(m) }
-func (*RemoveValueRequest) ProtoMessage()               {}
-func (*RemoveValueRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (m *NodeStatusRequest) Reset()                    { *m = NodeStatusRequest{} }
+func (m *NodeStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*NodeStatusRequest) ProtoMessage()               {}
+func (*NodeStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{61} }
 
-func (m *RemoveValueRequest) GetSession() string {
+type NodeStatusResponse struct {
+	Leader bool   `protobuf:"varint,1,opt,name=leader" json:"leader,omitempty"`
+	Index  uint64 `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	// ready is false while the node is draining its sessions
+	Ready bool `protobuf:"varint,3,opt,name=ready" json:"ready,omitempty"`
+}
+
+func (m *NodeStatusResponse) Reset()                    { *m = NodeStatusResponse{} }
+func (m *NodeStatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*NodeStatusResponse) ProtoMessage()               {}
+func (*NodeStatusResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{62} }
+
+func (m *NodeStatusResponse) GetLeader() bool {
 	if m != nil {
-		return m.Session
+		return m.Leader
 	}
-	return ""
+	return false
 }
 
-func (m *RemoveValueRequest) GetSource() string {
+func (m *NodeStatusResponse) GetIndex() uint64 {
 	if m != nil {
-		return m.Source
+		return m.Index
 	}
-	return ""
+	return 0
 }
 
-func (m *RemoveValueRequest) GetKey() string {
+func (m *NodeStatusResponse) GetReady() bool {
 	if m != nil {
-		return m.Key
+		return m.Ready
+	}
+	return false
+}
+
+// Command is the payload of a binary raft log entry, whose operation is identified by the type byte preceding it
+type Command struct {
+	Source      string          `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key         string          `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value       []byte          `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Conditional bool            `protobuf:"varint,4,opt,name=conditional" json:"conditional,omitempty"`
+	Revision    uint64          `protobuf:"varint,5,opt,name=revision" json:"revision,omitempty"`
+	Guards      []*TxnGuard     `protobuf:"bytes,6,rep,name=guards" json:"guards,omitempty"`
+	Operations  []*TxnOperation `protobuf:"bytes,7,rep,name=operations" json:"operations,omitempty"`
+	Lease       uint64          `protobuf:"varint,8,opt,name=lease" json:"lease,omitempty"`
+	// ttl in nanoseconds
+	Ttl int64 `protobuf:"varint,9,opt,name=ttl" json:"ttl,omitempty"`
+	// peer is the raft address leadership is transferred to
+	Peer string `protobuf:"bytes,10,opt,name=peer" json:"peer,omitempty"`
+	// deadline in nanoseconds since the epoch, until which members only vote for the peer
+	Deadline int64 `protobuf:"varint,11,opt,name=deadline" json:"deadline,omitempty"`
+	// address is the grpc address advertised by the peer
+	Address string `protobuf:"bytes,12,opt,name=address" json:"address,omitempty"`
+	// timestamp in nanoseconds since the epoch, at which the leader proposed the command
+	Timestamp int64 `protobuf:"varint,13,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *Command) Reset()                    { *m = Command{} }
+func (m *Command) String() string            { return proto.CompactTextString(m) }
+func (*Command) ProtoMessage()               {}
+func (*Command) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{63} }
+
+func (m *Command) GetSource() string {
+	if m != nil {
+		return m.Source
 	}
 	return ""
 }
 
-type RemoveValueResponse struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
-}
-
-func (m *RemoveValueResponse) Reset()                    { *m = RemoveValueResponse{} }
-func (m *RemoveValueResponse) String() string            { return proto.CompactTextString(m) }
-func (*RemoveValueResponse) ProtoMessage()               {}
-func (*RemoveValueResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
-
-func (m *RemoveValueResponse) GetSession() string {
+func (m *Command) GetKey() string {
 	if m != nil {
-		return m.Session
+		return m.Key
 	}
 	return ""
 }
 
-func (m *RemoveValueResponse) GetSource() string {
+func (m *Command) GetValue() []byte {
 	if m != nil {
-		return m.Source
+		return m.Value
 	}
-	return ""
+	return nil
 }
 
-func (m *RemoveValueResponse) GetKey() string {
+func (m *Command) GetConditional() bool {
 	if m != nil {
-		return m.Key
+		return m.Conditional
 	}
-	return ""
+	return false
 }
 
-type RemoveSourceRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+func (m *Command) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
 }
 
-func (m *RemoveSourceRequest) Reset()                    { *m = RemoveSourceRequest{} }
-func (m *RemoveSourceRequest) String() string            { return proto.CompactTextString(m) }
-func (*RemoveSourceRequest) ProtoMessage()               {}
-func (*RemoveSourceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
-
-func (m *RemoveSourceRequest) GetSession() string {
+func (m *Command) GetGuards() []*TxnGuard {
 	if m != nil {
-		return m.Session
+		return m.Guards
 	}
-	return ""
+	return nil
 }
 
-func (m *RemoveSourceRequest) GetSource() string {
+func (m *Command) GetOperations() []*TxnOperation {
 	if m != nil {
-		return m.Source
+		return m.Operations
 	}
-	return ""
+	return nil
 }
 
-type RemoveSourceResponse struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+func (m *Command) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
 }
 
-func (m *RemoveSourceResponse) Reset()                    { *m = RemoveSourceResponse{} }
-func (m *RemoveSourceResponse) String() string            { return proto.CompactTextString(m) }
-func (*RemoveSourceResponse) ProtoMessage()               {}
-func (*RemoveSourceResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
-
-func (m *RemoveSourceResponse) GetSession() string {
+func (m *Command) GetTtl() int64 {
 	if m != nil {
-		return m.Session
+		return m.Ttl
 	}
-	return ""
+	return 0
 }
 
-func (m *RemoveSourceResponse) GetSource() string {
+func (m *Command) GetPeer() string {
 	if m != nil {
-		return m.Source
+		return m.Peer
 	}
 	return ""
 }
 
-type GetKeysRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+func (m *Command) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
 }
 
-func (m *GetKeysRequest) Reset()                    { *m = GetKeysRequest{} }
-func (m *GetKeysRequest) String() string            { return proto.CompactTextString(m) }
-func (*GetKeysRequest) ProtoMessage()               {}
-func (*GetKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
-
-func (m *GetKeysRequest) GetSession() string {
+func (m *Command) GetAddress() string {
 	if m != nil {
-		return m.Session
+		return m.Address
 	}
 	return ""
 }
 
-func (m *GetKeysRequest) GetSource() string {
+func (m *Command) GetTimestamp() int64 {
 	if m != nil {
-		return m.Source
+		return m.Timestamp
 	}
-	return ""
+	return 0
 }
 
-type GetKeysResponse struct {
-	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+// SnapshotEntry is a record of a binary snapshot holding the current value of a key
+type SnapshotEntry struct {
+	Source    string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key       string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value     []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Revision  uint64 `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+	Lease     uint64 `protobuf:"varint,5,opt,name=lease" json:"lease,omitempty"`
+	Timestamp int64  `protobuf:"varint,6,opt,name=timestamp" json:"timestamp,omitempty"`
 }
 
-func (m *GetKeysResponse) Reset()                    { *m = GetKeysResponse{} }
-func (m *GetKeysResponse) String() string            { return proto.CompactTextString(m) }
-func (*GetKeysResponse) ProtoMessage()               {}
-func (*GetKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+func (m *SnapshotEntry) Reset()                    { *m = SnapshotEntry{} }
+func (m *SnapshotEntry) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotEntry) ProtoMessage()               {}
+func (*SnapshotEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{64} }
 
-func (m *GetKeysResponse) GetKey() string {
+func (m *SnapshotEntry) GetSource() string {
 	if m != nil {
-		return m.Key
+		return m.Source
 	}
 	return ""
 }
 
-type SubscribeRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-}
-
-func (m *SubscribeRequest) Reset()                    { *m = SubscribeRequest{} }
-func (m *SubscribeRequest) String() string            { return proto.CompactTextString(m) }
-func (*SubscribeRequest) ProtoMessage()               {}
-func (*SubscribeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
-
-func (m *SubscribeRequest) GetSession() string {
+func (m *SnapshotEntry) GetKey() string {
 	if m != nil {
-		return m.Session
+		return m.Key
 	}
 	return ""
 }
 
-func (m *SubscribeRequest) GetSource() string {
+func (m *SnapshotEntry) GetValue() []byte {
 	if m != nil {
-		return m.Source
+		return m.Value
 	}
-	return ""
+	return nil
 }
 
-type SubscribeResponse struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+func (m *SnapshotEntry) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
 }
 
-func (m *SubscribeResponse) Reset()                    { *m = SubscribeResponse{} }
-func (m *SubscribeResponse) String() string            { return proto.CompactTextString(m) }
-func (*SubscribeResponse) ProtoMessage()               {}
-func (*SubscribeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+func (m *SnapshotEntry) GetLease() uint64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
 
-func (m *SubscribeResponse) GetSource() string {
+func (m *SnapshotEntry) GetTimestamp() int64 {
 	if m != nil {
-		return m.Source
+		return m.Timestamp
 	}
-	return ""
+	return 0
 }
 
-type SubscribeKeyRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+// SnapshotLease is a record of a binary snapshot holding a lease
+type SnapshotLease struct {
+	Id uint64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	// ttl in nanoseconds
+	Ttl      int64 `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
+	Implicit bool  `protobuf:"varint,3,opt,name=implicit" json:"implicit,omitempty"`
 }
 
-func (m *SubscribeKeyRequest) Reset()                    { *m = SubscribeKeyRequest{} }
-func (m *SubscribeKeyRequest) String() string            { return proto.CompactTextString(m) }
-func (*SubscribeKeyRequest) ProtoMessage()               {}
-func (*SubscribeKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+func (m *SnapshotLease) Reset()                    { *m = SnapshotLease{} }
+func (m *SnapshotLease) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotLease) ProtoMessage()               {}
+func (*SnapshotLease) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{65} }
 
-func (m *SubscribeKeyRequest) GetSession() string {
+func (m *SnapshotLease) GetId() uint64 {
 	if m != nil {
-		return m.Session
+		return m.Id
 	}
-	return ""
+	return 0
 }
 
-func (m *SubscribeKeyRequest) GetSource() string {
+func (m *SnapshotLease) GetTtl() int64 {
 	if m != nil {
-		return m.Source
+		return m.Ttl
 	}
-	return ""
+	return 0
 }
 
-func (m *SubscribeKeyRequest) GetKey() string {
+func (m *SnapshotLease) GetImplicit() bool {
 	if m != nil {
-		return m.Key
+		return m.Implicit
 	}
-	return ""
+	return false
 }
 
-type SubscribeKeyResponse struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
-	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+// SnapshotHistory is a record of a binary snapshot describing the compaction of a key's history
+type SnapshotHistory struct {
+	Source    string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key       string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Compacted uint64 `protobuf:"varint,3,opt,name=compacted" json:"compacted,omitempty"`
+	// versions are only populated when the history is held by the bolt storage engine,
+	// since snapshots record each version separately
+	Versions []*SnapshotVersion `protobuf:"bytes,4,rep,name=versions" json:"versions,omitempty"`
 }
 
-func (m *SubscribeKeyResponse) Reset()                    { *m = SubscribeKeyResponse{} }
-func (m *SubscribeKeyResponse) String() string            { return proto.CompactTextString(m) }
-func (*SubscribeKeyResponse) ProtoMessage()               {}
-func (*SubscribeKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+func (m *SnapshotHistory) Reset()                    { *m = SnapshotHistory{} }
+func (m *SnapshotHistory) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotHistory) ProtoMessage()               {}
+func (*SnapshotHistory) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{66} }
 
-func (m *SubscribeKeyResponse) GetSource() string {
+func (m *SnapshotHistory) GetSource() string {
 	if m != nil {
 		return m.Source
 	}
 	return ""
 }
 
-func (m *SubscribeKeyResponse) GetKey() string {
+func (m *SnapshotHistory) GetKey() string {
 	if m != nil {
 		return m.Key
 	}
 	return ""
 }
 
-type UnsubscribeRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-}
-
-func (m *UnsubscribeRequest) Reset()                    { *m = UnsubscribeRequest{} }
-func (m *UnsubscribeRequest) String() string            { return proto.CompactTextString(m) }
-func (*UnsubscribeRequest) ProtoMessage()               {}
-func (*UnsubscribeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
-
-func (m *UnsubscribeRequest) GetSession() string {
+func (m *SnapshotHistory) GetCompacted() uint64 {
 	if m != nil {
-		return m.Session
+		return m.Compacted
 	}
-	return ""
+	return 0
 }
 
-func (m *UnsubscribeRequest) GetSource() string {
+func (m *SnapshotHistory) GetVersions() []*SnapshotVersion {
 	if m != nil {
-		return m.Source
+		return m.Versions
 	}
-	return ""
+	return nil
 }
 
-type UnsubscribeResponse struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+// SnapshotIndex is the first record of a binary snapshot, holding the raft log index it was taken at
+type SnapshotIndex struct {
+	Index uint64 `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
 }
 
-func (m *UnsubscribeResponse) Reset()                    { *m = UnsubscribeResponse{} }
-func (m *UnsubscribeResponse) String() string            { return proto.CompactTextString(m) }
-func (*UnsubscribeResponse) ProtoMessage()               {}
-func (*UnsubscribeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+func (m *SnapshotIndex) Reset()                    { *m = SnapshotIndex{} }
+func (m *SnapshotIndex) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotIndex) ProtoMessage()               {}
+func (*SnapshotIndex) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{67} }
 
-func (m *UnsubscribeResponse) GetSource() string {
+func (m *SnapshotIndex) GetIndex() uint64 {
 	if m != nil {
-		return m.Source
+		return m.Index
 	}
-	return ""
+	return 0
 }
 
-type UnsubscribeKeyRequest struct {
-	Session string `protobuf:"bytes,1,opt,name=session" json:"session,omitempty"`
-	Source  string `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
-	Key     string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+// SnapshotAddress is a record of a binary snapshot holding the grpc address advertised by a member of the cluster
+type SnapshotAddress struct {
+	Peer    string `protobuf:"bytes,1,opt,name=peer" json:"peer,omitempty"`
+	Address string `protobuf:"bytes,2,opt,name=address" json:"address,omitempty"`
 }
 
-func (m *UnsubscribeKeyRequest) Reset()                    { *m = UnsubscribeKeyRequest{} }
-func (m *UnsubscribeKeyRequest) String() string            { return proto.CompactTextString(m) }
-func (*UnsubscribeKeyRequest) ProtoMessage()               {}
-func (*UnsubscribeKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+func (m *SnapshotAddress) Reset()                    { *m = SnapshotAddress{} }
+func (m *SnapshotAddress) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotAddress) ProtoMessage()               {}
+func (*SnapshotAddress) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{68} }
 
-func (m *UnsubscribeKeyRequest) GetSession() string {
+func (m *SnapshotAddress) GetPeer() string {
 	if m != nil {
-		return m.Session
+		return m.Peer
 	}
 	return ""
 }
 
-func (m *UnsubscribeKeyRequest) GetSource() string {
+func (m *SnapshotAddress) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// SnapshotVersion is a record of a binary snapshot holding a previous version of a key
+type SnapshotVersion struct {
+	Source    string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Key       string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value     []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Revision  uint64 `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+	Timestamp int64  `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	Deleted   bool   `protobuf:"varint,6,opt,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *SnapshotVersion) Reset()                    { *m = SnapshotVersion{} }
+func (m *SnapshotVersion) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotVersion) ProtoMessage()               {}
+func (*SnapshotVersion) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{69} }
+
+func (m *SnapshotVersion) GetSource() string {
 	if m != nil {
 		return m.Source
 	}
 	return ""
 }
 
-func (m *UnsubscribeKeyRequest) GetKey() string {
+func (m *SnapshotVersion) GetKey() string {
 	if m != nil {
 		return m.Key
 	}
 	return ""
 }
 
-type UnsubscribeKeyResponse struct {
-	Source string `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
-	Key    string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+func (m *SnapshotVersion) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
 }
 
-func (m *UnsubscribeKeyResponse) Reset()                    { *m = UnsubscribeKeyResponse{} }
-func (m *UnsubscribeKeyResponse) String() string            { return proto.CompactTextString(m) }
-func (*UnsubscribeKeyResponse) ProtoMessage()               {}
-func (*UnsubscribeKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+func (m *SnapshotVersion) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
 
-func (m *UnsubscribeKeyResponse) GetSource() string {
+func (m *SnapshotVersion) GetTimestamp() int64 {
 	if m != nil {
-		return m.Source
+		return m.Timestamp
 	}
-	return ""
+	return 0
 }
 
-func (m *UnsubscribeKeyResponse) GetKey() string {
+func (m *SnapshotVersion) GetDeleted() bool {
 	if m != nil {
-		return m.Key
+		return m.Deleted
 	}
-	return ""
+	return false
 }
 
 func init() {
 	proto.RegisterType((*JoinRequest)(nil), "iris.pb.JoinRequest")
 	proto.RegisterType((*JoinResponse)(nil), "iris.pb.JoinResponse")
+	proto.RegisterType((*RemovePeerRequest)(nil), "iris.pb.RemovePeerRequest")
+	proto.RegisterType((*RemovePeerResponse)(nil), "iris.pb.RemovePeerResponse")
+	proto.RegisterType((*LeaveRequest)(nil), "iris.pb.LeaveRequest")
+	proto.RegisterType((*LeaveResponse)(nil), "iris.pb.LeaveResponse")
+	proto.RegisterType((*ListPeersRequest)(nil), "iris.pb.ListPeersRequest")
+	proto.RegisterType((*ListPeersResponse)(nil), "iris.pb.ListPeersResponse")
+	proto.RegisterType((*Peer)(nil), "iris.pb.Peer")
+	proto.RegisterType((*ClusterStatusRequest)(nil), "iris.pb.ClusterStatusRequest")
+	proto.RegisterType((*ClusterStatusResponse)(nil), "iris.pb.ClusterStatusResponse")
+	proto.RegisterType((*TransferLeadershipRequest)(nil), "iris.pb.TransferLeadershipRequest")
+	proto.RegisterType((*TransferLeadershipResponse)(nil), "iris.pb.TransferLeadershipResponse")
+	proto.RegisterType((*DrainRequest)(nil), "iris.pb.DrainRequest")
+	proto.RegisterType((*DrainResponse)(nil), "iris.pb.DrainResponse")
 	proto.RegisterType((*ConnectRequest)(nil), "iris.pb.ConnectRequest")
 	proto.RegisterType((*ConnectResponse)(nil), "iris.pb.ConnectResponse")
 	proto.RegisterType((*ListenRequest)(nil), "iris.pb.ListenRequest")
@@ -645,6 +2535,8 @@ func init() {
 	proto.RegisterType((*GetSourcesResponse)(nil), "iris.pb.GetSourcesResponse")
 	proto.RegisterType((*GetValueRequest)(nil), "iris.pb.GetValueRequest")
 	proto.RegisterType((*GetValueResponse)(nil), "iris.pb.GetValueResponse")
+	proto.RegisterType((*GetHistoryRequest)(nil), "iris.pb.GetHistoryRequest")
+	proto.RegisterType((*Version)(nil), "iris.pb.Version")
 	proto.RegisterType((*SetValueRequest)(nil), "iris.pb.SetValueRequest")
 	proto.RegisterType((*SetValueResponse)(nil), "iris.pb.SetValueResponse")
 	proto.RegisterType((*RemoveValueRequest)(nil), "iris.pb.RemoveValueRequest")
@@ -657,10 +2549,44 @@ func init() {
 	proto.RegisterType((*SubscribeResponse)(nil), "iris.pb.SubscribeResponse")
 	proto.RegisterType((*SubscribeKeyRequest)(nil), "iris.pb.SubscribeKeyRequest")
 	proto.RegisterType((*SubscribeKeyResponse)(nil), "iris.pb.SubscribeKeyResponse")
+	proto.RegisterType((*SubscribePatternRequest)(nil), "iris.pb.SubscribePatternRequest")
+	proto.RegisterType((*SubscribePatternResponse)(nil), "iris.pb.SubscribePatternResponse")
+	proto.RegisterType((*UnsubscribePatternRequest)(nil), "iris.pb.UnsubscribePatternRequest")
+	proto.RegisterType((*UnsubscribePatternResponse)(nil), "iris.pb.UnsubscribePatternResponse")
+	proto.RegisterType((*SubscribeSourcesRequest)(nil), "iris.pb.SubscribeSourcesRequest")
+	proto.RegisterType((*SubscribeSourcesResponse)(nil), "iris.pb.SubscribeSourcesResponse")
+	proto.RegisterType((*UnsubscribeSourcesRequest)(nil), "iris.pb.UnsubscribeSourcesRequest")
+	proto.RegisterType((*UnsubscribeSourcesResponse)(nil), "iris.pb.UnsubscribeSourcesResponse")
 	proto.RegisterType((*UnsubscribeRequest)(nil), "iris.pb.UnsubscribeRequest")
 	proto.RegisterType((*UnsubscribeResponse)(nil), "iris.pb.UnsubscribeResponse")
 	proto.RegisterType((*UnsubscribeKeyRequest)(nil), "iris.pb.UnsubscribeKeyRequest")
 	proto.RegisterType((*UnsubscribeKeyResponse)(nil), "iris.pb.UnsubscribeKeyResponse")
+	proto.RegisterType((*TxnGuard)(nil), "iris.pb.TxnGuard")
+	proto.RegisterType((*TxnOperation)(nil), "iris.pb.TxnOperation")
+	proto.RegisterType((*TxnRequest)(nil), "iris.pb.TxnRequest")
+	proto.RegisterType((*TxnResponse)(nil), "iris.pb.TxnResponse")
+	proto.RegisterType((*GrantLeaseRequest)(nil), "iris.pb.GrantLeaseRequest")
+	proto.RegisterType((*GrantLeaseResponse)(nil), "iris.pb.GrantLeaseResponse")
+	proto.RegisterType((*KeepAliveLeaseRequest)(nil), "iris.pb.KeepAliveLeaseRequest")
+	proto.RegisterType((*KeepAliveLeaseResponse)(nil), "iris.pb.KeepAliveLeaseResponse")
+	proto.RegisterType((*KeepAliveSessionRequest)(nil), "iris.pb.KeepAliveSessionRequest")
+	proto.RegisterType((*KeepAliveSessionResponse)(nil), "iris.pb.KeepAliveSessionResponse")
+	proto.RegisterType((*RevokeLeaseRequest)(nil), "iris.pb.RevokeLeaseRequest")
+	proto.RegisterType((*RevokeLeaseResponse)(nil), "iris.pb.RevokeLeaseResponse")
+	proto.RegisterType((*NodeStatusRequest)(nil), "iris.pb.NodeStatusRequest")
+	proto.RegisterType((*NodeStatusResponse)(nil), "iris.pb.NodeStatusResponse")
+	proto.RegisterType((*Command)(nil), "iris.pb.Command")
+	proto.RegisterType((*SnapshotEntry)(nil), "iris.pb.SnapshotEntry")
+	proto.RegisterType((*SnapshotLease)(nil), "iris.pb.SnapshotLease")
+	proto.RegisterType((*SnapshotHistory)(nil), "iris.pb.SnapshotHistory")
+	proto.RegisterType((*SnapshotIndex)(nil), "iris.pb.SnapshotIndex")
+	proto.RegisterType((*SnapshotAddress)(nil), "iris.pb.SnapshotAddress")
+	proto.RegisterType((*SnapshotVersion)(nil), "iris.pb.SnapshotVersion")
+	proto.RegisterEnum("iris.pb.UpdateOperation", UpdateOperation_name, UpdateOperation_value)
+	proto.RegisterEnum("iris.pb.ReadConsistency", ReadConsistency_name, ReadConsistency_value)
+	proto.RegisterEnum("iris.pb.Condition", Condition_name, Condition_value)
+	proto.RegisterEnum("iris.pb.GuardType", GuardType_name, GuardType_value)
+	proto.RegisterEnum("iris.pb.OperationType", OperationType_name, OperationType_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -676,18 +2602,33 @@ const _ = grpc.SupportPackageIsVersion4
 type IrisClient interface {
 	// Join the node reachable at the provided address to this cluster
 	Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error)
+	// RemovePeer removes the node reachable at the provided raft address from this cluster
+	RemovePeer(ctx context.Context, in *RemovePeerRequest, opts ...grpc.CallOption) (*RemovePeerResponse, error)
+	// Leave removes the node receiving the request from its cluster
+	Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error)
+	// ListPeers responds with the raft addresses of the members of the cluster
+	ListPeers(ctx context.Context, in *ListPeersRequest, opts ...grpc.CallOption) (*ListPeersResponse, error)
+	// ClusterStatus responds with the raft state of the node receiving the request and of each member of its cluster
+	ClusterStatus(ctx context.Context, in *ClusterStatusRequest, opts ...grpc.CallOption) (*ClusterStatusResponse, error)
+	// TransferLeadership moves leadership of the cluster to the node reachable at the provided raft address
+	TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*TransferLeadershipResponse, error)
+	// Drain begins or ends the maintenance mode of the node receiving the request, in which it refuses new sessions
+	// and ends the sessions it holds so that their clients move to another node
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error)
 	// Connect responds with a session identifier to be used for subsequent requests
 	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error)
 	// Listen responds with a stream of objects representing source, key, value updates
 	Listen(ctx context.Context, in *ListenRequest, opts ...grpc.CallOption) (Iris_ListenClient, error)
-	// GetSources responds with a stream of objects representing available sources
+	// GetSources responds with a stream of objects representing available sources, in lexical order
 	GetSources(ctx context.Context, in *GetSourcesRequest, opts ...grpc.CallOption) (Iris_GetSourcesClient, error)
-	// GetKeys expects a source and responds with a stream of objects representing available keys
+	// GetKeys expects a source and responds with a stream of objects representing available keys, in lexical order
 	GetKeys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (Iris_GetKeysClient, error)
 	// SetValue sets the value for the specified source and key
 	SetValue(ctx context.Context, in *SetValueRequest, opts ...grpc.CallOption) (*SetValueResponse, error)
-	// GetValue expects a source and key and responds with the associated value
+	// GetValue expects a source and key and responds with the associated value, optionally as of a past revision
 	GetValue(ctx context.Context, in *GetValueRequest, opts ...grpc.CallOption) (*GetValueResponse, error)
+	// GetHistory expects a source and key and responds with a stream of its retained versions, ending with the current value
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (Iris_GetHistoryClient, error)
 	// RemoveValue removes the specified value from the provided source
 	RemoveValue(ctx context.Context, in *RemoveValueRequest, opts ...grpc.CallOption) (*RemoveValueResponse, error)
 	// RemoveSource removes the specified source
@@ -702,6 +2643,28 @@ type IrisClient interface {
 	// UnsubscribeKey indicates that the client no longer wishes to be notified of updates associated
 	// with a specific key from the specified source
 	UnsubscribeKey(ctx context.Context, in *UnsubscribeKeyRequest, opts ...grpc.CallOption) (*UnsubscribeKeyResponse, error)
+	// SubscribePattern indicates that the client wishes to be notified of updates to the keys matching a glob
+	// pattern within the sources matching a glob pattern
+	SubscribePattern(ctx context.Context, in *SubscribePatternRequest, opts ...grpc.CallOption) (*SubscribePatternResponse, error)
+	// UnsubscribePattern indicates that the client no longer wishes to be notified of updates matching the patterns
+	UnsubscribePattern(ctx context.Context, in *UnsubscribePatternRequest, opts ...grpc.CallOption) (*UnsubscribePatternResponse, error)
+	// SubscribeSources indicates that the client wishes to be notified when sources are created or deleted
+	SubscribeSources(ctx context.Context, in *SubscribeSourcesRequest, opts ...grpc.CallOption) (*SubscribeSourcesResponse, error)
+	// UnsubscribeSources indicates that the client no longer wishes to be notified when sources are created or deleted
+	UnsubscribeSources(ctx context.Context, in *UnsubscribeSourcesRequest, opts ...grpc.CallOption) (*UnsubscribeSourcesResponse, error)
+	// Txn atomically applies a set of operations if all of the provided guards are satisfied
+	Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error)
+	// GrantLease creates a lease that expires after its time-to-live unless kept alive
+	GrantLease(ctx context.Context, in *GrantLeaseRequest, opts ...grpc.CallOption) (*GrantLeaseResponse, error)
+	// KeepAliveLease restarts the time-to-live of the specified lease
+	KeepAliveLease(ctx context.Context, in *KeepAliveLeaseRequest, opts ...grpc.CallOption) (*KeepAliveLeaseResponse, error)
+	// KeepAliveSession records a heartbeat of a session holding ephemeral values, which ends unless it heartbeats
+	// within its time-to-live
+	KeepAliveSession(ctx context.Context, in *KeepAliveSessionRequest, opts ...grpc.CallOption) (*KeepAliveSessionResponse, error)
+	// RevokeLease removes the specified lease along with every value attached to it
+	RevokeLease(ctx context.Context, in *RevokeLeaseRequest, opts ...grpc.CallOption) (*RevokeLeaseResponse, error)
+	// NodeStatus responds with the role and progress of the node receiving the request
+	NodeStatus(ctx context.Context, in *NodeStatusRequest, opts ...grpc.CallOption) (*NodeStatusResponse, error)
 }
 
 type irisClient struct {
@@ -721,6 +2684,60 @@ func (c *irisClient) Join(ctx context.Context, in *JoinRequest, opts ...grpc.Cal
 	return out, nil
 }
 
+func (c *irisClient) RemovePeer(ctx context.Context, in *RemovePeerRequest, opts ...grpc.CallOption) (*RemovePeerResponse, error) {
+	out := new(RemovePeerResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/RemovePeer", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error) {
+	out := new(LeaveResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/Leave", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) ListPeers(ctx context.Context, in *ListPeersRequest, opts ...grpc.CallOption) (*ListPeersResponse, error) {
+	out := new(ListPeersResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/ListPeers", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) ClusterStatus(ctx context.Context, in *ClusterStatusRequest, opts ...grpc.CallOption) (*ClusterStatusResponse, error) {
+	out := new(ClusterStatusResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/ClusterStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*TransferLeadershipResponse, error) {
+	out := new(TransferLeadershipResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/TransferLeadership", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error) {
+	out := new(DrainResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/Drain", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *irisClient) Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error) {
 	out := new(ConnectResponse)
 	err := grpc.Invoke(ctx, "/iris.pb.Iris/Connect", in, out, c.cc, opts...)
@@ -844,6 +2861,38 @@ func (c *irisClient) GetValue(ctx context.Context, in *GetValueRequest, opts ...
 	return out, nil
 }
 
+func (c *irisClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (Iris_GetHistoryClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Iris_serviceDesc.Streams[3], c.cc, "/iris.pb.Iris/GetHistory", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &irisGetHistoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Iris_GetHistoryClient interface {
+	Recv() (*Version, error)
+	grpc.ClientStream
+}
+
+type irisGetHistoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *irisGetHistoryClient) Recv() (*Version, error) {
+	m := new(Version)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *irisClient) RemoveValue(ctx context.Context, in *RemoveValueRequest, opts ...grpc.CallOption) (*RemoveValueResponse, error) {
 	out := new(RemoveValueResponse)
 	err := grpc.Invoke(ctx, "/iris.pb.Iris/RemoveValue", in, out, c.cc, opts...)
@@ -880,75 +2929,310 @@ func (c *irisClient) SubscribeKey(ctx context.Context, in *SubscribeKeyRequest,
 	return out, nil
 }
 
-func (c *irisClient) Unsubscribe(ctx context.Context, in *UnsubscribeRequest, opts ...grpc.CallOption) (*UnsubscribeResponse, error) {
-	out := new(UnsubscribeResponse)
-	err := grpc.Invoke(ctx, "/iris.pb.Iris/Unsubscribe", in, out, c.cc, opts...)
-	if err != nil {
+func (c *irisClient) Unsubscribe(ctx context.Context, in *UnsubscribeRequest, opts ...grpc.CallOption) (*UnsubscribeResponse, error) {
+	out := new(UnsubscribeResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/Unsubscribe", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) UnsubscribeKey(ctx context.Context, in *UnsubscribeKeyRequest, opts ...grpc.CallOption) (*UnsubscribeKeyResponse, error) {
+	out := new(UnsubscribeKeyResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/UnsubscribeKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) SubscribePattern(ctx context.Context, in *SubscribePatternRequest, opts ...grpc.CallOption) (*SubscribePatternResponse, error) {
+	out := new(SubscribePatternResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/SubscribePattern", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) UnsubscribePattern(ctx context.Context, in *UnsubscribePatternRequest, opts ...grpc.CallOption) (*UnsubscribePatternResponse, error) {
+	out := new(UnsubscribePatternResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/UnsubscribePattern", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) SubscribeSources(ctx context.Context, in *SubscribeSourcesRequest, opts ...grpc.CallOption) (*SubscribeSourcesResponse, error) {
+	out := new(SubscribeSourcesResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/SubscribeSources", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) UnsubscribeSources(ctx context.Context, in *UnsubscribeSourcesRequest, opts ...grpc.CallOption) (*UnsubscribeSourcesResponse, error) {
+	out := new(UnsubscribeSourcesResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/UnsubscribeSources", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error) {
+	out := new(TxnResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/Txn", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) GrantLease(ctx context.Context, in *GrantLeaseRequest, opts ...grpc.CallOption) (*GrantLeaseResponse, error) {
+	out := new(GrantLeaseResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/GrantLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) KeepAliveLease(ctx context.Context, in *KeepAliveLeaseRequest, opts ...grpc.CallOption) (*KeepAliveLeaseResponse, error) {
+	out := new(KeepAliveLeaseResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/KeepAliveLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) KeepAliveSession(ctx context.Context, in *KeepAliveSessionRequest, opts ...grpc.CallOption) (*KeepAliveSessionResponse, error) {
+	out := new(KeepAliveSessionResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/KeepAliveSession", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) RevokeLease(ctx context.Context, in *RevokeLeaseRequest, opts ...grpc.CallOption) (*RevokeLeaseResponse, error) {
+	out := new(RevokeLeaseResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/RevokeLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *irisClient) NodeStatus(ctx context.Context, in *NodeStatusRequest, opts ...grpc.CallOption) (*NodeStatusResponse, error) {
+	out := new(NodeStatusResponse)
+	err := grpc.Invoke(ctx, "/iris.pb.Iris/NodeStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Iris service
+
+type IrisServer interface {
+	// Join the node reachable at the provided address to this cluster
+	Join(context.Context, *JoinRequest) (*JoinResponse, error)
+	// RemovePeer removes the node reachable at the provided raft address from this cluster
+	RemovePeer(context.Context, *RemovePeerRequest) (*RemovePeerResponse, error)
+	// Leave removes the node receiving the request from its cluster
+	Leave(context.Context, *LeaveRequest) (*LeaveResponse, error)
+	// ListPeers responds with the raft addresses of the members of the cluster
+	ListPeers(context.Context, *ListPeersRequest) (*ListPeersResponse, error)
+	// ClusterStatus responds with the raft state of the node receiving the request and of each member of its cluster
+	ClusterStatus(context.Context, *ClusterStatusRequest) (*ClusterStatusResponse, error)
+	// TransferLeadership moves leadership of the cluster to the node reachable at the provided raft address
+	TransferLeadership(context.Context, *TransferLeadershipRequest) (*TransferLeadershipResponse, error)
+	// Drain begins or ends the maintenance mode of the node receiving the request, in which it refuses new sessions
+	// and ends the sessions it holds so that their clients move to another node
+	Drain(context.Context, *DrainRequest) (*DrainResponse, error)
+	// Connect responds with a session identifier to be used for subsequent requests
+	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
+	// Listen responds with a stream of objects representing source, key, value updates
+	Listen(*ListenRequest, Iris_ListenServer) error
+	// GetSources responds with a stream of objects representing available sources, in lexical order
+	GetSources(*GetSourcesRequest, Iris_GetSourcesServer) error
+	// GetKeys expects a source and responds with a stream of objects representing available keys, in lexical order
+	GetKeys(*GetKeysRequest, Iris_GetKeysServer) error
+	// SetValue sets the value for the specified source and key
+	SetValue(context.Context, *SetValueRequest) (*SetValueResponse, error)
+	// GetValue expects a source and key and responds with the associated value, optionally as of a past revision
+	GetValue(context.Context, *GetValueRequest) (*GetValueResponse, error)
+	// GetHistory expects a source and key and responds with a stream of its retained versions, ending with the current value
+	GetHistory(*GetHistoryRequest, Iris_GetHistoryServer) error
+	// RemoveValue removes the specified value from the provided source
+	RemoveValue(context.Context, *RemoveValueRequest) (*RemoveValueResponse, error)
+	// RemoveSource removes the specified source
+	RemoveSource(context.Context, *RemoveSourceRequest) (*RemoveSourceResponse, error)
+	// Subscribe indicates that the client wishes to be notified of all updates for the specified source
+	Subscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error)
+	// SubscribeKey indicates that the client wishes to be notified of updates associated with
+	// a specific key from the specified source
+	SubscribeKey(context.Context, *SubscribeKeyRequest) (*SubscribeKeyResponse, error)
+	// Unsubscribe indicates that the client no longer wishes to be notified of updates for the specified source
+	Unsubscribe(context.Context, *UnsubscribeRequest) (*UnsubscribeResponse, error)
+	// UnsubscribeKey indicates that the client no longer wishes to be notified of updates associated
+	// with a specific key from the specified source
+	UnsubscribeKey(context.Context, *UnsubscribeKeyRequest) (*UnsubscribeKeyResponse, error)
+	// SubscribePattern indicates that the client wishes to be notified of updates to the keys matching a glob
+	// pattern within the sources matching a glob pattern
+	SubscribePattern(context.Context, *SubscribePatternRequest) (*SubscribePatternResponse, error)
+	// UnsubscribePattern indicates that the client no longer wishes to be notified of updates matching the patterns
+	UnsubscribePattern(context.Context, *UnsubscribePatternRequest) (*UnsubscribePatternResponse, error)
+	// SubscribeSources indicates that the client wishes to be notified when sources are created or deleted
+	SubscribeSources(context.Context, *SubscribeSourcesRequest) (*SubscribeSourcesResponse, error)
+	// UnsubscribeSources indicates that the client no longer wishes to be notified when sources are created or deleted
+	UnsubscribeSources(context.Context, *UnsubscribeSourcesRequest) (*UnsubscribeSourcesResponse, error)
+	// Txn atomically applies a set of operations if all of the provided guards are satisfied
+	Txn(context.Context, *TxnRequest) (*TxnResponse, error)
+	// GrantLease creates a lease that expires after its time-to-live unless kept alive
+	GrantLease(context.Context, *GrantLeaseRequest) (*GrantLeaseResponse, error)
+	// KeepAliveLease restarts the time-to-live of the specified lease
+	KeepAliveLease(context.Context, *KeepAliveLeaseRequest) (*KeepAliveLeaseResponse, error)
+	// KeepAliveSession records a heartbeat of a session holding ephemeral values, which ends unless it heartbeats
+	// within its time-to-live
+	KeepAliveSession(context.Context, *KeepAliveSessionRequest) (*KeepAliveSessionResponse, error)
+	// RevokeLease removes the specified lease along with every value attached to it
+	RevokeLease(context.Context, *RevokeLeaseRequest) (*RevokeLeaseResponse, error)
+	// NodeStatus responds with the role and progress of the node receiving the request
+	NodeStatus(context.Context, *NodeStatusRequest) (*NodeStatusResponse, error)
+}
+
+func RegisterIrisServer(s *grpc.Server, srv IrisServer) {
+	s.RegisterService(&_Iris_serviceDesc, srv)
+}
+
+func _Iris_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/Join",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).Join(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_RemovePeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemovePeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).RemovePeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/RemovePeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).RemovePeer(ctx, req.(*RemovePeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/Leave",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).Leave(ctx, req.(*LeaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_ListPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeersRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(IrisServer).ListPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/ListPeers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).ListPeers(ctx, req.(*ListPeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *irisClient) UnsubscribeKey(ctx context.Context, in *UnsubscribeKeyRequest, opts ...grpc.CallOption) (*UnsubscribeKeyResponse, error) {
-	out := new(UnsubscribeKeyResponse)
-	err := grpc.Invoke(ctx, "/iris.pb.Iris/UnsubscribeKey", in, out, c.cc, opts...)
-	if err != nil {
+func _Iris_ClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClusterStatusRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-// Server API for Iris service
-
-type IrisServer interface {
-	// Join the node reachable at the provided address to this cluster
-	Join(context.Context, *JoinRequest) (*JoinResponse, error)
-	// Connect responds with a session identifier to be used for subsequent requests
-	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
-	// Listen responds with a stream of objects representing source, key, value updates
-	Listen(*ListenRequest, Iris_ListenServer) error
-	// GetSources responds with a stream of objects representing available sources
-	GetSources(*GetSourcesRequest, Iris_GetSourcesServer) error
-	// GetKeys expects a source and responds with a stream of objects representing available keys
-	GetKeys(*GetKeysRequest, Iris_GetKeysServer) error
-	// SetValue sets the value for the specified source and key
-	SetValue(context.Context, *SetValueRequest) (*SetValueResponse, error)
-	// GetValue expects a source and key and responds with the associated value
-	GetValue(context.Context, *GetValueRequest) (*GetValueResponse, error)
-	// RemoveValue removes the specified value from the provided source
-	RemoveValue(context.Context, *RemoveValueRequest) (*RemoveValueResponse, error)
-	// RemoveSource removes the specified source
-	RemoveSource(context.Context, *RemoveSourceRequest) (*RemoveSourceResponse, error)
-	// Subscribe indicates that the client wishes to be notified of all updates for the specified source
-	Subscribe(context.Context, *SubscribeRequest) (*SubscribeResponse, error)
-	// SubscribeKey indicates that the client wishes to be notified of updates associated with
-	// a specific key from the specified source
-	SubscribeKey(context.Context, *SubscribeKeyRequest) (*SubscribeKeyResponse, error)
-	// Unsubscribe indicates that the client no longer wishes to be notified of updates for the specified source
-	Unsubscribe(context.Context, *UnsubscribeRequest) (*UnsubscribeResponse, error)
-	// UnsubscribeKey indicates that the client no longer wishes to be notified of updates associated
-	// with a specific key from the specified source
-	UnsubscribeKey(context.Context, *UnsubscribeKeyRequest) (*UnsubscribeKeyResponse, error)
+	if interceptor == nil {
+		return srv.(IrisServer).ClusterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/ClusterStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).ClusterStatus(ctx, req.(*ClusterStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterIrisServer(s *grpc.Server, srv IrisServer) {
-	s.RegisterService(&_Iris_serviceDesc, srv)
+func _Iris_TransferLeadership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferLeadershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).TransferLeadership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/TransferLeadership",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).TransferLeadership(ctx, req.(*TransferLeadershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Iris_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(JoinRequest)
+func _Iris_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(IrisServer).Join(ctx, in)
+		return srv.(IrisServer).Drain(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/iris.pb.Iris/Join",
+		FullMethod: "/iris.pb.Iris/Drain",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(IrisServer).Join(ctx, req.(*JoinRequest))
+		return srv.(IrisServer).Drain(ctx, req.(*DrainRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -1070,6 +3354,27 @@ func _Iris_GetValue_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Iris_GetHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IrisServer).GetHistory(m, &irisGetHistoryServer{stream})
+}
+
+type Iris_GetHistoryServer interface {
+	Send(*Version) error
+	grpc.ServerStream
+}
+
+type irisGetHistoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *irisGetHistoryServer) Send(m *Version) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Iris_RemoveValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RemoveValueRequest)
 	if err := dec(in); err != nil {
@@ -1178,6 +3483,186 @@ func _Iris_UnsubscribeKey_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Iris_SubscribePattern_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribePatternRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).SubscribePattern(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/SubscribePattern",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).SubscribePattern(ctx, req.(*SubscribePatternRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_UnsubscribePattern_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnsubscribePatternRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).UnsubscribePattern(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/UnsubscribePattern",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).UnsubscribePattern(ctx, req.(*UnsubscribePatternRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_SubscribeSources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribeSourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).SubscribeSources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/SubscribeSources",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).SubscribeSources(ctx, req.(*SubscribeSourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_UnsubscribeSources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnsubscribeSourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).UnsubscribeSources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/UnsubscribeSources",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).UnsubscribeSources(ctx, req.(*UnsubscribeSourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/Txn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).Txn(ctx, req.(*TxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_GrantLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).GrantLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/GrantLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).GrantLease(ctx, req.(*GrantLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_KeepAliveLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeepAliveLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).KeepAliveLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/KeepAliveLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).KeepAliveLease(ctx, req.(*KeepAliveLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_KeepAliveSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeepAliveSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).KeepAliveSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/KeepAliveSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).KeepAliveSession(ctx, req.(*KeepAliveSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_RevokeLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).RevokeLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/RevokeLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).RevokeLease(ctx, req.(*RevokeLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Iris_NodeStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IrisServer).NodeStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/iris.pb.Iris/NodeStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IrisServer).NodeStatus(ctx, req.(*NodeStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Iris_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "iris.pb.Iris",
 	HandlerType: (*IrisServer)(nil),
@@ -1186,6 +3671,30 @@ var _Iris_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Join",
 			Handler:    _Iris_Join_Handler,
 		},
+		{
+			MethodName: "RemovePeer",
+			Handler:    _Iris_RemovePeer_Handler,
+		},
+		{
+			MethodName: "Leave",
+			Handler:    _Iris_Leave_Handler,
+		},
+		{
+			MethodName: "ListPeers",
+			Handler:    _Iris_ListPeers_Handler,
+		},
+		{
+			MethodName: "ClusterStatus",
+			Handler:    _Iris_ClusterStatus_Handler,
+		},
+		{
+			MethodName: "TransferLeadership",
+			Handler:    _Iris_TransferLeadership_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _Iris_Drain_Handler,
+		},
 		{
 			MethodName: "Connect",
 			Handler:    _Iris_Connect_Handler,
@@ -1222,6 +3731,46 @@ var _Iris_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UnsubscribeKey",
 			Handler:    _Iris_UnsubscribeKey_Handler,
 		},
+		{
+			MethodName: "SubscribePattern",
+			Handler:    _Iris_SubscribePattern_Handler,
+		},
+		{
+			MethodName: "UnsubscribePattern",
+			Handler:    _Iris_UnsubscribePattern_Handler,
+		},
+		{
+			MethodName: "SubscribeSources",
+			Handler:    _Iris_SubscribeSources_Handler,
+		},
+		{
+			MethodName: "UnsubscribeSources",
+			Handler:    _Iris_UnsubscribeSources_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _Iris_Txn_Handler,
+		},
+		{
+			MethodName: "GrantLease",
+			Handler:    _Iris_GrantLease_Handler,
+		},
+		{
+			MethodName: "KeepAliveLease",
+			Handler:    _Iris_KeepAliveLease_Handler,
+		},
+		{
+			MethodName: "KeepAliveSession",
+			Handler:    _Iris_KeepAliveSession_Handler,
+		},
+		{
+			MethodName: "RevokeLease",
+			Handler:    _Iris_RevokeLease_Handler,
+		},
+		{
+			MethodName: "NodeStatus",
+			Handler:    _Iris_NodeStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1239,6 +3788,11 @@ var _Iris_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Iris_GetKeys_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetHistory",
+			Handler:       _Iris_GetHistory_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "iris.proto",
 }
@@ -1246,44 +3800,160 @@ var _Iris_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("iris.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 623 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x56, 0x4b, 0x6f, 0xda, 0x4c,
-	0x14, 0x8d, 0x13, 0x3e, 0x48, 0x4e, 0xf8, 0x80, 0x5c, 0x08, 0xa5, 0xee, 0x2b, 0x9a, 0x2e, 0x4a,
-	0x95, 0x06, 0x45, 0xad, 0xb2, 0xad, 0x12, 0x1a, 0xd5, 0x79, 0xb4, 0x1b, 0x10, 0x55, 0xd3, 0xae,
-	0x78, 0xcc, 0xc2, 0x4a, 0x6b, 0x53, 0x8f, 0x89, 0xc4, 0x3f, 0xe9, 0xcf, 0xad, 0xc0, 0xe3, 0xf1,
-	0xf8, 0x41, 0x69, 0x08, 0x3b, 0xcf, 0xdc, 0x33, 0x67, 0xce, 0xbd, 0xcc, 0x3d, 0x17, 0xc0, 0xf6,
-	0x6c, 0xd1, 0x1a, 0x7b, 0xae, 0xef, 0x52, 0x21, 0xf8, 0x1e, 0xb0, 0x57, 0xd8, 0xbd, 0x72, 0x6d,
-	0xa7, 0xc3, 0x7f, 0x4d, 0xb8, 0xf0, 0xa9, 0x81, 0x42, 0x7f, 0x34, 0xf2, 0xb8, 0x10, 0x0d, 0xe3,
-	0xc0, 0x68, 0xee, 0x74, 0xc2, 0x25, 0x2b, 0xa1, 0x18, 0x00, 0xc5, 0xd8, 0x75, 0x04, 0x67, 0x15,
-	0x94, 0x3e, 0xb8, 0x8e, 0xc3, 0x87, 0xbe, 0x3c, 0xcb, 0x0e, 0x51, 0x56, 0x3b, 0x01, 0x68, 0x46,
-	0x27, 0xb8, 0x10, 0xb6, 0xeb, 0x84, 0x74, 0x72, 0xc9, 0x5e, 0xe3, 0xff, 0x4f, 0xb6, 0xf0, 0xb9,
-	0x7e, 0xf3, 0x02, 0xe8, 0x05, 0xf2, 0xbd, 0xf1, 0xa8, 0xef, 0x73, 0xaa, 0x23, 0x2f, 0xdc, 0x89,
-	0x37, 0xe4, 0x12, 0x22, 0x57, 0x54, 0xc1, 0xd6, 0x2d, 0x9f, 0x36, 0x36, 0xe7, 0x9b, 0xb3, 0x4f,
-	0xaa, 0xe1, 0xbf, 0xbb, 0xfe, 0x8f, 0x09, 0x6f, 0x6c, 0x1d, 0x18, 0xcd, 0x62, 0x27, 0x58, 0xb0,
-	0x23, 0xec, 0x59, 0xdc, 0xef, 0xce, 0x0f, 0x89, 0xe5, 0x17, 0xbf, 0x01, 0xe9, 0x70, 0x99, 0xd3,
-	0x02, 0x11, 0xac, 0x87, 0xb2, 0xc5, 0xfd, 0x2f, 0xb3, 0x8b, 0x96, 0x52, 0x6b, 0x24, 0x9b, 0x59,
-	0x99, 0x6c, 0xa9, 0x4c, 0x58, 0x13, 0x95, 0x88, 0x56, 0x4a, 0x50, 0xd9, 0x19, 0x7a, 0x76, 0xb7,
-	0x28, 0x77, 0xd7, 0x2f, 0x20, 0xba, 0x2c, 0xa7, 0x5f, 0xd6, 0x44, 0xa5, 0xfb, 0x6f, 0xb2, 0xbe,
-	0x82, 0x3a, 0xfc, 0xa7, 0x7b, 0xc7, 0xd7, 0x5e, 0x9a, 0x1b, 0x54, 0x63, 0xcc, 0xcb, 0x1e, 0xdd,
-	0x3d, 0xa8, 0xad, 0x90, 0x3a, 0xf8, 0xf5, 0x57, 0x56, 0xcd, 0x2e, 0x50, 0x8b, 0x13, 0xad, 0x2a,
-	0x92, 0xb5, 0x51, 0xb2, 0xb8, 0x7f, 0xcd, 0xa7, 0x62, 0x75, 0x35, 0x2f, 0xe7, 0x6f, 0x34, 0xe0,
-	0x90, 0x42, 0x64, 0xee, 0x46, 0x94, 0xfb, 0x39, 0x2a, 0xdd, 0xc9, 0x40, 0x0c, 0x3d, 0x7b, 0xf0,
-	0x80, 0xc4, 0x0f, 0xb1, 0xa7, 0xb1, 0x2c, 0xe9, 0x9d, 0x1b, 0x54, 0x15, 0xf8, 0x9a, 0x4f, 0xd7,
-	0xf9, 0x48, 0x4e, 0x51, 0x8b, 0x53, 0xff, 0x5d, 0x4a, 0xda, 0x4b, 0xd8, 0x47, 0x50, 0xcf, 0x11,
-	0x0f, 0xaf, 0xc8, 0x11, 0xaa, 0x31, 0x9e, 0x25, 0x35, 0xf9, 0x8e, 0x7d, 0x0d, 0xbe, 0xe6, 0xaa,
-	0xb4, 0x51, 0x4f, 0x92, 0xdf, 0xb7, 0x2e, 0x6f, 0x7f, 0x17, 0x90, 0xbb, 0xf4, 0x6c, 0x41, 0x27,
-	0xc8, 0xcd, 0x46, 0x03, 0xd5, 0x5a, 0x72, 0xaa, 0xb4, 0xb4, 0x91, 0x62, 0xee, 0x27, 0x76, 0xe5,
-	0xfc, 0xd8, 0xa0, 0xf7, 0x28, 0xc8, 0x79, 0x41, 0x8f, 0x14, 0x26, 0x3e, 0x53, 0xcc, 0x46, 0x3a,
-	0xa0, 0xce, 0x9f, 0x20, 0x1f, 0x8c, 0x10, 0xaa, 0x2b, 0x54, 0x6c, 0xa6, 0x98, 0x65, 0xb5, 0x1f,
-	0x0c, 0x10, 0xb6, 0x71, 0x6c, 0xd0, 0x25, 0x10, 0xb9, 0x3a, 0x99, 0x0a, 0x92, 0x9a, 0x0c, 0xe6,
-	0x93, 0xcc, 0x58, 0x78, 0xff, 0xb1, 0x41, 0xa7, 0x28, 0xc8, 0x76, 0xd2, 0x32, 0x88, 0x37, 0xa9,
-	0x96, 0x41, 0xa2, 0xf3, 0xe6, 0x0c, 0x67, 0xd8, 0x0e, 0x6d, 0x94, 0x22, 0x64, 0xc2, 0xc6, 0xcd,
-	0xc7, 0x19, 0x11, 0x55, 0x86, 0x33, 0x6c, 0x5b, 0x69, 0x0a, 0x6b, 0x21, 0x85, 0x95, 0xa6, 0xb8,
-	0xc2, 0xae, 0x66, 0xa4, 0x14, 0xe5, 0x9d, 0x36, 0x6e, 0xf3, 0x69, 0x76, 0x50, 0x71, 0x7d, 0x46,
-	0x51, 0x37, 0x3c, 0x4a, 0xe2, 0x63, 0x86, 0x6a, 0x3e, 0x5b, 0x10, 0x55, 0x74, 0xe7, 0xd8, 0x51,
-	0xed, 0x4b, 0x5a, 0x1d, 0x12, 0xed, 0x68, 0x9a, 0x59, 0x21, 0x5d, 0x94, 0x6e, 0x02, 0x9a, 0xa8,
-	0x0c, 0xdb, 0xd1, 0x44, 0x65, 0x39, 0x47, 0x50, 0x2f, 0xad, 0x7b, 0xb4, 0x7a, 0xa5, 0x7d, 0x42,
-	0xab, 0x57, 0x46, 0xf3, 0xb3, 0x0d, 0xea, 0xa2, 0x14, 0xef, 0x44, 0x7a, 0x9e, 0x75, 0x42, 0x93,
-	0xf7, 0x62, 0x61, 0x3c, 0x24, 0x6d, 0xe7, 0xbe, 0x6d, 0x8e, 0x07, 0x83, 0xfc, 0xfc, 0xbf, 0xde,
-	0xbb, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xf0, 0x5d, 0x0d, 0xfc, 0xf9, 0x09, 0x00, 0x00,
+	// 2480 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0xdf, 0x6f, 0xdb, 0xc8,
+	0xf1, 0x37, 0xf5, 0x5b, 0x23, 0xc9, 0xa6, 0xd7, 0x8e, 0xa3, 0x30, 0xce, 0xc5, 0xd9, 0x20, 0x5f,
+	0x24, 0x0e, 0x2e, 0x08, 0x72, 0x97, 0x2f, 0x8a, 0x43, 0x71, 0x17, 0x45, 0x66, 0x14, 0x25, 0x8a,
+	0x9d, 0x92, 0x72, 0x9a, 0xe4, 0x50, 0xa8, 0x8c, 0xb4, 0x49, 0x88, 0x93, 0x28, 0x95, 0xa4, 0x0d,
+	0x3b, 0x6f, 0x05, 0x0a, 0xf4, 0x80, 0x02, 0xed, 0x3f, 0xd0, 0x43, 0x9f, 0xda, 0x87, 0xfe, 0x25,
+	0x7d, 0xee, 0x5f, 0xd0, 0x7f, 0xa3, 0x4f, 0x2d, 0x76, 0xb9, 0x5c, 0x2e, 0x29, 0x4a, 0xf2, 0xaf,
+	0xf4, 0xc9, 0xda, 0x99, 0xe1, 0xec, 0xcc, 0x67, 0x67, 0x76, 0x77, 0x66, 0x0d, 0x60, 0xbb, 0xb6,
+	0x77, 0x6f, 0xe2, 0x8e, 0xfd, 0x31, 0x2a, 0x06, 0xbf, 0xdf, 0xe1, 0x67, 0x50, 0x79, 0x36, 0xb6,
+	0x1d, 0x83, 0xfc, 0xe6, 0x80, 0x78, 0x3e, 0xaa, 0x43, 0xd1, 0x1a, 0x0c, 0x5c, 0xe2, 0x79, 0x75,
+	0x65, 0x4b, 0xb9, 0x5d, 0x36, 0xc2, 0x21, 0xba, 0x01, 0xd5, 0x0f, 0xee, 0xa4, 0xdf, 0x0b, 0xd9,
+	0x19, 0xc6, 0xae, 0x50, 0x5a, 0x23, 0x20, 0xe1, 0x65, 0xa8, 0x06, 0xba, 0xbc, 0xc9, 0xd8, 0xf1,
+	0x08, 0xfe, 0x12, 0x56, 0x0d, 0x32, 0x1a, 0x1f, 0x92, 0x97, 0x84, 0xb8, 0x0b, 0x67, 0xc0, 0xeb,
+	0x80, 0x64, 0x71, 0xae, 0x64, 0x19, 0xaa, 0x1d, 0x62, 0x1d, 0x12, 0xfe, 0x3d, 0x5e, 0x81, 0x1a,
+	0x1f, 0x73, 0x01, 0x04, 0x6a, 0xc7, 0xf6, 0x7c, 0xfa, 0x91, 0x17, 0x0a, 0xdd, 0x81, 0x55, 0x89,
+	0x16, 0x08, 0xa2, 0x75, 0xc8, 0x4f, 0x28, 0xa1, 0xae, 0x6c, 0x65, 0x6f, 0x97, 0x8d, 0x60, 0x80,
+	0x7f, 0x52, 0x20, 0x47, 0xe5, 0xe6, 0xb8, 0xbe, 0x0e, 0x79, 0xcf, 0xb7, 0x7c, 0xc2, 0x7d, 0x0e,
+	0x06, 0x53, 0x80, 0x64, 0xa7, 0x00, 0xa1, 0x22, 0x43, 0xcb, 0xf3, 0x7b, 0xfd, 0xb1, 0xe3, 0x5b,
+	0x7d, 0xbf, 0x9e, 0xdb, 0x52, 0x6e, 0x67, 0x8d, 0x0a, 0xa5, 0x35, 0x03, 0x12, 0xba, 0x0e, 0x95,
+	0x91, 0xe5, 0xf7, 0x3f, 0xf6, 0x6c, 0x67, 0x40, 0x8e, 0xea, 0xf9, 0x2d, 0xe5, 0x76, 0xce, 0x00,
+	0x46, 0x6a, 0x53, 0x0a, 0xde, 0x80, 0xf5, 0xe6, 0xf0, 0xc0, 0xf3, 0x89, 0x6b, 0xfa, 0x96, 0x7f,
+	0x20, 0x5c, 0xfc, 0x97, 0x02, 0x97, 0x12, 0x0c, 0xee, 0xe7, 0x69, 0x1d, 0xd9, 0x80, 0xc2, 0x90,
+	0x58, 0x03, 0xe2, 0x72, 0x17, 0xf8, 0x08, 0x21, 0xc8, 0xf9, 0xc4, 0x1d, 0x31, 0xab, 0x73, 0x06,
+	0xfb, 0x4d, 0x3d, 0xea, 0x8f, 0x47, 0x23, 0xdb, 0x8f, 0xd9, 0x5b, 0x09, 0x68, 0xcc, 0x60, 0x74,
+	0x13, 0x6a, 0xd6, 0x64, 0x32, 0xb4, 0xc9, 0x80, 0xcb, 0x14, 0x98, 0x4c, 0x95, 0x13, 0x43, 0x21,
+	0xbe, 0x16, 0xc5, 0xad, 0xec, 0xed, 0xca, 0x83, 0xda, 0x3d, 0x1e, 0x8f, 0xf7, 0xd8, 0xda, 0xf3,
+	0xa5, 0x79, 0x08, 0x57, 0xba, 0xae, 0xe5, 0x78, 0xef, 0x89, 0xdb, 0x61, 0x26, 0x79, 0x1f, 0xed,
+	0xc9, 0xe2, 0x38, 0xfa, 0x1a, 0xb4, 0xb4, 0xcf, 0x38, 0x3a, 0x91, 0xb7, 0x8a, 0xec, 0x2d, 0xde,
+	0x86, 0xea, 0x8e, 0x6b, 0x45, 0x99, 0xa0, 0x41, 0x69, 0x40, 0xc7, 0xb6, 0xf3, 0x81, 0x49, 0x96,
+	0x0c, 0x31, 0xc6, 0x77, 0xa1, 0xc6, 0x65, 0xb9, 0x52, 0x0d, 0x4a, 0x1e, 0xf1, 0x3c, 0x7b, 0xec,
+	0x04, 0xd6, 0xd4, 0x0c, 0x31, 0xc6, 0x2a, 0x2c, 0x37, 0xc7, 0x8e, 0x43, 0xfa, 0x7e, 0xb8, 0x74,
+	0x77, 0x61, 0x45, 0x50, 0xa2, 0x35, 0xe3, 0x1f, 0x84, 0xde, 0xf0, 0x21, 0xbe, 0x03, 0x35, 0x1a,
+	0xca, 0x44, 0x4e, 0xd1, 0x19, 0xa2, 0x7f, 0xcf, 0x40, 0x61, 0x7f, 0x32, 0xe0, 0x6b, 0xea, 0x8d,
+	0x0f, 0xdc, 0x3e, 0x09, 0xbd, 0x0c, 0x46, 0x48, 0x85, 0xec, 0x0f, 0xe4, 0x98, 0xaf, 0x3f, 0xfd,
+	0x49, 0x63, 0xe2, 0xd0, 0x1a, 0x1e, 0x10, 0xb6, 0xf8, 0x55, 0x23, 0x18, 0xa0, 0x2d, 0xa8, 0xf8,
+	0x14, 0x43, 0xab, 0xef, 0xd3, 0x89, 0x82, 0x10, 0x90, 0x49, 0xe8, 0xff, 0xa1, 0x3c, 0x9e, 0x10,
+	0xd7, 0x62, 0x7c, 0x1a, 0x06, 0xcb, 0x0f, 0xea, 0x62, 0x15, 0x03, 0x2b, 0xf6, 0x42, 0xbe, 0x11,
+	0x89, 0xd2, 0xf9, 0xe4, 0xb0, 0x08, 0x06, 0x68, 0x13, 0xca, 0xbe, 0x3d, 0x22, 0x9e, 0x6f, 0x8d,
+	0x26, 0xf5, 0x22, 0x4b, 0x93, 0x88, 0x80, 0x6e, 0xc1, 0xf2, 0xc4, 0x25, 0x87, 0xf6, 0xf8, 0xc0,
+	0xeb, 0x05, 0xc6, 0x96, 0x98, 0xb1, 0xb5, 0x90, 0xfa, 0x8a, 0x19, 0x7d, 0x17, 0x56, 0x85, 0x18,
+	0xfd, 0xcb, 0x30, 0x2a, 0xb3, 0x69, 0xd4, 0x90, 0x61, 0x70, 0x3a, 0xfe, 0x31, 0x03, 0xab, 0x2d,
+	0xe2, 0x9b, 0x0c, 0x17, 0x6f, 0x21, 0xb8, 0x14, 0xd1, 0x89, 0x4b, 0xde, 0xdb, 0x47, 0x1c, 0x3c,
+	0x3e, 0xe2, 0x39, 0xe5, 0xfa, 0x3c, 0x79, 0x82, 0x01, 0xc5, 0x99, 0x38, 0x03, 0x86, 0x5b, 0xd9,
+	0xa0, 0x3f, 0xa9, 0xdc, 0xd0, 0x1e, 0xd9, 0x3e, 0xc3, 0xaa, 0x66, 0x04, 0x03, 0x84, 0x69, 0x3e,
+	0x39, 0xbe, 0xed, 0x1c, 0x04, 0x40, 0x16, 0xd8, 0x07, 0x31, 0x1a, 0xfa, 0x06, 0x2a, 0xfd, 0xb1,
+	0xe3, 0xb1, 0x20, 0xe8, 0x1f, 0xd7, 0x8b, 0x09, 0xac, 0x0d, 0x62, 0x0d, 0x9a, 0x11, 0xdf, 0x90,
+	0x85, 0x69, 0x32, 0x8e, 0xac, 0xa3, 0x9e, 0xe7, 0x5b, 0x43, 0xe2, 0xd0, 0x5c, 0x29, 0x31, 0x6c,
+	0xab, 0x23, 0xeb, 0xc8, 0x0c, 0x69, 0xf8, 0x3d, 0x20, 0x19, 0x89, 0x28, 0x51, 0x52, 0x43, 0x28,
+	0x69, 0x72, 0x26, 0xc5, 0x64, 0xb1, 0xc8, 0x59, 0x69, 0x91, 0xf1, 0x3f, 0x15, 0x58, 0x69, 0x11,
+	0x9f, 0x2d, 0xd6, 0x89, 0x00, 0xe7, 0xf3, 0x67, 0xd2, 0x42, 0x38, 0x1b, 0x85, 0xb0, 0x06, 0x25,
+	0xb1, 0xdc, 0x41, 0xa4, 0x8a, 0x71, 0x12, 0xbc, 0xfc, 0xb9, 0xc0, 0x2b, 0xa4, 0x80, 0xf7, 0x16,
+	0xd4, 0xc8, 0xa7, 0xe8, 0xa4, 0x09, 0xc2, 0x54, 0x91, 0x73, 0x4a, 0x36, 0x33, 0x93, 0x30, 0x33,
+	0x1d, 0xb0, 0x5f, 0xb2, 0x10, 0x7d, 0x6a, 0x7b, 0xfe, 0xd8, 0x3d, 0xbe, 0x40, 0xc4, 0xb0, 0x07,
+	0xc5, 0x57, 0xc4, 0x0d, 0x67, 0x3e, 0xa5, 0xad, 0xb1, 0x5c, 0xcd, 0x26, 0x73, 0xb5, 0x0e, 0xc5,
+	0x01, 0x19, 0x12, 0x9f, 0x04, 0xd1, 0x5f, 0x32, 0xc2, 0x21, 0xfe, 0x8f, 0x02, 0x2b, 0xe6, 0x67,
+	0x58, 0x7e, 0xe1, 0x41, 0x4e, 0xf6, 0xe0, 0x3e, 0x94, 0xfb, 0x63, 0x67, 0x60, 0x4b, 0xfb, 0x13,
+	0x12, 0xcb, 0xde, 0x0c, 0x39, 0x46, 0x24, 0x14, 0xf3, 0xb9, 0x90, 0xf0, 0x59, 0x85, 0xac, 0xef,
+	0x0f, 0xf9, 0xce, 0x44, 0x7f, 0xb2, 0x7c, 0x26, 0x96, 0x17, 0x6c, 0x45, 0x39, 0x23, 0x18, 0x50,
+	0x6c, 0xc8, 0xe4, 0x23, 0x19, 0x11, 0xd7, 0x1a, 0xb2, 0xad, 0xa7, 0x64, 0x44, 0x04, 0xbc, 0x03,
+	0xaa, 0x79, 0xee, 0x58, 0xc1, 0x7f, 0x53, 0xc2, 0x8b, 0xd2, 0x85, 0x43, 0x19, 0x03, 0x2d, 0x77,
+	0x5a, 0xd0, 0xf2, 0x09, 0x43, 0xdf, 0xc0, 0x5a, 0xcc, 0xce, 0x45, 0x67, 0xdd, 0x29, 0x02, 0xb8,
+	0x15, 0xaa, 0x0e, 0x76, 0xad, 0x33, 0x63, 0x80, 0x9f, 0xc2, 0x7a, 0x5c, 0xd1, 0x59, 0x8d, 0xc4,
+	0x3f, 0x65, 0x60, 0xb9, 0x45, 0xfc, 0xe7, 0xe4, 0xd8, 0x3b, 0xfb, 0x92, 0x44, 0xa7, 0x4c, 0x36,
+	0xfd, 0x94, 0xc9, 0xa5, 0x9c, 0x32, 0xf9, 0x94, 0x53, 0xa6, 0x30, 0xef, 0x94, 0x29, 0x2e, 0x3e,
+	0x65, 0x4a, 0xe7, 0xda, 0x28, 0xcb, 0x29, 0x1b, 0xe5, 0xaf, 0x60, 0x45, 0xc0, 0xc3, 0x41, 0xe6,
+	0xeb, 0xaa, 0x44, 0x01, 0x78, 0xf6, 0xc3, 0xe5, 0xf7, 0x0a, 0xa8, 0xe6, 0xc1, 0x3b, 0xaf, 0xef,
+	0xda, 0xef, 0xce, 0x91, 0x13, 0xb7, 0x60, 0x99, 0x61, 0x1b, 0x5d, 0x20, 0x82, 0x59, 0x6a, 0x8c,
+	0x1a, 0xde, 0x1e, 0xd8, 0x85, 0xcf, 0xb1, 0x26, 0xde, 0xc7, 0xb1, 0xcf, 0xb7, 0x39, 0x31, 0xc6,
+	0x43, 0x58, 0x95, 0x0c, 0x59, 0x70, 0x9a, 0xce, 0xdb, 0x68, 0x6f, 0x42, 0x2d, 0x54, 0xda, 0xf3,
+	0xec, 0x4f, 0xc1, 0x15, 0xad, 0x66, 0x54, 0x43, 0xa2, 0x69, 0x7f, 0x22, 0xf8, 0xcf, 0x0a, 0xac,
+	0x89, 0xe9, 0x9e, 0x93, 0x8b, 0x3c, 0x26, 0x52, 0xc0, 0xc8, 0x2d, 0x02, 0x23, 0x9f, 0x00, 0xe3,
+	0xb7, 0x0a, 0xac, 0xc7, 0xcd, 0x5b, 0x00, 0xc8, 0xf4, 0x0d, 0x55, 0x86, 0x28, 0xbb, 0x08, 0xa2,
+	0x5c, 0x0a, 0x44, 0xbf, 0x53, 0xe0, 0xb2, 0xb0, 0xe1, 0xa5, 0xe5, 0xfb, 0xc4, 0x75, 0xfe, 0xf7,
+	0x30, 0xe1, 0x5f, 0x43, 0x7d, 0xda, 0x8a, 0x8b, 0x44, 0x03, 0xf7, 0xe0, 0xca, 0xbe, 0xe3, 0x7d,
+	0x3e, 0x4f, 0xf1, 0x13, 0xd0, 0xd2, 0x26, 0x38, 0xad, 0x13, 0xf8, 0x50, 0x5a, 0x90, 0x13, 0xdf,
+	0xc0, 0xa7, 0x61, 0xce, 0x2c, 0x8a, 0xc6, 0x6c, 0x22, 0x1a, 0xbf, 0x87, 0xfa, 0xf4, 0xbc, 0x51,
+	0x0d, 0x27, 0x14, 0x2b, 0x8b, 0xc2, 0x2c, 0x93, 0x12, 0x66, 0x0f, 0x63, 0xe8, 0x9f, 0xd4, 0x2d,
+	0xbc, 0x09, 0x5a, 0xda, 0x67, 0xbc, 0xbb, 0xf1, 0x04, 0x90, 0xc4, 0x3d, 0xfb, 0x39, 0xf7, 0x25,
+	0xac, 0xc5, 0xf4, 0xcc, 0x5f, 0x32, 0xfc, 0x3d, 0x5c, 0x92, 0xc4, 0x2f, 0x76, 0x5b, 0xc1, 0x8f,
+	0x61, 0x23, 0xa9, 0xfc, 0xd4, 0x11, 0xf4, 0x47, 0x05, 0x4a, 0xdd, 0x23, 0xa7, 0x75, 0x60, 0xb9,
+	0x03, 0xf4, 0x7f, 0x90, 0xf3, 0x8f, 0x27, 0xc1, 0x47, 0xf2, 0x8d, 0x85, 0x71, 0xbb, 0xc7, 0x13,
+	0x62, 0x30, 0xfe, 0x05, 0x95, 0x14, 0xe2, 0xc6, 0x96, 0x97, 0x6e, 0x6c, 0xf8, 0x13, 0x54, 0xbb,
+	0x47, 0x8e, 0x28, 0x79, 0xd1, 0x76, 0xcc, 0xa6, 0x0d, 0x61, 0x93, 0x90, 0x38, 0x93, 0x5d, 0xa9,
+	0x77, 0x5d, 0xfc, 0xa3, 0x02, 0xd0, 0x3d, 0x3a, 0x41, 0xa6, 0xdf, 0x81, 0xc2, 0x07, 0x8a, 0x09,
+	0x6d, 0xdf, 0xd1, 0xbe, 0xcb, 0xaa, 0x30, 0x2b, 0xc4, 0xd2, 0xe0, 0x02, 0xe8, 0x21, 0x80, 0x28,
+	0xda, 0x69, 0x73, 0x8b, 0x8a, 0x5f, 0x92, 0xc5, 0xa3, 0xea, 0x5e, 0x12, 0xc4, 0x2d, 0xa8, 0x30,
+	0x4b, 0xf8, 0x82, 0x6e, 0x42, 0xd9, 0x3b, 0xe8, 0xf7, 0x09, 0x19, 0x90, 0x01, 0x6f, 0xa3, 0x44,
+	0x84, 0xb9, 0xb7, 0xdc, 0xef, 0x60, 0xb5, 0xe5, 0x5a, 0x8e, 0xdf, 0x21, 0x96, 0x77, 0x82, 0xb8,
+	0xe7, 0x17, 0xf4, 0x8c, 0xb8, 0xa0, 0xe3, 0x9f, 0x03, 0x92, 0x15, 0x44, 0xd7, 0xed, 0xe0, 0xda,
+	0xae, 0xc8, 0xd7, 0xf6, 0xe9, 0xaf, 0x5b, 0x70, 0xe9, 0x39, 0x21, 0x93, 0xc6, 0xd0, 0x3e, 0x24,
+	0x27, 0x34, 0x41, 0xa8, 0xce, 0x48, 0xaa, 0xf1, 0x23, 0xd8, 0x48, 0x2a, 0x3a, 0xa5, 0x29, 0x5f,
+	0xc1, 0x65, 0xa1, 0xc1, 0x0c, 0xe6, 0x5a, 0xbc, 0xab, 0x3c, 0x81, 0xfa, 0xf4, 0x47, 0x0b, 0xef,
+	0xb6, 0xd3, 0x93, 0xef, 0xd0, 0x5a, 0xe3, 0x70, 0xfc, 0xc3, 0xf9, 0x40, 0xb8, 0x0b, 0x6b, 0x31,
+	0x2d, 0xf3, 0x10, 0xc0, 0x6b, 0xb0, 0xba, 0x3b, 0x1e, 0x90, 0x78, 0xbb, 0xf3, 0x35, 0x20, 0x99,
+	0x98, 0xda, 0xcc, 0x2b, 0x89, 0xd6, 0xa5, 0xb8, 0x22, 0x66, 0xe4, 0x26, 0xd3, 0x3a, 0xe4, 0x5d,
+	0x62, 0x0d, 0x8e, 0xf9, 0xb1, 0x10, 0x0c, 0xf0, 0xbf, 0x33, 0x50, 0x6c, 0x8e, 0x47, 0x23, 0xcb,
+	0x19, 0x5c, 0x44, 0xdb, 0x4c, 0x94, 0x46, 0xd6, 0x90, 0xdf, 0x0c, 0x65, 0xd2, 0xbc, 0x7a, 0x49,
+	0xca, 0xce, 0xc2, 0xe9, 0xb2, 0xb3, 0x78, 0xc2, 0xec, 0x9c, 0x51, 0xb4, 0xf2, 0x55, 0x2f, 0x47,
+	0xc5, 0x2d, 0x82, 0xdc, 0x84, 0x10, 0xb7, 0x0e, 0xcc, 0x61, 0xf6, 0x9b, 0x35, 0x44, 0x89, 0x35,
+	0x18, 0xda, 0x0e, 0xa9, 0x57, 0x98, 0xa8, 0x18, 0xcb, 0xcd, 0xd8, 0x6a, 0xbc, 0xe5, 0x1c, 0x6b,
+	0x16, 0xd4, 0x12, 0xcd, 0x02, 0xfc, 0x17, 0x05, 0x6a, 0x26, 0x3f, 0x43, 0x75, 0xc7, 0x77, 0x8f,
+	0xcf, 0xbd, 0x02, 0x0b, 0x36, 0xee, 0xc0, 0xfb, 0x7c, 0xa2, 0x64, 0x8f, 0x2c, 0x2c, 0x24, 0x2d,
+	0x7c, 0x11, 0x19, 0xc8, 0x62, 0x17, 0x2d, 0x43, 0xc6, 0x1e, 0xf0, 0x80, 0xcd, 0xd8, 0x83, 0xe9,
+	0x94, 0xa1, 0x26, 0xd8, 0xa3, 0xc9, 0xd0, 0xee, 0xdb, 0xe2, 0x02, 0x12, 0x8e, 0xf1, 0x9f, 0x68,
+	0x0f, 0x84, 0xeb, 0xe3, 0x7d, 0x9d, 0x53, 0xb8, 0xbc, 0x49, 0xcb, 0xf3, 0xd1, 0xc4, 0xea, 0xd3,
+	0xee, 0x4a, 0x70, 0xf9, 0x8b, 0x08, 0xe8, 0x6b, 0x28, 0x1d, 0x06, 0x4d, 0x1d, 0xaf, 0x9e, 0x63,
+	0x11, 0x11, 0x95, 0x6f, 0xe1, 0x9c, 0xbc, 0xeb, 0x63, 0x08, 0x49, 0x7c, 0x2b, 0x72, 0xb0, 0x1d,
+	0x66, 0x49, 0x90, 0x3b, 0x8a, 0x5c, 0x5e, 0x7d, 0x17, 0xd9, 0x1d, 0xbe, 0x6e, 0x84, 0x41, 0xa2,
+	0x48, 0x41, 0x22, 0x05, 0x42, 0x26, 0xde, 0x95, 0xff, 0xab, 0xe4, 0x39, 0xb7, 0xe2, 0xb3, 0x2e,
+	0x76, 0x6c, 0x59, 0xf3, 0x73, 0xba, 0x54, 0x85, 0x58, 0x97, 0x6a, 0xfb, 0x0f, 0x0a, 0xac, 0x24,
+	0xda, 0xd7, 0xa8, 0x08, 0x59, 0x53, 0xef, 0xaa, 0x4b, 0x68, 0x19, 0x60, 0x47, 0xef, 0xe8, 0x5d,
+	0xbd, 0xf7, 0x5c, 0x7f, 0xa3, 0x2a, 0x68, 0x15, 0x6a, 0x7c, 0x6c, 0xee, 0xed, 0x1b, 0x4d, 0x5d,
+	0xcd, 0x20, 0x80, 0x82, 0xfe, 0xfa, 0x65, 0xdb, 0xd0, 0xd5, 0x2c, 0xaa, 0x42, 0xc9, 0xdc, 0x6d,
+	0xbc, 0x34, 0x9f, 0xee, 0x75, 0xd5, 0x1c, 0x42, 0xb0, 0x1c, 0x48, 0xf5, 0x9a, 0x86, 0xde, 0xe8,
+	0xea, 0x3b, 0x6a, 0x5e, 0xa2, 0x05, 0x7a, 0x76, 0xd4, 0x02, 0xd5, 0x60, 0xe8, 0xe6, 0x9b, 0xdd,
+	0xa6, 0x5a, 0xdc, 0xfe, 0x06, 0x56, 0x12, 0x95, 0x37, 0x65, 0x77, 0xf4, 0xc6, 0x8e, 0x6e, 0xa8,
+	0x4b, 0x48, 0x85, 0x6a, 0xa7, 0xbd, 0xab, 0x37, 0x8c, 0xf6, 0xdb, 0xc6, 0xe3, 0x8e, 0xae, 0x2a,
+	0xa8, 0x0c, 0x79, 0xb3, 0xdb, 0xe8, 0xe8, 0x6a, 0x66, 0xfb, 0x11, 0x94, 0x45, 0xcb, 0x86, 0x5a,
+	0xba, 0xbf, 0xdb, 0xdc, 0xdb, 0xdd, 0x69, 0x77, 0xdb, 0x7b, 0xbb, 0x8d, 0x8e, 0xba, 0x84, 0xd6,
+	0x41, 0x35, 0xf4, 0x57, 0x6d, 0xb3, 0xbd, 0xb7, 0xdb, 0x7b, 0xd1, 0xe8, 0x36, 0x9f, 0xea, 0xa6,
+	0xaa, 0x50, 0xf5, 0x8d, 0xc7, 0xa6, 0xbe, 0xdb, 0x55, 0x33, 0xdb, 0x8f, 0xa1, 0x2c, 0xae, 0x50,
+	0xd4, 0xf7, 0xe7, 0xfa, 0x9b, 0x9e, 0xfe, 0xba, 0x6d, 0x76, 0x4d, 0x75, 0x09, 0xad, 0xc1, 0x8a,
+	0xf8, 0x5c, 0xff, 0xc5, 0x7e, 0xa3, 0x43, 0xbf, 0x56, 0xa1, 0xfa, 0xaa, 0xd1, 0xd9, 0xd7, 0x43,
+	0x4a, 0x66, 0xbb, 0x09, 0xb5, 0xd8, 0x95, 0x07, 0xd5, 0xa0, 0x6c, 0xea, 0xdd, 0x1e, 0x13, 0x0b,
+	0x5c, 0x30, 0xf4, 0x17, 0x7b, 0xaf, 0x74, 0x4e, 0x61, 0xa0, 0x72, 0x4a, 0x08, 0xea, 0x83, 0x7f,
+	0xac, 0x42, 0xae, 0xed, 0xda, 0x74, 0xdf, 0xcb, 0xd1, 0x27, 0x46, 0xb4, 0x2e, 0x22, 0x5b, 0x7a,
+	0xbd, 0xd4, 0x2e, 0x25, 0xa8, 0xfc, 0x0e, 0xbd, 0x84, 0x5a, 0x00, 0xd1, 0xd3, 0x22, 0xd2, 0xee,
+	0x45, 0x5d, 0x8d, 0xc4, 0xf3, 0xa4, 0x76, 0x35, 0x95, 0x27, 0x14, 0xfd, 0x0c, 0xf2, 0xec, 0xf5,
+	0x11, 0x45, 0x53, 0xc9, 0xaf, 0x93, 0xda, 0x46, 0x92, 0x2c, 0xbe, 0xdc, 0x81, 0xb2, 0x78, 0x92,
+	0x44, 0x57, 0x22, 0xb1, 0xc4, 0xd3, 0xa5, 0xa6, 0xa5, 0xb1, 0x84, 0x96, 0x97, 0x50, 0x8b, 0x3d,
+	0xfa, 0xa1, 0x6b, 0x51, 0x7b, 0x2e, 0xe5, 0x95, 0x50, 0xfb, 0x62, 0x16, 0x5b, 0x68, 0xec, 0x01,
+	0x9a, 0x7e, 0x2d, 0x43, 0x38, 0x3a, 0x4b, 0x66, 0xbd, 0xc0, 0x69, 0x37, 0xe7, 0xca, 0xc8, 0x90,
+	0xb1, 0xc7, 0x32, 0x09, 0x32, 0xf9, 0xa1, 0x4d, 0xdb, 0x48, 0x92, 0xc5, 0x97, 0xdf, 0x42, 0x91,
+	0xbf, 0x93, 0xa1, 0xcb, 0x72, 0x17, 0x52, 0x7a, 0x4b, 0xd3, 0xea, 0xd3, 0x0c, 0xf1, 0xfd, 0x43,
+	0x28, 0x04, 0x4f, 0x67, 0x68, 0x23, 0x06, 0xaa, 0x78, 0x4b, 0xd3, 0x56, 0x12, 0x2f, 0x56, 0x78,
+	0xe9, 0xbe, 0x82, 0xda, 0x00, 0xd1, 0x73, 0x88, 0x14, 0x2c, 0x53, 0xaf, 0x45, 0xda, 0xd5, 0x54,
+	0x5e, 0x38, 0xff, 0x7d, 0x05, 0x3d, 0x82, 0x22, 0xef, 0x79, 0x49, 0x1e, 0xc4, 0x9b, 0x84, 0x5a,
+	0x7d, 0x9a, 0x21, 0x69, 0x68, 0x40, 0x29, 0x6c, 0x19, 0x23, 0x69, 0x3b, 0x8f, 0xb7, 0xd1, 0xb5,
+	0x2b, 0x29, 0x1c, 0x01, 0x43, 0x03, 0x4a, 0xad, 0x69, 0x15, 0xad, 0x99, 0x2a, 0x5a, 0xd3, 0x2a,
+	0xbe, 0x65, 0x90, 0x84, 0x07, 0x56, 0x0c, 0x92, 0xf8, 0xeb, 0x84, 0xa6, 0x0a, 0x1e, 0xdf, 0xe4,
+	0x99, 0x17, 0xcf, 0xa0, 0x22, 0x75, 0x82, 0x51, 0x32, 0xc9, 0x62, 0x86, 0x6c, 0xa6, 0x33, 0x85,
+	0x2d, 0x2f, 0xa0, 0x2a, 0x77, 0x6c, 0x51, 0x52, 0x3e, 0xd6, 0x11, 0xd6, 0xae, 0xcd, 0xe0, 0xca,
+	0x79, 0x29, 0x5a, 0x02, 0x52, 0x5e, 0x26, 0x5b, 0x89, 0x9a, 0x96, 0xc6, 0x92, 0x8d, 0x92, 0xbb,
+	0x5c, 0x92, 0x51, 0x29, 0xbd, 0x39, 0xed, 0xda, 0x0c, 0xae, 0x50, 0xf7, 0x0c, 0x2a, 0x52, 0x85,
+	0x2c, 0xe1, 0x35, 0xdd, 0x0b, 0xd0, 0x36, 0xd3, 0x99, 0x42, 0x97, 0x09, 0xcb, 0xf1, 0x6a, 0x1b,
+	0x7d, 0x91, 0xf6, 0x85, 0x64, 0xde, 0xf5, 0x99, 0x7c, 0xa1, 0xf4, 0x8d, 0xd4, 0x6c, 0xe5, 0x6d,
+	0x20, 0xb4, 0x35, 0xed, 0x55, 0xbc, 0x05, 0xa5, 0xdd, 0x98, 0x23, 0x21, 0x6f, 0x48, 0xd3, 0x3d,
+	0x26, 0x69, 0x43, 0x9a, 0xd9, 0xe1, 0xd2, 0x6e, 0xce, 0x95, 0x49, 0xb5, 0x3d, 0xcc, 0xf2, 0x14,
+	0xdb, 0x13, 0xb9, 0x7e, 0x63, 0x8e, 0xc4, 0x0c, 0xdb, 0x43, 0xe5, 0xa9, 0xb6, 0x27, 0xd4, 0xdf,
+	0x9c, 0x2b, 0x23, 0x26, 0x78, 0x00, 0xd9, 0xee, 0x91, 0x83, 0xd6, 0xe4, 0xab, 0x7e, 0xa8, 0x62,
+	0x3d, 0x4e, 0x94, 0x0f, 0xbf, 0xa8, 0x10, 0x96, 0x93, 0x37, 0x59, 0x5e, 0x6b, 0x57, 0x53, 0x79,
+	0x72, 0x24, 0xc5, 0x4b, 0x59, 0x29, 0x92, 0x52, 0x8b, 0x65, 0xed, 0xfa, 0x4c, 0xbe, 0xbc, 0x1a,
+	0xc9, 0x42, 0x55, 0x5a, 0x8d, 0x19, 0x85, 0xaf, 0x76, 0x63, 0x8e, 0x84, 0x9c, 0x45, 0x52, 0xd5,
+	0x19, 0xdb, 0x75, 0x92, 0x15, 0xad, 0xb6, 0x99, 0xce, 0x94, 0x41, 0x8c, 0xea, 0x4f, 0x09, 0xc4,
+	0xa9, 0x4a, 0x55, 0xbb, 0x9a, 0xca, 0x0b, 0x15, 0x3d, 0xce, 0xbd, 0xcd, 0x4c, 0xde, 0xbd, 0x2b,
+	0xb0, 0x7f, 0xc3, 0xfa, 0xea, 0xbf, 0x03, 0x00, 0x74, 0x11, 0x7c, 0x3d, 0x94, 0x25, 0x00, 0x00,
 }
@@ -0,0 +1,465 @@
+package store
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/forestgiant/iris/pb"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// boltFile is the name of the database file held in the raft directory
+	boltFile = "fsm.db"
+
+	// boltRestoreBatchSize is the number of writes committed at a time while restoring a snapshot,
+	// so that restoring a large snapshot does not hold every change in memory
+	boltRestoreBatchSize = 10000
+)
+
+// Every source is a bucket nested within the sources bucket, holding the entries of its keys.
+// The histories of keys are held in the same way within the history bucket.
+var (
+	bucketSources   = []byte("sources")
+	bucketHistory   = []byte("history")
+	bucketLeases    = []byte("leases")
+	bucketAddresses = []byte("addresses")
+	bucketMeta      = []byte("meta")
+
+	keyLastApplied = []byte("lastApplied")
+)
+
+// boltEngine holds the contents in a bolt database, so that the dataset need not fit in memory
+// and a restarted node only applies the log entries written since it stopped
+type boltEngine struct {
+	path    string
+	db      *bolt.DB
+	applied uint64
+}
+
+func openBoltEngine(dir string) (*boltEngine, error) {
+	b := &boltEngine{path: filepath.Join(dir, boltFile)}
+
+	var err error
+	if b.db, b.applied, err = openBolt(b.path); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// openBolt opens the database at path, creating its buckets if required, and returns the last applied index
+func openBolt(path string) (*bolt.DB, uint64, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var applied uint64
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSources, bucketHistory, bucketLeases, bucketAddresses, bucketMeta} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		if v := tx.Bucket(bucketMeta).Get(keyLastApplied); len(v) == 8 {
+			applied = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	return db, applied, nil
+}
+
+func (b *boltEngine) view(fn func(t txn)) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		t := &boltTxn{tx: tx}
+		fn(t)
+		return t.err
+	})
+}
+
+func (b *boltEngine) update(index uint64, fn func(t txn)) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		t := &boltTxn{tx: tx}
+		fn(t)
+		if t.err != nil {
+			return t.err
+		}
+
+		if index > b.applied {
+			return setLastApplied(tx, index)
+		}
+		return nil
+	})
+
+	if err == nil && index > b.applied {
+		b.applied = index
+	}
+	return err
+}
+
+func setLastApplied(tx *bolt.Tx, index uint64) error {
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], index)
+	return tx.Bucket(bucketMeta).Put(keyLastApplied, v[:])
+}
+
+func (b *boltEngine) lastApplied() uint64 {
+	return b.applied
+}
+
+// snapshot holds a read-only transaction open until released, which bolt keeps consistent while updates continue
+func (b *boltEngine) snapshot() (txn, func(), error) {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &boltTxn{tx: tx}, func() { tx.Rollback() }, nil
+}
+
+// restore writes the contents to a new database, which replaces the current one once fn succeeds
+func (b *boltEngine) restore(index uint64, fn func(t txn) error) error {
+	path := b.path + ".restore"
+	os.Remove(path)
+
+	db, _, err := openBolt(path)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		tx, err := db.Begin(true)
+		if err != nil {
+			return err
+		}
+
+		t := &boltTxn{tx: tx, db: db}
+		if err := fn(t); err != nil {
+			t.tx.Rollback()
+			return err
+		}
+
+		if t.err == nil {
+			t.err = setLastApplied(t.tx, index)
+		}
+
+		if t.err != nil {
+			t.tx.Rollback()
+			return t.err
+		}
+		return t.tx.Commit()
+	}()
+
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	// The restored database replaces the current one while it remains open, so that the engine keeps its current
+	// database should the restored one fail to be renamed or opened
+	if err := os.Rename(path, b.path); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	db, applied, err := openBolt(b.path)
+	if err != nil {
+		return err
+	}
+
+	previous := b.db
+	b.db, b.applied = db, applied
+	return previous.Close()
+}
+
+func (b *boltEngine) close() error {
+	return b.db.Close()
+}
+
+// boltTxn implements txn within a bolt transaction, retaining the first error encountered
+type boltTxn struct {
+	tx  *bolt.Tx
+	err error
+
+	// db is set while restoring, so that the transaction can be committed periodically
+	db     *bolt.DB
+	writes int
+}
+
+// wrote counts a write, committing and beginning a new transaction once a restore has written a full batch
+func (t *boltTxn) wrote() {
+	t.writes++
+	if t.db == nil || t.err != nil || t.writes%boltRestoreBatchSize != 0 {
+		return
+	}
+
+	if t.err = t.tx.Commit(); t.err != nil {
+		return
+	}
+
+	tx, err := t.db.Begin(true)
+	if err != nil {
+		t.err = err
+		return
+	}
+	t.tx = tx
+}
+
+func (t *boltTxn) get(source, key string) (entry, bool) {
+	b := t.tx.Bucket(bucketSources).Bucket([]byte(source))
+	if b == nil {
+		return entry{}, false
+	}
+
+	v := b.Get([]byte(key))
+	if v == nil {
+		return entry{}, false
+	}
+	return t.decodeEntry(v), true
+}
+
+func (t *boltTxn) decodeEntry(v []byte) entry {
+	var m pb.SnapshotEntry
+	if err := proto.Unmarshal(v, &m); err != nil && t.err == nil {
+		t.err = err
+	}
+	return entry{Value: m.Value, Revision: m.Revision, Lease: m.Lease, Timestamp: m.Timestamp}
+}
+
+func (t *boltTxn) put(source, key string, e entry) {
+	if t.err != nil {
+		return
+	}
+
+	v, err := proto.Marshal(&pb.SnapshotEntry{Value: e.Value, Revision: e.Revision, Lease: e.Lease, Timestamp: e.Timestamp})
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	b, err := t.tx.Bucket(bucketSources).CreateBucketIfNotExists([]byte(source))
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	t.err = b.Put([]byte(key), v)
+	t.wrote()
+}
+
+func (t *boltTxn) remove(source, key string) {
+	if t.err == nil {
+		t.err = removeNested(t.tx.Bucket(bucketSources), source, key)
+	}
+}
+
+// removeNested deletes the key from the nested bucket, deleting the bucket once it is empty
+func removeNested(parent *bolt.Bucket, name, key string) error {
+	b := parent.Bucket([]byte(name))
+	if b == nil {
+		return nil
+	}
+
+	if err := b.Delete([]byte(key)); err != nil {
+		return err
+	}
+
+	if k, _ := b.Cursor().First(); k == nil {
+		return parent.DeleteBucket([]byte(name))
+	}
+	return nil
+}
+
+// hasSource relies on the bucket of a source being deleted along with its last key
+func (t *boltTxn) hasSource(source string) bool {
+	return t.tx.Bucket(bucketSources).Bucket([]byte(source)) != nil
+}
+
+func (t *boltTxn) listSources(opts ListOptions) []string {
+	return listBucket(t.tx.Bucket(bucketSources), opts)
+}
+
+func (t *boltTxn) listKeys(source string, opts ListOptions) []string {
+	b := t.tx.Bucket(bucketSources).Bucket([]byte(source))
+	if b == nil {
+		return nil
+	}
+	return listBucket(b, opts)
+}
+
+// listBucket seeks to the beginning of the range, so that only the names listed are visited
+func listBucket(b *bolt.Bucket, opts ListOptions) []string {
+	var names []string
+	c := b.Cursor()
+	for k, _ := c.Seek([]byte(opts.from())); k != nil && !opts.full(names); k, _ = c.Next() {
+		name := string(k)
+		if opts.past(name) {
+			break
+		}
+
+		if opts.includes(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (t *boltTxn) forEach(fn func(source, key string, e entry)) {
+	forEachNested(t.tx.Bucket(bucketSources), func(source, key string, v []byte) {
+		fn(source, key, t.decodeEntry(v))
+	})
+}
+
+// forEachNested calls fn for every key within the buckets nested in parent
+func forEachNested(parent *bolt.Bucket, fn func(name, key string, v []byte)) {
+	parent.ForEach(func(name, _ []byte) error {
+		return parent.Bucket(name).ForEach(func(k, v []byte) error {
+			fn(string(name), string(k), v)
+			return nil
+		})
+	})
+}
+
+func (t *boltTxn) history(source, key string) *history {
+	b := t.tx.Bucket(bucketHistory).Bucket([]byte(source))
+	if b == nil {
+		return nil
+	}
+
+	v := b.Get([]byte(key))
+	if v == nil {
+		return nil
+	}
+	return t.decodeHistory(v)
+}
+
+func (t *boltTxn) decodeHistory(v []byte) *history {
+	var m pb.SnapshotHistory
+	if err := proto.Unmarshal(v, &m); err != nil && t.err == nil {
+		t.err = err
+	}
+
+	h := &history{Compacted: m.Compacted}
+	for _, version := range m.Versions {
+		h.Versions = append(h.Versions, Version{Value: version.Value, Revision: version.Revision, Timestamp: version.Timestamp, Deleted: version.Deleted})
+	}
+	return h
+}
+
+func (t *boltTxn) putHistory(source, key string, h *history) {
+	if t.err != nil {
+		return
+	}
+
+	if h == nil {
+		t.err = removeNested(t.tx.Bucket(bucketHistory), source, key)
+		return
+	}
+
+	m := &pb.SnapshotHistory{Compacted: h.Compacted}
+	for _, version := range h.Versions {
+		m.Versions = append(m.Versions, &pb.SnapshotVersion{Value: version.Value, Revision: version.Revision, Timestamp: version.Timestamp, Deleted: version.Deleted})
+	}
+
+	v, err := proto.Marshal(m)
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	b, err := t.tx.Bucket(bucketHistory).CreateBucketIfNotExists([]byte(source))
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	t.err = b.Put([]byte(key), v)
+	t.wrote()
+}
+
+func (t *boltTxn) forEachHistory(fn func(source, key string, h *history)) {
+	forEachNested(t.tx.Bucket(bucketHistory), func(source, key string, v []byte) {
+		fn(source, key, t.decodeHistory(v))
+	})
+}
+
+func leaseKey(id uint64) []byte {
+	var k [8]byte
+	binary.BigEndian.PutUint64(k[:], id)
+	return k[:]
+}
+
+func (t *boltTxn) putLease(l *lease) {
+	if t.err != nil {
+		return
+	}
+
+	v, err := proto.Marshal(&pb.SnapshotLease{Id: l.ID, Ttl: int64(l.TTL), Implicit: l.Implicit})
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	t.err = t.tx.Bucket(bucketLeases).Put(leaseKey(l.ID), v)
+	t.wrote()
+}
+
+func (t *boltTxn) removeLease(id uint64) {
+	if t.err == nil {
+		t.err = t.tx.Bucket(bucketLeases).Delete(leaseKey(id))
+	}
+}
+
+func (t *boltTxn) forEachLease(fn func(l *lease)) {
+	t.tx.Bucket(bucketLeases).ForEach(func(k, v []byte) error {
+		var m pb.SnapshotLease
+		if err := proto.Unmarshal(v, &m); err != nil {
+			if t.err == nil {
+				t.err = err
+			}
+			return nil
+		}
+
+		fn(&lease{ID: m.Id, TTL: time.Duration(m.Ttl), Implicit: m.Implicit})
+		return nil
+	})
+}
+
+func (t *boltTxn) address(peer string) (string, bool) {
+	v := t.tx.Bucket(bucketAddresses).Get([]byte(peer))
+	return string(v), v != nil
+}
+
+func (t *boltTxn) putAddress(peer, address string) {
+	if t.err != nil {
+		return
+	}
+
+	t.err = t.tx.Bucket(bucketAddresses).Put([]byte(peer), []byte(address))
+	t.wrote()
+}
+
+func (t *boltTxn) removeAddress(peer string) {
+	if t.err == nil {
+		t.err = t.tx.Bucket(bucketAddresses).Delete([]byte(peer))
+	}
+}
+
+func (t *boltTxn) forEachAddress(fn func(peer, address string)) {
+	t.tx.Bucket(bucketAddresses).ForEach(func(k, v []byte) error {
+		fn(string(k), string(v))
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fglog "github.com/forestgiant/log"
+	"github.com/hashicorp/raft"
+)
+
+func openTestBoltStore(t *testing.T, dir string) *Store {
+	s := NewStore("", dir, fglog.Logger{Writer: &SuppressedWriter{}})
+	s.Storage = StorageBolt
+	if err := s.openEngine(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func applyLog(t *testing.T, f *fsm, index uint64, c command) interface{} {
+	b, err := encodeCommand(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Apply(&raft.Log{Index: index, Data: b})
+}
+
+func TestBoltEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "com.forestgiant.iris.testing.bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := openTestBoltStore(t, dir)
+	f := (*fsm)(s)
+	applyLog(t, f, 1, command{Operation: operationGrantLease, TTL: time.Minute})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "leased", Value: []byte("value"), Lease: 1})
+	applyLog(t, f, 3, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("first")})
+	applyLog(t, f, 4, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("second")})
+	applyLog(t, f, 5, command{Operation: operationSet, Source: "other", Key: "key", Value: []byte("value")})
+	applyLog(t, f, 6, command{Operation: operationDeleteSource, Source: "other"})
+
+	if sources, err := s.GetSources(); err != nil || !keysMatch(sources, []string{"source"}) {
+		t.Error("Deleted sources should be removed from storage", sources, err)
+	}
+
+	if keys, err := s.GetKeys("source"); err != nil || !keysMatch(keys, []string{"leased", "key"}) {
+		t.Error("GetKeys did not return the keys of the source", keys, err)
+	}
+
+	if err := s.engine.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("TestReopen", func(t *testing.T) {
+		s = openTestBoltStore(t, dir)
+		f = (*fsm)(s)
+
+		if applied := s.engine.lastApplied(); applied != 6 {
+			t.Error("The last applied index was not persisted", applied)
+		}
+
+		if v, revision := s.Get("source", "key"); !valuesMatch(v, []byte("second")) || revision != 4 {
+			t.Error("Value was not persisted")
+		}
+
+		if versions := s.GetHistory("source", "key"); len(versions) != 2 {
+			t.Error("History was not persisted")
+		}
+
+		if _, ok := f.leases[1].keys["source"]["leased"]; !ok {
+			t.Error("Leases should be reloaded with their keys attached")
+		}
+
+		if resp := applyLog(t, f, 4, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("replayed")}); resp != nil {
+			t.Error("Entries that have already been applied should be skipped")
+		}
+
+		if v, _ := s.Get("source", "key"); !valuesMatch(v, []byte("second")) {
+			t.Error("Replaying an applied entry should not modify storage")
+		}
+
+		if resp, ok := applyLog(t, f, 7, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("third")}).(*applyResponse); !ok || resp.err != nil || resp.revision != 7 {
+			t.Error("Entries that have not been applied should be applied")
+		}
+	})
+
+	t.Run("TestSnapshot", func(t *testing.T) {
+		snap, err := f.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sink := &testSink{}
+		err = snap.Persist(sink)
+		snap.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.Restore(ioutil.NopCloser(bytes.NewReader(sink.Bytes()))); err != nil {
+			t.Error("Restoring a snapshot that has already been applied should be skipped", err)
+		}
+
+		other, err := ioutil.TempDir("", "com.forestgiant.iris.testing.bolt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(other)
+
+		restored := openTestBoltStore(t, other)
+		defer restored.engine.close()
+
+		corrupt := append([]byte(nil), sink.Bytes()...)
+		corrupt[bytes.Index(corrupt, []byte("third"))] = 'T'
+		if err := (*fsm)(restored).Restore(ioutil.NopCloser(bytes.NewReader(corrupt))); err != ErrSnapshotChecksum {
+			t.Error("Restoring a snapshot that does not match its checksum should fail", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(other, boltFile+".restore")); !os.IsNotExist(err) {
+			t.Error("A failed restore should discard the database it was written to")
+		}
+
+		if err := (*fsm)(restored).Restore(ioutil.NopCloser(bytes.NewReader(sink.Bytes()))); err != nil {
+			t.Fatal(err)
+		}
+
+		if applied := restored.engine.lastApplied(); applied != 7 {
+			t.Error("Restoring a snapshot should record its index as the last applied", applied)
+		}
+
+		if v, revision := restored.Get("source", "key"); !valuesMatch(v, []byte("third")) || revision != 7 {
+			t.Error("Value was not restored from the snapshot")
+		}
+
+		if versions := restored.GetHistory("source", "key"); len(versions) != 3 {
+			t.Error("History was not restored from the snapshot", len(versions))
+		}
+
+		if ttl, err := restored.LeaseTTL(1); err != nil || ttl != time.Minute {
+			t.Error("Lease was not restored from the snapshot")
+		}
+	})
+
+	s.engine.close()
+}
+
+func TestBoltRestoreFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "com.forestgiant.iris.testing.bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := openTestBoltStore(t, dir)
+	defer s.engine.close()
+
+	f := (*fsm)(s)
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("first")})
+
+	snap, err := f.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &testSink{}
+	err = snap.Persist(sink)
+	snap.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in place of the database prevents the restored database from replacing it
+	path := filepath.Join(dir, boltFile)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(path, "blocked"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	s.engine.(*boltEngine).applied = 0
+	if err := f.Restore(ioutil.NopCloser(bytes.NewReader(sink.Bytes()))); err == nil {
+		t.Fatal("Restoring should fail when the restored database cannot replace the current one")
+	}
+
+	if _, err := os.Stat(path + ".restore"); !os.IsNotExist(err) {
+		t.Error("A failed restore should discard the database it was written to")
+	}
+
+	// The engine keeps using its current database
+	if resp, ok := applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("second")}).(*applyResponse); !ok || resp.err != nil {
+		t.Error("Entries should still be applied once a restore fails", resp)
+	}
+
+	if v, revision := s.Get("source", "key"); !valuesMatch(v, []byte("second")) || revision != 2 {
+		t.Error("Values should still be read once a restore fails", string(v), revision)
+	}
+}
+
+func TestListOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "com.forestgiant.iris.testing.bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bolt := openTestBoltStore(t, dir)
+	defer bolt.engine.close()
+
+	tests := []struct {
+		opts     ListOptions
+		expected []string
+	}{
+		{ListOptions{}, []string{"a", "ab", "abc", "b", "ba", "c"}},
+		{ListOptions{Prefix: "ab"}, []string{"ab", "abc"}},
+		{ListOptions{Prefix: "b"}, []string{"b", "ba"}},
+		{ListOptions{Start: "ab", End: "ba"}, []string{"ab", "abc", "b"}},
+		{ListOptions{Prefix: "a", Start: "abb"}, []string{"abc"}},
+		{ListOptions{Limit: 2}, []string{"a", "ab"}},
+		{ListOptions{Start: "b", Limit: 5}, []string{"b", "ba", "c"}},
+		{ListOptions{Prefix: "d"}, nil},
+	}
+
+	for name, s := range map[string]*Store{"memory": NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}}), "bolt": bolt} {
+		for _, key := range []string{"c", "ab", "a", "ba", "abc", "b"} {
+			(*fsm)(s).set("source", key, []byte(key), 1)
+		}
+
+		for _, test := range tests {
+			keys, err := s.ListKeys("source", test.opts)
+			if err != nil || strings.Join(keys, ",") != strings.Join(test.expected, ",") {
+				t.Error("ListKeys did not return the expected keys", name, test.opts, keys, err)
+			}
+		}
+	}
+}
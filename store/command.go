@@ -0,0 +1,162 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Raft log entries are encoded as the command format version, followed by a byte identifying the operation
+// and the protobuf encoded pb.Command.  Entries written by earlier releases are JSON objects, and are
+// recognized by their leading brace.
+const commandFormatVersion = 1
+
+// commandTypes maps each operation to the byte identifying it in an encoded command.
+// Values must never be reused, since they are persisted in the raft log.
+var commandTypes = map[string]byte{
+	operationSet:            1,
+	operationDeleteKey:      2,
+	operationDeleteSource:   3,
+	operationTransaction:    4,
+	operationGrantLease:     5,
+	operationKeepAliveLease: 6,
+	operationRevokeLease:    7,
+	operationExpireLease:    8,
+
+	operationTransferLeadership: 9,
+	operationPeerAddress:        10,
+	operationCompact:            11,
+}
+
+// commandOperations is the inverse of commandTypes
+var commandOperations = make(map[byte]string)
+
+func init() {
+	for op, t := range commandTypes {
+		commandOperations[t] = op
+	}
+}
+
+var guardTypes = map[string]pb.GuardType{
+	GuardKeyExists:      pb.GuardType_KEY_EXISTS,
+	GuardRevisionEquals: pb.GuardType_REVISION_EQUALS,
+	GuardValueEquals:    pb.GuardType_VALUE_EQUALS,
+}
+
+var operationTypes = map[string]pb.OperationType{
+	OperationSet:          pb.OperationType_SET_VALUE,
+	OperationDeleteKey:    pb.OperationType_REMOVE_VALUE,
+	OperationDeleteSource: pb.OperationType_REMOVE_SOURCE,
+}
+
+// encodeCommand produces the binary representation of the command stored in the raft log
+func encodeCommand(c *command) ([]byte, error) {
+	t, ok := commandTypes[c.Operation]
+	if !ok {
+		return nil, fmt.Errorf("Unrecognized command operation %s", c.Operation)
+	}
+
+	m := &pb.Command{
+		Source:      c.Source,
+		Key:         c.Key,
+		Value:       c.Value,
+		Conditional: c.Conditional,
+		Revision:    c.Revision,
+		Lease:       c.Lease,
+		Ttl:         int64(c.TTL),
+		Peer:        c.Peer,
+		Deadline:    c.Deadline,
+		Address:     c.Address,
+		Timestamp:   c.Timestamp,
+	}
+
+	for _, g := range c.Guards {
+		gt, ok := guardTypes[g.Type]
+		if !ok {
+			return nil, fmt.Errorf("Unrecognized transaction guard %s", g.Type)
+		}
+		m.Guards = append(m.Guards, &pb.TxnGuard{Type: gt, Source: g.Source, Key: g.Key, Revision: g.Revision, Value: g.Value})
+	}
+
+	for _, op := range c.Operations {
+		ot, ok := operationTypes[op.Operation]
+		if !ok {
+			return nil, fmt.Errorf("Unrecognized transaction operation %s", op.Operation)
+		}
+		m.Operations = append(m.Operations, &pb.TxnOperation{Type: ot, Source: op.Source, Key: op.Key, Value: op.Value})
+	}
+
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{commandFormatVersion, t}, payload...), nil
+}
+
+// decodeCommand parses a raft log entry in either the binary format or the legacy JSON format
+func decodeCommand(b []byte) (command, error) {
+	var c command
+	if len(b) > 0 && b[0] == '{' {
+		err := json.Unmarshal(b, &c)
+		return c, err
+	}
+
+	if len(b) < 2 {
+		return c, fmt.Errorf("Command of %d bytes is too short to be decoded", len(b))
+	}
+
+	if b[0] != commandFormatVersion {
+		return c, fmt.Errorf("Unsupported command format version %d", b[0])
+	}
+
+	op, ok := commandOperations[b[1]]
+	if !ok {
+		return c, fmt.Errorf("Unrecognized command type %d", b[1])
+	}
+
+	var m pb.Command
+	if err := proto.Unmarshal(b[2:], &m); err != nil {
+		return c, err
+	}
+
+	c = command{
+		Operation:   op,
+		Source:      m.Source,
+		Key:         m.Key,
+		Value:       m.Value,
+		Conditional: m.Conditional,
+		Revision:    m.Revision,
+		Lease:       m.Lease,
+		TTL:         time.Duration(m.Ttl),
+		Peer:        m.Peer,
+		Deadline:    m.Deadline,
+		Address:     m.Address,
+		Timestamp:   m.Timestamp,
+	}
+
+	for _, g := range m.Guards {
+		guard := TxnGuard{Source: g.Source, Key: g.Key, Revision: g.Revision, Value: g.Value}
+		for name, gt := range guardTypes {
+			if gt == g.Type {
+				guard.Type = name
+			}
+		}
+		c.Guards = append(c.Guards, guard)
+	}
+
+	for _, o := range m.Operations {
+		op := TxnOp{Source: o.Source, Key: o.Key, Value: o.Value}
+		for name, ot := range operationTypes {
+			if ot == o.Type {
+				op.Operation = name
+			}
+		}
+		c.Operations = append(c.Operations, op)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,231 @@
+package store
+
+import "fmt"
+
+// Storage engines that may hold the contents of the store
+const (
+	StorageMemory = "memory"
+	StorageBolt   = "bolt"
+)
+
+// engine holds the contents of the state machine.  Each update records the raft log index it was applied at,
+// so that an engine persisted across restarts can skip the entries it has already applied.
+type engine interface {
+	// view calls fn with read access to the contents
+	view(fn func(t txn)) error
+
+	// update calls fn with write access to the contents and records index as the last applied
+	update(index uint64, fn func(t txn)) error
+
+	// lastApplied returns the index of the last log entry applied to the contents
+	lastApplied() uint64
+
+	// snapshot returns a read-only copy of the contents that is unaffected by later updates,
+	// along with a function that must be called once it is no longer needed
+	snapshot() (txn, func(), error)
+
+	// restore replaces the contents with those written by fn, leaving them unchanged if fn fails
+	restore(index uint64, fn func(t txn) error) error
+
+	close() error
+}
+
+// txn provides access to the contents of an engine for the duration of a view or update.
+// Errors encountered while writing are reported when the update completes.
+type txn interface {
+	get(source, key string) (entry, bool)
+	put(source, key string, e entry)
+	remove(source, key string)
+	hasSource(source string) bool
+	listSources(opts ListOptions) []string
+	listKeys(source string, opts ListOptions) []string
+	forEach(fn func(source, key string, e entry))
+
+	// history returns the history of the key, or nil if it has none.  Changes to the history
+	// are only retained once it is passed to putHistory, where a nil history removes it.
+	history(source, key string) *history
+	putHistory(source, key string, h *history)
+	forEachHistory(fn func(source, key string, h *history))
+
+	putLease(l *lease)
+	removeLease(id uint64)
+	forEachLease(fn func(l *lease))
+
+	// address returns the grpc address advertised by the member of the cluster at the raft address peer
+	address(peer string) (string, bool)
+	putAddress(peer, address string)
+	removeAddress(peer string)
+	forEachAddress(fn func(peer, address string))
+}
+
+// newEngine opens the storage engine with the given name, where an empty name selects the in-memory engine
+func newEngine(name string, dir string) (engine, error) {
+	switch name {
+	case "", StorageMemory:
+		return newMemoryEngine(), nil
+	case StorageBolt:
+		return openBoltEngine(dir)
+	default:
+		return nil, fmt.Errorf("Unrecognized storage engine %s", name)
+	}
+}
+
+// memoryEngine holds the contents in maps, and serves as its own txn
+type memoryEngine struct {
+	entries   map[string]kvs
+	histories map[string]map[string]*history
+	addresses map[string]string
+	applied   uint64
+}
+
+func newMemoryEngine() *memoryEngine {
+	return &memoryEngine{
+		entries:   make(map[string]kvs),
+		histories: make(map[string]map[string]*history),
+		addresses: make(map[string]string),
+	}
+}
+
+func (m *memoryEngine) view(fn func(t txn)) error {
+	fn(m)
+	return nil
+}
+
+func (m *memoryEngine) update(index uint64, fn func(t txn)) error {
+	fn(m)
+	if index > m.applied {
+		m.applied = index
+	}
+	return nil
+}
+
+func (m *memoryEngine) lastApplied() uint64 {
+	return m.applied
+}
+
+func (m *memoryEngine) snapshot() (txn, func(), error) {
+	addresses := make(map[string]string)
+	for peer, address := range m.addresses {
+		addresses[peer] = address
+	}
+	return &memoryEngine{entries: clone(m.entries), histories: cloneHistory(m.histories), addresses: addresses, applied: m.applied}, func() {}, nil
+}
+
+func (m *memoryEngine) restore(index uint64, fn func(t txn) error) error {
+	restored := newMemoryEngine()
+	if err := fn(restored); err != nil {
+		return err
+	}
+
+	m.entries = restored.entries
+	m.histories = restored.histories
+	m.addresses = restored.addresses
+	m.applied = index
+	return nil
+}
+
+func (m *memoryEngine) close() error {
+	return nil
+}
+
+func (m *memoryEngine) get(source, key string) (entry, bool) {
+	e, ok := m.entries[source][key]
+	return e, ok
+}
+
+func (m *memoryEngine) put(source, key string, e entry) {
+	if m.entries[source] == nil {
+		m.entries[source] = make(kvs)
+	}
+	m.entries[source][key] = e
+}
+
+func (m *memoryEngine) remove(source, key string) {
+	if keys, ok := m.entries[source]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(m.entries, source)
+		}
+	}
+}
+
+func (m *memoryEngine) hasSource(source string) bool {
+	return len(m.entries[source]) > 0
+}
+
+func (m *memoryEngine) listSources(opts ListOptions) []string {
+	var sources []string
+	for source := range m.entries {
+		sources = append(sources, source)
+	}
+	return opts.list(sources)
+}
+
+func (m *memoryEngine) listKeys(source string, opts ListOptions) []string {
+	var keys []string
+	for key := range m.entries[source] {
+		keys = append(keys, key)
+	}
+	return opts.list(keys)
+}
+
+func (m *memoryEngine) forEach(fn func(source, key string, e entry)) {
+	for source, keys := range m.entries {
+		for key, e := range keys {
+			fn(source, key, e)
+		}
+	}
+}
+
+func (m *memoryEngine) history(source, key string) *history {
+	return m.histories[source][key]
+}
+
+func (m *memoryEngine) putHistory(source, key string, h *history) {
+	if h == nil {
+		if keys, ok := m.histories[source]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(m.histories, source)
+			}
+		}
+		return
+	}
+
+	if m.histories[source] == nil {
+		m.histories[source] = make(map[string]*history)
+	}
+	m.histories[source][key] = h
+}
+
+func (m *memoryEngine) forEachHistory(fn func(source, key string, h *history)) {
+	for source, keys := range m.histories {
+		for key, h := range keys {
+			fn(source, key, h)
+		}
+	}
+}
+
+// Leases are held by the state machine itself, so the in-memory engine has no need to retain them
+func (m *memoryEngine) putLease(l *lease)              {}
+func (m *memoryEngine) removeLease(id uint64)          {}
+func (m *memoryEngine) forEachLease(fn func(l *lease)) {}
+
+func (m *memoryEngine) address(peer string) (string, bool) {
+	address, ok := m.addresses[peer]
+	return address, ok
+}
+
+func (m *memoryEngine) putAddress(peer, address string) {
+	m.addresses[peer] = address
+}
+
+func (m *memoryEngine) removeAddress(peer string) {
+	delete(m.addresses, peer)
+}
+
+func (m *memoryEngine) forEachAddress(fn func(peer, address string)) {
+	for peer, address := range m.addresses {
+		fn(peer, address)
+	}
+}
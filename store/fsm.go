@@ -1,107 +1,345 @@
 package store
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/hashicorp/raft"
 )
 
 type fsm Store
 
-func (f *fsm) set(source, key string, value []byte) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+// applyResponse is the result of applying a command, made available through the raft ApplyFuture
+type applyResponse struct {
+	revision uint64
+	err      error
+}
+
+// pendingChanges collects the changes made outside of storage while an update of the engine is made: the leases
+// that are granted, changed or removed, and the updates to publish.  They only take effect once the engine has
+// committed the update, so that a failed update leaves the leases and subscribers consistent with storage.
+type pendingChanges struct {
+	timestamp int64             // the time the changes are made at, in nanoseconds since the epoch
+	leases    map[uint64]*lease // staged copies of the changed leases, where nil marks a removed lease
+	updates   []*Update
+	after     []func()
+}
+
+// updateLocked makes a single update of the engine at the timestamp, recording index as the last applied unless it is zero,
+// and commits the changes collected outside of storage once the update succeeds.  It must only be called while holding the lock.
+func (f *fsm) updateLocked(index uint64, timestamp int64, fn func(t txn)) error {
+	f.pending = &pendingChanges{timestamp: timestamp, leases: make(map[uint64]*lease)}
+	defer func() { f.pending = nil }()
+
+	if err := f.engine.update(index, fn); err != nil {
+		return err
+	}
+
+	for id, l := range f.pending.leases {
+		if l == nil {
+			delete(f.leases, id)
+		} else {
+			f.leases[id] = l
+		}
+	}
+
+	for _, fn := range f.pending.after {
+		fn()
+	}
 
-	if f.storage[source] == nil {
-		f.storage[source] = make(kvs)
+	for _, u := range f.pending.updates {
+		f.queueLocked(publication{update: u})
 	}
-	f.storage[source][key] = value
+	return nil
+}
+
+// afterLocked calls the function once the update being made has been committed.
+// It must only be called while holding the lock.
+func (f *fsm) afterLocked(fn func()) {
+	f.pending.after = append(f.pending.after, fn)
 }
 
-func (f *fsm) deleteSource(source string) []string {
+func (f *fsm) set(source, key string, value []byte, revision uint64) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	f.updateLocked(0, time.Now().UnixNano(), func(t txn) {
+		f.setLocked(t, source, key, value, revision, 0)
+	})
+}
 
-	keys := []string{}
-	if m, ok := f.storage[source]; ok {
-		for k := range m {
-			keys = append(keys, k)
+// setLocked stores the value and attaches the key to the lease, detaching it from any lease it was previously attached to,
+// and returns the updates describing the change, followed by the creation of the source if the key is its first.
+// It must only be called while holding the lock.
+func (f *fsm) setLocked(t txn, source, key string, value []byte, revision uint64, lease uint64) []*Update {
+	u := &Update{Source: source, Key: key, Value: value, Operation: OperationSet, Index: revision, Timestamp: f.pending.timestamp}
+	updates := []*Update{u}
+	if !t.hasSource(source) {
+		updates = append(updates, &Update{Source: source, Operation: OperationSourceCreated, Index: revision, Timestamp: u.Timestamp})
+	}
+
+	if prev, ok := t.get(source, key); ok {
+		if prev.Lease != lease {
+			f.detachLocked(t, source, key, prev.Lease)
 		}
-		delete(f.storage, source)
+		f.recordLocked(t, source, key, Version{Value: prev.Value, Revision: prev.Revision, Timestamp: prev.Timestamp})
+		u.PreviousValue, u.PreviousRevision = prev.Value, prev.Revision
 	}
+	t.put(source, key, entry{Value: value, Revision: revision, Lease: lease, Timestamp: u.Timestamp})
+	f.attachLocked(source, key, lease)
+	return updates
+}
 
-	return keys
+// revision returns the current revision of the key, or zero if it does not exist
+func (f *fsm) revision(source, key string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var revision uint64
+	f.engine.view(func(t txn) {
+		e, _ := t.get(source, key)
+		revision = e.Revision
+	})
+	return revision
 }
 
-func (f *fsm) deleteKey(source, key string) bool {
+func (f *fsm) deleteSource(source string, revision uint64) []string {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	found := false
-	if m, ok := f.storage[source]; ok {
-		if _, ok := m[key]; ok {
-			found = true
-			delete(m, key)
+	keys := []string{}
+	f.updateLocked(0, time.Now().UnixNano(), func(t txn) {
+		for _, u := range f.deleteSourceLocked(t, source, revision) {
+			if len(u.Key) > 0 {
+				keys = append(keys, u.Key)
+			}
 		}
+	})
+	return keys
+}
 
-		if len(m) == 0 {
-			delete(f.storage, source)
+// deleteSourceLocked deletes every key of the source and returns the updates describing the changes.
+// It must only be called while holding the lock.
+func (f *fsm) deleteSourceLocked(t txn, source string, revision uint64) []*Update {
+	var updates []*Update
+	for _, k := range t.listKeys(source, ListOptions{}) {
+		for _, u := range f.deleteKeyLocked(t, source, k, revision) {
+			if u.Operation == OperationDeleteKey {
+				u.Operation = OperationDeleteSource
+			}
+			updates = append(updates, u)
 		}
 	}
 
+	return updates
+}
+
+func (f *fsm) deleteKey(source, key string, revision uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var found bool
+	f.updateLocked(0, time.Now().UnixNano(), func(t txn) {
+		found = len(f.deleteKeyLocked(t, source, key, revision)) > 0
+	})
 	return found
 }
 
-func (f *fsm) applyCommand(c command) interface{} {
+// deleteKeyLocked deletes the key and returns the updates describing the change, followed by the deletion of the source
+// if the key was its last, or nil if the key does not exist.  It must only be called while holding the lock.
+func (f *fsm) deleteKeyLocked(t txn, source, key string, revision uint64) []*Update {
+	e, ok := t.get(source, key)
+	if !ok {
+		return nil
+	}
+
+	u := &Update{Source: source, Key: key, Operation: OperationDeleteKey, Index: revision, Timestamp: f.pending.timestamp, PreviousValue: e.Value, PreviousRevision: e.Revision}
+	t.remove(source, key)
+	f.detachLocked(t, source, key, e.Lease)
+	f.retireLocked(t, source, key, e, revision, u.Timestamp)
+
+	updates := []*Update{u}
+	if !t.hasSource(source) {
+		updates = append(updates, &Update{Source: source, Operation: OperationSourceDeleted, Index: revision, Timestamp: u.Timestamp})
+	}
+	return updates
+}
+
+// retireLocked records the removed entry in the history of the key, followed by the revision at which it was removed.
+// It must only be called while holding the lock.
+func (f *fsm) retireLocked(t txn, source, key string, e entry, revision uint64, timestamp int64) {
+	f.recordLocked(t, source, key, Version{Value: e.Value, Revision: e.Revision, Timestamp: e.Timestamp})
+	f.recordLocked(t, source, key, Version{Revision: revision, Timestamp: timestamp, Deleted: true})
+}
+
+// satisfiedLocked indicates whether the guard holds for the current contents of storage.
+// It must only be called while holding the lock.
+func (f *fsm) satisfiedLocked(t txn, g TxnGuard) bool {
+	e, ok := t.get(g.Source, g.Key)
+	switch g.Type {
+	case GuardKeyExists:
+		return ok
+	case GuardRevisionEquals:
+		return e.Revision == g.Revision
+	case GuardValueEquals:
+		return ok && bytes.Equal(e.Value, g.Value)
+	default:
+		return false
+	}
+}
+
+// applyCommand applies the command within a single update of the engine, recording index as the last applied
+func (f *fsm) applyCommand(index uint64, c command) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Entries written by earlier releases were not stamped by the leader, and are applied at the local time
+	timestamp := c.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().UnixNano()
+	}
+
+	var resp interface{}
+	err := f.updateLocked(index, timestamp, func(t txn) {
+		resp = f.applyLocked(t, index, c)
+	})
+
+	if err != nil {
+		f.logger.Error("Failed to update storage.", "index", index, "error", err)
+		return &applyResponse{err: err}
+	}
+	return resp
+}
+
+// applyLocked must only be called while holding the lock
+func (f *fsm) applyLocked(t txn, index uint64, c command) interface{} {
+	if c.Conditional {
+		if e, _ := t.get(c.Source, c.Key); e.Revision != c.Revision {
+			return &applyResponse{err: ErrRevisionMismatch}
+		}
+	}
+
 	switch c.Operation {
 	case operationSet:
-		return f.applySet(c.Source, c.Key, c.Value)
+		return f.applySet(t, index, c.Source, c.Key, c.Value, c.TTL, c.Lease)
 	case operationDeleteSource:
-		return f.appleDeleteSource(c.Source)
+		return f.appleDeleteSource(t, index, c.Source)
 	case operationDeleteKey:
-		return f.appleDeleteKey(c.Source, c.Key)
+		return f.appleDeleteKey(t, index, c.Source, c.Key)
+	case operationTransaction:
+		return f.applyTransaction(t, index, c.Guards, c.Operations)
+	case operationGrantLease:
+		return f.applyGrantLease(t, index, c.TTL)
+	case operationKeepAliveLease:
+		return f.applyKeepAliveLease(c.Lease)
+	case operationRevokeLease:
+		return f.applyRevokeLease(t, index, c.Lease, false)
+	case operationExpireLease:
+		return f.applyRevokeLease(t, index, c.Lease, true)
+	case operationTransferLeadership:
+		return f.applyTransferLeadership(index, c.Peer, c.Deadline)
+	case operationPeerAddress:
+		return f.applyPeerAddress(t, index, c.Peer, c.Address)
+	case operationCompact:
+		return f.applyCompact(t)
 	default:
-		f.logger.Error("Unrecognized transaction operation.", "operation", c.Operation)
-		return nil
+		f.logger.Error("Unrecognized command operation.", "operation", c.Operation)
+		return &applyResponse{err: fmt.Errorf("Unrecognized command operation %s", c.Operation)}
 	}
 }
 
 func (f *fsm) Apply(l *raft.Log) interface{} {
-	var c command
-	if err := json.Unmarshal(l.Data, &c); err != nil {
-		f.logger.Error("Failed to unmarshal command.", "error", err)
+	// A persistent engine may already hold the changes of entries replayed after a restart
+	f.mu.Lock()
+	applied := f.engine.lastApplied()
+	f.mu.Unlock()
+	if l.Index <= applied {
 		return nil
 	}
 
-	return f.applyCommand(c)
+	c, err := decodeCommand(l.Data)
+	if err != nil {
+		f.logger.Error("Failed to decode command.", "error", err)
+		return &applyResponse{err: err}
+	}
+
+	return f.applyCommand(l.Index, c)
 }
 
-func (f *fsm) applySet(source string, key string, value []byte) interface{} {
-	f.logger.Info("SET", "source", source, "key", key, "value", value)
-	f.set(source, key, value)
-	go f.publishCallback(source, key, value)
+func (f *fsm) applySet(t txn, index uint64, source string, key string, value []byte, ttl time.Duration, lease uint64) interface{} {
+	f.logger.Info("SET", "source", source, "key", key, "value", value, "revision", index)
+	if lease > 0 {
+		if _, ok := f.leaseLocked(lease); !ok {
+			return &applyResponse{err: ErrLeaseNotFound}
+		}
+	}
 
-	return nil
+	// A value set with a TTL is attached to its own lease, identified by the revision of the value
+	if ttl > 0 {
+		f.grantLocked(t, newLease(index, ttl, true))
+		lease = index
+	}
+
+	for _, u := range f.setLocked(t, source, key, value, index, lease) {
+		f.publishLocked(u)
+	}
+
+	return &applyResponse{revision: index}
 }
 
-func (f *fsm) appleDeleteSource(source string) interface{} {
+func (f *fsm) appleDeleteSource(t txn, index uint64, source string) interface{} {
 	f.logger.Info("DELETE", "source")
-	deletedKeys := f.deleteSource(source)
-	for _, k := range deletedKeys {
-		go f.publishCallback(source, k, nil)
+	for _, u := range f.deleteSourceLocked(t, source, index) {
+		f.publishLocked(u)
 	}
 	return nil
 }
 
-func (f *fsm) appleDeleteKey(source string, key string) interface{} {
+func (f *fsm) appleDeleteKey(t txn, index uint64, source string, key string) interface{} {
 	f.logger.Info("DELETE", "source", source, "key", key)
-	if f.deleteKey(source, key) {
-		go f.publishCallback(source, key, nil)
+	for _, u := range f.deleteKeyLocked(t, source, key, index) {
+		f.publishLocked(u)
 	}
 	return nil
 }
 
+func (f *fsm) applyTransaction(t txn, index uint64, guards []TxnGuard, ops []TxnOp) interface{} {
+	f.logger.Info("TRANSACTION", "guards", len(guards), "operations", len(ops), "revision", index)
+
+	for _, op := range ops {
+		if op.Operation != operationSet && op.Operation != operationDeleteKey && op.Operation != operationDeleteSource {
+			return &applyResponse{err: fmt.Errorf("Unrecognized transaction operation %s", op.Operation)}
+		}
+	}
+
+	for _, g := range guards {
+		if !f.satisfiedLocked(t, g) {
+			return &applyResponse{err: ErrGuardFailed}
+		}
+	}
+
+	var updates []*Update
+	for _, op := range ops {
+		switch op.Operation {
+		case operationSet:
+			updates = append(updates, f.setLocked(t, op.Source, op.Key, op.Value, index, 0)...)
+		case operationDeleteKey:
+			updates = append(updates, f.deleteKeyLocked(t, op.Source, op.Key, index)...)
+		case operationDeleteSource:
+			updates = append(updates, f.deleteSourceLocked(t, op.Source, index)...)
+		}
+	}
+
+	// Publish the updates together so that subscribers can apply the transaction as a unit
+	for _, u := range updates {
+		u.Transaction = index
+		f.publishLocked(u)
+	}
+
+	return &applyResponse{revision: index}
+}
+
 func clone(o map[string]kvs) map[string]kvs {
 	clone := make(map[string]kvs)
 	for s, m := range o {
@@ -116,55 +354,39 @@ func clone(o map[string]kvs) map[string]kvs {
 func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return &fsmSnapshot{store: clone(f.storage)}, nil
+
+	contents, release, err := f.engine.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &fsmSnapshot{index: f.engine.lastApplied(), contents: contents, leases: cloneLeases(f.leases), release: release}, nil
 }
 
 func (f *fsm) Restore(rc io.ReadCloser) error {
-	s := make(map[string]kvs)
-	if err := json.NewDecoder(rc).Decode(&s); err != nil {
+	index, load, err := openSnapshot(rc)
+	if err != nil {
 		return err
 	}
 
-	// Set the state from the snapshot
-	// No lock required according to Hashicorp docs
-	f.storage = s
-	return nil
-}
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-func (f *fsm) publishCallback(source string, key string, value []byte) {
-	if f.PublishCallback != nil {
-		go f.PublishCallback(source, key, value)
+	// A persistent engine restarted after the snapshot was taken already holds its contents
+	if index > 0 && index <= f.engine.lastApplied() {
+		f.logger.Info("Storage already contains the snapshot.", "index", index)
+		return nil
 	}
-}
-
-type fsmSnapshot struct {
-	store map[string]kvs
-}
-
-func (f *fsmSnapshot) Persist(s raft.SnapshotSink) error {
-	err := func() error {
-		b, err := json.Marshal(f.store)
-		if err != nil {
-			return err
-		}
-
-		if _, err := s.Write(b); err != nil {
-			return err
-		}
 
-		if err := s.Close(); err != nil {
-			return err
-		}
-
-		return nil
-	}()
+	var leases []*lease
+	err = f.engine.restore(index, func(t txn) error {
+		var err error
+		leases, err = load(t)
+		return err
+	})
 
 	if err != nil {
-		s.Cancel()
 		return err
 	}
 
-	return nil
+	return f.restoreLeasesLocked(leases)
 }
-
-func (f *fsmSnapshot) Release() {}
@@ -1,9 +1,17 @@
 package store
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	fglog "github.com/forestgiant/log"
+	"github.com/hashicorp/raft"
 )
 
 func keysMatch(keys1 []string, keys2 []string) bool {
@@ -36,16 +44,55 @@ func TestFSM(t *testing.T) {
 		testSource := "testFSMSetSource"
 		testKey := "testFSMSetKey"
 		testValue := []byte("testFSMSetValue")
-		fsm.set(testSource, testKey, testValue)
+		fsm.set(testSource, testKey, testValue, 3)
 
 		fsm.mu.Lock()
-		if fsm.storage == nil || fsm.storage[testSource] == nil ||
-			!valuesMatch(testValue, fsm.storage[testSource][testKey]) {
+		if memoryOf(s).entries == nil || memoryOf(s).entries[testSource] == nil ||
+			!valuesMatch(testValue, memoryOf(s).entries[testSource][testKey].Value) {
 			t.Error("FSM set did not result in the appropriate value in storage")
 		}
+		if memoryOf(s).entries[testSource][testKey].Revision != 3 {
+			t.Error("FSM set did not result in the appropriate revision in storage")
+		}
 		fsm.mu.Unlock()
 	})
 
+	t.Run("TestConditionalSet", func(t *testing.T) {
+		testSource := "testFSMConditionalSource"
+		testKey := "testFSMConditionalKey"
+
+		c := command{Operation: operationSet, Source: testSource, Key: testKey, Value: []byte("first"), Conditional: true}
+		resp, ok := fsm.applyCommand(5, c).(*applyResponse)
+		if !ok || resp.err != nil || resp.revision != 5 {
+			t.Error("Conditional set of an absent key should succeed with the log index as its revision")
+			return
+		}
+
+		c.Value = []byte("second")
+		if resp := fsm.applyCommand(6, c).(*applyResponse); resp.err != ErrRevisionMismatch {
+			t.Error("Conditional set of an existing key with a stale revision should fail")
+		}
+
+		c.Revision = 5
+		if resp := fsm.applyCommand(7, c).(*applyResponse); resp.err != nil || resp.revision != 7 {
+			t.Error("Conditional set with the current revision should succeed")
+		}
+
+		d := command{Operation: operationDeleteKey, Source: testSource, Key: testKey, Conditional: true, Revision: 5}
+		if resp, ok := fsm.applyCommand(8, d).(*applyResponse); !ok || resp.err != ErrRevisionMismatch {
+			t.Error("Conditional delete with a stale revision should fail")
+		}
+
+		d.Revision = 7
+		if fsm.applyCommand(9, d) != nil {
+			t.Error("Conditional delete with the current revision should succeed")
+		}
+
+		if fsm.revision(testSource, testKey) != 0 {
+			t.Error("Key should have been deleted")
+		}
+	})
+
 	t.Run("TestDeleteSourceWithKeys", func(t *testing.T) {
 		testSource := "testFSMDeleteSource"
 		testKey1 := "testFSMDeleteSourceKey1"
@@ -53,20 +100,19 @@ func TestFSM(t *testing.T) {
 		testValue := []byte("testFSMDeleteSourceValue")
 
 		fsm.mu.Lock()
-		fsm.storage = make(map[string]kvs)
-		fsm.storage[testSource] = make(kvs)
-		fsm.storage[testSource][testKey1] = testValue
-		fsm.storage[testSource][testKey2] = testValue
+		fsm.engine = newMemoryEngine()
+		memoryOf(s).put(testSource, testKey1, entry{Value: testValue})
+		memoryOf(s).put(testSource, testKey2, entry{Value: testValue})
 		fsm.mu.Unlock()
 
 		expected := []string{testKey1, testKey2}
-		received := fsm.deleteSource(testSource)
+		received := fsm.deleteSource(testSource, 0)
 		if !keysMatch(expected, received) {
 			t.Error("Keys received from deleteSource did not match the expected set of keys.")
 		}
 
 		fsm.mu.Lock()
-		if fsm.storage != nil && fsm.storage[testSource] != nil {
+		if memoryOf(s).entries != nil && memoryOf(s).entries[testSource] != nil {
 			t.Error("Source was not successfully deleted")
 		}
 		fsm.mu.Unlock()
@@ -75,17 +121,16 @@ func TestFSM(t *testing.T) {
 	t.Run("TestDeleteEmptySource", func(t *testing.T) {
 		testSource := "testFSMDeleteSource"
 		fsm.mu.Lock()
-		fsm.storage = make(map[string]kvs)
-		fsm.storage[testSource] = make(kvs)
+		fsm.engine = newMemoryEngine()
 		fsm.mu.Unlock()
 
-		if len(fsm.deleteSource(testSource)) > 0 {
+		if len(fsm.deleteSource(testSource, 0)) > 0 {
 			t.Error("DeleteSource should have returned an empty set of keys that were deleted.")
 		}
 	})
 
 	t.Run("TestDeleteUnknownSource", func(t *testing.T) {
-		if len(fsm.deleteSource("unknownSource")) > 0 {
+		if len(fsm.deleteSource("unknownSource", 0)) > 0 {
 			t.Error("DeleteSource should have returned an empty set of keys that were deleted.")
 		}
 	})
@@ -95,18 +140,17 @@ func TestFSM(t *testing.T) {
 		testKey := "testFSMDeleteSourceKey"
 		testValue := []byte("testFSMDeleteSourceValue")
 		fsm.mu.Lock()
-		fsm.storage = make(map[string]kvs)
-		fsm.storage[testSource] = make(kvs)
-		fsm.storage[testSource][testKey] = testValue
+		fsm.engine = newMemoryEngine()
+		memoryOf(s).put(testSource, testKey, entry{Value: testValue})
 		fsm.mu.Unlock()
 
-		if !fsm.deleteKey(testSource, testKey) {
+		if !fsm.deleteKey(testSource, testKey, 0) {
 			t.Error("DeleteKey should have indicated that it successfully removed a key")
 			return
 		}
 
 		fsm.mu.Lock()
-		if fsm.storage != nil && fsm.storage[testSource] != nil && fsm.storage[testSource][testKey] != nil {
+		if memoryOf(s).entries != nil && memoryOf(s).entries[testSource] != nil && memoryOf(s).entries[testSource][testKey].Value != nil {
 			t.Error("DeleteKey did not successfully remove the key")
 		}
 		fsm.mu.Unlock()
@@ -115,34 +159,223 @@ func TestFSM(t *testing.T) {
 	t.Run("TestDeleteUnknownKey", func(t *testing.T) {
 		testSource := "testFSMDeleteSource"
 		fsm.mu.Lock()
-		fsm.storage = make(map[string]kvs)
-		fsm.storage[testSource] = make(kvs)
+		fsm.engine = newMemoryEngine()
 		fsm.mu.Unlock()
 
-		if fsm.deleteKey(testSource, "unknownKey") {
+		if fsm.deleteKey(testSource, "unknownKey", 0) {
 			t.Error("DeleteKey should have indicated that key removal was not required")
 			return
 		}
 	})
 
+	t.Run("TestTransaction", func(t *testing.T) {
+		testSource := "testFSMTransactionSource"
+		fsm.mu.Lock()
+		fsm.engine = newMemoryEngine()
+		memoryOf(s).put(testSource, "existing", entry{Value: []byte("existing"), Revision: 2})
+		fsm.mu.Unlock()
+
+		guards := []TxnGuard{
+			{Type: GuardKeyExists, Source: testSource, Key: "existing"},
+			{Type: GuardRevisionEquals, Source: testSource, Key: "existing", Revision: 2},
+			{Type: GuardValueEquals, Source: testSource, Key: "existing", Value: []byte("existing")},
+			{Type: GuardRevisionEquals, Source: testSource, Key: "missing", Revision: 0},
+		}
+		ops := []TxnOp{
+			{Operation: OperationSet, Source: testSource, Key: "key1", Value: []byte("value1")},
+			{Operation: OperationSet, Source: testSource, Key: "key2", Value: []byte("value2")},
+			{Operation: OperationDeleteKey, Source: testSource, Key: "existing"},
+		}
+
+		failing := append([]TxnGuard{{Type: GuardValueEquals, Source: testSource, Key: "existing", Value: []byte("other")}}, guards...)
+		c := command{Operation: operationTransaction, Guards: failing, Operations: ops}
+		if resp, ok := fsm.applyCommand(10, c).(*applyResponse); !ok || resp.err != ErrGuardFailed {
+			t.Error("Transaction with an unsatisfied guard should fail")
+			return
+		}
+
+		if fsm.revision(testSource, "key1") != 0 || fsm.revision(testSource, "existing") != 2 {
+			t.Error("Transaction with an unsatisfied guard should not modify storage")
+			return
+		}
+
+		c.Guards = guards
+		if resp, ok := fsm.applyCommand(11, c).(*applyResponse); !ok || resp.err != nil || resp.revision != 11 {
+			t.Error("Transaction with satisfied guards should succeed")
+			return
+		}
+
+		if fsm.revision(testSource, "key1") != 11 || fsm.revision(testSource, "key2") != 11 || fsm.revision(testSource, "existing") != 0 {
+			t.Error("Transaction operations were not applied")
+		}
+
+		c = command{Operation: operationTransaction, Operations: append(ops, TxnOp{Operation: "testFSMBadOperation"})}
+		if resp, ok := fsm.applyCommand(12, c).(*applyResponse); !ok || resp.err == nil {
+			t.Error("Transaction with an unrecognized operation should fail")
+		}
+	})
+
 	t.Run("TestApplyBadCommand", func(t *testing.T) {
 		c := command{Operation: "testFSMBadCommand"}
-		if fsm.applyCommand(c) != nil {
-			t.Error("Expected applyCommand to return nil")
+		if resp, ok := fsm.applyCommand(0, c).(*applyResponse); !ok || resp.err == nil {
+			t.Error("Expected applyCommand to return an error for an unrecognized operation")
+		}
+
+		for _, data := range [][]byte{nil, []byte("not a command"), {commandFormatVersion, 0xff}, {commandFormatVersion + 1, 1}} {
+			if resp, ok := fsm.Apply(&raft.Log{Index: 100, Data: data}).(*applyResponse); !ok || resp.err == nil {
+				t.Error("Expected Apply to return an error for an undecodable command", data)
+			}
+		}
+	})
+
+	t.Run("TestLeases", func(t *testing.T) {
+		testSource := "testFSMLeaseSource"
+
+		resp, ok := fsm.applyCommand(20, command{Operation: operationGrantLease, TTL: time.Minute}).(*applyResponse)
+		if !ok || resp.err != nil || resp.revision != 20 {
+			t.Error("Granting a lease should return the log index as its id")
+			return
+		}
+
+		resp, ok = fsm.applyCommand(21, command{Operation: operationSet, Source: testSource, Key: "leased", Value: []byte("value"), Lease: 20}).(*applyResponse)
+		if !ok || resp.err != nil {
+			t.Error("Setting a value with an existing lease should succeed")
+		}
+
+		resp, ok = fsm.applyCommand(22, command{Operation: operationSet, Source: testSource, Key: "missing", Value: []byte("value"), Lease: 99}).(*applyResponse)
+		if !ok || resp.err != ErrLeaseNotFound {
+			t.Error("Setting a value with an unknown lease should return ErrLeaseNotFound")
+		}
+
+		fsm.applyCommand(23, command{Operation: operationSet, Source: testSource, Key: "ttl", Value: []byte("value"), TTL: time.Minute})
+		fsm.mu.Lock()
+		if l, ok := fsm.leases[23]; !ok || !l.Implicit || memoryOf(s).entries[testSource]["ttl"].Lease != 23 {
+			t.Error("Setting a value with a TTL should attach it to an implicit lease")
+		}
+		fsm.mu.Unlock()
+
+		fsm.applyCommand(24, command{Operation: operationSet, Source: testSource, Key: "ttl", Value: []byte("value")})
+		fsm.mu.Lock()
+		if _, ok := fsm.leases[23]; ok {
+			t.Error("An implicit lease should be discarded once no keys are attached")
+		}
+		fsm.mu.Unlock()
+
+		if expired := fsm.expiredLeases(time.Now().Add(2 * time.Minute)); len(expired) != 1 || expired[0] != 20 {
+			t.Error("Lease should be reported as expired once its deadline has passed")
+		}
+
+		resp, ok = fsm.applyCommand(25, command{Operation: operationKeepAliveLease, Lease: 99}).(*applyResponse)
+		if !ok || resp.err != ErrLeaseNotFound {
+			t.Error("Keeping an unknown lease alive should return ErrLeaseNotFound")
+		}
+
+		fsm.applyCommand(26, command{Operation: operationExpireLease, Lease: 20})
+		fsm.mu.Lock()
+		if _, ok := memoryOf(s).entries[testSource]["leased"]; ok {
+			t.Error("Keys attached to an expired lease should be deleted")
+		}
+		if _, ok := memoryOf(s).entries[testSource]["ttl"]; !ok {
+			t.Error("Keys detached from the lease should not be deleted")
+		}
+		fsm.mu.Unlock()
+
+		if resp := fsm.applyCommand(27, command{Operation: operationExpireLease, Lease: 20}); resp != nil {
+			t.Error("Expiring a lease that no longer exists should be ignored")
+		}
+
+		resp, ok = fsm.applyCommand(28, command{Operation: operationRevokeLease, Lease: 20}).(*applyResponse)
+		if !ok || resp.err != ErrLeaseNotFound {
+			t.Error("Revoking an unknown lease should return ErrLeaseNotFound")
+		}
+	})
+
+	t.Run("TestHistory", func(t *testing.T) {
+		testSource := "testFSMHistorySource"
+		testKey := "testFSMHistoryKey"
+
+		fsm.applyCommand(30, command{Operation: operationSet, Source: testSource, Key: testKey, Value: []byte("first")})
+		fsm.applyCommand(31, command{Operation: operationSet, Source: testSource, Key: testKey, Value: []byte("second")})
+		fsm.applyCommand(32, command{Operation: operationDeleteKey, Source: testSource, Key: testKey})
+		fsm.applyCommand(33, command{Operation: operationSet, Source: testSource, Key: testKey, Value: []byte("third")})
+
+		expected := []struct {
+			value    string
+			revision uint64
+			deleted  bool
+		}{{"first", 30, false}, {"second", 31, false}, {"", 32, true}, {"third", 33, false}}
+
+		versions := s.GetHistory(testSource, testKey)
+		if len(versions) != len(expected) {
+			t.Error("History did not contain the expected number of versions", len(versions))
+			return
+		}
+
+		for i, v := range versions {
+			if string(v.Value) != expected[i].value || v.Revision != expected[i].revision || v.Deleted != expected[i].deleted {
+				t.Error("History version did not match the expected version", i)
+			}
+		}
+
+		for revision, value := range map[uint64]string{29: "", 30: "first", 31: "second", 32: "", 33: "third", 40: "third"} {
+			v, _, err := s.GetAt(testSource, testKey, revision)
+			if err != nil || string(v) != value {
+				t.Error("GetAt did not return the expected value for revision", revision)
+			}
+		}
+
+		fsm.mu.Lock()
+		fsm.HistoryLimit = 2
+		fsm.compactLocked(fsm.engine.(*memoryEngine), time.Now())
+		fsm.HistoryLimit = DefaultHistoryLimit
+		fsm.mu.Unlock()
+
+		if _, _, err := s.GetAt(testSource, testKey, 30); err != ErrCompacted {
+			t.Error("GetAt should return ErrCompacted for a version that is no longer retained")
+		}
+
+		if _, _, err := s.GetAt(testSource, testKey, 32); err != nil {
+			t.Error("GetAt should succeed for a version that is still retained", err)
+		}
+
+		// Taking a snapshot leaves the history unchanged, since compactions are made through the log
+		fsm.HistoryMaxAge = time.Nanosecond
+		snap, err := fsm.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap.Release()
+
+		if versions := s.GetHistory(testSource, testKey); len(versions) != 3 {
+			t.Error("Taking a snapshot should not discard versions", len(versions))
+		}
+
+		// Versions are discarded by age as of the time the leader proposed the compaction
+		fsm.HistoryMaxAge = time.Minute
+		fsm.applyCommand(34, command{Operation: operationCompact, Timestamp: time.Now().UnixNano()})
+		if versions := s.GetHistory(testSource, testKey); len(versions) != 3 {
+			t.Error("Versions within the maximum age should be retained", len(versions))
+		}
+
+		fsm.applyCommand(35, command{Operation: operationCompact, Timestamp: time.Now().Add(time.Hour).UnixNano()})
+		fsm.HistoryMaxAge = 0
+
+		if versions := s.GetHistory(testSource, testKey); len(versions) != 1 || string(versions[0].Value) != "third" {
+			t.Error("Versions older than the maximum age should be discarded")
 		}
 	})
 
 	t.Run("TestCloneStorage", func(t *testing.T) {
 		original := make(map[string]kvs)
 		original["cloneSource1"] = make(kvs)
-		original["cloneSource1"]["cloneKey1"] = []byte("cloneValue1")
-		original["cloneSource1"]["cloneKey2"] = []byte("cloneValue2")
-		original["cloneSource1"]["cloneKey3"] = []byte("cloneValue3")
+		original["cloneSource1"]["cloneKey1"] = entry{Value: []byte("cloneValue1"), Revision: 1}
+		original["cloneSource1"]["cloneKey2"] = entry{Value: []byte("cloneValue2"), Revision: 2}
+		original["cloneSource1"]["cloneKey3"] = entry{Value: []byte("cloneValue3"), Revision: 3}
 		original["cloneSource2"] = make(kvs)
-		original["cloneSource2"]["cloneKey1"] = []byte("cloneValue1")
-		original["cloneSource2"]["cloneKey2"] = []byte("cloneValue2")
+		original["cloneSource2"]["cloneKey1"] = entry{Value: []byte("cloneValue1"), Revision: 4}
+		original["cloneSource2"]["cloneKey2"] = entry{Value: []byte("cloneValue2"), Revision: 5}
 		original["cloneSource3"] = make(kvs)
-		original["cloneSource3"]["cloneKey1"] = []byte("cloneValue1")
+		original["cloneSource3"]["cloneKey1"] = entry{Value: []byte("cloneValue1"), Revision: 6}
 		original["cloneSource4"] = make(kvs)
 
 		c := clone(original)
@@ -168,7 +401,7 @@ func TestFSM(t *testing.T) {
 					continue
 				}
 
-				if !valuesMatch(original[s][k], v) {
+				if !valuesMatch(original[s][k].Value, v.Value) || original[s][k].Revision != v.Revision {
 					t.Error("Value in clone did not match original")
 					continue
 				}
@@ -176,3 +409,497 @@ func TestFSM(t *testing.T) {
 		}
 	})
 }
+
+func TestRestoreLegacyEntries(t *testing.T) {
+	var restored map[string]kvs
+	legacy := []byte(`{"source":{"key":"dmFsdWU="}}`)
+	if err := json.Unmarshal(legacy, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if !valuesMatch(restored["source"]["key"].Value, []byte("value")) || restored["source"]["key"].Revision != 0 {
+		t.Error("Legacy snapshot entry was not decoded properly")
+	}
+
+	current, err := json.Marshal(map[string]kvs{"source": {"key": entry{Value: []byte("value"), Revision: 9}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored = nil
+	if err := json.Unmarshal(current, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if !valuesMatch(restored["source"]["key"].Value, []byte("value")) || restored["source"]["key"].Revision != 9 {
+		t.Error("Snapshot entry was not decoded properly")
+	}
+}
+
+// testSink is an in-memory raft.SnapshotSink
+type testSink struct {
+	bytes.Buffer
+	cancelled bool
+}
+
+func (s *testSink) ID() string    { return "test" }
+func (s *testSink) Close() error  { return nil }
+func (s *testSink) Cancel() error { s.cancelled = true; return nil }
+
+func TestRestoreSnapshot(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	legacy := []byte(`{"source":{"key":"dmFsdWU="}}`)
+	if err := f.Restore(ioutil.NopCloser(bytes.NewReader(legacy))); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := s.Get("source", "key"); !valuesMatch(v, []byte("value")) {
+		t.Error("Legacy snapshot was not restored properly")
+	}
+
+	f.applyCommand(10, command{Operation: operationGrantLease, TTL: time.Minute})
+	f.applyCommand(11, command{Operation: operationSet, Source: "source", Key: "leased", Value: []byte("value"), Lease: 10})
+	f.applyCommand(12, command{Operation: operationSet, Source: "source", Key: "leased", Value: []byte("updated"), Lease: 10})
+	f.applyCommand(13, command{Operation: operationPeerAddress, Peer: "[::1]:7001", Address: "[::1]:7000"})
+
+	snap, err := f.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := snap.(*fsmSnapshot)
+	contents := fs.contents.(*memoryEngine)
+	versioned, err := json.Marshal(jsonSnapshot{Version: jsonSnapshotVersion, Storage: contents.entries, Leases: fs.leases, History: contents.histories})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &testSink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, b := range map[string][]byte{"binary": sink.Bytes(), "json": versioned} {
+		restored := (*fsm)(NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}}))
+		if err := restored.Restore(ioutil.NopCloser(bytes.NewReader(b))); err != nil {
+			t.Error(name, err)
+			continue
+		}
+
+		if v, revision := (*Store)(restored).Get("source", "leased"); !valuesMatch(v, []byte("updated")) || revision != 12 {
+			t.Error("Value was not restored from the snapshot", name)
+		}
+
+		if ttl, err := (*Store)(restored).LeaseTTL(10); err != nil || ttl != time.Minute {
+			t.Error("Lease was not restored from the snapshot", name)
+		}
+
+		if _, ok := restored.leases[10].keys["source"]["leased"]; !ok {
+			t.Error("Restored lease should have its keys attached", name)
+		}
+
+		if versions := (*Store)(restored).GetHistory("source", "leased"); len(versions) != 2 {
+			t.Error("History was not restored from the snapshot", name)
+		}
+
+		// JSON snapshots predate the recording of peer addresses
+		if address, ok := (*Store)(restored).PeerAddress("[::1]:7001"); name == "binary" && (!ok || address != "[::1]:7000") {
+			t.Error("Peer address was not restored from the snapshot", address)
+		}
+	}
+}
+
+func TestRestoreCorruptSnapshot(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+	f.applyCommand(1, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("value")})
+
+	snap, err := f.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &testSink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatal(err)
+	}
+	b := sink.Bytes()
+
+	// Restore to a store that has not applied the snapshot, since it would otherwise be skipped
+	s = NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f = (*fsm)(s)
+	f.set("source", "key", []byte("value"), 1)
+
+	corrupt := append([]byte(nil), b...)
+	corrupt[bytes.Index(corrupt, []byte("value"))] = 'V'
+	if err := f.Restore(ioutil.NopCloser(bytes.NewReader(corrupt))); err != ErrSnapshotChecksum {
+		t.Error("Restoring a snapshot that does not match its checksum should fail")
+	}
+
+	if err := f.Restore(ioutil.NopCloser(bytes.NewReader(b[:len(b)-6]))); err != io.ErrUnexpectedEOF {
+		t.Error("Restoring a truncated snapshot should fail", err)
+	}
+
+	if v, _ := s.Get("source", "key"); !valuesMatch(v, []byte("value")) {
+		t.Error("A failed restore should not modify storage")
+	}
+}
+
+func TestCommandEncoding(t *testing.T) {
+	c := command{
+		Operation: operationTransaction,
+		Guards:    []TxnGuard{{Type: GuardRevisionEquals, Source: "source", Key: "key", Revision: 4}},
+		Operations: []TxnOp{
+			{Operation: OperationSet, Source: "source", Key: "key", Value: []byte{0, 1, 2}},
+			{Operation: OperationDeleteSource, Source: "other"},
+		},
+	}
+
+	b, err := encodeCommand(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeCommand(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Operation != c.Operation || len(decoded.Guards) != 1 || decoded.Guards[0].Type != GuardRevisionEquals || decoded.Guards[0].Revision != 4 || len(decoded.Operations) != 2 ||
+		!valuesMatch(decoded.Operations[0].Value, c.Operations[0].Value) || decoded.Operations[1].Operation != OperationDeleteSource {
+		t.Error("Decoded command did not match the encoded command")
+	}
+
+	set := command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("value"), Conditional: true, Revision: 3, TTL: time.Second, Timestamp: 42}
+	if b, err = encodeCommand(&set); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded, err = decodeCommand(b); err != nil || decoded.Source != set.Source || decoded.Key != set.Key ||
+		!valuesMatch(decoded.Value, set.Value) || !decoded.Conditional || decoded.Revision != 3 || decoded.TTL != time.Second || decoded.Timestamp != 42 {
+		t.Error("Decoded set command did not match the encoded command", err)
+	}
+
+	legacy := []byte(`{"operation":"set","source":"source","key":"key","value":"dmFsdWU="}`)
+	if decoded, err = decodeCommand(legacy); err != nil || decoded.Operation != operationSet || !valuesMatch(decoded.Value, []byte("value")) {
+		t.Error("Legacy JSON command was not decoded properly", err)
+	}
+
+	if _, err := encodeCommand(&command{Operation: "unknown"}); err == nil {
+		t.Error("Encoding an unrecognized operation should fail")
+	}
+}
+
+func TestPublishOrder(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	published := make(chan []byte, 200)
+	s.UpdateCallback = func(u *Update) {
+		if len(u.Key) > 0 {
+			published <- u.Value
+		}
+	}
+
+	var expected []string
+	for i := uint64(1); i <= 100; i++ {
+		value := []byte(fmt.Sprintf("value%d", i))
+		applyLog(t, f, 2*i-1, command{Operation: operationSet, Source: "source", Key: "key", Value: value})
+		applyLog(t, f, 2*i, command{Operation: operationDeleteKey, Source: "source", Key: "key"})
+		expected = append(expected, string(value), "")
+	}
+
+	for i, e := range expected {
+		select {
+		case v := <-published:
+			if string(v) != e {
+				t.Fatal("Updates should be published in the order they were applied", i, string(v), e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for published updates", i)
+		}
+	}
+}
+
+func TestPublishedUpdates(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	published := make(chan *Update, 20)
+	s.UpdateCallback = func(u *Update) {
+		published <- u
+	}
+
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("first")})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte{}})
+	applyLog(t, f, 3, command{Operation: operationDeleteKey, Source: "source", Key: "key"})
+	applyLog(t, f, 4, command{Operation: operationSet, Source: "source", Key: "other", Value: []byte("value")})
+	applyLog(t, f, 5, command{Operation: operationDeleteSource, Source: "source"})
+	applyLog(t, f, 6, command{Operation: operationTransaction, Operations: []TxnOp{{Operation: OperationSet, Source: "source", Key: "key", Value: []byte("txn")}}})
+
+	expected := []Update{
+		{Source: "source", Key: "key", Value: []byte("first"), Operation: OperationSet, Index: 1},
+		{Source: "source", Operation: OperationSourceCreated, Index: 1},
+		{Source: "source", Key: "key", Value: []byte{}, Operation: OperationSet, Index: 2, PreviousValue: []byte("first"), PreviousRevision: 1},
+		{Source: "source", Key: "key", Operation: OperationDeleteKey, Index: 3, PreviousValue: []byte{}, PreviousRevision: 2},
+		{Source: "source", Operation: OperationSourceDeleted, Index: 3},
+		{Source: "source", Key: "other", Value: []byte("value"), Operation: OperationSet, Index: 4},
+		{Source: "source", Operation: OperationSourceCreated, Index: 4},
+		{Source: "source", Key: "other", Operation: OperationDeleteSource, Index: 5, PreviousValue: []byte("value"), PreviousRevision: 4},
+		{Source: "source", Operation: OperationSourceDeleted, Index: 5},
+		{Source: "source", Key: "key", Value: []byte("txn"), Operation: OperationSet, Index: 6, Transaction: 6},
+		{Source: "source", Operation: OperationSourceCreated, Index: 6, Transaction: 6},
+	}
+
+	for i, e := range expected {
+		select {
+		case u := <-published:
+			if u.Source != e.Source || u.Key != e.Key || !valuesMatch(u.Value, e.Value) || u.Operation != e.Operation || u.Index != e.Index ||
+				u.Transaction != e.Transaction || !valuesMatch(u.PreviousValue, e.PreviousValue) || u.PreviousRevision != e.PreviousRevision {
+				t.Error("Published update did not describe the change", i, u)
+			}
+
+			if u.Timestamp == 0 {
+				t.Error("Published update should include the time it was applied", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for published updates", i)
+		}
+	}
+}
+
+func TestPublishCallback(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	published := make(chan string, 20)
+	s.PublishCallback = func(source, key string, value []byte, transaction uint64) {
+		published <- fmt.Sprintf("%s/%s=%s:%v:%d", source, key, value, value == nil, transaction)
+	}
+
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("first")})
+	applyLog(t, f, 2, command{Operation: operationDeleteKey, Source: "source", Key: "key"})
+	applyLog(t, f, 3, command{Operation: operationTransaction, Operations: []TxnOp{{Operation: OperationSet, Source: "source", Key: "key", Value: []byte("txn")}}})
+
+	// Changes to the list of sources are not described to the deprecated callback
+	expected := []string{"source/key=first:false:0", "source/key=:true:0", "source/key=txn:false:3"}
+	for i, e := range expected {
+		select {
+		case p := <-published:
+			if p != e {
+				t.Error("The deprecated callback should be called with each update to a key", i, p, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for published updates", i)
+		}
+	}
+
+	select {
+	case p := <-published:
+		t.Error("The deprecated callback should only be called with updates to keys", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLeaderTimestamp(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	// Commands are applied at the time the leader proposed them, so that every member records the same timestamps
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("first"), Timestamp: 100})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "key", Value: []byte("second"), Timestamp: 200})
+	applyLog(t, f, 3, command{Operation: operationDeleteKey, Source: "source", Key: "key", Timestamp: 300})
+
+	versions := s.GetHistory("source", "key")
+	if len(versions) != 3 || versions[0].Timestamp != 100 || versions[1].Timestamp != 200 || versions[2].Timestamp != 300 || !versions[2].Deleted {
+		t.Error("Versions should be stamped with the time the leader proposed them", versions)
+	}
+}
+
+func TestTombstoneCompaction(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	now := time.Now()
+	index := uint64(0)
+	apply := func(c command) {
+		index++
+		c.Timestamp = now.UnixNano()
+		applyLog(t, f, index, c)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		apply(command{Operation: operationSet, Source: "source", Key: key, Value: []byte("value")})
+		apply(command{Operation: operationDeleteKey, Source: "source", Key: key})
+	}
+	apply(command{Operation: operationSet, Source: "source", Key: "kept", Value: []byte("first")})
+	apply(command{Operation: operationSet, Source: "source", Key: "kept", Value: []byte("second")})
+
+	histories := func() int {
+		count := 0
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.engine.view(func(t txn) {
+			t.forEachHistory(func(source, key string, h *history) {
+				count++
+			})
+		})
+		return count
+	}
+
+	if count := histories(); count != 101 {
+		t.Fatal("Every removed key should retain its history until compacted", count)
+	}
+
+	// Removed keys retain their history until their removal is older than the tombstone retention
+	index++
+	applyLog(t, f, index, command{Operation: operationCompact, Timestamp: now.Add(DefaultTombstoneMaxAge / 2).UnixNano()})
+	if count := histories(); count != 101 {
+		t.Error("Removed keys should retain their history within the tombstone retention", count)
+	}
+
+	index++
+	applyLog(t, f, index, command{Operation: operationCompact, Timestamp: now.Add(DefaultTombstoneMaxAge + time.Minute).UnixNano()})
+	if count := histories(); count != 1 {
+		t.Error("The history of removed keys should be discarded once their removal is no longer retained", count)
+	}
+
+	if versions := s.GetHistory("source", "kept"); len(versions) != 2 {
+		t.Error("The history of an existing key should be retained", versions)
+	}
+
+	if value, revision, err := s.GetAt("source", "key0", 1); err != nil || value != nil || revision != 0 {
+		t.Error("A key whose history was discarded should not have existed", value, revision, err)
+	}
+}
+
+// failingEngine discards the updates made to the engine it wraps while fail is set, returning an error
+type failingEngine struct {
+	*memoryEngine
+	fail bool
+}
+
+func (e *failingEngine) update(index uint64, fn func(t txn)) error {
+	if !e.fail {
+		return e.memoryEngine.update(index, fn)
+	}
+
+	// The changes are made to a copy of storage, which is discarded
+	t, _, _ := e.memoryEngine.snapshot()
+	fn(t)
+	return errors.New("Failed to commit")
+}
+
+func TestFailedUpdate(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+	e := &failingEngine{memoryEngine: newMemoryEngine()}
+	s.engine = e
+
+	published := make(chan *Update, 20)
+	s.UpdateCallback = func(u *Update) {
+		published <- u
+	}
+
+	applyLog(t, f, 1, command{Operation: operationGrantLease, TTL: time.Minute})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "leased", Value: []byte("value"), Lease: 1})
+	<-published
+	<-published
+
+	e.fail = true
+	for i, c := range []command{
+		{Operation: operationSet, Source: "source", Key: "ttl", Value: []byte("value"), TTL: time.Minute},
+		{Operation: operationGrantLease, TTL: time.Minute},
+		{Operation: operationDeleteKey, Source: "source", Key: "leased"},
+		{Operation: operationRevokeLease, Lease: 1},
+	} {
+		if resp, ok := applyLog(t, f, uint64(i+3), c).(*applyResponse); !ok || resp.err == nil {
+			t.Error("Command should fail when storage is not updated", c.Operation)
+		}
+	}
+
+	select {
+	case u := <-published:
+		t.Error("Updates should not be published when storage is not updated", u)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if len(s.leases) != 1 {
+		t.Error("Leases should not change when storage is not updated", len(s.leases))
+	}
+
+	if l, ok := s.leases[1]; !ok || len(l.keys["source"]) != 1 {
+		t.Error("Keys should remain attached to their lease when storage is not updated")
+	}
+}
+
+func TestSnapshotOrder(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	delivered := make(chan string, 10)
+	s.UpdateCallback = func(u *Update) {
+		if len(u.Key) > 0 {
+			delivered <- fmt.Sprintf("update %d", u.Index)
+		}
+	}
+
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "source", Key: "a", Value: []byte("a")})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "source", Key: "b", Value: []byte("b")})
+
+	var snapshot []*Update
+	if err := s.Snapshot("source", "", func(updates []*Update, index uint64) {
+		snapshot = updates
+		delivered <- fmt.Sprintf("snapshot %d", index)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	applyLog(t, f, 3, command{Operation: operationSet, Source: "source", Key: "c", Value: []byte("c")})
+
+	// The snapshot follows the updates it includes, and precedes those applied after it
+	for _, expected := range []string{"update 1", "update 2", "snapshot 2", "update 3"} {
+		select {
+		case d := <-delivered:
+			if d != expected {
+				t.Error("Snapshot was not delivered in order with the published updates", d, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for published updates", expected)
+		}
+	}
+
+	if len(snapshot) != 2 || snapshot[0].Key != "a" || snapshot[1].Key != "b" || snapshot[1].Index != 2 || snapshot[1].Operation != OperationSnapshot {
+		t.Error("Snapshot did not hold the contents of the source", snapshot)
+	}
+}
+
+func TestSnapshotSources(t *testing.T) {
+	s := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+	f := (*fsm)(s)
+
+	applyLog(t, f, 1, command{Operation: operationSet, Source: "b", Key: "key", Value: []byte("value")})
+	applyLog(t, f, 2, command{Operation: operationSet, Source: "a", Key: "key", Value: []byte("value")})
+	applyLog(t, f, 3, command{Operation: operationSet, Source: "c", Key: "key", Value: []byte("value")})
+	applyLog(t, f, 4, command{Operation: operationDeleteKey, Source: "c", Key: "key"})
+
+	done := make(chan []*Update, 1)
+	if err := s.SnapshotSources(func(updates []*Update, index uint64) {
+		done <- updates
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case snapshot := <-done:
+		if len(snapshot) != 2 || snapshot[0].Source != "a" || snapshot[1].Source != "b" || len(snapshot[1].Key) > 0 ||
+			snapshot[1].Index != 4 || snapshot[1].Operation != OperationSnapshot {
+			t.Error("Snapshot did not hold the sources that exist", snapshot)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the snapshot")
+	}
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultHistoryLimit is the number of previous versions retained for each key unless configured otherwise
+const DefaultHistoryLimit = 10
+
+// DefaultTombstoneMaxAge is the duration the history of a removed key is retained for unless configured otherwise
+const DefaultTombstoneMaxAge = 24 * time.Hour
+
+// operationCompact enforces the retention of history as of the time the leader proposed it
+const operationCompact = "compact"
+
+// historyCompactInterval is how often the leader proposes the compaction of history
+const historyCompactInterval = time.Minute
+
+// ErrCompacted is returned when a read is made at a revision whose version is no longer retained
+var ErrCompacted = errors.New("The requested revision has been compacted")
+
+// Version is a value a key held at some point in time
+type Version struct {
+	Value     []byte `json:"value,omitempty"`
+	Revision  uint64 `json:"revision"`
+	Timestamp int64  `json:"timestamp"`
+
+	// Deleted versions mark the revision at which the key was removed
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// history holds the previous versions of a key in the order they were written
+type history struct {
+	Versions []Version `json:"versions"`
+
+	// Compacted is the highest revision that has been discarded from the history
+	Compacted uint64 `json:"compacted,omitempty"`
+}
+
+// trim discards the oldest versions beyond the limit, along with those written before the cutoff.
+// A limit of zero or a zero cutoff leaves the corresponding bound unenforced.
+func (h *history) trim(limit int, cutoff int64) {
+	drop := 0
+	if limit > 0 && len(h.Versions) > limit {
+		drop = len(h.Versions) - limit
+	}
+
+	if cutoff > 0 {
+		for drop < len(h.Versions) && h.Versions[drop].Timestamp < cutoff {
+			drop++
+		}
+	}
+
+	if drop == 0 {
+		return
+	}
+
+	h.Compacted = h.Versions[drop-1].Revision
+	h.Versions = append([]Version(nil), h.Versions[drop:]...)
+}
+
+// recordLocked appends a previous version of the key to its history.
+// It must only be called while holding the lock.
+func (f *fsm) recordLocked(t txn, source, key string, v Version) {
+	if f.HistoryLimit < 0 {
+		return
+	}
+
+	h := t.history(source, key)
+	if h == nil {
+		h = &history{}
+	}
+
+	h.Versions = append(h.Versions, v)
+	h.trim(f.HistoryLimit, 0)
+	t.putHistory(source, key, h)
+}
+
+// compactLocked enforces the configured retention on every history, discarding those of removed keys with no remaining
+// versions, or whose removal is older than the tombstone retention.  It must only be called while holding the lock.
+func (f *fsm) compactLocked(t txn, now time.Time) {
+	var cutoff, tombstoneCutoff int64
+	if f.HistoryMaxAge > 0 {
+		cutoff = now.Add(-f.HistoryMaxAge).UnixNano()
+	}
+
+	if f.TombstoneMaxAge > 0 {
+		tombstoneCutoff = now.Add(-f.TombstoneMaxAge).UnixNano()
+	}
+
+	// Changes are made once every history has been visited, since engines may not support modification during iteration
+	type compaction struct {
+		source, key string
+		h           *history
+	}
+
+	var compacted []compaction
+	t.forEachHistory(func(source, key string, h *history) {
+		versions, compactedAt := len(h.Versions), h.Compacted
+		h.trim(f.HistoryLimit, cutoff)
+
+		// The last version of a removed key is the tombstone recording its removal
+		if _, ok := t.get(source, key); !ok {
+			if n := len(h.Versions); n == 0 || (tombstoneCutoff > 0 && h.Versions[n-1].Timestamp < tombstoneCutoff) {
+				compacted = append(compacted, compaction{source, key, nil})
+				return
+			}
+		}
+
+		if len(h.Versions) != versions || h.Compacted != compactedAt {
+			compacted = append(compacted, compaction{source, key, h})
+		}
+	})
+
+	for _, c := range compacted {
+		t.putHistory(c.source, c.key, c.h)
+	}
+}
+
+// applyCompact enforces the retention of history as of the time the leader proposed the compaction, so that every
+// member discards the same versions at the same point in the log
+func (f *fsm) applyCompact(t txn) interface{} {
+	f.compactLocked(t, time.Unix(0, f.pending.timestamp))
+	return nil
+}
+
+// compactHistory runs for the lifetime of the store.  Only the leader proposes the compaction of history, so that
+// versions are discarded by age through the log rather than by the clock of each member.
+func (s *Store) compactHistory() {
+	ticker := time.NewTicker(historyCompactInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.IsLeader() || (s.HistoryMaxAge <= 0 && s.TombstoneMaxAge <= 0) {
+			continue
+		}
+
+		if _, err := s.apply(&command{Operation: operationCompact}); err != nil {
+			s.logger.Error("Failed to compact history.", "error", err)
+		}
+	}
+}
+
+// cloneHistory copies every history so that it may be persisted while changes continue to be applied
+func cloneHistory(o map[string]map[string]*history) map[string]map[string]*history {
+	clone := make(map[string]map[string]*history)
+	for s, m := range o {
+		clone[s] = make(map[string]*history)
+		for k, h := range m {
+			clone[s][k] = &history{
+				Versions:  append([]Version(nil), h.Versions...),
+				Compacted: h.Compacted,
+			}
+		}
+	}
+	return clone
+}
+
+// GetAt returns the value the key held at the given revision, along with the revision at which that value was written.
+// A revision of zero indicates that the key did not exist at the requested revision.  ErrCompacted is returned if the
+// version is no longer retained.
+func (s *Store) GetAt(source string, key string, revision uint64) ([]byte, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var value []byte
+	var at uint64
+	var err error
+	if verr := s.engine.view(func(t txn) {
+		value, at, err = getAt(t, source, key, revision)
+	}); verr != nil {
+		return nil, 0, verr
+	}
+	return value, at, err
+}
+
+func getAt(t txn, source string, key string, revision uint64) ([]byte, uint64, error) {
+	if e, ok := t.get(source, key); ok && e.Revision <= revision {
+		return e.Value, e.Revision, nil
+	}
+
+	h := t.history(source, key)
+	if h != nil {
+		for i := len(h.Versions) - 1; i >= 0; i-- {
+			v := h.Versions[i]
+			if v.Revision > revision {
+				continue
+			}
+
+			if v.Deleted {
+				return nil, 0, nil
+			}
+			return v.Value, v.Revision, nil
+		}
+
+		if h.Compacted > 0 {
+			return nil, 0, ErrCompacted
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// GetHistory returns the retained versions of the key in the order they were written, ending with its current value
+func (s *Store) GetHistory(source string, key string) []Version {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var versions []Version
+	if err := s.engine.view(func(t txn) {
+		if h := t.history(source, key); h != nil {
+			versions = append(versions, h.Versions...)
+		}
+
+		if e, ok := t.get(source, key); ok {
+			versions = append(versions, Version{Value: e.Value, Revision: e.Revision, Timestamp: e.Timestamp})
+		}
+	}); err != nil {
+		s.logger.Error("Failed to read history.", "source", source, "key", key, "error", err)
+	}
+	return versions
+}
@@ -0,0 +1,312 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	operationGrantLease     = "grantLease"
+	operationKeepAliveLease = "keepAliveLease"
+	operationRevokeLease    = "revokeLease"
+	operationExpireLease    = "expireLease"
+)
+
+// leaseCheckInterval is how often the leader looks for leases that have expired
+const leaseCheckInterval = 250 * time.Millisecond
+
+// ErrLeaseNotFound is returned when an operation refers to a lease that does not exist or has already expired
+var ErrLeaseNotFound = errors.New("The requested lease does not exist")
+
+// ErrInvalidTTL is returned when a lease or value is given a time-to-live that is not positive
+var ErrInvalidTTL = errors.New("The time-to-live must be greater than zero")
+
+// lease is a replicated time-to-live that keys may be attached to.  When the lease expires or is revoked,
+// every key attached to it is deleted.
+type lease struct {
+	ID  uint64        `json:"id"`
+	TTL time.Duration `json:"ttl"`
+
+	// Implicit leases are created for values set with a TTL, and are discarded once no keys remain attached
+	Implicit bool `json:"implicit,omitempty"`
+
+	// The deadline is tracked locally by each node from the time the lease was last granted or kept alive.
+	// Only the leader acts upon it, so clock differences between nodes do not affect which keys are removed.
+	expires time.Time
+	keys    map[string]map[string]struct{}
+}
+
+func newLease(id uint64, ttl time.Duration, implicit bool) *lease {
+	return &lease{
+		ID:       id,
+		TTL:      ttl,
+		Implicit: implicit,
+		expires:  time.Now().Add(ttl),
+		keys:     make(map[string]map[string]struct{}),
+	}
+}
+
+// attach the key to the lease
+func (l *lease) attach(source, key string) {
+	if l.keys[source] == nil {
+		l.keys[source] = make(map[string]struct{})
+	}
+	l.keys[source][key] = struct{}{}
+}
+
+// detach the key from the lease
+func (l *lease) detach(source, key string) {
+	if m, ok := l.keys[source]; ok {
+		delete(m, key)
+		if len(m) == 0 {
+			delete(l.keys, source)
+		}
+	}
+}
+
+// clone copies the lease along with the keys attached to it
+func (l *lease) clone() *lease {
+	c := *l
+	c.keys = make(map[string]map[string]struct{}, len(l.keys))
+	for source, keys := range l.keys {
+		c.keys[source] = make(map[string]struct{}, len(keys))
+		for key := range keys {
+			c.keys[source][key] = struct{}{}
+		}
+	}
+	return &c
+}
+
+// leaseLocked returns the lease with the given id, including the changes made by the update of the engine being made.
+// It must only be called while holding the lock.
+func (f *fsm) leaseLocked(id uint64) (*lease, bool) {
+	if f.pending != nil {
+		if l, ok := f.pending.leases[id]; ok {
+			return l, l != nil
+		}
+	}
+
+	l, ok := f.leases[id]
+	return l, ok
+}
+
+// stageLocked returns the lease with the given id to be changed by the update of the engine being made, which is a copy
+// that replaces the lease once the update has been committed.  It must only be called while holding the lock.
+func (f *fsm) stageLocked(id uint64) (*lease, bool) {
+	l, ok := f.leaseLocked(id)
+	if !ok || f.pending == nil {
+		return l, ok
+	}
+
+	if _, staged := f.pending.leases[id]; !staged {
+		l = l.clone()
+		f.pending.leases[id] = l
+	}
+	return l, true
+}
+
+// grantLocked stores the lease, which is added to the leases once the update of the engine being made has been committed.
+// It must only be called while holding the lock.
+func (f *fsm) grantLocked(t txn, l *lease) {
+	t.putLease(l)
+	f.pending.leases[l.ID] = l
+}
+
+// removeLocked removes the lease with the given id from storage, and from the leases once the update of the engine being
+// made has been committed.  It must only be called while holding the lock.
+func (f *fsm) removeLocked(t txn, id uint64) {
+	t.removeLease(id)
+	f.pending.leases[id] = nil
+}
+
+// attachLocked attaches the key to the lease with the given id, if any.
+// It must only be called while holding the lock.
+func (f *fsm) attachLocked(source, key string, id uint64) {
+	if l, ok := f.stageLocked(id); ok {
+		l.attach(source, key)
+	}
+}
+
+// detachLocked removes the key from the lease with the given id, discarding implicit leases that are no longer in use.
+// It must only be called while holding the lock.
+func (f *fsm) detachLocked(t txn, source, key string, id uint64) {
+	l, ok := f.stageLocked(id)
+	if !ok {
+		return
+	}
+
+	l.detach(source, key)
+	if l.Implicit && len(l.keys) == 0 {
+		f.removeLocked(t, id)
+	}
+}
+
+func (f *fsm) applyGrantLease(t txn, index uint64, ttl time.Duration) interface{} {
+	f.logger.Info("GRANT", "lease", index, "ttl", ttl)
+	f.grantLocked(t, newLease(index, ttl, false))
+
+	return &applyResponse{revision: index}
+}
+
+func (f *fsm) applyKeepAliveLease(id uint64) interface{} {
+	f.logger.Info("KEEPALIVE", "lease", id)
+	l, ok := f.leaseLocked(id)
+	if !ok {
+		return &applyResponse{err: ErrLeaseNotFound}
+	}
+	f.afterLocked(func() { l.expires = time.Now().Add(l.TTL) })
+
+	return &applyResponse{revision: id}
+}
+
+// applyRevokeLease deletes the lease and every key attached to it.  Expiring a lease that no longer exists is not an error,
+// since the leader may propose the expiration more than once before it is applied.
+func (f *fsm) applyRevokeLease(t txn, index uint64, id uint64, expired bool) interface{} {
+	if expired {
+		f.logger.Info("EXPIRE", "lease", id)
+	} else {
+		f.logger.Info("REVOKE", "lease", id)
+	}
+
+	l, ok := f.leaseLocked(id)
+	if !ok {
+		if expired {
+			return nil
+		}
+		return &applyResponse{err: ErrLeaseNotFound}
+	}
+
+	f.removeLocked(t, id)
+	for source, keys := range l.keys {
+		for key := range keys {
+			for _, u := range f.deleteKeyLocked(t, source, key, index) {
+				if expired && u.Operation == OperationDeleteKey {
+					u.Operation = OperationExpire
+				}
+				f.publishLocked(u)
+			}
+		}
+	}
+
+	return &applyResponse{revision: id}
+}
+
+// expiredLeases returns the ids of leases whose deadline has passed
+func (f *fsm) expiredLeases(now time.Time) []uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var expired []uint64
+	for id, l := range f.leases {
+		if now.After(l.expires) {
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// cloneLeases copies the persisted properties of the leases
+func cloneLeases(o map[uint64]*lease) []*lease {
+	leases := make([]*lease, 0, len(o))
+	for _, l := range o {
+		leases = append(leases, &lease{ID: l.ID, TTL: l.TTL, Implicit: l.Implicit})
+	}
+	return leases
+}
+
+// restoreLeasesLocked rebuilds the leases, attaching the keys found in storage and restarting each deadline.
+// It must only be called while holding the lock.
+func (f *fsm) restoreLeasesLocked(leases []*lease) error {
+	f.leases = make(map[uint64]*lease)
+	for _, l := range leases {
+		f.leases[l.ID] = newLease(l.ID, l.TTL, l.Implicit)
+	}
+
+	if len(f.leases) == 0 {
+		return nil
+	}
+
+	return f.engine.view(func(t txn) {
+		t.forEach(func(source, key string, e entry) {
+			f.attachLocked(source, key, e.Lease)
+		})
+	})
+}
+
+// loadLeasesLocked restores the leases retained by a persistent engine.
+// It must only be called while holding the lock.
+func (f *fsm) loadLeasesLocked() error {
+	var leases []*lease
+	if err := f.engine.view(func(t txn) {
+		t.forEachLease(func(l *lease) {
+			leases = append(leases, l)
+		})
+	}); err != nil {
+		return err
+	}
+	return f.restoreLeasesLocked(leases)
+}
+
+// expireLeases runs for the lifetime of the store.  Only the leader proposes the removal of expired leases,
+// so that every node deletes the same keys at the same point in the log.
+func (s *Store) expireLeases() {
+	ticker := time.NewTicker(leaseCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.IsLeader() {
+			continue
+		}
+
+		for _, id := range (*fsm)(s).expiredLeases(time.Now()) {
+			if _, err := s.apply(&command{Operation: operationExpireLease, Lease: id}); err != nil {
+				s.logger.Error("Failed to expire lease.", "lease", id, "error", err)
+			}
+		}
+	}
+}
+
+// GrantLease creates a lease that expires after the ttl unless kept alive, and returns its id
+func (s *Store) GrantLease(ttl time.Duration) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("GrantLease should only be called on the leader")
+	}
+
+	if ttl <= 0 {
+		return 0, ErrInvalidTTL
+	}
+
+	return s.apply(&command{Operation: operationGrantLease, TTL: ttl})
+}
+
+// KeepAliveLease restarts the time-to-live of the lease.  ErrLeaseNotFound is returned if the lease does not exist.
+func (s *Store) KeepAliveLease(id uint64) error {
+	if !s.IsLeader() {
+		return errors.New("KeepAliveLease should only be called on the leader")
+	}
+
+	_, err := s.apply(&command{Operation: operationKeepAliveLease, Lease: id})
+	return err
+}
+
+// RevokeLease deletes the lease along with every key attached to it.  ErrLeaseNotFound is returned if the lease does not exist.
+func (s *Store) RevokeLease(id uint64) error {
+	if !s.IsLeader() {
+		return errors.New("RevokeLease should only be called on the leader")
+	}
+
+	_, err := s.apply(&command{Operation: operationRevokeLease, Lease: id})
+	return err
+}
+
+// LeaseTTL returns the time-to-live the lease was granted with.  ErrLeaseNotFound is returned if the lease does not exist.
+func (s *Store) LeaseTTL(id uint64) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return 0, ErrLeaseNotFound
+	}
+	return l.TTL, nil
+}
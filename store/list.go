@@ -0,0 +1,83 @@
+package store
+
+import (
+	"sort"
+	"strings"
+)
+
+// ListOptions select a range of sources or keys, which are listed in lexical order.
+// Options left as their zero value are ignored.
+type ListOptions struct {
+	// Prefix limits the listing to names beginning with it
+	Prefix string
+
+	// Start is the first name listed, inclusive, and End is the name the listing stops before
+	Start string
+	End   string
+
+	// Limit is the maximum number of names listed
+	Limit int
+}
+
+// from returns the name the listing begins at
+func (o ListOptions) from() string {
+	if o.Start > o.Prefix {
+		return o.Start
+	}
+	return o.Prefix
+}
+
+// includes indicates whether the name is within the range
+func (o ListOptions) includes(name string) bool {
+	return strings.HasPrefix(name, o.Prefix) && name >= o.Start && (len(o.End) == 0 || name < o.End)
+}
+
+// past indicates whether the name, and every name following it, is beyond the range
+func (o ListOptions) past(name string) bool {
+	return (len(o.End) > 0 && name >= o.End) || (name > o.Prefix && !strings.HasPrefix(name, o.Prefix))
+}
+
+// full indicates whether the listing has reached its limit
+func (o ListOptions) full(names []string) bool {
+	return o.Limit > 0 && len(names) >= o.Limit
+}
+
+// list returns the names within the range in order, limited as required
+func (o ListOptions) list(names []string) []string {
+	var listed []string
+	for _, name := range names {
+		if o.includes(name) {
+			listed = append(listed, name)
+		}
+	}
+
+	sort.Strings(listed)
+	if o.full(listed) {
+		listed = listed[:o.Limit]
+	}
+	return listed
+}
+
+// ListSources returns the sources found in storage that are selected by opts, in lexical order
+func (s *Store) ListSources(opts ListOptions) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var response []string
+	err := s.engine.view(func(t txn) {
+		response = t.listSources(opts)
+	})
+	return response, err
+}
+
+// ListKeys returns the keys for the given source found in storage that are selected by opts, in lexical order
+func (s *Store) ListKeys(source string, opts ListOptions) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var response []string
+	err := s.engine.view(func(t txn) {
+		response = t.listKeys(source, opts)
+	})
+	return response, err
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const operationPeerAddress = "peerAddress"
+
+// Peer states reported for members of the cluster other than this store.  Only the leader knows which members follow
+// it, and any other store reports the members it does not know to lead the cluster as unknown.
+const (
+	PeerStateLeader   = "Leader"
+	PeerStateFollower = "Follower"
+	PeerStateUnknown  = "Unknown"
+)
+
+// Peer describes a member of the cluster as seen by this store.  LastContact and MatchIndex are only reported by the
+// leader, and hold the time the peer last responded to it and the index of the last entry the peer is known to hold.
+type Peer struct {
+	Address     string
+	State       string
+	GRPCAddress string
+	LastContact time.Time
+	MatchIndex  uint64
+}
+
+// replication records the responses of a peer to the entries appended by the leader during term
+type replication struct {
+	term        uint64
+	lastContact time.Time
+	matchIndex  uint64
+}
+
+// Status describes the raft state of this store along with the members of its cluster
+type Status struct {
+	Address      string
+	State        string
+	Leader       string
+	Term         uint64
+	CommitIndex  uint64
+	AppliedIndex uint64
+	Peers        []Peer
+}
+
+// RemovePeer removes the node located at addr from the cluster.  If the leader removes itself, a new leader
+// is elected by the remaining nodes.
+func (s *Store) RemovePeer(addr string) error {
+	if !s.IsLeader() {
+		return errors.New("RemovePeer should only be called on the leader")
+	}
+
+	s.logger.Info("Received remove request for node", "address", addr)
+	if err := s.raft.RemovePeer(addr).Error(); err != nil {
+		if err == raft.ErrUnknownPeer {
+			s.logger.Info("Removed node is not a peer in this cluster", "address", addr)
+			return nil
+		}
+
+		return err
+	}
+
+	s.logger.Info("Node successfully removed", "address", addr)
+
+	// A leader that removed itself leaves its address to be recorded again should it rejoin
+	if s.IsLeader() {
+		return s.SetPeerAddress(addr, "")
+	}
+	return nil
+}
+
+// Leave removes this store from the cluster, after which its raft consensus mechanism shuts down.
+// Leave should only be called on the leader, as followers must ask the leader to remove them.
+// The last member of a cluster does not leave it, so that it can be restarted with its data.
+func (s *Store) Leave() error {
+	peers, err := s.ListPeers()
+	if err != nil {
+		return err
+	}
+
+	if len(peers) < 2 {
+		s.logger.Info("Not leaving the cluster as this node is its last member")
+		return nil
+	}
+	return s.RemovePeer(s.RaftAddr())
+}
+
+// ListPeers returns the raft addresses of the members of the cluster, including this store, in sorted order
+func (s *Store) ListPeers() ([]string, error) {
+	if s.peers == nil {
+		return nil, errors.New("ListPeers should only be called once the store is open")
+	}
+
+	peers, err := s.peers.Peers()
+	if err != nil {
+		return nil, err
+	}
+
+	// A store started as the leader of a new cluster has yet to record itself as a peer
+	if !raft.PeerContained(peers, s.RaftAddr()) {
+		peers = append(peers, s.RaftAddr())
+	}
+
+	sort.Strings(peers)
+	return peers, nil
+}
+
+// Status returns the raft state of this store along with the state of each member of the cluster.  The leader reports
+// each peer that responded to it within an election timeout as a follower, and any other as unknown, while other stores
+// only report which peer is the leader.
+func (s *Store) Status() (*Status, error) {
+	peers, err := s.ListPeers()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.raft.Stats()
+	status := &Status{
+		Address:      s.RaftAddr(),
+		State:        s.raft.State().String(),
+		Leader:       s.Leader(),
+		Term:         parseStat(stats["term"]),
+		CommitIndex:  parseStat(stats["commit_index"]),
+		AppliedIndex: parseStat(stats["applied_index"]),
+	}
+
+	leading := status.State == raft.Leader.String()
+	for _, addr := range peers {
+		peer := Peer{Address: addr, State: PeerStateUnknown}
+		peer.GRPCAddress, _ = s.PeerAddress(addr)
+		switch {
+		case addr == status.Address:
+			peer.State = status.State
+		case addr == status.Leader:
+			peer.State = PeerStateLeader
+		case leading:
+			if r, ok := s.replicationOf(addr, status.Term); ok {
+				peer.LastContact, peer.MatchIndex = r.lastContact, r.matchIndex
+				if time.Since(r.lastContact) < s.electionTimeout {
+					peer.State = PeerStateFollower
+				}
+			}
+		}
+		status.Peers = append(status.Peers, peer)
+	}
+	return status, nil
+}
+
+// replicated records the response of peer to entries appended by the leader, unless the peer holds a newer term
+func (s *Store) replicated(peer string, req *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) {
+	if resp.Term > req.Term {
+		return
+	}
+
+	s.replicationMu.Lock()
+	defer s.replicationMu.Unlock()
+
+	r := s.replication[peer]
+	if r.term != req.Term {
+		r = replication{term: req.Term}
+	}
+
+	r.lastContact = time.Now()
+	if n := len(req.Entries); resp.Success && n > 0 {
+		r.matchIndex = req.Entries[n-1].Index
+	}
+	s.replication[peer] = r
+}
+
+// replicationOf returns the responses of peer to the entries appended by the leader during term, if it responded
+func (s *Store) replicationOf(peer string, term uint64) (replication, bool) {
+	s.replicationMu.Lock()
+	defer s.replicationMu.Unlock()
+
+	r, ok := s.replication[peer]
+	return r, ok && r.term == term
+}
+
+// SetPeerAddress records the grpc address advertised by the member of the cluster at the raft address peer, so that
+// every member can forward requests to it, and must be called on the leader.  An empty address removes the record.
+func (s *Store) SetPeerAddress(peer, address string) error {
+	if !s.IsLeader() {
+		return errors.New("SetPeerAddress should only be called on the leader")
+	}
+
+	// Members record their address each time they start, which only needs to reach the log when it changes
+	if current, ok := s.PeerAddress(peer); current == address && (ok || len(address) == 0) {
+		return nil
+	}
+
+	s.logger.Info("Recording peer address", "peer", peer, "address", address)
+	_, err := s.apply(&command{Operation: operationPeerAddress, Peer: peer, Address: address})
+	return err
+}
+
+// PeerAddress returns the grpc address advertised by the member of the cluster at the raft address peer, if recorded
+func (s *Store) PeerAddress(peer string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var address string
+	var ok bool
+	if err := s.engine.view(func(t txn) {
+		address, ok = t.address(peer)
+	}); err != nil {
+		s.logger.Error("Failed to read peer address.", "peer", peer, "error", err)
+	}
+	return address, ok
+}
+
+// applyPeerAddress records the grpc address of the peer, where an empty address removes the record
+func (f *fsm) applyPeerAddress(t txn, index uint64, peer, address string) interface{} {
+	if len(address) == 0 {
+		t.removeAddress(peer)
+	} else {
+		t.putAddress(peer, address)
+	}
+	return &applyResponse{revision: index}
+}
+
+// parseStat returns the value of a numeric raft statistic, or zero if it is missing
+func parseStat(stat string) uint64 {
+	v, _ := strconv.ParseUint(stat, 10, 64)
+	return v
+}
@@ -0,0 +1,178 @@
+package store
+
+import "sync"
+
+// OperationExpire describes an update removing a key because its lease expired
+const OperationExpire = "expire"
+
+// OperationSnapshot describes an update carrying the current value of a key, or a source that exists,
+// as captured by Snapshot or SnapshotSources
+const OperationSnapshot = "snapshot"
+
+// Operations of the updates published when a source is created by setting its first key, and deleted by
+// removing its last key.  These updates carry no key, and follow the update to the key that caused them.
+const (
+	OperationSourceCreated = "source-created"
+	OperationSourceDeleted = "source-deleted"
+)
+
+// Update describes a change applied to a key, which is published once the change has been applied
+type Update struct {
+	Source string
+	Key    string
+	Value  []byte
+
+	// Operation is the change made to the key, one of OperationSet, OperationDeleteKey, OperationDeleteSource or OperationExpire,
+	// or OperationSnapshot when the update describes the current value of the key.  Updates to the list of sources carry
+	// no key, and are one of OperationSourceCreated, OperationSourceDeleted or OperationSnapshot.
+	Operation string
+
+	// Index is the raft log index the change was applied at, and Transaction is set to the same index
+	// when the change was made by a transaction
+	Index       uint64
+	Transaction uint64
+
+	// Timestamp is the time the change was applied, in nanoseconds since the Unix epoch
+	Timestamp int64
+
+	// PreviousValue is the value the key held before the change, where a PreviousRevision of zero
+	// indicates that the key did not exist
+	PreviousValue    []byte
+	PreviousRevision uint64
+}
+
+// publisher delivers updates to the UpdateCallback one at a time, in the order they were applied,
+// without holding up the application of later log entries.  The updates applied while the callback is
+// called are queued in memory without bound, so the callback should not block for long.
+type publisher struct {
+	mu      sync.Mutex
+	pending []publication
+	active  bool
+}
+
+// publication is either an update to deliver to the UpdateCallback, or a function to call
+// once every update queued before it has been delivered
+type publication struct {
+	update *Update
+	fn     func()
+}
+
+// publishLocked queues the update for delivery once the update of the engine being made has been committed.
+// It must only be called while holding the lock, so that updates are queued in the order their entries were applied.
+func (f *fsm) publishLocked(u *Update) {
+	if f.UpdateCallback == nil && f.PublishCallback == nil {
+		return
+	}
+	f.pending.updates = append(f.pending.updates, u)
+}
+
+// queueLocked queues the publication for delivery.  It must only be called while holding the lock.
+func (f *fsm) queueLocked(pub publication) {
+	p := &f.updates
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = append(p.pending, pub)
+	if !p.active {
+		p.active = true
+		go f.deliver()
+	}
+}
+
+// deliver calls the UpdateCallback, or the deprecated PublishCallback, for each queued update, and each queued
+// function, until none remain
+func (f *fsm) deliver() {
+	p := &f.updates
+	for {
+		p.mu.Lock()
+		if len(p.pending) == 0 {
+			p.active = false
+			p.mu.Unlock()
+			return
+		}
+
+		pub := p.pending[0]
+		p.pending[0] = publication{}
+		p.pending = p.pending[1:]
+		p.mu.Unlock()
+
+		if pub.fn != nil {
+			pub.fn()
+		} else if callback := f.UpdateCallback; callback != nil {
+			callback(pub.update)
+		} else if callback := f.PublishCallback; callback != nil && len(pub.update.Key) > 0 {
+			callback(pub.update.Source, pub.update.Key, pub.update.Value, pub.update.Transaction)
+		}
+	}
+}
+
+// Snapshot captures the current value of every key of the source, or of the key alone when key is not empty,
+// along with the index the store has applied.  The function is called with the snapshot once every update
+// applied at or before the index has been published, and before any later update is published, so that
+// the snapshot can be followed by the updates applied after it.
+func (s *Store) Snapshot(source string, key string, fn func(snapshot []*Update, index uint64)) error {
+	return s.snapshot(func(t txn) []*Update {
+		keys := []string{key}
+		if len(key) == 0 {
+			keys = t.listKeys(source, ListOptions{})
+		}
+
+		var snapshot []*Update
+		for _, k := range keys {
+			if e, ok := t.get(source, k); ok {
+				snapshot = append(snapshot, &Update{
+					Source:    source,
+					Key:       k,
+					Value:     e.Value,
+					Operation: OperationSnapshot,
+					Index:     e.Revision,
+					Timestamp: e.Timestamp,
+				})
+			}
+		}
+		return snapshot
+	}, fn)
+}
+
+// SnapshotSources captures the sources that currently exist, each described by an update without a key,
+// and calls the function with them as described by Snapshot
+func (s *Store) SnapshotSources(fn func(snapshot []*Update, index uint64)) error {
+	return s.snapshot(func(t txn) []*Update {
+		var snapshot []*Update
+		for _, source := range t.listSources(ListOptions{}) {
+			snapshot = append(snapshot, &Update{Source: source, Operation: OperationSnapshot})
+		}
+		return snapshot
+	}, fn)
+}
+
+// snapshot captures the updates returned by capture under the lock, and queues the function to be called with them
+func (s *Store) snapshot(capture func(t txn) []*Update, fn func(snapshot []*Update, index uint64)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snapshot []*Update
+	var index uint64
+	err := s.engine.view(func(t txn) {
+		snapshot = capture(t)
+		index = s.engine.lastApplied()
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range snapshot {
+		if len(u.Key) == 0 {
+			u.Index = index
+		}
+	}
+
+	call := func() { fn(snapshot, index) }
+	if s.UpdateCallback == nil && s.PublishCallback == nil {
+		go call()
+		return nil
+	}
+
+	(*fsm)(s).queueLocked(publication{fn: call})
+	return nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrStale is returned when a node has been out of contact with the leader for longer than a read allows
+var ErrStale = errors.New("The node has been out of contact with the leader for longer than the maximum staleness")
+
+// AppliedIndex returns the index of the last raft log entry applied to the local state machine
+func (s *Store) AppliedIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.lastApplied()
+}
+
+// Staleness returns how long it has been since this node was last in contact with the leader.
+// The leader is never stale, while a node that has never been in contact with a leader is stale indefinitely.
+func (s *Store) Staleness() time.Duration {
+	if s.raft == nil {
+		return time.Duration(math.MaxInt64)
+	}
+
+	if s.IsLeader() {
+		return 0
+	}
+
+	contact := s.raft.LastContact()
+	if contact.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Since(contact)
+}
+
+// CheckStaleness returns ErrStale if this node has been out of contact with the leader for longer than
+// maxStaleness, where zero allows any staleness
+func (s *Store) CheckStaleness(maxStaleness time.Duration) error {
+	if maxStaleness > 0 && s.Staleness() > maxStaleness {
+		return ErrStale
+	}
+	return nil
+}
+
+// VerifyRead confirms that this node is still the leader and has applied every entry committed before the call,
+// so that a read served from the local state machine afterwards is linearizable
+func (s *Store) VerifyRead() error {
+	if !s.IsLeader() {
+		return errors.New("VerifyRead should only be called on the leader")
+	}
+
+	// The barrier is only committed once a quorum has acknowledged this node's leadership,
+	// and is only applied after every preceding entry
+	return s.raft.Barrier(raftTimeout).Error()
+}
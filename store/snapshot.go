@@ -0,0 +1,412 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+)
+
+// Snapshots are persisted as a header followed by a stream of records, each of which is a record type byte,
+// the length of the encoded protobuf message as a uvarint, and the message itself.  The stream is terminated
+// by an end record and the CRC-32 (Castagnoli) checksum of everything that precedes it.  Since version 2,
+// the first record holds the raft log index the snapshot was taken at.
+const (
+	snapshotMagic         = "IRIS"
+	snapshotFormatVersion = 2
+
+	// maxSnapshotRecordSize guards against allocating huge buffers when reading a corrupt snapshot
+	maxSnapshotRecordSize = 1 << 30
+)
+
+const (
+	recordEntry byte = iota + 1
+	recordLease
+	recordHistory
+	recordVersion
+	recordEnd
+	recordIndex
+	recordAddress
+)
+
+// jsonSnapshotVersion identifies the JSON snapshots persisted before the binary format was introduced
+const jsonSnapshotVersion = 1
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrSnapshotChecksum is returned when a snapshot being restored does not match its checksum
+var ErrSnapshotChecksum = errors.New("The snapshot checksum does not match its contents")
+
+// jsonSnapshot is the representation of the state machine used by JSON snapshots.
+// Snapshots persisted before leases were introduced contain only the storage map.
+type jsonSnapshot struct {
+	Version int            `json:"version"`
+	Storage map[string]kvs `json:"storage"`
+	Leases  []*lease       `json:"leases,omitempty"`
+
+	History map[string]map[string]*history `json:"history,omitempty"`
+}
+
+type fsmSnapshot struct {
+	index    uint64
+	contents txn
+	leases   []*lease
+	release  func()
+}
+
+func (f *fsmSnapshot) Persist(s raft.SnapshotSink) error {
+	err := func() error {
+		if err := f.write(s); err != nil {
+			return err
+		}
+
+		if err := s.Close(); err != nil {
+			return err
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		s.Cancel()
+		return err
+	}
+
+	return nil
+}
+
+func (f *fsmSnapshot) Release() {
+	f.release()
+}
+
+// write the snapshot to w incrementally, so that only a single record is encoded in memory at a time
+func (f *fsmSnapshot) write(w io.Writer) error {
+	crc := crc32.New(crcTable)
+	sw := &snapshotWriter{w: bufio.NewWriter(io.MultiWriter(w, crc))}
+
+	sw.header()
+	sw.record(recordIndex, &pb.SnapshotIndex{Index: f.index})
+	for _, l := range f.leases {
+		sw.record(recordLease, &pb.SnapshotLease{
+			Id:       l.ID,
+			Ttl:      int64(l.TTL),
+			Implicit: l.Implicit,
+		})
+	}
+
+	f.contents.forEachAddress(func(peer, address string) {
+		sw.record(recordAddress, &pb.SnapshotAddress{Peer: peer, Address: address})
+	})
+
+	f.contents.forEach(func(source, key string, e entry) {
+		sw.record(recordEntry, &pb.SnapshotEntry{
+			Source:    source,
+			Key:       key,
+			Value:     e.Value,
+			Revision:  e.Revision,
+			Lease:     e.Lease,
+			Timestamp: e.Timestamp,
+		})
+	})
+
+	f.contents.forEachHistory(func(source, key string, h *history) {
+		sw.record(recordHistory, &pb.SnapshotHistory{
+			Source:    source,
+			Key:       key,
+			Compacted: h.Compacted,
+		})
+
+		for _, v := range h.Versions {
+			sw.record(recordVersion, &pb.SnapshotVersion{
+				Source:    source,
+				Key:       key,
+				Value:     v.Value,
+				Revision:  v.Revision,
+				Timestamp: v.Timestamp,
+				Deleted:   v.Deleted,
+			})
+		}
+	})
+
+	sw.record(recordEnd, nil)
+	if sw.err != nil {
+		return sw.err
+	}
+
+	if err := sw.w.Flush(); err != nil {
+		return err
+	}
+
+	// The checksum itself is written directly so that it is not included in the sum
+	return binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// snapshotWriter encodes records, retaining the first error encountered
+type snapshotWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *snapshotWriter) header() {
+	if sw.err != nil {
+		return
+	}
+
+	if _, sw.err = sw.w.WriteString(snapshotMagic); sw.err != nil {
+		return
+	}
+	sw.err = sw.w.WriteByte(snapshotFormatVersion)
+}
+
+func (sw *snapshotWriter) record(t byte, m proto.Message) {
+	if sw.err != nil {
+		return
+	}
+
+	var b []byte
+	if m != nil {
+		if b, sw.err = proto.Marshal(m); sw.err != nil {
+			return
+		}
+	}
+
+	if sw.err = sw.w.WriteByte(t); sw.err != nil {
+		return
+	}
+
+	n := binary.PutUvarint(sw.buf[:], uint64(len(b)))
+	if _, sw.err = sw.w.Write(sw.buf[:n]); sw.err != nil {
+		return
+	}
+	_, sw.err = sw.w.Write(b)
+}
+
+// checksumReader hashes every byte read through it
+type checksumReader struct {
+	r *bufio.Reader
+	h hash.Hash32
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	return n, err
+}
+
+func (c *checksumReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.h.Write([]byte{b})
+	}
+	return b, err
+}
+
+// snapshotLoader writes the contents of a snapshot to the txn, returning the leases it holds
+type snapshotLoader func(t txn) ([]*lease, error)
+
+// openSnapshot reads a snapshot in either the binary format or the JSON format used by earlier releases,
+// returning the index it was taken at along with a function that loads its contents.  The index of
+// snapshots that do not record it is zero.
+func openSnapshot(r io.Reader) (uint64, snapshotLoader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(snapshotMagic))
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+
+	if string(magic) != snapshotMagic {
+		return openJSONSnapshot(br)
+	}
+	return openBinarySnapshot(br)
+}
+
+func openJSONSnapshot(r io.Reader) (uint64, snapshotLoader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var snap jsonSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil || snap.Version != jsonSnapshotVersion {
+		snap = jsonSnapshot{}
+		if err := json.Unmarshal(b, &snap.Storage); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return 0, func(t txn) ([]*lease, error) {
+		for source, keys := range snap.Storage {
+			for key, e := range keys {
+				t.put(source, key, e)
+			}
+		}
+
+		for source, keys := range snap.History {
+			for key, h := range keys {
+				t.putHistory(source, key, h)
+			}
+		}
+
+		for _, l := range snap.Leases {
+			t.putLease(l)
+		}
+		return snap.Leases, nil
+	}, nil
+}
+
+func openBinarySnapshot(br *bufio.Reader) (uint64, snapshotLoader, error) {
+	cr := &checksumReader{r: br, h: crc32.New(crcTable)}
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return 0, nil, err
+	}
+
+	version := header[len(snapshotMagic)]
+	if version != 1 && version != snapshotFormatVersion {
+		return 0, nil, fmt.Errorf("Unsupported snapshot format version %d", version)
+	}
+
+	var index uint64
+	if version >= 2 {
+		t, buf, err := readRecord(cr)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if t != recordIndex {
+			return 0, nil, fmt.Errorf("Snapshot begins with record type %d rather than its index", t)
+		}
+
+		var m pb.SnapshotIndex
+		if err := proto.Unmarshal(buf, &m); err != nil {
+			return 0, nil, err
+		}
+		index = m.Index
+	}
+
+	return index, func(t txn) ([]*lease, error) {
+		return loadBinarySnapshot(br, cr, t)
+	}, nil
+}
+
+// loadBinarySnapshot writes the records following the index to the txn, verifying the checksum once the end is reached
+func loadBinarySnapshot(br *bufio.Reader, cr *checksumReader, tx txn) ([]*lease, error) {
+	var leases []*lease
+
+	// The versions of a key follow its history record, so they are accumulated and written together
+	var source, key string
+	var h *history
+	flush := func() {
+		if h != nil {
+			tx.putHistory(source, key, h)
+		}
+		h = nil
+	}
+
+	for {
+		t, buf, err := readRecord(cr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t {
+		case recordEntry:
+			var m pb.SnapshotEntry
+			if err := proto.Unmarshal(buf, &m); err != nil {
+				return nil, err
+			}
+			tx.put(m.Source, m.Key, entry{Value: m.Value, Revision: m.Revision, Lease: m.Lease, Timestamp: m.Timestamp})
+		case recordLease:
+			var m pb.SnapshotLease
+			if err := proto.Unmarshal(buf, &m); err != nil {
+				return nil, err
+			}
+
+			l := &lease{ID: m.Id, TTL: time.Duration(m.Ttl), Implicit: m.Implicit}
+			tx.putLease(l)
+			leases = append(leases, l)
+		case recordAddress:
+			var m pb.SnapshotAddress
+			if err := proto.Unmarshal(buf, &m); err != nil {
+				return nil, err
+			}
+			tx.putAddress(m.Peer, m.Address)
+		case recordHistory:
+			var m pb.SnapshotHistory
+			if err := proto.Unmarshal(buf, &m); err != nil {
+				return nil, err
+			}
+
+			flush()
+			source, key, h = m.Source, m.Key, &history{Compacted: m.Compacted}
+		case recordVersion:
+			var m pb.SnapshotVersion
+			if err := proto.Unmarshal(buf, &m); err != nil {
+				return nil, err
+			}
+
+			if h == nil || m.Source != source || m.Key != key {
+				flush()
+				source, key, h = m.Source, m.Key, &history{}
+			}
+			h.Versions = append(h.Versions, Version{Value: m.Value, Revision: m.Revision, Timestamp: m.Timestamp, Deleted: m.Deleted})
+		case recordEnd:
+			flush()
+
+			var sum uint32
+			if err := binary.Read(br, binary.BigEndian, &sum); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+
+			if sum != cr.h.Sum32() {
+				return nil, ErrSnapshotChecksum
+			}
+			return leases, nil
+		default:
+			return nil, fmt.Errorf("Unrecognized snapshot record type %d", t)
+		}
+	}
+}
+
+// readRecord reads the type and encoded message of the next record
+func readRecord(cr *checksumReader) (byte, []byte, error) {
+	t, err := cr.ReadByte()
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+
+	size, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+
+	if size > maxSnapshotRecordSize {
+		return 0, nil, fmt.Errorf("Snapshot record of %d bytes exceeds the maximum size", size)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(cr, buf); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return t, buf, nil
+}
+
+// unexpectedEOF reports a snapshot that ended before its end record as truncated
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package store
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -24,37 +26,169 @@ const (
 	operationSet          = "set"
 	operationDeleteKey    = "deletekey"
 	operationDeleteSource = "deleteSource"
+	operationTransaction  = "transaction"
 )
 
+// Operations that may be included in a transaction
+const (
+	OperationSet          = operationSet
+	OperationDeleteKey    = operationDeleteKey
+	OperationDeleteSource = operationDeleteSource
+)
+
+// Guards that may be used to make a transaction conditional
+const (
+	GuardKeyExists      = "exists"
+	GuardRevisionEquals = "revision"
+	GuardValueEquals    = "value"
+)
+
+// ErrRevisionMismatch is returned when a conditional operation finds the key at a different revision than expected
+var ErrRevisionMismatch = errors.New("The current revision of the key does not match the expected revision")
+
+// ErrGuardFailed is returned when a transaction was not applied because one of its guards was not satisfied
+var ErrGuardFailed = errors.New("One or more transaction guards were not satisfied")
+
+// TxnGuard is a condition that must be satisfied for a transaction to be applied
+type TxnGuard struct {
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	Key      string `json:"key"`
+	Revision uint64 `json:"revision,omitempty"`
+	Value    []byte `json:"value,omitempty"`
+}
+
+// TxnOp is a change applied as part of a transaction
+type TxnOp struct {
+	Operation string `json:"operation"`
+	Source    string `json:"source"`
+	Key       string `json:"key,omitempty"`
+	Value     []byte `json:"value,omitempty"`
+}
+
+// command is a change to the state machine replicated through the raft log.
+// The json tags are retained so that entries written by earlier releases can still be decoded.
 type command struct {
-	Operation string `json:"operation, omitempty"`
-	Source    string `json:"source, omitempty"`
-	Key       string `json:"key, omitempty"`
-	Value     []byte `json:"value, omitempty"`
+	Operation   string     `json:"operation,omitempty"`
+	Source      string     `json:"source,omitempty"`
+	Key         string     `json:"key,omitempty"`
+	Value       []byte     `json:"value,omitempty"`
+	Conditional bool       `json:"conditional,omitempty"`
+	Revision    uint64     `json:"revision,omitempty"`
+	Guards      []TxnGuard `json:"guards,omitempty"`
+	Operations  []TxnOp    `json:"operations,omitempty"`
+
+	Lease uint64        `json:"lease,omitempty"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+
+	Peer     string `json:"peer,omitempty"`
+	Deadline int64  `json:"deadline,omitempty"`
+	Address  string `json:"address,omitempty"`
+
+	// Timestamp is the time, in nanoseconds since the epoch, at which the leader proposed the command.  Every member
+	// applies the command at this time, so that the timestamps of the values and versions it writes are identical.
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
 
-type kvs map[string][]byte
+// entry is a value in storage along with the raft log index at which it was last modified
+// and the lease it is attached to, if any
+type entry struct {
+	Value    []byte `json:"value"`
+	Revision uint64 `json:"revision"`
+	Lease    uint64 `json:"lease,omitempty"`
+
+	// Timestamp is the time, in nanoseconds since the epoch, at which the leader proposed the value
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// UnmarshalJSON also accepts snapshots persisted before revisions were tracked, where each key mapped directly to its value
+func (e *entry) UnmarshalJSON(b []byte) error {
+	var value []byte
+	if err := json.Unmarshal(b, &value); err == nil {
+		*e = entry{Value: value}
+		return nil
+	}
+
+	type plain entry
+	return json.Unmarshal(b, (*plain)(e))
+}
+
+type kvs map[string]entry
 
 // Store is a collection of key-value stores, where all changes are made via Raft consensus
 type Store struct {
-	RaftBindAddr    string
-	RaftDir         string
-	PublishCallback func(source, key string, value []byte)
-
-	raft   *raft.Raft
-	logger *fglog.Logger
+	RaftBindAddr string
+	RaftDir      string
+
+	// RaftAdvertiseAddr is the raft address the other members of the cluster reach this store at, which is required
+	// when RaftBindAddr is not reachable by them, such as when it binds every interface or the store is behind NAT.
+	// RaftBindAddr is advertised when it is empty.
+	RaftAdvertiseAddr string
+
+	// RaftTLSConfig secures raft communications between the members of the cluster with mutual TLS when provided.
+	// It must hold the certificate of this member, the certificate authorities verifying the other members, and the
+	// server name their certificates are issued to.  Communications are unencrypted when it is nil.
+	RaftTLSConfig *tls.Config
+
+	// UpdateCallback is called with each update once it has been applied, one at a time in the order
+	// the updates were applied
+	UpdateCallback func(u *Update)
+
+	// PublishCallback is called with the source, key, value and transaction of each update to a key when no
+	// UpdateCallback is set, where the value of a removed key is nil.
+	//
+	// Deprecated: Use UpdateCallback, which also describes the operation, revision and previous value of each
+	// update, along with changes to the list of sources.
+	PublishCallback func(source, key string, value []byte, transaction uint64)
+
+	// HistoryLimit is the number of previous versions retained for each key, where zero retains every version
+	// until it is discarded by HistoryMaxAge, and a negative limit disables history
+	HistoryLimit int
+
+	// HistoryMaxAge is the duration previous versions are retained for, where zero retains them regardless of age.
+	// Versions older than this are discarded by compactions the leader proposes through the log every minute.
+	HistoryMaxAge time.Duration
+
+	// TombstoneMaxAge is the duration the history of a removed key is retained for once it was removed, after which
+	// the history is discarded by a compaction, where zero retains it until its versions are discarded by age.
+	TombstoneMaxAge time.Duration
+
+	// Storage selects the engine holding the contents of the store when it is opened.  StorageMemory is used
+	// unless StorageBolt is selected, which persists the contents in the raft directory so that they need not
+	// fit in memory and restarts only apply the log entries written since the store was last open.
+	Storage string
+
+	raft     *raft.Raft
+	raftAddr string
+	peers    raft.PeerStore
+	logger   *fglog.Logger
+	updates  publisher
 
 	mu      sync.Mutex
-	storage map[string]kvs
+	engine  engine
+	leases  map[uint64]*lease
+	pending *pendingChanges
+
+	transferMu      sync.Mutex
+	transfer        *leadershipTransfer
+	electionTimeout time.Duration
+
+	replicationMu sync.Mutex
+	replication   map[string]replication
 }
 
 // NewStore initializes a new store with the provided properties
 func NewStore(raftBindAddr, raftDir string, logger fglog.Logger) *Store {
 	return &Store{
-		RaftBindAddr: raftBindAddr,
-		RaftDir:      raftDir,
-		storage:      make(map[string]kvs),
-		logger:       &logger,
+		RaftBindAddr:    raftBindAddr,
+		RaftDir:         raftDir,
+		engine:          newMemoryEngine(),
+		leases:          make(map[uint64]*lease),
+		replication:     make(map[string]replication),
+		HistoryLimit:    DefaultHistoryLimit,
+		TombstoneMaxAge: DefaultTombstoneMaxAge,
+		Storage:         StorageMemory,
+		logger:          &logger,
 	}
 }
 
@@ -62,18 +196,29 @@ func NewStore(raftBindAddr, raftDir string, logger fglog.Logger) *Store {
 func (s *Store) Open(startAsLeader bool) error {
 	// Setup raft configuration
 	config := raft.DefaultConfig()
+	s.electionTimeout = config.ElectionTimeout
 
 	// Setup raft communication
-	addr, err := net.ResolveTCPAddr("tcp", s.RaftBindAddr)
+	addr, err := net.ResolveTCPAddr("tcp", s.RaftAddr())
 	if err != nil {
 		return err
 	}
 
-	transport, err := raft.NewTCPTransport(s.RaftBindAddr, addr, 3, raftTimeout, os.Stdout)
+	var netTransport *raft.NetworkTransport
+	if s.RaftTLSConfig != nil {
+		netTransport, err = newTLSTransport(s.RaftBindAddr, addr, s.RaftTLSConfig, 3, raftTimeout, os.Stdout)
+	} else {
+		netTransport, err = raft.NewTCPTransport(s.RaftBindAddr, addr, 3, raftTimeout, os.Stdout)
+	}
+
 	if err != nil {
 		return err
 	}
 
+	// Raft identifies this store by the resolved form of the advertised address
+	s.raftAddr = netTransport.LocalAddr()
+	transport := newTransferTransport(s, netTransport)
+
 	// Create the peer store
 	peerStore := raft.NewJSONPeers(s.RaftDir, transport)
 
@@ -97,6 +242,11 @@ func (s *Store) Open(startAsLeader bool) error {
 		return err
 	}
 
+	// Open the storage engine, which may already hold the contents from a previous run
+	if err := s.openEngine(); err != nil {
+		return err
+	}
+
 	// Create the boltdb store (log and stable stores)
 	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(s.RaftDir, "raft.db"))
 	if err != nil {
@@ -110,9 +260,37 @@ func (s *Store) Open(startAsLeader bool) error {
 	}
 
 	s.raft = r
+	s.peers = peerStore
+	go s.expireLeases()
+	go s.compactHistory()
 	return nil
 }
 
+// openEngine replaces the contents of the store with those of the selected storage engine
+func (s *Store) openEngine() error {
+	e, err := newEngine(s.Storage, s.RaftDir)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.engine = e
+	return (*fsm)(s).loadLeasesLocked()
+}
+
+// RaftAddr returns the raft address that identifies this store to the other members of the cluster
+func (s *Store) RaftAddr() string {
+	switch {
+	case len(s.raftAddr) > 0:
+		return s.raftAddr
+	case len(s.RaftAdvertiseAddr) > 0:
+		return s.RaftAdvertiseAddr
+	default:
+		return s.RaftBindAddr
+	}
+}
+
 // IsLeader indicates whether this store is currently the leader of the cluster
 func (s *Store) IsLeader() bool {
 	if s.raft == nil {
@@ -129,54 +307,117 @@ func (s *Store) Leader() string {
 	return s.raft.Leader()
 }
 
-// Set the value for the given source and key in storage
-func (s *Store) Set(source string, key string, value []byte) error {
-	if !s.IsLeader() {
-		return errors.New("Set should only be called on the leader")
+// apply the command via raft consensus and return the resulting revision
+func (s *Store) apply(c *command) (uint64, error) {
+	// Writes would extend the log beyond the peer leadership is being transferred to
+	if transfer, ok := s.transferring(); ok && transfer.peer != s.RaftAddr() && c.Operation != operationTransferLeadership {
+		return 0, ErrTransferring
 	}
 
-	c := &command{Operation: operationSet, Source: source, Key: key, Value: value}
-	b, err := json.Marshal(c)
+	c.Timestamp = time.Now().UnixNano()
+	b, err := encodeCommand(c)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	f := s.raft.Apply(b, raftTimeout)
+	if err := f.Error(); err != nil {
+		return 0, err
 	}
 
-	return s.raft.Apply(b, raftTimeout).Error()
+	if resp, ok := f.Response().(*applyResponse); ok && resp != nil {
+		return resp.revision, resp.err
+	}
+	return 0, nil
 }
 
-// GetSources returns a list of sources found in storage
-func (s *Store) GetSources() ([]string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Set the value for the given source and key in storage and return its new revision
+func (s *Store) Set(source string, key string, value []byte) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("Set should only be called on the leader")
+	}
+
+	return s.apply(&command{Operation: operationSet, Source: source, Key: key, Value: value})
+}
 
-	var response []string
-	for k := range s.storage {
-		response = append(response, k)
+// CompareAndSet sets the value for the given source and key only if the key is currently at the provided revision.
+// A revision of zero indicates that the key must not exist.  ErrRevisionMismatch is returned if the condition fails.
+func (s *Store) CompareAndSet(source string, key string, value []byte, revision uint64) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("CompareAndSet should only be called on the leader")
 	}
-	return response, nil
+
+	return s.apply(&command{Operation: operationSet, Source: source, Key: key, Value: value, Conditional: true, Revision: revision})
 }
 
-// GetKeys returns a list of keys for the given source found in storage
-func (s *Store) GetKeys(source string) ([]string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// SetIfAbsent sets the value for the given source and key only if the key does not already exist
+func (s *Store) SetIfAbsent(source string, key string, value []byte) (uint64, error) {
+	return s.CompareAndSet(source, key, value, 0)
+}
 
-	var response []string
-	for k := range s.storage[source] {
-		response = append(response, k)
+// SetOptions control how a value is set.  Options left as their zero value are ignored.
+type SetOptions struct {
+	// Conditional requires the key to be at Revision for the value to be set, where zero indicates it must not exist
+	Conditional bool
+	Revision    uint64
+
+	// TTL attaches the key to a new lease that expires after the duration
+	TTL time.Duration
+
+	// Lease attaches the key to an existing lease
+	Lease uint64
+}
+
+// SetWithOptions sets the value for the given source and key in storage as described by opts and returns its new revision.
+// ErrLeaseNotFound is returned if the key is to be attached to a lease that does not exist.
+func (s *Store) SetWithOptions(source string, key string, value []byte, opts SetOptions) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("SetWithOptions should only be called on the leader")
+	}
+
+	if opts.TTL < 0 {
+		return 0, ErrInvalidTTL
+	}
+
+	if opts.TTL > 0 && opts.Lease > 0 {
+		return 0, errors.New("A value may be given either a TTL or a lease, but not both")
 	}
-	return response, nil
+
+	return s.apply(&command{
+		Operation:   operationSet,
+		Source:      source,
+		Key:         key,
+		Value:       value,
+		Conditional: opts.Conditional,
+		Revision:    opts.Revision,
+		TTL:         opts.TTL,
+		Lease:       opts.Lease,
+	})
+}
+
+// GetSources returns a list of sources found in storage, in lexical order
+func (s *Store) GetSources() ([]string, error) {
+	return s.ListSources(ListOptions{})
 }
 
-// Get the value for the given source and key in storage
-func (s *Store) Get(source string, key string) []byte {
+// GetKeys returns a list of keys for the given source found in storage, in lexical order
+func (s *Store) GetKeys(source string) ([]string, error) {
+	return s.ListKeys(source, ListOptions{})
+}
+
+// Get the value and revision for the given source and key in storage.
+// A revision of zero indicates that the key does not exist.
+func (s *Store) Get(source string, key string) ([]byte, uint64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.storage[source] == nil {
-		return nil
+	var e entry
+	if err := s.engine.view(func(t txn) {
+		e, _ = t.get(source, key)
+	}); err != nil {
+		s.logger.Error("Failed to read value.", "source", source, "key", key, "error", err)
 	}
-	return s.storage[source][key]
+	return e.Value, e.Revision
 }
 
 // DeleteKey deletes the key and value for the given source in storage
@@ -185,12 +426,19 @@ func (s *Store) DeleteKey(source string, key string) error {
 		return errors.New("DeleteKey should only be called on the leader")
 	}
 
-	c := &command{Operation: operationDeleteKey, Source: source, Key: key}
-	b, err := json.Marshal(c)
-	if err != nil {
-		return err
+	_, err := s.apply(&command{Operation: operationDeleteKey, Source: source, Key: key})
+	return err
+}
+
+// CompareAndDelete deletes the key and value for the given source only if the key is currently at the provided revision.
+// ErrRevisionMismatch is returned if the condition fails.
+func (s *Store) CompareAndDelete(source string, key string, revision uint64) error {
+	if !s.IsLeader() {
+		return errors.New("CompareAndDelete should only be called on the leader")
 	}
-	return s.raft.Apply(b, raftTimeout).Error()
+
+	_, err := s.apply(&command{Operation: operationDeleteKey, Source: source, Key: key, Conditional: true, Revision: revision})
+	return err
 }
 
 // DeleteSource deletes the given source in storage
@@ -199,12 +447,30 @@ func (s *Store) DeleteSource(source string) error {
 		return errors.New("DeleteSource should only be called on the leader")
 	}
 
-	c := &command{Operation: operationDeleteSource, Source: source}
-	b, err := json.Marshal(c)
-	if err != nil {
-		return err
+	_, err := s.apply(&command{Operation: operationDeleteSource, Source: source})
+	return err
+}
+
+// Transaction atomically applies the operations if every guard is satisfied, returning the revision
+// assigned to all values set by the transaction.  ErrGuardFailed is returned if any guard is not satisfied.
+func (s *Store) Transaction(guards []TxnGuard, ops []TxnOp) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("Transaction should only be called on the leader")
+	}
+
+	for _, g := range guards {
+		if g.Type != GuardKeyExists && g.Type != GuardRevisionEquals && g.Type != GuardValueEquals {
+			return 0, fmt.Errorf("Unrecognized transaction guard %s", g.Type)
+		}
+	}
+
+	for _, op := range ops {
+		if op.Operation != OperationSet && op.Operation != OperationDeleteKey && op.Operation != OperationDeleteSource {
+			return 0, fmt.Errorf("Unrecognized transaction operation %s", op.Operation)
+		}
 	}
-	return s.raft.Apply(b, raftTimeout).Error()
+
+	return s.apply(&command{Operation: operationTransaction, Guards: guards, Operations: ops})
 }
 
 // Join the node located at addr to this store.
@@ -215,6 +481,13 @@ func (s *Store) Join(addr string) error {
 	}
 
 	s.logger.Info("Received join request for remote node", "address", addr)
+
+	// A peer added before the leader applies the configuration it was elected with is removed again once that
+	// configuration is applied, leaving the addition waiting on a quorum that includes the removed peer
+	if err := s.raft.Barrier(raftTimeout).Error(); err != nil {
+		return err
+	}
+
 	f := s.raft.AddPeer(addr)
 	if err := f.Error(); err != nil {
 		if err == raft.ErrKnownPeer {
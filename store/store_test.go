@@ -1,19 +1,50 @@
 package store
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
+	"sync"
 	"testing"
+	"time"
 
 	"os"
 
 	fglog "github.com/forestgiant/log"
 	"github.com/forestgiant/portutil"
+	"github.com/hashicorp/raft"
 )
 
 var (
 	testStore *Store
+
+	// testPublished guards the function the test store's UpdateCallback passes each update to, which
+	// tests replace while the store may be delivering updates
+	testPublished struct {
+		sync.Mutex
+		fn func(u *Update)
+	}
 )
 
+// onPublish has the test store pass each update it publishes to the function, until it is replaced
+func onPublish(fn func(u *Update)) {
+	testPublished.Lock()
+	defer testPublished.Unlock()
+	testPublished.fn = fn
+}
+
+// memoryOf returns the in-memory engine holding the contents of the store
+func memoryOf(s *Store) *memoryEngine {
+	return s.engine.(*memoryEngine)
+}
+
 type SuppressedWriter struct{}
 
 func (w *SuppressedWriter) Write(p []byte) (n int, err error) {
@@ -33,6 +64,13 @@ func TestMain(m *testing.M) {
 		logger := fglog.Logger{Writer: &SuppressedWriter{}}
 
 		testStore = NewStore(raftAddr, raftDir, logger)
+		testStore.UpdateCallback = func(u *Update) {
+			testPublished.Lock()
+			defer testPublished.Unlock()
+			if testPublished.fn != nil {
+				testPublished.fn(u)
+			}
+		}
 		defer os.RemoveAll(raftDir)
 		if err := testStore.Open(true); err != nil {
 			fmt.Println("Failed to open test store.", err)
@@ -40,12 +78,10 @@ func TestMain(m *testing.M) {
 		}
 
 		testStore.mu.Lock()
-		testStore.storage["testsource1"] = make(kvs)
-		testStore.storage["testsource1"]["testkey1"] = []byte("testvalue1")
-		testStore.storage["testsource1"]["testkey2"] = []byte("testvalue2")
-		testStore.storage["testsource2"] = make(kvs)
-		testStore.storage["testsource2"]["testkey1"] = []byte("testvalue1")
-		testStore.storage["testsource2"]["testkey2"] = []byte("testvalue2")
+		memoryOf(testStore).put("testsource1", "testkey1", entry{Value: []byte("testvalue1")})
+		memoryOf(testStore).put("testsource1", "testkey2", entry{Value: []byte("testvalue2")})
+		memoryOf(testStore).put("testsource2", "testkey1", entry{Value: []byte("testvalue1")})
+		memoryOf(testStore).put("testsource2", "testkey2", entry{Value: []byte("testvalue2")})
 		testStore.mu.Unlock()
 
 		return m.Run()
@@ -82,12 +118,12 @@ func TestGetSourcesAndKeys(t *testing.T) {
 			return
 		}
 
-		if len(sources) != len(testStore.storage) {
-			t.Error("Wrong number of sources returned.  Expected", len(testStore.storage), "but returned", len(sources))
+		if len(sources) != len(memoryOf(testStore).entries) {
+			t.Error("Wrong number of sources returned.  Expected", len(memoryOf(testStore).entries), "but returned", len(sources))
 			return
 		}
 
-		for s := range testStore.storage {
+		for s := range memoryOf(testStore).entries {
 			found := false
 			for _, source := range sources {
 				if source == s {
@@ -110,12 +146,12 @@ func TestGetSourcesAndKeys(t *testing.T) {
 				return
 			}
 
-			if len(keys) != len(testStore.storage[source]) {
-				t.Error("Wrong number of keys returned for source.  Expected", len(testStore.storage[source]), "but returned", len(keys))
+			if len(keys) != len(memoryOf(testStore).entries[source]) {
+				t.Error("Wrong number of keys returned for source.  Expected", len(memoryOf(testStore).entries[source]), "but returned", len(keys))
 				return
 			}
 
-			for k := range testStore.storage[source] {
+			for k := range memoryOf(testStore).entries[source] {
 				found := false
 				for _, key := range keys {
 					if key == k {
@@ -147,7 +183,7 @@ func TestGet(t *testing.T) {
 		}
 
 		for _, test := range tests {
-			v := testStore.Get(test.Source, test.Key)
+			v, _ := testStore.Get(test.Source, test.Key)
 			if !valuesMatch(v, test.Value) {
 				t.Error("Received value does not match expected value.")
 			}
@@ -155,15 +191,15 @@ func TestGet(t *testing.T) {
 	})
 
 	t.Run("TestUnknownSource", func(t *testing.T) {
-		v := testStore.Get("unknownsource", "testkey1")
-		if v != nil {
+		v, revision := testStore.Get("unknownsource", "testkey1")
+		if v != nil || revision != 0 {
 			t.Error("Get should return an empty value for an unknown source.")
 		}
 	})
 
 	t.Run("TestUnknownKey", func(t *testing.T) {
-		v := testStore.Get("testsource1", "unknownkey")
-		if v != nil {
+		v, revision := testStore.Get("testsource1", "unknownkey")
+		if v != nil || revision != 0 {
 			t.Error("Get should return an empty value for an unknown source.")
 		}
 	})
@@ -176,7 +212,7 @@ func TestSet(t *testing.T) {
 			t.Error("Store should not be the leader if Open was never called.")
 		}
 
-		if err := notleader.Set("source", "key", []byte("value")); err == nil {
+		if _, err := notleader.Set("source", "key", []byte("value")); err == nil {
 			t.Error("Set should fail if the store is not the leader.")
 		}
 	})
@@ -185,25 +221,86 @@ func TestSet(t *testing.T) {
 		testSetSource := "testsetsource"
 		testSetKey := "testsetkey"
 		testSetValue := []byte("testsetvalue")
-		err := testStore.Set(testSetSource, testSetKey, testSetValue)
+		revision, err := testStore.Set(testSetSource, testSetKey, testSetValue)
 		if err != nil {
 			t.Error(err)
 		}
 
+		if revision == 0 {
+			t.Error("Set should return the revision of the new value")
+		}
+
 		testStore.mu.Lock()
-		if testStore.storage == nil {
+		if memoryOf(testStore).entries == nil {
 			t.Error("Underlying storage is still nil")
 		}
-		if testStore.storage[testSetSource] == nil {
+		if memoryOf(testStore).entries[testSetSource] == nil {
 			t.Error("Underlying storage does not have an entry for the source")
 		}
-		if !valuesMatch(testSetValue, testStore.storage[testSetSource][testSetKey]) {
+		if !valuesMatch(testSetValue, memoryOf(testStore).entries[testSetSource][testSetKey].Value) {
 			t.Error("Value not properly set in underlying storage")
 		}
+		if memoryOf(testStore).entries[testSetSource][testSetKey].Revision != revision {
+			t.Error("Revision not properly set in underlying storage")
+		}
 		testStore.mu.Unlock()
 	})
 }
 
+func TestCompareAndSet(t *testing.T) {
+	t.Run("TestNotLeader", func(t *testing.T) {
+		notleader := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+		if _, err := notleader.CompareAndSet("source", "key", []byte("value"), 1); err == nil {
+			t.Error("CompareAndSet should fail if the store is not the leader.")
+		}
+
+		if err := notleader.CompareAndDelete("source", "key", 1); err == nil {
+			t.Error("CompareAndDelete should fail if the store is not the leader.")
+		}
+	})
+
+	t.Run("TestExpectedValue", func(t *testing.T) {
+		testSource := "testcassource"
+		testKey := "testcaskey"
+
+		revision, err := testStore.SetIfAbsent(testSource, testKey, []byte("first"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, err := testStore.SetIfAbsent(testSource, testKey, []byte("second")); err != ErrRevisionMismatch {
+			t.Error("SetIfAbsent should fail when the key already exists.")
+		}
+
+		if _, err := testStore.CompareAndSet(testSource, testKey, []byte("second"), revision+1000); err != ErrRevisionMismatch {
+			t.Error("CompareAndSet should fail when the revision does not match.")
+		}
+
+		updated, err := testStore.CompareAndSet(testSource, testKey, []byte("second"), revision)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if updated <= revision {
+			t.Error("CompareAndSet should produce a newer revision.")
+		}
+
+		if err := testStore.CompareAndDelete(testSource, testKey, revision); err != ErrRevisionMismatch {
+			t.Error("CompareAndDelete should fail when the revision does not match.")
+		}
+
+		if err := testStore.CompareAndDelete(testSource, testKey, updated); err != nil {
+			t.Error(err)
+		}
+
+		if v, rev := testStore.Get(testSource, testKey); v != nil || rev != 0 {
+			t.Error("Value was not removed from underlying storage.")
+		}
+	})
+}
+
 func TestDeleteKey(t *testing.T) {
 	t.Run("TestNotLeader", func(t *testing.T) {
 		notleader := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
@@ -221,13 +318,13 @@ func TestDeleteKey(t *testing.T) {
 		testDeleteKey := "testdeletekeykey"
 
 		testStore.mu.Lock()
-		if testStore.storage == nil {
-			testStore.storage = make(map[string]kvs)
+		if memoryOf(testStore).entries == nil {
+			memoryOf(testStore).entries = make(map[string]kvs)
 		}
-		if testStore.storage[testDeleteSource] == nil {
-			testStore.storage[testDeleteSource] = make(kvs)
+		if memoryOf(testStore).entries[testDeleteSource] == nil {
+			memoryOf(testStore).entries[testDeleteSource] = make(kvs)
 		}
-		testStore.storage[testDeleteSource][testDeleteKey] = []byte("testdeletekeyvalue")
+		memoryOf(testStore).entries[testDeleteSource][testDeleteKey] = entry{Value: []byte("testdeletekeyvalue")}
 		testStore.mu.Unlock()
 
 		if err := testStore.DeleteKey(testDeleteSource, testDeleteKey); err != nil {
@@ -235,7 +332,7 @@ func TestDeleteKey(t *testing.T) {
 		}
 
 		testStore.mu.Lock()
-		if testStore.storage != nil && testStore.storage[testDeleteSource] != nil && testStore.storage[testDeleteSource][testDeleteKey] != nil {
+		if memoryOf(testStore).entries != nil && memoryOf(testStore).entries[testDeleteSource] != nil && memoryOf(testStore).entries[testDeleteSource][testDeleteKey].Value != nil {
 			t.Error("Value was not removed from underlying storage.")
 		}
 		testStore.mu.Unlock()
@@ -257,12 +354,7 @@ func TestDeleteSource(t *testing.T) {
 	t.Run("TestExpectedValue", func(t *testing.T) {
 		testDeleteSource := "testdeletesourcesource"
 		testStore.mu.Lock()
-		if testStore.storage == nil {
-			testStore.storage = make(map[string]kvs)
-		}
-		if testStore.storage[testDeleteSource] == nil {
-			testStore.storage[testDeleteSource] = make(kvs)
-		}
+		memoryOf(testStore).put(testDeleteSource, "testdeletesourcekey", entry{Value: []byte("testdeletesourcevalue")})
 		testStore.mu.Unlock()
 
 		if err := testStore.DeleteSource(testDeleteSource); err != nil {
@@ -270,13 +362,173 @@ func TestDeleteSource(t *testing.T) {
 		}
 
 		testStore.mu.Lock()
-		if testStore.storage != nil && testStore.storage[testDeleteSource] != nil {
+		if memoryOf(testStore).entries != nil && memoryOf(testStore).entries[testDeleteSource] != nil {
 			t.Error("Source was not removed from underlying storage.")
 		}
 		testStore.mu.Unlock()
 	})
 }
 
+func TestTransaction(t *testing.T) {
+	t.Run("TestNotLeader", func(t *testing.T) {
+		notleader := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+		if _, err := notleader.Transaction(nil, []TxnOp{{Operation: OperationSet, Source: "source", Key: "key"}}); err == nil {
+			t.Error("Transaction should fail if the store is not the leader.")
+		}
+	})
+
+	t.Run("TestUnrecognized", func(t *testing.T) {
+		if _, err := testStore.Transaction([]TxnGuard{{Type: "unknown"}}, nil); err == nil {
+			t.Error("Transaction should fail when provided an unrecognized guard.")
+		}
+
+		if _, err := testStore.Transaction(nil, []TxnOp{{Operation: "unknown"}}); err == nil {
+			t.Error("Transaction should fail when provided an unrecognized operation.")
+		}
+	})
+
+	t.Run("TestExpectedValue", func(t *testing.T) {
+		testSource := "testtransactionsource"
+		ops := []TxnOp{
+			{Operation: OperationSet, Source: testSource, Key: "key1", Value: []byte("value1")},
+			{Operation: OperationSet, Source: testSource, Key: "key2", Value: []byte("value2")},
+		}
+
+		revision, err := testStore.Transaction([]TxnGuard{{Type: GuardRevisionEquals, Source: testSource, Key: "key1"}}, ops)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for _, op := range ops {
+			if v, rev := testStore.Get(op.Source, op.Key); !valuesMatch(v, op.Value) || rev != revision {
+				t.Error("Transaction did not set the expected value and revision.")
+			}
+		}
+
+		if _, err := testStore.Transaction([]TxnGuard{{Type: GuardRevisionEquals, Source: testSource, Key: "key1"}}, ops); err != ErrGuardFailed {
+			t.Error("Transaction should fail when a guard is not satisfied.")
+		}
+	})
+}
+
+func TestLeases(t *testing.T) {
+	testSource := "testLeaseSource"
+
+	t.Run("TestExpiry", func(t *testing.T) {
+		deleted := make(chan string, 1)
+		onPublish(func(u *Update) {
+			if u.Source == testSource && u.Operation == OperationExpire {
+				deleted <- u.Key
+			}
+		})
+		defer onPublish(nil)
+
+		if _, err := testStore.SetWithOptions(testSource, "ttl", []byte("value"), SetOptions{TTL: 300 * time.Millisecond}); err != nil {
+			t.Error(err)
+			return
+		}
+
+		select {
+		case key := <-deleted:
+			if key != "ttl" {
+				t.Error("Unexpected key deleted", key)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("Key set with a TTL should be deleted once it expires")
+			return
+		}
+
+		if _, revision := testStore.Get(testSource, "ttl"); revision != 0 {
+			t.Error("Expired key should not remain in storage")
+		}
+	})
+
+	t.Run("TestKeepAliveAndRevoke", func(t *testing.T) {
+		lease, err := testStore.GrantLease(500 * time.Millisecond)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, err := testStore.SetWithOptions(testSource, "leased", []byte("value"), SetOptions{Lease: lease}); err != nil {
+			t.Error(err)
+			return
+		}
+
+		for i := 0; i < 4; i++ {
+			time.Sleep(250 * time.Millisecond)
+			if err := testStore.KeepAliveLease(lease); err != nil {
+				t.Error("Lease should remain alive while kept alive", err)
+				return
+			}
+		}
+
+		if err := testStore.RevokeLease(lease); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, revision := testStore.Get(testSource, "leased"); revision != 0 {
+			t.Error("Keys attached to a revoked lease should be deleted")
+		}
+
+		if err := testStore.KeepAliveLease(lease); err != ErrLeaseNotFound {
+			t.Error("Keeping a revoked lease alive should return ErrLeaseNotFound")
+		}
+	})
+
+	t.Run("TestInvalidOptions", func(t *testing.T) {
+		if _, err := testStore.GrantLease(0); err != ErrInvalidTTL {
+			t.Error("Granting a lease without a TTL should fail")
+		}
+
+		if _, err := testStore.SetWithOptions(testSource, "key", nil, SetOptions{TTL: time.Second, Lease: 1}); err == nil {
+			t.Error("Setting a value with both a TTL and a lease should fail")
+		}
+
+		if _, err := testStore.SetWithOptions(testSource, "key", nil, SetOptions{Lease: 12345}); err != ErrLeaseNotFound {
+			t.Error("Setting a value with an unknown lease should return ErrLeaseNotFound")
+		}
+	})
+}
+
+func TestReadConsistency(t *testing.T) {
+	t.Run("TestNotLeader", func(t *testing.T) {
+		notleader := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
+		if err := notleader.VerifyRead(); err == nil {
+			t.Error("VerifyRead should fail if the store is not the leader.")
+		}
+
+		if err := notleader.CheckStaleness(time.Minute); err != ErrStale {
+			t.Error("A store that has never been in contact with a leader should be stale")
+		}
+
+		if err := notleader.CheckStaleness(0); err != nil {
+			t.Error("A maximum staleness of zero should allow any staleness")
+		}
+	})
+
+	t.Run("TestLeader", func(t *testing.T) {
+		revision, err := testStore.Set("testreadsource", "key", []byte("value"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := testStore.VerifyRead(); err != nil {
+			t.Error("VerifyRead should succeed on the leader", err)
+		}
+
+		if applied := testStore.AppliedIndex(); applied < revision {
+			t.Error("The applied index should include every entry committed before the read", applied, revision)
+		}
+
+		if err := testStore.CheckStaleness(time.Nanosecond); err != nil {
+			t.Error("The leader should never be stale")
+		}
+	})
+}
+
 func TestJoin(t *testing.T) {
 	t.Run("TestNotLeader", func(t *testing.T) {
 		notleader := NewStore("", "", fglog.Logger{Writer: &SuppressedWriter{}})
@@ -290,6 +542,418 @@ func TestJoin(t *testing.T) {
 	})
 }
 
+func TestMembership(t *testing.T) {
+	peers, err := testStore.ListPeers()
+	if err != nil || len(peers) != 1 || peers[0] != testStore.RaftBindAddr {
+		t.Fatal("The leader of a new cluster should be its only peer", peers, err)
+	}
+
+	if err := testStore.Leave(); err != nil || !testStore.IsLeader() {
+		t.Fatal("The last member of a cluster should not leave it", err)
+	}
+
+	p, err := portutil.GetUniqueTCP()
+	if err != nil {
+		t.Fatal("Failed to obtain test port", err)
+	}
+
+	followerDir := "com.forestgiant.iris.testing.store.followerRaftDir"
+	defer os.RemoveAll(followerDir)
+
+	// The follower binds every interface, and so must advertise the address it is reached at
+	follower := NewStore(fmt.Sprintf(":%d", p), followerDir, fglog.Logger{Writer: &SuppressedWriter{}})
+	follower.RaftAdvertiseAddr = fmt.Sprintf("127.0.0.1:%d", p)
+	if err := follower.Open(false); err != nil {
+		t.Fatal("Failed to open follower store", err)
+	}
+	defer follower.raft.Shutdown()
+
+	if err := testStore.Join(follower.RaftAddr()); err != nil {
+		t.Fatal("Failed to join follower store", err)
+	}
+
+	peers, err = testStore.ListPeers()
+	if err != nil || len(peers) != 2 {
+		t.Fatal("The joined store should be listed as a peer", peers, err)
+	}
+
+	if err := follower.SetPeerAddress(follower.RaftAddr(), "[::1]:7000"); err == nil {
+		t.Error("SetPeerAddress should fail if the store is not the leader")
+	}
+
+	if err := testStore.SetPeerAddress(follower.RaftAddr(), "[::1]:7000"); err != nil {
+		t.Fatal("Failed to record the peer address of the follower", err)
+	}
+
+	status, err := testStore.Status()
+	if err != nil {
+		t.Fatal("Failed to obtain cluster status", err)
+	}
+
+	if status.State != PeerStateLeader || status.Leader != testStore.RaftBindAddr || status.CommitIndex == 0 {
+		t.Error("The status should describe the leader", status)
+	}
+
+	for _, peer := range status.Peers {
+		expected := PeerStateFollower
+		if peer.Address == testStore.RaftBindAddr {
+			expected = PeerStateLeader
+		}
+
+		if peer.State != expected {
+			t.Error("Unexpected peer state", peer.Address, peer.State, expected)
+		}
+
+		if peer.Address == follower.RaftAddr() && peer.GRPCAddress != "[::1]:7000" {
+			t.Error("The status should include the recorded peer address", peer.GRPCAddress)
+		}
+
+		if peer.Address == follower.RaftAddr() && (peer.MatchIndex == 0 || peer.LastContact.IsZero()) {
+			t.Error("The leader should report the replication state of the follower", peer)
+		}
+	}
+
+	if err := follower.RemovePeer(testStore.RaftBindAddr); err == nil {
+		t.Error("RemovePeer should fail if the store is not the leader")
+	}
+
+	if err := testStore.RemovePeer(follower.RaftAddr()); err != nil {
+		t.Fatal("Failed to remove follower store", err)
+	}
+
+	if address, ok := testStore.PeerAddress(follower.RaftAddr()); ok {
+		t.Error("The address of the removed store should no longer be recorded", address)
+	}
+
+	peers, err = testStore.ListPeers()
+	if err != nil || len(peers) != 1 || peers[0] != testStore.RaftBindAddr {
+		t.Error("The removed store should no longer be listed as a peer", peers, err)
+	}
+
+	if _, err := testStore.Set("testmembership", "key", []byte("value")); err != nil {
+		t.Error("The leader should commit alone once the follower is removed", err)
+	}
+}
+
+// openTestCluster opens the stores of a cluster led by the first, returning them along with a function closing them
+func openTestCluster(t *testing.T, name string, size int) ([]*Store, func()) {
+	var stores []*Store
+	closeAll := func() {
+		for _, s := range stores {
+			s.raft.Shutdown().Error()
+			os.RemoveAll(s.RaftDir)
+		}
+	}
+
+	for i := 0; i < size; i++ {
+		p, err := portutil.GetUniqueTCP()
+		if err != nil {
+			closeAll()
+			t.Fatal("Failed to obtain test port", err)
+		}
+
+		dir := fmt.Sprintf("com.forestgiant.iris.testing.store.%sRaftDir%d", name, i)
+		s := NewStore(fmt.Sprintf("127.0.0.1:%d", p), dir, fglog.Logger{Writer: &SuppressedWriter{}})
+		if err := s.Open(i == 0); err != nil {
+			os.RemoveAll(dir)
+			closeAll()
+			t.Fatal("Failed to open store", err)
+		}
+		stores = append(stores, s)
+	}
+
+	for !stores[0].IsLeader() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, s := range stores[1:] {
+		if err := stores[0].Join(s.RaftBindAddr); err != nil {
+			closeAll()
+			t.Fatal("Failed to join store", err)
+		}
+	}
+	return stores, closeAll
+}
+
+func TestStatus(t *testing.T) {
+	stores, closeAll := openTestCluster(t, "status", 3)
+	defer closeAll()
+
+	leader, follower, failed := stores[0], stores[1], stores[2]
+	index, err := leader.Set("teststatus", "key", []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerStates := func(s *Store) map[string]Peer {
+		status, err := s.Status()
+		if err != nil {
+			t.Fatal("Failed to obtain cluster status", err)
+		}
+
+		peers := make(map[string]Peer)
+		for _, peer := range status.Peers {
+			peers[peer.Address] = peer
+		}
+		return peers
+	}
+
+	for follower.raft.AppliedIndex() < index {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	peers := peerStates(follower)
+	if peers[leader.RaftBindAddr].State != PeerStateLeader || peers[follower.RaftBindAddr].State != PeerStateFollower {
+		t.Error("A follower should report the leader and itself", peers)
+	}
+
+	if peer := peers[failed.RaftBindAddr]; peer.State != PeerStateUnknown || peer.MatchIndex != 0 || !peer.LastContact.IsZero() {
+		t.Error("A follower should not report the state of the other members", peer)
+	}
+
+	for _, s := range []*Store{follower, failed} {
+		if peer := peerStates(leader)[s.RaftBindAddr]; peer.State != PeerStateFollower || peer.MatchIndex < index {
+			t.Error("The leader should report the replication state of each follower", peer, index)
+		}
+	}
+
+	failed.raft.Shutdown().Error()
+	time.Sleep(2 * leader.electionTimeout)
+
+	peers = peerStates(leader)
+	if peer := peers[failed.RaftBindAddr]; peer.State != PeerStateUnknown || peer.MatchIndex < index || time.Since(peer.LastContact) < leader.electionTimeout {
+		t.Error("The leader should report a member that stopped responding as unknown", peer)
+	}
+
+	if peer := peers[follower.RaftBindAddr]; peer.State != PeerStateFollower {
+		t.Error("The leader should report a responsive member as a follower", peer)
+	}
+}
+
+func TestTransferLeadership(t *testing.T) {
+	stores, closeAll := openTestCluster(t, "transfer", 3)
+	defer closeAll()
+
+	leader, target := stores[0], stores[2]
+	if _, err := leader.Set("testtransfer", "key", []byte("before")); err != nil {
+		t.Fatal(err)
+	}
+
+	caughtUp := func(index uint64) error {
+		for target.raft.AppliedIndex() < index {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+
+	if err := leader.TransferLeadership("127.0.0.1:1", time.Second, caughtUp); err != ErrUnknownPeer {
+		t.Error("Leadership should only be transferred to a peer", err)
+	}
+
+	if err := target.TransferLeadership(leader.RaftBindAddr, time.Second, caughtUp); err == nil {
+		t.Error("TransferLeadership should fail if the store is not the leader")
+	}
+
+	if err := leader.TransferLeadership(target.RaftBindAddr, 10*time.Second, caughtUp); err != nil {
+		t.Fatal("Failed to transfer leadership", err)
+	}
+
+	if !target.IsLeader() || leader.IsLeader() {
+		t.Fatal("Leadership should have moved to the target")
+	}
+
+	if _, err := target.Set("testtransfer", "key", []byte("after")); err != nil {
+		t.Error("The new leader should accept writes", err)
+	}
+
+	if value, _ := target.Get("testtransfer", "key"); string(value) != "after" {
+		t.Error("The new leader should hold every write", string(value))
+	}
+}
+
+func TestTransferLeadershipFailure(t *testing.T) {
+	errUnreachable := errors.New("The target cannot be reached")
+
+	// The target fails once every member has been told of the transfer, before it begins its election.
+	// The transfer ends at once if its failure is noticed, and otherwise once its election timeouts have passed.
+	for _, noticed := range []bool{true, false} {
+		stores, closeAll := openTestCluster(t, fmt.Sprintf("transferFailure%t", noticed), 3)
+		defer closeAll()
+
+		leader, target := stores[0], stores[2]
+		calls := 0
+		caughtUp := func(index uint64) error {
+			if noticed && target.raft.State() == raft.Shutdown {
+				return errUnreachable
+			}
+
+			for target.raft.AppliedIndex() < index {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if calls++; calls == 2 {
+				target.raft.Shutdown().Error()
+			}
+			return nil
+		}
+
+		expected, bound := ErrTransferTimeout, (transferElectionTimeouts+1)*leader.electionTimeout
+		if noticed {
+			expected, bound = errUnreachable, leader.electionTimeout
+		}
+
+		start := time.Now()
+		if err := leader.TransferLeadership(target.RaftBindAddr, 30*time.Second, caughtUp); err != expected {
+			t.Error("Leadership transfer should fail when the target fails", noticed, err)
+		}
+
+		if elapsed := time.Since(start); elapsed > bound {
+			t.Error("The failed transfer should end within its election timeouts", noticed, elapsed)
+		}
+
+		if !leader.IsLeader() {
+			t.Fatal("The leader should remain in place when the transfer fails", noticed)
+		}
+
+		if _, err := leader.Set("testtransfer", "key", []byte("value")); err != nil {
+			t.Error("Writes should be accepted once the transfer fails", noticed, err)
+		}
+
+		for _, s := range stores[:2] {
+			if _, ok := s.transferring(); ok {
+				t.Error("The transfer should end on every member", noticed, s.RaftBindAddr)
+			}
+		}
+	}
+}
+
+func TestRaftTLS(t *testing.T) {
+	pool, issue := newTestAuthority(t)
+
+	var stores []*Store
+	for i := 0; i < 2; i++ {
+		p, err := portutil.GetUniqueTCP()
+		if err != nil {
+			t.Fatal("Failed to obtain test port", err)
+		}
+
+		dir := fmt.Sprintf("com.forestgiant.iris.testing.store.tlsRaftDir%d", i)
+		defer os.RemoveAll(dir)
+
+		s := NewStore(fmt.Sprintf("127.0.0.1:%d", p), dir, fglog.Logger{Writer: &SuppressedWriter{}})
+		s.RaftTLSConfig = &tls.Config{ServerName: "Iris", Certificates: []tls.Certificate{issue()}, RootCAs: pool}
+		if err := s.Open(i == 0); err != nil {
+			t.Fatal("Failed to open store", err)
+		}
+		defer func(s *Store) { s.raft.Shutdown().Error() }(s)
+		stores = append(stores, s)
+	}
+
+	leader, follower := stores[0], stores[1]
+	for !leader.IsLeader() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := leader.Join(follower.RaftAddr()); err != nil {
+		t.Fatal("Failed to join store", err)
+	}
+
+	if _, err := leader.Set("testtls", "key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for value, _ := follower.Get("testtls", "key"); string(value) != "value"; value, _ = follower.Get("testtls", "key") {
+		if time.Now().After(deadline) {
+			t.Fatal("The value was not replicated over TLS")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Members must present a certificate issued by the trusted authority
+	_, issueUntrusted := newTestAuthority(t)
+	untrusted, err := newTLSTransport("127.0.0.1:0", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		&tls.Config{ServerName: "Iris", Certificates: []tls.Certificate{issueUntrusted()}, RootCAs: pool}, 1, time.Second, &SuppressedWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer untrusted.Close()
+
+	plaintext, err := raft.NewTCPTransport("127.0.0.1:0", nil, 1, time.Second, &SuppressedWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plaintext.Close()
+
+	for name, transport := range map[string]*raft.NetworkTransport{"untrusted": untrusted, "plaintext": plaintext} {
+		req := &raft.AppendEntriesRequest{Term: 1 << 20, Leader: transport.EncodePeer(transport.LocalAddr())}
+		if err := transport.AppendEntries(leader.RaftAddr(), req, &raft.AppendEntriesResponse{}); err == nil {
+			t.Error("Raft communications should be refused without a trusted certificate", name)
+		}
+	}
+
+	if !leader.IsLeader() {
+		t.Error("Refused communications should not affect the leader")
+	}
+}
+
+// newTestAuthority returns the pool holding a new certificate authority, along with a function issuing certificates
+// signed by it to the server name Iris
+func newTestAuthority(t *testing.T) (*x509.CertPool, func() tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Iris Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	var serial int64 = 1
+	return pool, func() tls.Certificate {
+		leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		serial++
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "Iris"},
+			DNSNames:     []string{"Iris"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, leaf, ca, &leafKey.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: leafKey}
+	}
+}
+
 func valuesMatch(v1 []byte, v2 []byte) bool {
 	if len(v1) != len(v2) {
 		return false
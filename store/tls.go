@@ -0,0 +1,63 @@
+package store
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// errNotAdvertisable is returned when the raft transport binds every interface and has no other address to advertise
+var errNotAdvertisable = errors.New("The raft bind address cannot be advertised to the other members of the cluster")
+
+// tlsStreamLayer is a raft stream layer securing every connection between the members of the cluster with mutual
+// TLS, so that each member only exchanges raft communications with members presenting a certificate it verifies
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	config    *tls.Config
+}
+
+// newTLSTransport returns a raft transport listening on bindAddr and advertising the address to the other members,
+// which makes and accepts only connections whose certificates are verified by the config.  Connections are accepted
+// from members presenting a certificate signed by the ClientCAs of the config, or its RootCAs if it has none.
+func newTLSTransport(bindAddr string, advertise *net.TCPAddr, config *tls.Config, maxPool int, timeout time.Duration, logOutput io.Writer) (*raft.NetworkTransport, error) {
+	if advertise.IP.IsUnspecified() {
+		return nil, errNotAdvertisable
+	}
+
+	if len(config.Certificates) == 0 && config.GetCertificate == nil {
+		return nil, errors.New("The raft TLS configuration must provide the certificate of this member")
+	}
+
+	config = config.Clone()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if config.ClientCAs == nil {
+		config.ClientCAs = config.RootCAs
+	}
+
+	listener, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &tlsStreamLayer{
+		Listener:  tls.NewListener(listener, config),
+		advertise: advertise,
+		config:    config,
+	}
+	return raft.NewNetworkTransport(stream, maxPool, timeout, logOutput), nil
+}
+
+// Dial connects to the member at the address, completing the TLS handshake before the connection is used
+func (t *tlsStreamLayer) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, t.config)
+}
+
+// Addr returns the address advertised to the other members of the cluster
+func (t *tlsStreamLayer) Addr() net.Addr {
+	return t.advertise
+}
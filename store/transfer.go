@@ -0,0 +1,362 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const operationTransferLeadership = "transferLeadership"
+
+// transferPollInterval is how often the leader checks whether leadership has reached the peer it was transferred to
+const transferPollInterval = 50 * time.Millisecond
+
+// transferElectionTimeouts bounds, in election timeouts, the time members only vote for the peer leadership is transferred
+// to.  The peer must notice that replication is withheld from it and win its election within this time, or the transfer ends
+// and any member may be elected.
+const transferElectionTimeouts = 5
+
+// ErrTransferring is returned by writes made while leadership of the cluster is being transferred to another node
+var ErrTransferring = errors.New("Leadership of the cluster is being transferred to another node")
+
+// ErrUnknownPeer is returned when leadership is transferred to a node that is not a member of the cluster
+var ErrUnknownPeer = errors.New("The address is not a peer of this cluster")
+
+// ErrTransferTimeout is returned when leadership did not reach the peer it was transferred to before the timeout
+var ErrTransferTimeout = errors.New("Leadership was not transferred before the timeout")
+
+var (
+	errTransferVote        = errors.New("Only the target of the leadership transfer may be elected")
+	errTransferReplication = errors.New("Replication is withheld while leadership is transferred")
+)
+
+// leadershipTransfer records the peer that leadership of the cluster is being transferred to, until it expires.
+// The leader sets stepDown once the peer holds every entry of its log, and campaigning is set once the peer requests a vote.
+type leadershipTransfer struct {
+	peer        string
+	expires     time.Time
+	stepDown    bool
+	campaigning bool
+}
+
+// TransferLeadership moves leadership of the cluster to the peer at addr, and must be called on the leader.
+//
+// The vendored raft library cannot hand leadership to a chosen peer, so the transfer is made through the log.  Writes
+// are refused while the transfer is in progress, and once caughtUp confirms that the peer has applied the leader's last
+// log index, every member is told to only vote for the peer for a few election timeouts.  Once the peer holds that entry
+// too, the leader withholds replication from the peer alone, so that the peer begins an election before any other member.
+// Once the peer requests a vote the leader withholds replication from every member and steps down, and as the other
+// members only vote for the peer, which is ahead of them in term, it wins the election.  Should the peer fail or lose its
+// election, the transfer ends once its election timeouts have passed, and the members elect a leader as usual.
+func (s *Store) TransferLeadership(addr string, timeout time.Duration, caughtUp func(index uint64) error) error {
+	if !s.IsLeader() {
+		return errors.New("TransferLeadership should only be called on the leader")
+	}
+
+	if addr == s.RaftAddr() {
+		return nil
+	}
+
+	peers, err := s.ListPeers()
+	if err != nil {
+		return err
+	}
+
+	if !raft.PeerContained(peers, addr) {
+		return ErrUnknownPeer
+	}
+
+	s.logger.Info("Transferring leadership", "address", addr)
+	deadline := time.Now().Add(timeout)
+
+	// Writes are refused by this store alone while the peer catches up, so that the transfer ends the log
+	s.setTransfer(&leadershipTransfer{peer: addr, expires: deadline})
+	defer s.setTransfer(nil)
+
+	// The barrier follows any writes made before writes were refused, so the log ends with it
+	if err := s.raft.Barrier(raftTimeout).Error(); err != nil {
+		return err
+	}
+
+	if err := caughtUp(s.raft.LastIndex()); err != nil {
+		return err
+	}
+
+	window := time.Now().Add(transferElectionTimeouts * s.electionTimeout)
+	if _, err := s.apply(&command{Operation: operationTransferLeadership, Peer: addr, Deadline: window.UnixNano()}); err != nil {
+		return err
+	}
+
+	index := s.raft.LastIndex()
+	if err := caughtUp(index); err != nil {
+		s.cancelTransfer()
+		return err
+	}
+
+	s.transferMu.Lock()
+	if s.transfer != nil {
+		s.transfer.stepDown = true
+	}
+	s.transferMu.Unlock()
+
+	for time.Now().Before(deadline) {
+		if s.Leader() == addr {
+			s.logger.Info("Leadership transferred", "address", addr)
+			return nil
+		}
+
+		// The transfer ends early if the peer stops responding, or fails to win its election in time
+		if err := caughtUp(index); err != nil {
+			s.endTransfer()
+			return err
+		}
+
+		if _, ok := s.transferring(); !ok {
+			break
+		}
+		time.Sleep(transferPollInterval)
+	}
+
+	s.endTransfer()
+	return ErrTransferTimeout
+}
+
+// setTransfer replaces the transfer in progress on this store
+func (s *Store) setTransfer(transfer *leadershipTransfer) {
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+	s.transfer = transfer
+}
+
+// cancelTransfer ends the transfer on every member of the cluster, while this store is still the leader
+func (s *Store) cancelTransfer() {
+	if _, err := s.apply(&command{Operation: operationTransferLeadership}); err != nil {
+		s.logger.Error("Failed to cancel leadership transfer.", "error", err)
+	}
+}
+
+// endTransfer ends a transfer that did not complete.  A leader that has not yet stepped down cancels the transfer,
+// while the members of a leader that has stepped down elect a leader as usual once the transfer expires.
+func (s *Store) endTransfer() {
+	s.logger.Info("Leadership transfer failed")
+	if s.IsLeader() {
+		s.cancelTransfer()
+	}
+}
+
+// transferCampaigning records that the peer of the transfer has begun its election
+func (s *Store) transferCampaigning() {
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+
+	if s.transfer != nil {
+		s.transfer.campaigning = true
+	}
+}
+
+// transferring returns the transfer in progress, if any.  A transfer ends once it expires, or once its peer leads.
+func (s *Store) transferring() (leadershipTransfer, bool) {
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+
+	if s.transfer == nil || time.Now().After(s.transfer.expires) {
+		return leadershipTransfer{}, false
+	}
+
+	if s.Leader() == s.transfer.peer {
+		return leadershipTransfer{}, false
+	}
+	return *s.transfer, true
+}
+
+// applyTransferLeadership records the transfer on this member of the cluster until the deadline set by the leader, or for
+// the election timeouts of a transfer if they end sooner, where a transfer without a peer cancels the transfer in progress.
+// Replayed transfers have passed their deadline, and so have no effect.
+func (f *fsm) applyTransferLeadership(index uint64, peer string, deadline int64) interface{} {
+	f.afterLocked(func() {
+		f.transferMu.Lock()
+		defer f.transferMu.Unlock()
+
+		f.transfer = nil
+		if len(peer) > 0 {
+			expires := time.Now().Add(transferElectionTimeouts * f.electionTimeout)
+			if d := time.Unix(0, deadline); d.Before(expires) {
+				expires = d
+			}
+			f.transfer = &leadershipTransfer{peer: peer, expires: expires}
+		}
+	})
+	return &applyResponse{revision: index}
+}
+
+// transferTransport is the raft transport of a store.  While leadership is being transferred it refuses the votes
+// requested by any node other than the transfer's peer, and withholds replication while the leader steps down.  It also
+// records the responses of each peer to the entries the leader appends, which the leader reports in its status.
+type transferTransport struct {
+	*raft.NetworkTransport
+	store    *Store
+	consumer chan raft.RPC
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newTransferTransport(s *Store, t *raft.NetworkTransport) *transferTransport {
+	tt := &transferTransport{
+		NetworkTransport: t,
+		store:            s,
+		consumer:         make(chan raft.RPC),
+		done:             make(chan struct{}),
+	}
+
+	go tt.filter()
+	return tt
+}
+
+// Consumer returns the channel delivering the RPCs that were not refused
+func (t *transferTransport) Consumer() <-chan raft.RPC {
+	return t.consumer
+}
+
+// filter passes the RPCs received by the transport on to raft, refusing votes for any node other than the peer
+func (t *transferTransport) filter() {
+	for {
+		var rpc raft.RPC
+		select {
+		case rpc = <-t.NetworkTransport.Consumer():
+		case <-t.done:
+			return
+		}
+
+		if req, ok := rpc.Command.(*raft.RequestVoteRequest); ok {
+			if transfer, ok := t.store.transferring(); ok {
+				if t.DecodePeer(req.Candidate) != transfer.peer {
+					rpc.Respond(nil, errTransferVote)
+					continue
+				}
+
+				if transfer.stepDown {
+					t.store.transferCampaigning()
+				}
+			}
+		}
+
+		select {
+		case t.consumer <- rpc:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// withholding indicates whether the leader no longer replicates its log to the target while it steps down for a
+// transfer.  Replication is withheld from the transfer's peer until it campaigns, and then from every member.
+func (t *transferTransport) withholding(target string) bool {
+	transfer, ok := t.store.transferring()
+	if !ok || !transfer.stepDown {
+		return false
+	}
+	return transfer.campaigning || target == transfer.peer
+}
+
+// AppendEntries sends entries to the target unless replication is withheld
+func (t *transferTransport) AppendEntries(target string, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) error {
+	if t.withholding(target) {
+		return errTransferReplication
+	}
+	if err := t.NetworkTransport.AppendEntries(target, args, resp); err != nil {
+		return err
+	}
+	t.store.replicated(target, args, resp)
+	return nil
+}
+
+// AppendEntriesPipeline returns a pipeline to the target that fails once replication is withheld
+func (t *transferTransport) AppendEntriesPipeline(target string) (raft.AppendPipeline, error) {
+	p, err := t.NetworkTransport.AppendEntriesPipeline(target)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := &transferPipeline{
+		AppendPipeline: p,
+		transport:      t,
+		target:         target,
+		consumer:       make(chan raft.AppendFuture),
+		done:           make(chan struct{}),
+	}
+
+	go pipeline.observe()
+	return pipeline, nil
+}
+
+// InstallSnapshot sends a snapshot to the target unless replication is withheld
+func (t *transferTransport) InstallSnapshot(target string, args *raft.InstallSnapshotRequest, resp *raft.InstallSnapshotResponse, data io.Reader) error {
+	if t.withholding(target) {
+		return errTransferReplication
+	}
+	return t.NetworkTransport.InstallSnapshot(target, args, resp, data)
+}
+
+// Close stops filtering RPCs and closes the underlying transport
+func (t *transferTransport) Close() error {
+	t.once.Do(func() {
+		close(t.done)
+	})
+	return t.NetworkTransport.Close()
+}
+
+// transferPipeline is an append pipeline that fails once replication is withheld, and records the responses of its target
+type transferPipeline struct {
+	raft.AppendPipeline
+	transport *transferTransport
+	target    string
+	consumer  chan raft.AppendFuture
+	done      chan struct{}
+	once      sync.Once
+}
+
+// AppendEntries pipelines entries to the target unless replication is withheld
+func (p *transferPipeline) AppendEntries(args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) (raft.AppendFuture, error) {
+	if p.transport.withholding(p.target) {
+		return nil, errTransferReplication
+	}
+	return p.AppendPipeline.AppendEntries(args, resp)
+}
+
+// Consumer returns the channel delivering the completed appends of the pipeline
+func (p *transferPipeline) Consumer() <-chan raft.AppendFuture {
+	return p.consumer
+}
+
+// observe records the response of each completed append before passing it on to raft
+func (p *transferPipeline) observe() {
+	responses := p.AppendPipeline.Consumer()
+	for {
+		var future raft.AppendFuture
+		select {
+		case future = <-responses:
+		case <-p.done:
+			return
+		}
+
+		if future.Error() == nil {
+			p.transport.store.replicated(p.target, future.Request(), future.Response())
+		}
+
+		select {
+		case p.consumer <- future:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Close stops observing the pipeline and closes it
+func (p *transferPipeline) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	return p.AppendPipeline.Close()
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultSessionTTL is the time a session holding ephemeral values remains alive without a heartbeat
+// when the server does not specify otherwise
+const DefaultSessionTTL = 10 * time.Second
+
+// leaseTimeout bounds the time taken to keep alive or revoke the lease of a session
+const leaseTimeout = 5 * time.Second
+
+var errUnknownSession = grpc.Errorf(codes.NotFound, "The session is not held by this node")
+
+var errEphemeralLease = errors.New("An ephemeral value may not be given a ttl or a lease")
+
+// ErrSessionExpired ends the update stream of a session holding ephemeral values that did not heartbeat within its time-to-live
+var ErrSessionExpired = grpc.Errorf(codes.DeadlineExceeded, "The session expired because it did not heartbeat within its time-to-live")
+
+// ErrSessionLeaseExpired ends the update stream of a session whose lease expired or was revoked, removing its ephemeral values
+var ErrSessionLeaseExpired = grpc.Errorf(codes.DeadlineExceeded, "The session ended because the lease holding its ephemeral values no longer exists")
+
+// sessionTTL returns the time a session holding ephemeral values remains alive without a heartbeat
+func (s *Server) sessionTTL() time.Duration {
+	if s.SessionTTL <= 0 {
+		return DefaultSessionTTL
+	}
+	return s.SessionTTL
+}
+
+// localSession returns the session if it is held by this node
+func (s *Server) localSession(identifier string) (*Session, error) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	session, ok := s.sessions[identifier]
+	if !ok {
+		return nil, errUnknownSession
+	}
+	return session, nil
+}
+
+// sessionLease returns the lease holding the ephemeral values of the session, granting it through the leader when
+// the session sets its first ephemeral value.  The lease is kept alive by this node while the session heartbeats,
+// and revoked when the session ends, so that every node removes the session's ephemeral values.  Should this node
+// fail, the lease expires along with the session's values.
+func (s *Server) sessionLease(ctx context.Context, identifier string) (uint64, error) {
+	session, err := s.localSession(identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	session.leaseMu.Lock()
+	defer session.leaseMu.Unlock()
+
+	session.heartbeat = time.Now()
+	if session.lease > 0 {
+		return session.lease, nil
+	}
+
+	ttl := s.sessionTTL()
+	resp, err := s.GrantLease(ctx, &pb.GrantLeaseRequest{Session: identifier, Ttl: int64(ttl / time.Millisecond)})
+	if err != nil {
+		return 0, err
+	}
+
+	session.lease = resp.Lease
+	go s.keepSessionAlive(session, resp.Lease, ttl)
+	return session.lease, nil
+}
+
+// keepSessionAlive keeps the lease of the session alive until the session ends or fails to heartbeat within
+// the time-to-live, and then revokes it.  Should the lease no longer exist, the session is ended so that its client
+// learns that its ephemeral values were removed.
+func (s *Server) keepSessionAlive(session *Session, lease uint64, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+		defer cancel()
+		s.RevokeLease(ctx, &pb.RevokeLeaseRequest{Session: session.ID, Lease: lease})
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-session.done:
+			return
+		}
+
+		session.leaseMu.Lock()
+		lapsed := time.Since(session.heartbeat) > ttl
+		session.leaseMu.Unlock()
+
+		if lapsed {
+			session.close(ErrSessionExpired)
+			s.removeSession(session.ID)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+		_, err := s.KeepAliveLease(ctx, &pb.KeepAliveLeaseRequest{Session: session.ID, Lease: lease})
+		cancel()
+
+		if grpc.Code(err) == codes.NotFound {
+			session.close(ErrSessionLeaseExpired)
+			s.removeSession(session.ID)
+			return
+		}
+	}
+}
+
+// KeepAliveSession records a heartbeat of a session holding ephemeral values, which ends unless it heartbeats
+// within its time-to-live
+func (s *Server) KeepAliveSession(ctx context.Context, req *pb.KeepAliveSessionRequest) (*pb.KeepAliveSessionResponse, error) {
+	s.initialize()
+
+	if len(req.Session) == 0 {
+		return nil, errors.New("KeepAliveSession requires that you provide a valid session")
+	}
+
+	session, err := s.localSession(req.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	session.leaseMu.Lock()
+	session.heartbeat = time.Now()
+	session.leaseMu.Unlock()
+
+	return &pb.KeepAliveSessionResponse{
+		Session: req.Session,
+		Ttl:     int64(s.sessionTTL() / time.Millisecond),
+	}, nil
+}
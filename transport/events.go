@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultEventLogSize is the number of updates retained for replay when the server does not specify otherwise
+const DefaultEventLogSize = 10000
+
+var errEventsCompacted = grpc.Errorf(codes.OutOfRange, "Updates following the requested revision are no longer retained, the subscriber must resync")
+
+// eventLog retains the most recent updates in the order they were published, so that a subscription
+// can begin from a past revision
+type eventLog struct {
+	events []*pb.Update
+	start  int
+	count  int
+
+	// compacted is the highest index whose updates may no longer be retained
+	compacted uint64
+
+	// latest is the highest index whose updates have been published
+	latest uint64
+}
+
+// newEventLog returns a log retaining up to size updates, where the updates of every index up to and
+// including compacted are unknown
+func newEventLog(size int, compacted uint64) *eventLog {
+	if size <= 0 {
+		size = DefaultEventLogSize
+	}
+	return &eventLog{events: make([]*pb.Update, size), compacted: compacted}
+}
+
+// append retains the update, discarding the oldest update if the log is full
+func (l *eventLog) append(u *pb.Update) {
+	if u.Index > l.latest {
+		l.latest = u.Index
+	}
+
+	if l.count == len(l.events) {
+		if oldest := l.events[l.start]; oldest.Index > l.compacted {
+			l.compacted = oldest.Index
+		}
+		l.events[l.start] = u
+		l.start = (l.start + 1) % len(l.events)
+		return
+	}
+
+	l.events[(l.start+l.count)%len(l.events)] = u
+	l.count++
+}
+
+// last returns the index of the last update published
+func (l *eventLog) last() uint64 {
+	if l.compacted > l.latest {
+		return l.compacted
+	}
+	return l.latest
+}
+
+// since returns the retained updates matched by fn that were applied at or after the revision,
+// or an error if some of those updates are no longer retained
+func (l *eventLog) since(revision uint64, fn func(u *pb.Update) bool) ([]*pb.Update, error) {
+	if revision <= l.compacted {
+		return nil, errEventsCompacted
+	}
+
+	var updates []*pb.Update
+	for i := 0; i < l.count; i++ {
+		u := l.events[(l.start+i)%len(l.events)]
+		if u.Index >= revision && fn(u) {
+			updates = append(updates, u)
+		}
+	}
+	return updates, nil
+}
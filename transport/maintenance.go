@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/forestgiant/iris"
+	"github.com/forestgiant/iris/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultTransferTimeout bounds the time taken to transfer leadership when the request has no deadline
+const DefaultTransferTimeout = 10 * time.Second
+
+// transferPollInterval is how often the leader checks whether the target of a transfer has caught up with its log
+const transferPollInterval = 50 * time.Millisecond
+
+// ErrDraining is returned to clients of a node draining its sessions for maintenance, which should reconnect to another node
+var ErrDraining = grpc.Errorf(codes.Unavailable, "%s", iris.DrainingMessage)
+
+// TransferLeadership moves leadership of the cluster to the node reachable at the provided raft address.
+// The transfer must complete before the deadline of the request, or DefaultTransferTimeout if it has none.
+func (s *Server) TransferLeadership(ctx context.Context, req *pb.TransferLeadershipRequest) (*pb.TransferLeadershipResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.TransferLeadership(ctx, req, s.leaderAddress)
+	}
+
+	if len(req.Address) == 0 {
+		return nil, errors.New("TransferLeadership requires that you provide the raft address of the node to transfer leadership to")
+	}
+
+	if s.Proxy == nil {
+		return nil, errors.New("Failed to reach the node leadership is transferred to: No proxy mechanism configured")
+	}
+
+	timeout := DefaultTransferTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
+	}
+
+	// The target must apply every entry of the leader's log before the leader steps down for it
+	caughtUp := func(index uint64) error {
+		for {
+			status, err := s.Proxy.ClusterStatus(ctx, &pb.ClusterStatusRequest{}, s.grpcAddress(req.Address))
+			if err != nil {
+				return err
+			}
+
+			if status.AppliedIndex >= index {
+				return nil
+			}
+
+			select {
+			case <-time.After(transferPollInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	if err := s.Store.TransferLeadership(req.Address, timeout, caughtUp); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &pb.TransferLeadershipResponse{
+		Leader: s.Leader(),
+	}, nil
+}
+
+// Drain begins or ends the maintenance mode of this node.  While draining, the node refuses new sessions with
+// ErrDraining and reports that it is not ready, and beginning to drain ends every session it holds with ErrDraining,
+// so that their clients reconnect to another node.  Ending a session revokes its lease, so the ephemeral values of
+// the drained sessions are removed along with the locks and leadership they hold.  Requests made without a session
+// are still served.
+func (s *Server) Drain(ctx context.Context, req *pb.DrainRequest) (*pb.DrainResponse, error) {
+	s.initialize()
+
+	var ended []string
+	s.sessionsMutex.Lock()
+	s.draining = req.Draining
+	if s.draining {
+		for identifier, session := range s.sessions {
+			session.close(ErrDraining)
+			ended = append(ended, identifier)
+		}
+	}
+	s.sessionsMutex.Unlock()
+
+	// Sessions that are listening are also removed once their update stream ends
+	for _, identifier := range ended {
+		s.removeSession(identifier)
+	}
+
+	return &pb.DrainResponse{
+		Sessions: uint32(len(ended)),
+	}, nil
+}
+
+// isDraining indicates whether this node is draining its sessions for maintenance
+func (s *Server) isDraining() bool {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	return s.draining
+}
@@ -0,0 +1,121 @@
+package transport
+
+import "github.com/forestgiant/iris"
+
+// patternSubscription identifies a session's subscription to the keys matching a pattern within the sources
+// matching a pattern, where an empty key pattern matches every key
+type patternSubscription struct {
+	session string
+	source  string
+	key     string
+}
+
+// matches indicates whether the subscription matches the source and key
+func (ps patternSubscription) matches(source, key string) bool {
+	if !iris.MatchPattern(ps.source, source) {
+		return false
+	}
+	return len(ps.key) == 0 || iris.MatchPattern(ps.key, key)
+}
+
+// patternIndex holds pattern subscriptions in a trie keyed by the literal prefix of their source pattern, so that
+// publishing an update only matches it against the patterns whose prefix begins its source
+type patternIndex struct {
+	root     *patternNode
+	sessions map[string]map[patternSubscription]struct{}
+}
+
+type patternNode struct {
+	children      map[byte]*patternNode
+	subscriptions map[patternSubscription]struct{}
+}
+
+func newPatternIndex() *patternIndex {
+	return &patternIndex{
+		root:     &patternNode{},
+		sessions: make(map[string]map[patternSubscription]struct{}),
+	}
+}
+
+// add indexes the subscription
+func (x *patternIndex) add(ps patternSubscription) {
+	node := x.root
+	prefix := iris.PatternPrefix(ps.source)
+	for i := 0; i < len(prefix); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*patternNode)
+		}
+
+		child, ok := node.children[prefix[i]]
+		if !ok {
+			child = &patternNode{}
+			node.children[prefix[i]] = child
+		}
+		node = child
+	}
+
+	if node.subscriptions == nil {
+		node.subscriptions = make(map[patternSubscription]struct{})
+	}
+	node.subscriptions[ps] = struct{}{}
+
+	if x.sessions[ps.session] == nil {
+		x.sessions[ps.session] = make(map[patternSubscription]struct{})
+	}
+	x.sessions[ps.session][ps] = struct{}{}
+}
+
+// remove discards the subscription, pruning the nodes left without subscriptions or children,
+// and indicates whether the subscription was indexed
+func (x *patternIndex) remove(ps patternSubscription) bool {
+	if _, ok := x.sessions[ps.session][ps]; !ok {
+		return false
+	}
+
+	delete(x.sessions[ps.session], ps)
+	if len(x.sessions[ps.session]) == 0 {
+		delete(x.sessions, ps.session)
+	}
+
+	prefix := iris.PatternPrefix(ps.source)
+	path := []*patternNode{x.root}
+	for i := 0; i < len(prefix); i++ {
+		path = append(path, path[i].children[prefix[i]])
+	}
+
+	delete(path[len(path)-1].subscriptions, ps)
+	for i := len(path) - 1; i > 0; i-- {
+		if len(path[i].subscriptions) > 0 || len(path[i].children) > 0 {
+			break
+		}
+		delete(path[i-1].children, prefix[i-1])
+	}
+	return true
+}
+
+// removeSession discards every subscription of the session
+func (x *patternIndex) removeSession(session string) {
+	for ps := range x.sessions[session] {
+		x.remove(ps)
+	}
+}
+
+// match calls fn with the session of each subscription matching the source and key
+func (x *patternIndex) match(source, key string, fn func(session string)) {
+	node := x.root
+	for i := 0; ; i++ {
+		for ps := range node.subscriptions {
+			if ps.matches(source, key) {
+				fn(ps.session)
+			}
+		}
+
+		if i == len(source) {
+			return
+		}
+
+		if node = node.children[source[i]]; node == nil {
+			return
+		}
+	}
+}
@@ -2,121 +2,365 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
-	"net"
-	"strconv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
 
-	iris_api "github.com/forestgiant/iris/api"
 	"github.com/forestgiant/iris/pb"
+	"github.com/forestgiant/iris/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
+// DefaultElectionWait is the time a forwarded request waits for a leader to be elected when the proxy does not
+// specify otherwise
+const DefaultElectionWait = 5 * time.Second
+
+// leaderPollInterval is how often a forwarded request checks whether a leader has been elected
+const leaderPollInterval = 50 * time.Millisecond
+
+// forwardedKey is the metadata key marking a request forwarded to the leader, so that it is not forwarded again
+const forwardedKey = "iris-forwarded"
+
+// errNotLeader is returned by a node that received a forwarded request after losing leadership of the cluster
+var errNotLeader = grpc.Errorf(codes.Unavailable, "The node is not the leader of the cluster")
+
+// errNoLeader is returned when no leader was elected within the time a forwarded request waits for an election
+var errNoLeader = grpc.Errorf(codes.Unavailable, "The cluster has no leader to forward the request to")
+
 //Proxy is used to redirect request to an alternate Iris instance
 type Proxy struct {
-	ServerName string
-	CertPath   string
-	KeyPath    string
-	CAPath     string
+	ServerName   string
+	CertPath     string
+	KeyPath      string
+	CAPath       string
+	Insecure     bool          //forward requests without transport security
+	ElectionWait time.Duration //time a request waits for a leader to be elected, DefaultElectionWait if zero
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn //long-lived connections to the other nodes, by grpc address
+	creds credentials.TransportCredentials
 }
 
-var errProxyLeader = errors.New("Unable to determine appropriate proxy address for raft cluster leader")
+// dialOptions returns the options used to connect to the other nodes, loading the proxy's credentials once
+func (p *Proxy) dialOptions() ([]grpc.DialOption, error) {
+	if p.Insecure {
+		return []grpc.DialOption{grpc.WithInsecure()}, nil
+	}
 
-func (p *Proxy) getProxyAddress(leaderRaftAddr string) string {
-	host, portString, err := net.SplitHostPort(leaderRaftAddr)
-	port, err := strconv.Atoi(portString)
-	if err != nil {
-		return ""
+	if p.creds == nil {
+		creds, err := clientCredentials(p.ServerName, p.CertPath, p.KeyPath, p.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		p.creds = creds
 	}
-	return net.JoinHostPort(host, strconv.Itoa(port-1))
+	return []grpc.DialOption{grpc.WithTransportCredentials(p.creds)}, nil
 }
 
-func (p *Proxy) getProxyClient(ctx context.Context, address string) (*iris_api.Client, error) {
-	proxyAddr := p.getProxyAddress(address)
-	return iris_api.NewTLSClient(ctx, proxyAddr, p.ServerName, p.CertPath, p.KeyPath, p.CAPath)
-}
+// getProxyClient returns a client of the node serving grpc requests at the address.  The connection to each node
+// is made once and shared by every request forwarded to it.
+func (p *Proxy) getProxyClient(addr string) (pb.IrisClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-//Join is used to redirect a Join request to an alternate server
-func (p *Proxy) Join(ctx context.Context, req *pb.JoinRequest, addr string) (*pb.JoinResponse, error) {
-	client, err := p.getProxyClient(ctx, addr)
+	if conn, ok := p.conns[addr]; ok {
+		return pb.NewIrisClient(conn), nil
+	}
+
+	opts, err := p.dialOptions()
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
 
-	if err := client.Join(ctx, req.Address); err != nil {
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
 		return nil, err
 	}
 
-	return &pb.JoinResponse{}, nil
+	if p.conns == nil {
+		p.conns = make(map[string]*grpc.ClientConn)
+	}
+	p.conns[addr] = conn
+	return pb.NewIrisClient(conn), nil
 }
 
-//SetValue is used to redirect a SetValue request to an alternate server
-func (p *Proxy) SetValue(ctx context.Context, req *pb.SetValueRequest, addr string) (*pb.SetValueResponse, error) {
-	client, err := p.getProxyClient(ctx, addr)
-	if err != nil {
-		return nil, err
+// Close tears down the proxy's connections to the other nodes
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for addr, conn := range p.conns {
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		delete(p.conns, addr)
 	}
-	defer client.Close()
+	return err
+}
 
-	if err := client.SetValue(ctx, req.Source, req.Key, req.Value); err != nil {
-		return nil, err
+// electionWait returns the time a forwarded request waits for a leader to be elected
+func (p *Proxy) electionWait() time.Duration {
+	if p.ElectionWait <= 0 {
+		return DefaultElectionWait
 	}
+	return p.ElectionWait
+}
+
+// awaitLeader returns the grpc address of the leader reported by leader, waiting for an election while there is
+// no leader or the leader is still reported as the node that was found to no longer lead the cluster
+func (p *Proxy) awaitLeader(ctx context.Context, leader func() string, previous string) (string, error) {
+	deadline := time.Now().Add(p.electionWait())
+	for {
+		if addr := leader(); len(addr) > 0 && addr != previous {
+			return addr, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", errNoLeader
+		}
 
-	return &pb.SetValueResponse{
-		Value: req.Value,
-	}, nil
+		select {
+		case <-time.After(leaderPollInterval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
 }
 
-//GetValue is used to redirect a GetValue request to an alternate server
-func (p *Proxy) GetValue(ctx context.Context, req *pb.GetValueRequest, addr string) (*pb.GetValueResponse, error) {
-	client, err := p.getProxyClient(ctx, addr)
-	if err != nil {
-		return nil, err
+// forward sends the request made by fn to the leader of the cluster, whose grpc address is reported by leader.  A request reaching a node that is no longer
+// the leader is retried once when a new leader is known.  A request that was itself forwarded to this node is not
+// forwarded again, so that requests do not travel between nodes that disagree about the leader.
+func (p *Proxy) forward(ctx context.Context, leader func() string, fn func(ctx context.Context, rpc pb.IrisClient) error) error {
+	if md, ok := metadata.FromContext(ctx); ok && len(md[forwardedKey]) > 0 {
+		return errNotLeader
 	}
-	defer client.Close()
+	ctx = metadata.NewContext(ctx, metadata.Pairs(forwardedKey, "true"))
 
-	value, err := client.GetValue(ctx, req.Source, req.Key)
-	if err != nil {
-		return nil, err
+	var previous string
+	for attempt := 0; ; attempt++ {
+		addr, err := p.awaitLeader(ctx, leader, previous)
+		if err != nil {
+			return err
+		}
+
+		rpc, err := p.getProxyClient(addr)
+		if err != nil {
+			return err
+		}
+
+		err = fn(ctx, rpc)
+		if attempt > 0 || !isNotLeader(err) {
+			return err
+		}
+		previous = addr
 	}
+}
 
-	return &pb.GetValueResponse{
-		Value: value,
-	}, nil
+// isNotLeader indicates whether the error was produced by a node that is no longer the leader of the cluster, or
+// that is handing leadership to another node
+func isNotLeader(err error) bool {
+	if err == nil || grpc.Code(err) != codes.Unavailable {
+		return false
+	}
+
+	desc := grpc.ErrorDesc(err)
+	return desc == grpc.ErrorDesc(errNotLeader) || desc == store.ErrTransferring.Error()
 }
 
-//RemoveValue is used to redirect a RemoveValue request to an alternate server
-func (p *Proxy) RemoveValue(ctx context.Context, req *pb.RemoveValueRequest, addr string) (*pb.RemoveValueResponse, error) {
-	client, err := p.getProxyClient(ctx, addr)
+// clientCredentials loads the certificates used to make TLS connections to the server with the common name
+func clientCredentials(serverName string, cert string, privateKey string, certificateAuthority string) (credentials.TransportCredentials, error) {
+	if len(cert) == 0 || len(privateKey) == 0 || len(certificateAuthority) == 0 || len(serverName) == 0 {
+		return nil, errors.New("Insufficient security credentials provided")
+	}
+
+	// Load the client certificates from disk
+	certificate, err := tls.LoadX509KeyPair(cert, privateKey)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Could not load client key pair: %s", err)
 	}
-	defer client.Close()
 
-	if err := client.RemoveValue(ctx, req.Source, req.Key); err != nil {
-		return nil, err
+	// Create a certificate pool from the certificate authority
+	certPool := x509.NewCertPool()
+	ca, err := ioutil.ReadFile(certificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read ca certificate: %s", err)
 	}
 
-	return &pb.RemoveValueResponse{
-		Session: "",
-		Source:  req.Source,
-		Key:     req.Key,
-	}, nil
+	// Append the certificates from the CA
+	if ok := certPool.AppendCertsFromPEM(ca); !ok {
+		return nil, errors.New("Failed to append ca certs")
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		ServerName:   serverName,
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      certPool,
+	}), nil
 }
 
-//RemoveSource is used to redirect a RemoveSource request to an alternate server
-func (p *Proxy) RemoveSource(ctx context.Context, req *pb.RemoveSourceRequest, addr string) (*pb.RemoveSourceResponse, error) {
-	client, err := p.getProxyClient(ctx, addr)
+//Join is used to redirect a Join request to an alternate server
+func (p *Proxy) Join(ctx context.Context, req *pb.JoinRequest, leader func() string) (resp *pb.JoinResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.Join(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//RemovePeer is used to redirect a RemovePeer request to an alternate server
+func (p *Proxy) RemovePeer(ctx context.Context, req *pb.RemovePeerRequest, leader func() string) (resp *pb.RemovePeerResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.RemovePeer(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//TransferLeadership is used to redirect a TransferLeadership request to an alternate server
+func (p *Proxy) TransferLeadership(ctx context.Context, req *pb.TransferLeadershipRequest, leader func() string) (resp *pb.TransferLeadershipResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.TransferLeadership(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//ClusterStatus is used to request the cluster status of the node serving grpc requests at the address
+func (p *Proxy) ClusterStatus(ctx context.Context, req *pb.ClusterStatusRequest, addr string) (*pb.ClusterStatusResponse, error) {
+	rpc, err := p.getProxyClient(addr)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
+	return rpc.ClusterStatus(ctx, req)
+}
 
-	if err := client.RemoveSource(ctx, req.Source); err != nil {
-		return nil, err
-	}
+//SetValue is used to redirect a SetValue request to an alternate server
+func (p *Proxy) SetValue(ctx context.Context, req *pb.SetValueRequest, leader func() string) (resp *pb.SetValueResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.SetValue(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//GetValue is used to redirect a GetValue request to an alternate server
+func (p *Proxy) GetValue(ctx context.Context, req *pb.GetValueRequest, leader func() string) (resp *pb.GetValueResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.GetValue(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//GetSources is used to redirect a GetSources request to an alternate server
+func (p *Proxy) GetSources(ctx context.Context, req *pb.GetSourcesRequest, stream pb.Iris_GetSourcesServer, leader func() string) error {
+	return p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		sources, err := rpc.GetSources(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for {
+			resp, err := sources.Recv()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+//GetKeys is used to redirect a GetKeys request to an alternate server
+func (p *Proxy) GetKeys(ctx context.Context, req *pb.GetKeysRequest, stream pb.Iris_GetKeysServer, leader func() string) error {
+	return p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		keys, err := rpc.GetKeys(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for {
+			resp, err := keys.Recv()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+//RemoveValue is used to redirect a RemoveValue request to an alternate server
+func (p *Proxy) RemoveValue(ctx context.Context, req *pb.RemoveValueRequest, leader func() string) (resp *pb.RemoveValueResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.RemoveValue(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//RemoveSource is used to redirect a RemoveSource request to an alternate server
+func (p *Proxy) RemoveSource(ctx context.Context, req *pb.RemoveSourceRequest, leader func() string) (resp *pb.RemoveSourceResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.RemoveSource(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//Txn is used to redirect a Txn request to an alternate server
+func (p *Proxy) Txn(ctx context.Context, req *pb.TxnRequest, leader func() string) (resp *pb.TxnResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.Txn(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//GrantLease is used to redirect a GrantLease request to an alternate server
+func (p *Proxy) GrantLease(ctx context.Context, req *pb.GrantLeaseRequest, leader func() string) (resp *pb.GrantLeaseResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.GrantLease(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+//KeepAliveLease is used to redirect a KeepAliveLease request to an alternate server
+func (p *Proxy) KeepAliveLease(ctx context.Context, req *pb.KeepAliveLeaseRequest, leader func() string) (resp *pb.KeepAliveLeaseResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.KeepAliveLease(ctx, req)
+		return err
+	})
+	return resp, err
+}
 
-	return &pb.RemoveSourceResponse{
-		Session: "",
-		Source:  req.Source,
-	}, nil
+//RevokeLease is used to redirect a RevokeLease request to an alternate server
+func (p *Proxy) RevokeLease(ctx context.Context, req *pb.RevokeLeaseRequest, leader func() string) (resp *pb.RevokeLeaseResponse, err error) {
+	err = p.forward(ctx, leader, func(ctx context.Context, rpc pb.IrisClient) error {
+		resp, err = rpc.RevokeLease(ctx, req)
+		return err
+	})
+	return resp, err
 }
@@ -2,38 +2,67 @@ package transport
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/forestgiant/iris/pb"
 	"github.com/forestgiant/iris/store"
+	"github.com/hashicorp/raft"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
+var errRemoveIfAbsent = errors.New("The absent condition is not supported when removing a value")
+
+var errSnapshotReplay = grpc.Errorf(codes.InvalidArgument, "A subscription cannot both replay updates and begin with a snapshot")
+
+var errSnapshotExhausted = grpc.Errorf(codes.ResourceExhausted, "The snapshot holds more updates than the session's queue has room for")
+
+// updateOperations maps the operations of published store updates to their protobuf representation
+var updateOperations = map[string]pb.UpdateOperation{
+	store.OperationSet:           pb.UpdateOperation_SET,
+	store.OperationDeleteKey:     pb.UpdateOperation_DELETE_KEY,
+	store.OperationDeleteSource:  pb.UpdateOperation_DELETE_SOURCE,
+	store.OperationExpire:        pb.UpdateOperation_EXPIRE,
+	store.OperationSnapshot:      pb.UpdateOperation_SNAPSHOT,
+	store.OperationSourceCreated: pb.UpdateOperation_SOURCE_CREATED,
+	store.OperationSourceDeleted: pb.UpdateOperation_SOURCE_DELETED,
+}
+
 // SourceFactory describes a method that returns a new source with the provided identifier
 // type SourceFactory func(identifier string) iris.Source
 
 // SessionMap is a map used to efficiently store and search for sessions
 type SessionMap map[string]struct{}
 
-// Session represents an server-side update stream
-type Session struct {
-	ID       string
-	Listener pb.Iris_ListenServer
-}
-
 // Server implements the generated pb.IrisServer interface
 type Server struct {
-	Store           *store.Store                     //data storage using raft consensus mechanisms
-	Proxy           *Proxy                           //request proxying mechanism
-	initialized     bool                             //indicates whether Init has been called
-	sessions        map[string]*Session              //collection of sessions
-	sessionsMutex   *sync.Mutex                      //used to lock the sessions collection
-	sourceSubs      map[string]SessionMap            //collection of sessions subscribed to sources
-	sourceSubsMutex *sync.Mutex                      //used to lock the source subscriptions collection
-	keySubs         map[string]map[string]SessionMap //collection of sessions subscribed to a source and key
-	keySubsMutex    *sync.Mutex                      //used to lock the key subscriptions collection
+	Store               *store.Store                     //data storage using raft consensus mechanisms
+	Proxy               *Proxy                           //request proxying mechanism
+	initialized         bool                             //indicates whether Init has been called
+	sessions            map[string]*Session              //collection of sessions
+	sessionsMutex       *sync.Mutex                      //used to lock the sessions collection
+	sourceSubs          map[string]SessionMap            //collection of sessions subscribed to sources
+	sourceSubsMutex     *sync.Mutex                      //used to lock the source subscriptions collection
+	keySubs             map[string]map[string]SessionMap //collection of sessions subscribed to a source and key
+	keySubsMutex        *sync.Mutex                      //used to lock the key subscriptions collection
+	sourceListSubs      SessionMap                       //collection of sessions subscribed to the creation and deletion of sources
+	sourceListSubsMutex *sync.Mutex                      //used to lock the source list subscriptions collection
+	patterns            *patternIndex                    //collection of sessions subscribed to source and key patterns
+	patternsMutex       *sync.Mutex                      //used to lock the pattern subscriptions collection
+	QueueSize           int                              //number of updates queued for each session, DefaultQueueSize if zero
+	SlowConsumer        string                           //policy applied when a session's queue is full, SlowConsumerDisconnect if empty
+	EventLogSize        int                              //number of updates retained for replay to new subscriptions, DefaultEventLogSize if zero
+	SessionTTL          time.Duration                    //time a session holding ephemeral values remains alive without a heartbeat, DefaultSessionTTL if zero
+	draining            bool                             //indicates whether new sessions are refused for maintenance, locked by the sessions mutex
+	events              *eventLog                        //updates retained for replay to new subscriptions
+	eventsMutex         *sync.Mutex                      //used to lock the event log
 }
 
 //initialize the server's caching/state mechanisms
@@ -46,11 +75,22 @@ func (s *Server) initialize() {
 	s.sessionsMutex = &sync.Mutex{}
 	s.sourceSubsMutex = &sync.Mutex{}
 	s.keySubsMutex = &sync.Mutex{}
+	s.sourceListSubsMutex = &sync.Mutex{}
+	s.patterns = newPatternIndex()
+	s.patternsMutex = &sync.Mutex{}
+	s.eventsMutex = &sync.Mutex{}
+
+	s.eventsMutex.Lock()
+	defer s.eventsMutex.Unlock()
+	s.events = newEventLog(s.EventLogSize, 0)
 
 	if s.Store != nil {
-		s.Store.PublishCallback = func(source, key string, value []byte) {
-			s.publish(source, key, value)
+		s.Store.UpdateCallback = func(u *store.Update) {
+			s.publish(u)
 		}
+
+		// Updates applied before publishing began are unknown to the event log
+		s.events.compacted = s.Store.AppliedIndex()
 	}
 }
 
@@ -70,7 +110,36 @@ func (s *Server) Leader() string {
 	return s.Store.Leader()
 }
 
-// Join the node reachable at the provided address to this cluster
+// leaderAddress returns the grpc address of the cluster leader, or an empty string if unknown
+func (s *Server) leaderAddress() string {
+	leader := s.Leader()
+	if len(leader) == 0 {
+		return ""
+	}
+	return s.grpcAddress(leader)
+}
+
+// grpcAddress returns the grpc address advertised by the member of the cluster at the raft address.  Members that
+// have not recorded their address, such as those running an earlier release, are assumed to serve grpc requests on
+// the port preceding their raft port.
+func (s *Server) grpcAddress(raftAddr string) string {
+	if addr, ok := s.Store.PeerAddress(raftAddr); ok {
+		return addr
+	}
+
+	host, portString, err := net.SplitHostPort(raftAddr)
+	if err != nil {
+		return raftAddr
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return raftAddr
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port-1))
+}
+
+// Join the node reachable at the provided raft address to this cluster, recording the grpc address it advertises if provided
 func (s *Server) Join(ctx context.Context, req *pb.JoinRequest) (*pb.JoinResponse, error) {
 	s.initialize()
 
@@ -78,16 +147,137 @@ func (s *Server) Join(ctx context.Context, req *pb.JoinRequest) (*pb.JoinRespons
 		if s.Proxy == nil {
 			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
 		}
-		return s.Proxy.Join(ctx, req, s.Leader())
+		return s.Proxy.Join(ctx, req, s.leaderAddress)
 	}
 
-	if err := s.Store.Join(req.Address); err != nil {
-		return nil, err
+	if len(req.GrpcAddress) > 0 {
+		if _, _, err := net.SplitHostPort(req.GrpcAddress); err != nil {
+			return nil, fmt.Errorf("Join requires that the grpc address be a host and port: %s", err)
+		}
+	}
+
+	// Members record their grpc address with the leader when they start, and the leader is already a member
+	if req.Address != s.Store.RaftAddr() {
+		if err := s.Store.Join(req.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	// Joining nodes record the address they serve grpc requests at, so that requests can be forwarded to them
+	if len(req.GrpcAddress) > 0 {
+		if err := s.Store.SetPeerAddress(req.Address, req.GrpcAddress); err != nil {
+			return nil, storeError(err)
+		}
 	}
 
 	return &pb.JoinResponse{}, nil
 }
 
+// RemovePeer removes the node reachable at the provided raft address from this cluster
+func (s *Server) RemovePeer(ctx context.Context, req *pb.RemovePeerRequest) (*pb.RemovePeerResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.RemovePeer(ctx, req, s.leaderAddress)
+	}
+
+	if len(req.Address) == 0 {
+		return nil, errors.New("RemovePeer requires that you provide the raft address of the node to remove")
+	}
+
+	if err := s.Store.RemovePeer(req.Address); err != nil {
+		return nil, err
+	}
+
+	return &pb.RemovePeerResponse{}, nil
+}
+
+// Leave removes this node from its cluster.  A follower asks the leader to remove it.
+func (s *Server) Leave(ctx context.Context, req *pb.LeaveRequest) (*pb.LeaveResponse, error) {
+	s.initialize()
+
+	if s.Store == nil {
+		return nil, errors.New("No store is configured for this node")
+	}
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+
+		if _, err := s.Proxy.RemovePeer(ctx, &pb.RemovePeerRequest{Address: s.Store.RaftAddr()}, s.leaderAddress); err != nil {
+			return nil, err
+		}
+		return &pb.LeaveResponse{}, nil
+	}
+
+	if err := s.Store.Leave(); err != nil {
+		return nil, err
+	}
+
+	return &pb.LeaveResponse{}, nil
+}
+
+// ListPeers responds with the raft addresses of the members of this node's cluster
+func (s *Server) ListPeers(ctx context.Context, req *pb.ListPeersRequest) (*pb.ListPeersResponse, error) {
+	s.initialize()
+
+	if s.Store == nil {
+		return nil, errors.New("No store is configured for this node")
+	}
+
+	peers, err := s.Store.ListPeers()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListPeersResponse{
+		Peers: peers,
+	}, nil
+}
+
+// ClusterStatus responds with the raft state of this node and of each member of its cluster as seen by this node
+func (s *Server) ClusterStatus(ctx context.Context, req *pb.ClusterStatusRequest) (*pb.ClusterStatusResponse, error) {
+	s.initialize()
+
+	if s.Store == nil {
+		return nil, errors.New("No store is configured for this node")
+	}
+
+	status, err := s.Store.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ClusterStatusResponse{
+		Address:      status.Address,
+		State:        status.State,
+		Leader:       status.Leader,
+		Term:         status.Term,
+		CommitIndex:  status.CommitIndex,
+		AppliedIndex: status.AppliedIndex,
+	}
+
+	for _, peer := range status.Peers {
+		var lastContact int64
+		if !peer.LastContact.IsZero() {
+			lastContact = peer.LastContact.UnixNano()
+		}
+
+		resp.Peers = append(resp.Peers, &pb.Peer{
+			Address:     peer.Address,
+			State:       peer.State,
+			GrpcAddress: peer.GRPCAddress,
+			LastContact: lastContact,
+			MatchIndex:  peer.MatchIndex,
+		})
+	}
+	return resp, nil
+}
+
 // Connect responds with a stream of objects representing source, key, value updates
 func (s *Server) Connect(ctx context.Context, req *pb.ConnectRequest) (*pb.ConnectResponse, error) {
 	s.initialize()
@@ -97,7 +287,7 @@ func (s *Server) Connect(ctx context.Context, req *pb.ConnectRequest) (*pb.Conne
 		return nil, fmt.Errorf("Unable to generate session identifier. %s", err)
 	}
 
-	if _, err := s.addSession(session, nil); err != nil {
+	if _, err := s.addSession(session); err != nil {
 		return nil, err
 	}
 
@@ -110,26 +300,63 @@ func (s *Server) Connect(ctx context.Context, req *pb.ConnectRequest) (*pb.Conne
 func (s *Server) Listen(req *pb.ListenRequest, stream pb.Iris_ListenServer) error {
 	s.initialize()
 
-	if _, err := s.addSession(req.Session, stream); err != nil {
+	session, err := s.listenSession(req.Session)
+	if err != nil {
+		return err
+	}
+
+	if err := session.attach(stream); err != nil {
 		return err
 	}
 
-	<-stream.Context().Done()
+	select {
+	case <-stream.Context().Done():
+		err = stream.Context().Err()
+	case <-session.done:
+		err = session.err
+	}
+
+	// The stream ends once the update being sent, if any, has been sent, so that the error is not lost with it
 	s.removeSession(req.Session)
-	return stream.Context().Err()
+	<-session.sent
+	return err
 }
 
 // GetSources responds with a stream of objects representing available sources
 func (s *Server) GetSources(req *pb.GetSourcesRequest, stream pb.Iris_GetSourcesServer) error {
 	s.initialize()
 
-	sources, err := s.Store.GetSources()
+	local, err := s.prepareRead(req.Consistency, req.MaxStaleness)
+	if err != nil {
+		return err
+	}
+
+	if !local {
+		if s.Proxy == nil {
+			return errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.GetSources(stream.Context(), req, stream, s.leaderAddress)
+	}
+
+	opts, err := listOptions(req.Prefix, req.Start, req.End, req.Limit, req.Continuation)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	for _, s := range sources {
-		if err := stream.Send(&pb.GetSourcesResponse{Source: s}); err != nil {
+	index := s.Store.AppliedIndex()
+	sources, err := s.Store.ListSources(opts)
+	if err != nil {
+		return err
+	}
+
+	sources, continuation := page(sources, req.Limit)
+	for i, src := range sources {
+		resp := &pb.GetSourcesResponse{Source: src, Index: index}
+		if i == len(sources)-1 {
+			resp.Continuation = continuation
+		}
+
+		if err := stream.Send(resp); err != nil {
 			return err
 		}
 	}
@@ -145,28 +372,120 @@ func (s *Server) GetKeys(req *pb.GetKeysRequest, stream pb.Iris_GetKeysServer) e
 		return errors.New("You must provide the source to retrieve keys for")
 	}
 
-	keys, err := s.Store.GetKeys(req.Source)
+	local, err := s.prepareRead(req.Consistency, req.MaxStaleness)
 	if err != nil {
 		return err
 	}
 
-	for _, k := range keys {
-		if err := stream.Send(&pb.GetKeysResponse{Key: k}); err != nil {
+	if !local {
+		if s.Proxy == nil {
+			return errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.GetKeys(stream.Context(), req, stream, s.leaderAddress)
+	}
+
+	opts, err := listOptions(req.Prefix, req.Start, req.End, req.Limit, req.Continuation)
+	if err != nil {
+		return err
+	}
+
+	index := s.Store.AppliedIndex()
+	keys, err := s.Store.ListKeys(req.Source, opts)
+	if err != nil {
+		return err
+	}
+
+	keys, continuation := page(keys, req.Limit)
+	for i, k := range keys {
+		resp := &pb.GetKeysResponse{Key: k, Index: index}
+		if i == len(keys)-1 {
+			resp.Continuation = continuation
+		}
+
+		if err := stream.Send(resp); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// SetValue sets the value for the specified source and key
+// prepareRead ensures the read can be served with the requested consistency, and indicates whether it should be served
+// from the local state machine rather than proxied to the leader
+func (s *Server) prepareRead(consistency pb.ReadConsistency, maxStaleness int64) (bool, error) {
+	if maxStaleness < 0 {
+		return false, grpc.Errorf(codes.InvalidArgument, "You must provide a positive max staleness for the read")
+	}
+
+	switch consistency {
+	case pb.ReadConsistency_STALE:
+		return true, storeError(s.Store.CheckStaleness(time.Duration(maxStaleness) * time.Millisecond))
+	case pb.ReadConsistency_LINEARIZABLE:
+		if !s.IsLeader() {
+			return false, nil
+		}
+		return true, s.Store.VerifyRead()
+	}
+	return s.IsLeader(), nil
+}
+
+// listOptions describes the requested range, resuming after the name identified by the continuation if provided.
+// One more name than the limit is requested, so that it can be determined whether the listing was cut short.
+func listOptions(prefix, start, end string, limit uint32, continuation string) (store.ListOptions, error) {
+	opts := store.ListOptions{Prefix: prefix, Start: start, End: end}
+	if len(continuation) > 0 {
+		last, err := base64.RawURLEncoding.DecodeString(continuation)
+		if err != nil {
+			return opts, grpc.Errorf(codes.InvalidArgument, "Invalid continuation token")
+		}
+
+		// The smallest name following the last one listed
+		if next := string(last) + "\x00"; next > opts.Start {
+			opts.Start = next
+		}
+	}
+
+	if limit > 0 {
+		opts.Limit = int(limit) + 1
+	}
+	return opts, nil
+}
+
+// page trims the listed names to the limit, returning a continuation token if any names remain
+func page(names []string, limit uint32) ([]string, string) {
+	if limit == 0 || len(names) <= int(limit) {
+		return names, ""
+	}
+
+	names = names[:limit]
+	return names, base64.RawURLEncoding.EncodeToString([]byte(names[len(names)-1]))
+}
+
+// SetValue sets the value for the specified source and key.  An ephemeral value must be set through the node
+// holding the session, and is removed when the session ends.
 func (s *Server) SetValue(ctx context.Context, req *pb.SetValueRequest) (*pb.SetValueResponse, error) {
 	s.initialize()
 
+	// Ephemeral values are attached to the lease of the session, which is held by this node
+	if req.Ephemeral {
+		if req.Ttl != 0 || req.Lease > 0 {
+			return nil, errEphemeralLease
+		}
+
+		lease, err := s.sessionLease(ctx, req.Session)
+		if err != nil {
+			return nil, err
+		}
+
+		attached := *req
+		attached.Ephemeral, attached.Lease = false, lease
+		req = &attached
+	}
+
 	if !s.IsLeader() {
 		if s.Proxy == nil {
 			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
 		}
-		return s.Proxy.SetValue(ctx, req, s.Leader())
+		return s.Proxy.SetValue(ctx, req, s.leaderAddress)
 	}
 
 	if len(req.Source) == 0 {
@@ -177,13 +496,34 @@ func (s *Server) SetValue(ctx context.Context, req *pb.SetValueRequest) (*pb.Set
 		return nil, errors.New("You must provide the key for the value you would like to set")
 	}
 
-	err := s.Store.Set(req.Source, req.Key, req.Value)
+	if req.Ttl < 0 {
+		return nil, errors.New("You must provide a positive ttl for the value")
+	}
+
+	opts := store.SetOptions{
+		TTL:   time.Duration(req.Ttl) * time.Millisecond,
+		Lease: req.Lease,
+	}
+
+	switch req.Condition {
+	case pb.Condition_REVISION_MATCHES:
+		if req.Revision == 0 {
+			return nil, errors.New("You must provide the revision the key is expected to be at")
+		}
+		opts.Conditional = true
+		opts.Revision = req.Revision
+	case pb.Condition_ABSENT:
+		opts.Conditional = true
+	}
+
+	revision, err := s.Store.SetWithOptions(req.Source, req.Key, req.Value, opts)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return &pb.SetValueResponse{
-		Value: req.Value,
+		Value:    req.Value,
+		Revision: revision,
 	}, nil
 }
 
@@ -191,11 +531,16 @@ func (s *Server) SetValue(ctx context.Context, req *pb.SetValueRequest) (*pb.Set
 func (s *Server) GetValue(ctx context.Context, req *pb.GetValueRequest) (*pb.GetValueResponse, error) {
 	s.initialize()
 
-	if !s.IsLeader() {
+	local, err := s.prepareRead(req.Consistency, req.MaxStaleness)
+	if err != nil {
+		return nil, err
+	}
+
+	if !local {
 		if s.Proxy == nil {
 			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
 		}
-		return s.Proxy.GetValue(ctx, req, s.Leader())
+		return s.Proxy.GetValue(ctx, req, s.leaderAddress)
 	}
 
 	if len(req.Source) == 0 {
@@ -206,13 +551,54 @@ func (s *Server) GetValue(ctx context.Context, req *pb.GetValueRequest) (*pb.Get
 		return nil, errors.New("You must provide the key for the value you would like to get")
 	}
 
-	value := s.Store.Get(req.Source, req.Key)
+	index := s.Store.AppliedIndex()
+	if req.Revision > 0 {
+		value, revision, err := s.Store.GetAt(req.Source, req.Key, req.Revision)
+		if err != nil {
+			return nil, storeError(err)
+		}
+
+		return &pb.GetValueResponse{
+			Value:    value,
+			Revision: revision,
+			Index:    index,
+		}, nil
+	}
+
+	value, revision := s.Store.Get(req.Source, req.Key)
 
 	return &pb.GetValueResponse{
-		Value: value,
+		Value:    value,
+		Revision: revision,
+		Index:    index,
 	}, nil
 }
 
+// GetHistory expects a source and key and responds with a stream of its retained versions, ending with the current value
+func (s *Server) GetHistory(req *pb.GetHistoryRequest, stream pb.Iris_GetHistoryServer) error {
+	s.initialize()
+
+	if len(req.Source) == 0 {
+		return errors.New("You must provide the source you would like to get the history for")
+	}
+
+	if len(req.Key) == 0 {
+		return errors.New("You must provide the key you would like to get the history for")
+	}
+
+	for _, v := range s.Store.GetHistory(req.Source, req.Key) {
+		if err := stream.Send(&pb.Version{
+			Value:     v.Value,
+			Revision:  v.Revision,
+			Timestamp: v.Timestamp,
+			Deleted:   v.Deleted,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // RemoveValue removes the specified value from the provided source
 func (s *Server) RemoveValue(ctx context.Context, req *pb.RemoveValueRequest) (*pb.RemoveValueResponse, error) {
 	s.initialize()
@@ -221,7 +607,7 @@ func (s *Server) RemoveValue(ctx context.Context, req *pb.RemoveValueRequest) (*
 		if s.Proxy == nil {
 			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
 		}
-		return s.Proxy.RemoveValue(ctx, req, s.Leader())
+		return s.Proxy.RemoveValue(ctx, req, s.leaderAddress)
 	}
 
 	if len(req.Source) == 0 {
@@ -232,8 +618,21 @@ func (s *Server) RemoveValue(ctx context.Context, req *pb.RemoveValueRequest) (*
 		return nil, errors.New("You must provide the key of the value you would like to be removed")
 	}
 
-	if err := s.Store.DeleteKey(req.Source, req.Key); err != nil {
-		return nil, err
+	var err error
+	switch req.Condition {
+	case pb.Condition_REVISION_MATCHES:
+		if req.Revision == 0 {
+			return nil, errors.New("You must provide the revision the key is expected to be at")
+		}
+		err = s.Store.CompareAndDelete(req.Source, req.Key, req.Revision)
+	case pb.Condition_ABSENT:
+		return nil, errRemoveIfAbsent
+	default:
+		err = s.Store.DeleteKey(req.Source, req.Key)
+	}
+
+	if err != nil {
+		return nil, storeError(err)
 	}
 
 	return &pb.RemoveValueResponse{
@@ -251,7 +650,7 @@ func (s *Server) RemoveSource(ctx context.Context, req *pb.RemoveSourceRequest)
 		if s.Proxy == nil {
 			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
 		}
-		return s.Proxy.RemoveSource(ctx, req, s.Leader())
+		return s.Proxy.RemoveSource(ctx, req, s.leaderAddress)
 	}
 
 	if len(req.Source) == 0 {
@@ -280,6 +679,32 @@ func (s *Server) Subscribe(ctx context.Context, req *pb.SubscribeRequest) (*pb.S
 		return nil, errors.New("Subscribe requires that you provide a source")
 	}
 
+	if req.Snapshot {
+		if req.StartRevision > 0 {
+			return nil, errSnapshotReplay
+		}
+
+		snapshot := func(fn func([]*store.Update, uint64)) error { return s.Store.Snapshot(req.Source, "", fn) }
+		revision, size, err := s.subscribeSnapshot(ctx, req.Session, snapshot, func() { s.subscribe(req) })
+		if err != nil {
+			return nil, err
+		}
+		return &pb.SubscribeResponse{Source: req.Source, Revision: revision, SnapshotSize: size}, nil
+	}
+
+	match := func(u *pb.Update) bool {
+		return u.Source == req.Source && !isSourceListUpdate(u)
+	}
+
+	revision, err := s.subscribeFrom(req.Session, req.StartRevision, match, func() { s.subscribe(req) })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubscribeResponse{Source: req.Source, Revision: revision}, nil
+}
+
+// subscribe adds the session to the subscribers of the source
+func (s *Server) subscribe(req *pb.SubscribeRequest) {
 	s.sourceSubsMutex.Lock()
 	defer s.sourceSubsMutex.Unlock()
 
@@ -293,7 +718,6 @@ func (s *Server) Subscribe(ctx context.Context, req *pb.SubscribeRequest) (*pb.S
 
 	var empty struct{}
 	s.sourceSubs[req.Source][req.Session] = empty
-	return &pb.SubscribeResponse{Source: req.Source}, nil
 }
 
 // SubscribeKey indicates that the client wishes to be notified of updates associated with
@@ -313,6 +737,125 @@ func (s *Server) SubscribeKey(ctx context.Context, req *pb.SubscribeKeyRequest)
 		return nil, errors.New("SubscribeKey requires that you provide a key")
 	}
 
+	if req.Snapshot {
+		if req.StartRevision > 0 {
+			return nil, errSnapshotReplay
+		}
+
+		snapshot := func(fn func([]*store.Update, uint64)) error { return s.Store.Snapshot(req.Source, req.Key, fn) }
+		revision, size, err := s.subscribeSnapshot(ctx, req.Session, snapshot, func() { s.subscribeKey(req) })
+		if err != nil {
+			return nil, err
+		}
+		return &pb.SubscribeKeyResponse{Source: req.Source, Key: req.Key, Revision: revision, SnapshotSize: size}, nil
+	}
+
+	match := func(u *pb.Update) bool {
+		return u.Source == req.Source && u.Key == req.Key
+	}
+
+	revision, err := s.subscribeFrom(req.Session, req.StartRevision, match, func() { s.subscribeKey(req) })
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubscribeKeyResponse{Source: req.Source, Key: req.Key, Revision: revision}, nil
+}
+
+// subscribeFrom registers a subscription and returns the index of the last update published before it began.  When a
+// revision is provided, the retained updates the subscription matches that were applied at or after it are first queued
+// for delivery to the session.  The event log remains locked throughout, so that no update is missed or delivered twice.
+func (s *Server) subscribeFrom(identifier string, revision uint64, match func(u *pb.Update) bool, register func()) (uint64, error) {
+	s.eventsMutex.Lock()
+	defer s.eventsMutex.Unlock()
+
+	if revision == 0 {
+		register()
+		return s.events.last(), nil
+	}
+
+	updates, err := s.events.since(revision, match)
+	if err != nil {
+		return 0, err
+	}
+
+	s.sessionsMutex.Lock()
+	session, ok := s.sessions[identifier]
+	s.sessionsMutex.Unlock()
+	if !ok {
+		return 0, errors.New("Unable to replay updates to an unknown session")
+	}
+
+	register()
+	for _, u := range updates {
+		session.enqueue(u)
+	}
+	return s.events.last(), nil
+}
+
+// subscribeSnapshot registers a subscription once the updates captured by snapshot, describing the current contents
+// of a source, a key or the source list, have been queued for delivery to the session.  The snapshot is captured under
+// the store's lock and queued in order with the published updates, so that the subscription receives every update
+// applied after the snapshot and none applied before it.  The index the snapshot was captured at is returned with the
+// number of updates it holds.  The subscription fails without being registered if its context is done before the
+// snapshot is queued, or if queueing the snapshot would drop its updates or disconnect the session.
+func (s *Server) subscribeSnapshot(ctx context.Context, identifier string, snapshot func(fn func([]*store.Update, uint64)) error, register func()) (uint64, uint32, error) {
+	s.sessionsMutex.Lock()
+	session, ok := s.sessions[identifier]
+	s.sessionsMutex.Unlock()
+	if !ok {
+		return 0, 0, errors.New("Unable to deliver a snapshot to an unknown session")
+	}
+
+	type snapshotResult struct {
+		revision uint64
+		size     uint32
+		err      error
+	}
+
+	done := make(chan snapshotResult, 1)
+	err := snapshot(func(updates []*store.Update, index uint64) {
+		s.eventsMutex.Lock()
+		defer s.eventsMutex.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			done <- snapshotResult{err: err}
+			return
+		}
+
+		if !session.accepts(len(updates)) {
+			done <- snapshotResult{err: errSnapshotExhausted}
+			return
+		}
+
+		register()
+		for _, u := range updates {
+			session.enqueue(pbUpdate(u))
+		}
+		done <- snapshotResult{revision: index, size: uint32(len(updates))}
+	})
+	if err != nil {
+		return 0, 0, storeError(err)
+	}
+
+	var r snapshotResult
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		// The snapshot is queued under the events lock, after which it either registered the subscription or will
+		// see that the context is done
+		s.eventsMutex.Lock()
+		select {
+		case r = <-done:
+		default:
+			r.err = ctx.Err()
+		}
+		s.eventsMutex.Unlock()
+	}
+	return r.revision, r.size, r.err
+}
+
+// subscribeKey adds the session to the subscribers of the key
+func (s *Server) subscribeKey(req *pb.SubscribeKeyRequest) {
 	s.keySubsMutex.Lock()
 	defer s.keySubsMutex.Unlock()
 
@@ -330,7 +873,6 @@ func (s *Server) SubscribeKey(ctx context.Context, req *pb.SubscribeKeyRequest)
 
 	var empty struct{}
 	s.keySubs[req.Source][req.Key][req.Session] = empty
-	return &pb.SubscribeKeyResponse{Source: req.Source, Key: req.Key}, nil
 }
 
 // Unsubscribe indicates that the client no longer wishes to be notified of updates for the specified source
@@ -357,6 +899,9 @@ func (s *Server) Unsubscribe(ctx context.Context, req *pb.UnsubscribeRequest) (*
 	}
 
 	delete(s.sourceSubs[req.Source], req.Session)
+	if len(s.sourceSubs[req.Source]) == 0 {
+		delete(s.sourceSubs, req.Source)
+	}
 	return &pb.UnsubscribeResponse{Source: req.Source}, nil
 }
 
@@ -389,40 +934,237 @@ func (s *Server) UnsubscribeKey(ctx context.Context, req *pb.UnsubscribeKeyReque
 	}
 
 	delete(s.keySubs[req.Source][req.Key], req.Session)
+	if len(s.keySubs[req.Source][req.Key]) == 0 {
+		delete(s.keySubs[req.Source], req.Key)
+		if len(s.keySubs[req.Source]) == 0 {
+			delete(s.keySubs, req.Source)
+		}
+	}
 	return &pb.UnsubscribeKeyResponse{Source: req.Source, Key: req.Key}, nil
 }
 
-// Sends the provided value to any streams subscribed to the specified source and key
-func (s *Server) publish(source string, key string, value []byte) error {
+// SubscribePattern indicates that the client wishes to be notified of updates to the keys matching a glob
+// pattern within the sources matching a glob pattern, where an empty key pattern matches every key
+func (s *Server) SubscribePattern(ctx context.Context, req *pb.SubscribePatternRequest) (*pb.SubscribePatternResponse, error) {
 	s.initialize()
 
-	update := &pb.Update{
-		Source: source,
-		Key:    key,
-		Value:  value,
+	if len(req.Session) == 0 {
+		return nil, errors.New("SubscribePattern requires that you provide a valid session")
 	}
 
-	notify := func(identifier string, update *pb.Update) error {
-		stream, ok := s.sessions[identifier]
-		if ok {
-			if stream.Listener != nil {
-				if err := stream.Listener.Send(update); err != nil {
-					return err
-				}
-			}
+	if len(req.Source) == 0 {
+		return nil, errors.New("SubscribePattern requires that you provide a source pattern")
+	}
+
+	ps := patternSubscription{session: req.Session, source: req.Source, key: req.Key}
+	match := func(u *pb.Update) bool {
+		return !isSourceListUpdate(u) && ps.matches(u.Source, u.Key)
+	}
+
+	revision, err := s.subscribeFrom(req.Session, req.StartRevision, match, func() {
+		s.patternsMutex.Lock()
+		defer s.patternsMutex.Unlock()
+		s.patterns.add(ps)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubscribePatternResponse{Source: req.Source, Key: req.Key, Revision: revision}, nil
+}
+
+// UnsubscribePattern indicates that the client no longer wishes to be notified of updates matching the patterns
+func (s *Server) UnsubscribePattern(ctx context.Context, req *pb.UnsubscribePatternRequest) (*pb.UnsubscribePatternResponse, error) {
+	s.initialize()
+
+	if len(req.Session) == 0 {
+		return nil, errors.New("UnsubscribePattern requires that you provide a valid session")
+	}
+
+	if len(req.Source) == 0 {
+		return nil, errors.New("UnsubscribePattern requires that you provide a source pattern")
+	}
+
+	s.patternsMutex.Lock()
+	defer s.patternsMutex.Unlock()
+
+	if !s.patterns.remove(patternSubscription{session: req.Session, source: req.Source, key: req.Key}) {
+		return &pb.UnsubscribePatternResponse{}, nil
+	}
+	return &pb.UnsubscribePatternResponse{Source: req.Source, Key: req.Key}, nil
+}
+
+// SubscribeSources indicates that the client wishes to be notified when sources are created or deleted
+func (s *Server) SubscribeSources(ctx context.Context, req *pb.SubscribeSourcesRequest) (*pb.SubscribeSourcesResponse, error) {
+	s.initialize()
+
+	if len(req.Session) == 0 {
+		return nil, errors.New("SubscribeSources requires that you provide a valid session")
+	}
+
+	register := func() {
+		s.sourceListSubsMutex.Lock()
+		defer s.sourceListSubsMutex.Unlock()
+
+		if s.sourceListSubs == nil {
+			s.sourceListSubs = make(SessionMap)
+		}
+		s.sourceListSubs[req.Session] = struct{}{}
+	}
+
+	if req.Snapshot {
+		if req.StartRevision > 0 {
+			return nil, errSnapshotReplay
+		}
+
+		revision, size, err := s.subscribeSnapshot(ctx, req.Session, s.Store.SnapshotSources, register)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.SubscribeSourcesResponse{Revision: revision, SnapshotSize: size}, nil
+	}
+
+	revision, err := s.subscribeFrom(req.Session, req.StartRevision, isSourceListUpdate, register)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubscribeSourcesResponse{Revision: revision}, nil
+}
+
+// UnsubscribeSources indicates that the client no longer wishes to be notified when sources are created or deleted
+func (s *Server) UnsubscribeSources(ctx context.Context, req *pb.UnsubscribeSourcesRequest) (*pb.UnsubscribeSourcesResponse, error) {
+	s.initialize()
+
+	if len(req.Session) == 0 {
+		return nil, errors.New("UnsubscribeSources requires that you provide a valid session")
+	}
+
+	s.sourceListSubsMutex.Lock()
+	defer s.sourceListSubsMutex.Unlock()
+
+	delete(s.sourceListSubs, req.Session)
+	return &pb.UnsubscribeSourcesResponse{}, nil
+}
+
+// Txn atomically applies a set of operations if all of the provided guards are satisfied
+func (s *Server) Txn(ctx context.Context, req *pb.TxnRequest) (*pb.TxnResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.Txn(ctx, req, s.leaderAddress)
+	}
+
+	if len(req.Operations) == 0 {
+		return nil, errors.New("You must provide at least one operation for the transaction")
+	}
+
+	var guards []store.TxnGuard
+	for _, g := range req.Guards {
+		if len(g.Source) == 0 || len(g.Key) == 0 {
+			return nil, errors.New("You must provide the source and key for each transaction guard")
 		}
 
-		return nil
+		guard := store.TxnGuard{Source: g.Source, Key: g.Key, Revision: g.Revision, Value: g.Value}
+		switch g.Type {
+		case pb.GuardType_KEY_EXISTS:
+			guard.Type = store.GuardKeyExists
+		case pb.GuardType_REVISION_EQUALS:
+			guard.Type = store.GuardRevisionEquals
+		case pb.GuardType_VALUE_EQUALS:
+			guard.Type = store.GuardValueEquals
+		default:
+			return nil, fmt.Errorf("Unrecognized transaction guard %s", g.Type)
+		}
+		guards = append(guards, guard)
 	}
 
-	var returnErrors []error
+	var ops []store.TxnOp
+	for _, o := range req.Operations {
+		if len(o.Source) == 0 {
+			return nil, errors.New("You must provide the source for each transaction operation")
+		}
+
+		op := store.TxnOp{Source: o.Source, Key: o.Key, Value: o.Value}
+		switch o.Type {
+		case pb.OperationType_SET_VALUE:
+			op.Operation = store.OperationSet
+		case pb.OperationType_REMOVE_VALUE:
+			op.Operation = store.OperationDeleteKey
+		case pb.OperationType_REMOVE_SOURCE:
+			op.Operation = store.OperationDeleteSource
+		default:
+			return nil, fmt.Errorf("Unrecognized transaction operation %s", o.Type)
+		}
+
+		if op.Operation != store.OperationDeleteSource && len(op.Key) == 0 {
+			return nil, errors.New("You must provide the key for each transaction operation")
+		}
+		ops = append(ops, op)
+	}
+
+	revision, err := s.Store.Transaction(guards, ops)
+	if err == store.ErrGuardFailed {
+		return &pb.TxnResponse{Succeeded: false}, nil
+	} else if err != nil {
+		return nil, storeError(err)
+	}
 
+	return &pb.TxnResponse{
+		Succeeded: true,
+		Revision:  revision,
+	}, nil
+}
+
+// Queues the provided update for delivery to any sessions subscribed to its source and key
+func (s *Server) publish(u *store.Update) error {
+	s.initialize()
+
+	source, key := u.Source, u.Key
+	update := pbUpdate(u)
+
+	// A session subscribed to both the source and the key receives the update once
+	identifiers := make(SessionMap)
+
+	s.eventsMutex.Lock()
+	s.events.append(update)
+
+	// Updates to the list of sources carry no key, and are only delivered to its subscribers
+	if isSourceListUpdate(update) {
+		s.sourceListSubsMutex.Lock()
+		for identifier := range s.sourceListSubs {
+			identifiers[identifier] = struct{}{}
+		}
+		s.sourceListSubsMutex.Unlock()
+	} else {
+		s.subscribers(source, key, identifiers)
+	}
+
+	var sessions []*Session
+	s.sessionsMutex.Lock()
+	for identifier := range identifiers {
+		if session, ok := s.sessions[identifier]; ok {
+			sessions = append(sessions, session)
+		}
+	}
+	s.sessionsMutex.Unlock()
+	s.eventsMutex.Unlock()
+
+	// Sessions are notified outside of the locks, since a full queue may block until its session catches up
+	for _, session := range sessions {
+		session.enqueue(update)
+	}
+
+	return nil
+}
+
+// subscribers adds the sessions subscribed to the key of the source, whether directly or by pattern, to the identifiers
+func (s *Server) subscribers(source string, key string, identifiers SessionMap) {
 	s.sourceSubsMutex.Lock()
 	if s.sourceSubs != nil && s.sourceSubs[source] != nil {
 		for identifier := range s.sourceSubs[source] {
-			if err := notify(identifier, update); err != nil {
-				returnErrors = append(returnErrors, err)
-			}
+			identifiers[identifier] = struct{}{}
 		}
 	}
 	s.sourceSubsMutex.Unlock()
@@ -430,18 +1172,156 @@ func (s *Server) publish(source string, key string, value []byte) error {
 	s.keySubsMutex.Lock()
 	if s.keySubs != nil && s.keySubs[source] != nil && s.keySubs[source][key] != nil {
 		for identifier := range s.keySubs[source][key] {
-			if err := notify(identifier, update); err != nil {
-				returnErrors = append(returnErrors, err)
-			}
+			identifiers[identifier] = struct{}{}
 		}
 	}
 	s.keySubsMutex.Unlock()
 
-	if len(returnErrors) > 0 {
-		return errors.New("An issue was encountered attempting to send updates to some clients")
+	s.patternsMutex.Lock()
+	s.patterns.match(source, key, func(identifier string) {
+		identifiers[identifier] = struct{}{}
+	})
+	s.patternsMutex.Unlock()
+}
+
+// isSourceListUpdate indicates whether the update describes the creation or deletion of a source, or a source
+// included in a snapshot of the source list, rather than a change to a key
+func isSourceListUpdate(u *pb.Update) bool {
+	return len(u.Key) == 0
+}
+
+// pbUpdate converts the store update to its protobuf representation
+func pbUpdate(u *store.Update) *pb.Update {
+	return &pb.Update{
+		Source:           u.Source,
+		Key:              u.Key,
+		Value:            u.Value,
+		Transaction:      u.Transaction,
+		Operation:        updateOperations[u.Operation],
+		Index:            u.Index,
+		Timestamp:        u.Timestamp,
+		PreviousValue:    u.PreviousValue,
+		PreviousRevision: u.PreviousRevision,
 	}
+}
 
-	return nil
+// GrantLease creates a lease that expires after its time-to-live unless kept alive
+func (s *Server) GrantLease(ctx context.Context, req *pb.GrantLeaseRequest) (*pb.GrantLeaseResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.GrantLease(ctx, req, s.leaderAddress)
+	}
+
+	if req.Ttl <= 0 {
+		return nil, errors.New("You must provide a positive ttl for the lease")
+	}
+
+	lease, err := s.Store.GrantLease(time.Duration(req.Ttl) * time.Millisecond)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return &pb.GrantLeaseResponse{
+		Lease: lease,
+		Ttl:   req.Ttl,
+	}, nil
+}
+
+// KeepAliveLease restarts the time-to-live of the specified lease
+func (s *Server) KeepAliveLease(ctx context.Context, req *pb.KeepAliveLeaseRequest) (*pb.KeepAliveLeaseResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.KeepAliveLease(ctx, req, s.leaderAddress)
+	}
+
+	if req.Lease == 0 {
+		return nil, errors.New("You must provide the lease you would like to keep alive")
+	}
+
+	if err := s.Store.KeepAliveLease(req.Lease); err != nil {
+		return nil, storeError(err)
+	}
+
+	ttl, err := s.Store.LeaseTTL(req.Lease)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return &pb.KeepAliveLeaseResponse{
+		Lease: req.Lease,
+		Ttl:   int64(ttl / time.Millisecond),
+	}, nil
+}
+
+// RevokeLease removes the specified lease along with every value attached to it
+func (s *Server) RevokeLease(ctx context.Context, req *pb.RevokeLeaseRequest) (*pb.RevokeLeaseResponse, error) {
+	s.initialize()
+
+	if !s.IsLeader() {
+		if s.Proxy == nil {
+			return nil, errors.New("Failed to proxy request to the leader: No proxy mechanism configured")
+		}
+		return s.Proxy.RevokeLease(ctx, req, s.leaderAddress)
+	}
+
+	if req.Lease == 0 {
+		return nil, errors.New("You must provide the lease you would like to revoke")
+	}
+
+	if err := s.Store.RevokeLease(req.Lease); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &pb.RevokeLeaseResponse{
+		Lease: req.Lease,
+	}, nil
+}
+
+// NodeStatus responds with whether this node is the leader, the raft log index it has applied, and whether it is
+// ready to accept new sessions
+func (s *Server) NodeStatus(ctx context.Context, req *pb.NodeStatusRequest) (*pb.NodeStatusResponse, error) {
+	s.initialize()
+
+	if s.Store == nil {
+		return nil, errors.New("No store is configured for this node")
+	}
+
+	return &pb.NodeStatusResponse{
+		Leader: s.IsLeader(),
+		Index:  s.Store.AppliedIndex(),
+		Ready:  !s.isDraining(),
+	}, nil
+}
+
+// storeError converts errors produced by the store into errors carrying an appropriate grpc status code
+func storeError(err error) error {
+	switch err {
+	case store.ErrRevisionMismatch:
+		return grpc.Errorf(codes.FailedPrecondition, "%s", err)
+	case store.ErrLeaseNotFound:
+		return grpc.Errorf(codes.NotFound, "%s", err)
+	case store.ErrInvalidTTL:
+		return grpc.Errorf(codes.InvalidArgument, "%s", err)
+	case store.ErrCompacted:
+		return grpc.Errorf(codes.OutOfRange, "%s", err)
+	case store.ErrStale, store.ErrTransferring:
+		return grpc.Errorf(codes.Unavailable, "%s", err)
+	case store.ErrUnknownPeer:
+		return grpc.Errorf(codes.NotFound, "%s", err)
+	case store.ErrTransferTimeout:
+		return grpc.Errorf(codes.DeadlineExceeded, "%s", err)
+	case raft.ErrNotLeader:
+		return errNotLeader
+	}
+	return err
 }
 
 // generateSessionID produces a unique session identifier for this server
@@ -469,41 +1349,84 @@ func (s *Server) generateSessionID(length int) (string, error) {
 	return session, nil
 }
 
-// addSession adds the session to the server's collection
-func (s *Server) addSession(sessionIdentifier string, listener pb.Iris_ListenServer) (*Session, error) {
+// addSession adds the session to the server's collection, unless the server is draining its sessions
+func (s *Server) addSession(sessionIdentifier string) (*Session, error) {
 	s.initialize()
 
 	s.sessionsMutex.Lock()
 	defer s.sessionsMutex.Unlock()
 
+	if s.draining {
+		return nil, ErrDraining
+	}
+
 	if s.sessions == nil {
 		s.sessions = make(map[string]*Session)
 	}
 
-	session := &Session{ID: sessionIdentifier, Listener: listener}
+	session := newSession(sessionIdentifier, s.QueueSize, s.SlowConsumer)
+	if previous, ok := s.sessions[sessionIdentifier]; ok {
+		previous.close(nil)
+	}
 	s.sessions[sessionIdentifier] = session
 
 	return session, nil
 }
 
+// listenSession returns the session to attach a listener to, adding it to the server's collection if it was not created by Connect
+func (s *Server) listenSession(sessionIdentifier string) (*Session, error) {
+	s.sessionsMutex.Lock()
+	session, ok := s.sessions[sessionIdentifier]
+	s.sessionsMutex.Unlock()
+
+	if ok {
+		return session, nil
+	}
+	return s.addSession(sessionIdentifier)
+}
+
 // removeSession removes the session from the server's collection
 func (s *Server) removeSession(sessionIdentifier string) error {
 	s.initialize()
 
+	// The subscriptions are indexed by source and key, so the session is removed from each of them
 	s.sourceSubsMutex.Lock()
-	if s.sourceSubs != nil {
-		delete(s.sourceSubs, sessionIdentifier)
+	for source, sessions := range s.sourceSubs {
+		delete(sessions, sessionIdentifier)
+		if len(sessions) == 0 {
+			delete(s.sourceSubs, source)
+		}
 	}
 	s.sourceSubsMutex.Unlock()
 
 	s.keySubsMutex.Lock()
-	if s.keySubs != nil {
-		delete(s.keySubs, sessionIdentifier)
+	for source, keys := range s.keySubs {
+		for key, sessions := range keys {
+			delete(sessions, sessionIdentifier)
+			if len(sessions) == 0 {
+				delete(keys, key)
+			}
+		}
+
+		if len(keys) == 0 {
+			delete(s.keySubs, source)
+		}
 	}
 	s.keySubsMutex.Unlock()
 
+	s.sourceListSubsMutex.Lock()
+	delete(s.sourceListSubs, sessionIdentifier)
+	s.sourceListSubsMutex.Unlock()
+
+	s.patternsMutex.Lock()
+	s.patterns.removeSession(sessionIdentifier)
+	s.patternsMutex.Unlock()
+
 	s.sessionsMutex.Lock()
 	if s.sessions != nil {
+		if session, ok := s.sessions[sessionIdentifier]; ok {
+			session.close(nil)
+		}
 		delete(s.sessions, sessionIdentifier)
 	}
 
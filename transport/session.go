@@ -0,0 +1,168 @@
+package transport
+
+import (
+	"sync"
+	"time"
+
+	"github.com/forestgiant/iris/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Policies describing how updates are handled when a session's queue is full
+const (
+	// SlowConsumerBlock waits for the session to make room in its queue, delaying the updates of every session.  The
+	// store queues the updates applied meanwhile in memory without bound, so a session that stops receiving its
+	// updates holds up every other session and grows the server's memory until it is closed.
+	SlowConsumerBlock = "block"
+
+	// SlowConsumerDropOldest discards the oldest update in the session's queue to make room for the new one
+	SlowConsumerDropOldest = "drop"
+
+	// SlowConsumerDisconnect ends the session's update stream with ErrSlowConsumer, and is applied when no policy is
+	// specified
+	SlowConsumerDisconnect = "disconnect"
+)
+
+// DefaultQueueSize is the number of updates queued for a session when the server does not specify otherwise
+const DefaultQueueSize = 1024
+
+var errSessionListening = grpc.Errorf(codes.AlreadyExists, "The session is already listening for updates")
+
+// ErrSlowConsumer ends the update stream of a session that could not keep up with its updates
+var ErrSlowConsumer = grpc.Errorf(codes.ResourceExhausted, "The session was disconnected because it could not keep up with its updates")
+
+// Session represents an server-side update stream
+type Session struct {
+	ID       string
+	Listener pb.Iris_ListenServer
+
+	mu     sync.Mutex
+	policy string
+	queue  chan *pb.Update
+	done   chan struct{}
+	sent   chan struct{} //closed once updates are no longer sent to the listener
+	once   sync.Once
+	err    error
+
+	// lease holds the session's ephemeral values once it sets one, after which the session must heartbeat
+	leaseMu   sync.Mutex
+	lease     uint64
+	heartbeat time.Time
+}
+
+// newSession returns a session that queues up to size updates until they can be sent to its listener
+func newSession(id string, size int, policy string) *Session {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	return &Session{
+		ID:     id,
+		policy: policy,
+		queue:  make(chan *pb.Update, size),
+		done:   make(chan struct{}),
+		sent:   make(chan struct{}),
+	}
+}
+
+// attach begins sending queued updates to the listener, including those queued before it was attached
+func (s *Session) attach(listener pb.Iris_ListenServer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Listener != nil {
+		return errSessionListening
+	}
+
+	s.Listener = listener
+	go s.send(listener)
+	return nil
+}
+
+// listening indicates whether a listener has been attached to the session
+func (s *Session) listening() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Listener != nil
+}
+
+// send delivers queued updates to the listener in order until the session is closed
+func (s *Session) send(listener pb.Iris_ListenServer) {
+	defer close(s.sent)
+
+	for {
+		select {
+		case update := <-s.queue:
+			if err := listener.Send(update); err != nil {
+				s.close(err)
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// enqueue adds the update to the session's queue, applying the slow consumer policy if the queue is full.
+// Until a listener is attached, the oldest updates are dropped to make room.
+func (s *Session) enqueue(update *pb.Update) {
+	select {
+	case s.queue <- update:
+		return
+	case <-s.done:
+		return
+	default:
+	}
+
+	policy := s.policy
+	if !s.listening() {
+		policy = SlowConsumerDropOldest
+	}
+
+	switch policy {
+	case SlowConsumerDropOldest:
+		for {
+			select {
+			case s.queue <- update:
+				return
+			case <-s.done:
+				return
+			default:
+			}
+
+			select {
+			case <-s.queue:
+			default:
+			}
+		}
+	case SlowConsumerBlock:
+		select {
+		case s.queue <- update:
+		case <-s.done:
+		}
+	default:
+		s.close(ErrSlowConsumer)
+	}
+}
+
+// accepts indicates whether the number of updates can be queued without dropping updates or disconnecting the session,
+// either because the queue has room for them or because the session's listener is waited for
+func (s *Session) accepts(count int) bool {
+	if count <= cap(s.queue)-len(s.queue) {
+		return true
+	}
+
+	if s.policy != SlowConsumerBlock {
+		return false
+	}
+	return s.listening()
+}
+
+// close stops the delivery of updates, recording the error that ended the session if any
+func (s *Session) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}